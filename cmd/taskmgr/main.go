@@ -1,18 +1,33 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/getsentry/sentry-go"
 
 	"taskmgr/internal/cli"
+	"taskmgr/internal/config"
 	"taskmgr/internal/display"
+	"taskmgr/internal/formats"
+	"taskmgr/internal/gitsync"
+	"taskmgr/internal/hooks"
+	"taskmgr/internal/keyagent"
+	"taskmgr/internal/mcp"
+	"taskmgr/internal/rpc"
+	"taskmgr/internal/server"
+	_ "taskmgr/internal/sqlstore"
 	"taskmgr/internal/tasks"
+	"taskmgr/internal/tui"
 )
 
 func main() {
@@ -26,14 +41,118 @@ func main() {
 	defer sentry.Flush(2 * time.Second)
 
 	cmd, args := cli.ParseArgs(os.Args[1:])
-	store := tasks.NewFileStore("tasks.json") // Updated to file-based store
+	settings, err := config.Load(config.DefaultDir)
+	if err != nil {
+		fmt.Println("Error reading configuration:", err)
+		os.Exit(1)
+	}
+	store, err := tasks.Open(settings.Store)
+	if err != nil {
+		fmt.Println("Error opening task store:", err)
+		os.Exit(1)
+	}
+	if closer, ok := store.(io.Closer); ok {
+		defer closer.Close()
+	}
+	// An encrypted task file opens with a key cached by the agent, or one
+	// derived from $TASKMGR_KEYFILE, $TASKMGR_PASSPHRASE or a typed passphrase
+	keyring := &keyagent.Keyring{
+		Agent: keyagent.Client{Path: keyagent.SocketPath()},
+		Secret: func() ([]byte, error) {
+			return keyagent.ReadSecret(os.Getenv("TASKMGR_KEYFILE"), "TASKMGR_PASSPHRASE", "Passphrase for "+tasks.StorePath(store)+": ", false)
+		},
+	}
+	if fileStore, ok := store.(*tasks.FileStore); ok {
+		fileStore.SetKeyring(keyring)
+	}
+	// Read the tasks now, so that a damaged file or a wrong passphrase is
+	// reported as such rather than as an empty list. The commands that
+	// look into or repair a damaged store go ahead.
+	switch cmd {
+	case "", "agent", "doctor", "backup":
+	default:
+		if _, err := store.List(context.Background()); err != nil {
+			fmt.Println("Error opening task store:", err)
+			if errors.Is(err, tasks.ErrCorrupt) {
+				fmt.Println("Run taskmgr doctor to see what is wrong, then taskmgr doctor --repair to recover")
+				fmt.Println("every task that can be, or taskmgr backup restore to go back to a backup.")
+			}
+			os.Exit(1)
+		}
+	}
+	// Reads bring a file saved by an older taskmgr up to date in memory;
+	// saving it that way is up to the user
+	if fileStore, ok := store.(*tasks.FileStore); ok && cmd != "migrate" {
+		if version, err := fileStore.Version(); err == nil && version < tasks.SchemaVersion {
+			fmt.Fprintf(os.Stderr, "Note: %s was saved by an older taskmgr; run taskmgr migrate --dry-run to see what migrating changes.\n", fileStore.Path())
+		}
+	}
 	manager := tasks.NewTaskManager(store)
+	// The search index is kept next to a task file, so that a search only
+	// indexes what changed since the last command
+	if path := tasks.StorePath(store); path != "" {
+		manager.SetIndexFile(path + ".index")
+	}
+	// Completed tasks are moved to an archive store next to the task store.
+	// It is opened for the commands that look into it, and for every
+	// command when tasks are archived automatically.
+	var autoArchive time.Duration
+	if settings.AutoArchive != "" {
+		if autoArchive, err = tasks.ParseAge(settings.AutoArchive); err != nil {
+			fmt.Fprintln(os.Stderr, "Warning: auto-archive disabled:", err)
+			autoArchive = 0
+		}
+	}
+	openArchive := autoArchive > 0
+	switch cmd {
+	case "archive", "unarchive", "encrypt", "decrypt", "rekey":
+		openArchive = true
+	case "list":
+		opts := cli.ParseListCommand(args)
+		openArchive = openArchive || opts.Archived || opts.All
+	}
+	var archive tasks.Store
+	if openArchive {
+		archive, err = tasks.Open(settings.ArchiveStore())
+		if err != nil {
+			fmt.Println("Error opening archive:", err)
+			os.Exit(1)
+		}
+		if closer, ok := archive.(io.Closer); ok {
+			defer closer.Close()
+		}
+		// The archive of an encrypted task file is encrypted with it
+		archiveFile, archiveIsFile := archive.(*tasks.FileStore)
+		if fileStore, ok := store.(*tasks.FileStore); ok && archiveIsFile {
+			if err := archiveFile.MatchEncryption(fileStore); err != nil {
+				fmt.Println("Error opening archive:", err)
+				os.Exit(1)
+			}
+		}
+		manager.SetArchive(archive)
+		manager.SetAutoArchive(autoArchive)
+	}
+	// Hook scripts and webhooks hear about every change; a broken
+	// configuration should not stop the task list from working
+	hookConfig, err := hooks.Load(hooks.DefaultDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: hooks disabled:", err)
+	} else {
+		hookConfig.Install(manager)
+	}
+	// Once git sync is set up, every change is committed to its repository
+	syncRepo, err := gitsync.Open(gitsync.DefaultDir)
+	if err != nil && !errors.Is(err, gitsync.ErrNotInitialized) {
+		fmt.Fprintln(os.Stderr, "Warning: git sync disabled:", err)
+	} else if syncRepo != nil {
+		syncRepo.Install(manager)
+	}
 
 	switch cmd {
 	case "add":
 		opts := cli.ParseAddCommand(args)
 		if opts.Title == "" {
-			fmt.Println("Usage: taskmgr add <title> [--priority=<low|medium|high|critical>] [--due=<date>] [--tags=<tag1,tag2,...>]")
+			fmt.Println("Usage: taskmgr add <title> [--priority=<low|medium|high|critical>] [--due=<date>] [--tags=<tag1,tag2,...>] [--project=<name>] [--notes=<text>]")
 			fmt.Println("Examples:")
 			fmt.Println("  taskmgr add \"Fix bug\" --priority=high --due=2024-01-15 --tags=work,urgent")
 			fmt.Println("  taskmgr add \"Review PR\" --priority=medium --due=tomorrow --tags=work,code-review")
@@ -41,7 +160,7 @@ func main() {
 			os.Exit(1)
 		}
 		
-		t := tasks.Task{Title: opts.Title, Priority: tasks.Medium, Tags: opts.Tags} // Default priority
+		t := tasks.Task{Title: opts.Title, Priority: tasks.Medium, Tags: opts.Tags, Project: opts.Project, Notes: opts.Notes} // Default priority
 		
 		// Parse priority if provided
 		if opts.Priority != "" {
@@ -72,26 +191,27 @@ func main() {
 		fmt.Println("Task added.")
 	case "list":
 		opts := cli.ParseListCommand(args)
-		var tasksToShow []tasks.Task
 		
-		// Apply filters
-		if opts.Priority != "" {
-			if priority, err := tasks.ParsePriority(opts.Priority); err != nil {
-				fmt.Println("Error parsing priority:", err)
+		// Apply every filter given, to the task list or to the archive
+		filter, err := opts.Filter()
+		if err != nil {
+			fmt.Println("Error parsing priority:", err)
+			os.Exit(1)
+		}
+		filtered := func(manager *tasks.TaskManager) []tasks.Task {
+			taskList, err := manager.Query(filter)
+			if err != nil {
+				fmt.Println("Error listing tasks:", err)
 				os.Exit(1)
-			} else {
-				tasksToShow = manager.ListByPriority(priority)
-			}
-		} else if opts.Tag != "" {
-			tasksToShow = manager.ListByTag(opts.Tag)
-		} else if opts.Overdue {
-			tasksToShow = manager.ListOverdue()
-		} else if opts.DueToday {
-			tasksToShow = manager.ListDueToday()
-		} else if opts.DueWithin > 0 {
-			tasksToShow = manager.ListDueWithin(opts.DueWithin)
-		} else {
-			tasksToShow = manager.List()
+			}
+			return taskList
+		}
+		var tasksToShow, archivedToShow []tasks.Task
+		if !opts.Archived {
+			tasksToShow = filtered(manager)
+		}
+		if opts.Archived || opts.All {
+			archivedToShow = filtered(tasks.NewTaskManager(archive))
 		}
 		
 		// Create display options
@@ -136,6 +256,14 @@ func main() {
 		for i, t := range tasksToShow {
 			fmt.Println(formatter.FormatTask(i, t))
 		}
+		// Archived tasks are numbered on their own, as taskmgr unarchive takes them
+		if opts.All && len(archivedToShow) > 0 {
+			fmt.Println()
+			fmt.Println("Archived:")
+		}
+		for i, t := range archivedToShow {
+			fmt.Println(formatter.FormatTask(i, t))
+		}
 	case "done":
 		if len(args) < 1 {
 			fmt.Println("Usage: taskmgr done <index>")
@@ -149,16 +277,110 @@ func main() {
 		}
 		fmt.Println("Task marked as done.")
 	case "remove":
-		if len(args) < 1 {
-			fmt.Println("Usage: taskmgr remove <index>")
+		opts := cli.ParseRemoveCommand(args)
+		if opts.Task == "" {
+			fmt.Println("Usage: taskmgr remove <index> [--force]")
+			fmt.Println("Removed tasks go to the trash; --force deletes the task for good.")
 			os.Exit(1)
 		}
-		err := manager.Remove(args[0])
+		remove := manager.Remove
+		if opts.Force {
+			remove = manager.Delete
+		}
+		err := remove(opts.Task)
 		if err != nil {
 			fmt.Println("Error removing task:", err)
 			os.Exit(1)
 		}
-		fmt.Println("Task removed.")
+		if opts.Force {
+			fmt.Println("Task deleted.")
+		} else {
+			fmt.Println("Task moved to the trash; taskmgr trash restore brings it back.")
+		}
+	case "trash":
+		opts := cli.ParseTrashCommand(args)
+		switch opts.Action {
+		case "list":
+			trash, err := manager.ListTrash()
+			if err != nil {
+				fmt.Println("Error reading the trash:", err)
+				os.Exit(1)
+			}
+			if len(trash) == 0 {
+				fmt.Println("The trash is empty.")
+				return
+			}
+			fmt.Println("Trash, most recently removed first:")
+			for i, t := range trash {
+				fmt.Printf("  %d: %s  removed %s  %s\n", i, t.ID, t.DeletedAt.Local().Format("2006-01-02 15:04"), t.Title)
+			}
+		case "restore":
+			if opts.Task == "" {
+				fmt.Println("Usage: taskmgr trash restore <index|id>")
+				fmt.Println("Indices are those of taskmgr trash list.")
+				os.Exit(1)
+			}
+			t, err := manager.Restore(opts.Task)
+			if err != nil {
+				fmt.Println("Error restoring task:", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Task %q is back in the list.\n", t.Title)
+		case "empty":
+			// Without --older-than, the whole trash is emptied
+			var olderThan time.Duration
+			if opts.OlderThan != "" {
+				if olderThan, err = tasks.ParseAge(opts.OlderThan); err != nil {
+					fmt.Println("Error parsing --older-than:", err)
+					os.Exit(1)
+				}
+			}
+			deleted, err := manager.EmptyTrash(olderThan)
+			if err != nil {
+				fmt.Println("Error emptying the trash:", err)
+				os.Exit(1)
+			}
+			if deleted == 0 {
+				fmt.Println("Nothing to delete from the trash.")
+				return
+			}
+			fmt.Printf("Deleted %s for good.\n", plural(deleted, "task"))
+		default:
+			fmt.Println("Usage: taskmgr trash [list | restore <index|id> | empty [--older-than=<age>]]")
+			os.Exit(1)
+		}
+	case "archive":
+		opts := cli.ParseArchiveCommand(args)
+		// Without --older-than, every completed task is archived
+		var olderThan time.Duration
+		if opts.OlderThan != "" {
+			if olderThan, err = tasks.ParseAge(opts.OlderThan); err != nil {
+				fmt.Println("Error parsing --older-than:", err)
+				os.Exit(1)
+			}
+		}
+		moved, err := manager.ArchiveDone(olderThan)
+		if err != nil {
+			fmt.Println("Error archiving tasks:", err)
+			os.Exit(1)
+		}
+		if moved == 0 {
+			fmt.Println("No completed tasks to archive.")
+			return
+		}
+		fmt.Printf("Archived %s to %s.\n", plural(moved, "task"), settings.ArchiveStore())
+	case "unarchive":
+		if len(args) < 1 {
+			fmt.Println("Usage: taskmgr unarchive <index|id>")
+			fmt.Println("Indices are those of taskmgr list --archived.")
+			os.Exit(1)
+		}
+		t, err := manager.Unarchive(args[0])
+		if err != nil {
+			fmt.Println("Error unarchiving task:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Task %q is back in the list.\n", t.Title)
 	case "undodone":
 		if len(args) < 1 {
 			fmt.Println("Usage: taskmgr undodone <index>")
@@ -170,6 +392,31 @@ func main() {
 			os.Exit(1)
 		}
 		fmt.Println("Task marked as not done.")
+	case "log":
+		if len(args) < 1 {
+			fmt.Println("Usage: taskmgr log <index|id>")
+			os.Exit(1)
+		}
+		task, changes, err := manager.History(args[0])
+		if err != nil {
+			fmt.Println("Error reading change log:", err)
+			os.Exit(1)
+		}
+		displayOpts := display.DisplayOptions{
+			ShowColors:   display.IsColorSupported(),
+			ShowIcons:    true,
+			ColorScheme:  display.DefaultColorScheme,
+		}
+		for _, arg := range args[1:] {
+			if arg == "--no-color" {
+				displayOpts.ShowColors = false
+			}
+			if arg == "--no-icons" {
+				displayOpts.ShowIcons = false
+			}
+		}
+		formatter := display.NewTaskFormatter(displayOpts)
+		fmt.Println(formatter.FormatChangeLog(task, changes))
 	case "find":
 		if len(args) < 1 {
 			fmt.Println("Usage: taskmgr find <title>")
@@ -211,6 +458,51 @@ func main() {
 			os.Exit(1)
 		}
 		fmt.Println("All tasks marked as done.")
+	case "search":
+		opts := cli.ParseSearchCommand(args)
+		results, err := manager.Search(opts.Query)
+		if errors.Is(err, tasks.ErrEmptyQuery) {
+			fmt.Println("Usage: taskmgr search <query> [--limit=<n>]")
+			fmt.Println("Finds tasks by the words in their title, tags, description and notes, best match first.")
+			fmt.Println("Examples:")
+			fmt.Println("  taskmgr search deploy website")
+			fmt.Println("  taskmgr search \"release notes\"")
+			fmt.Println("  taskmgr search deploy*")
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Println("Error searching tasks:", err)
+			os.Exit(1)
+		}
+		if len(results) == 0 {
+			fmt.Println("No tasks match.")
+			return
+		}
+		if opts.Limit > 0 && len(results) > opts.Limit {
+			results = results[:opts.Limit]
+		}
+		formatter := display.NewTaskFormatter(display.DisplayOptions{
+			ShowColors:   display.IsColorSupported(),
+			ShowIcons:    true,
+			ShowTags:     true,
+			ShowDueDate:  true,
+			ShowPriority: true,
+			ColorScheme:  display.DefaultColorScheme,
+		})
+		// Each task keeps its list index, so that it can be acted on
+		for _, r := range results {
+			fmt.Println(formatter.FormatTask(r.Index, r.Task))
+		}
+	case "note":
+		if len(args) < 2 {
+			fmt.Println("Usage: taskmgr note <index> <text>")
+			os.Exit(1)
+		}
+		if err := manager.AddNote(args[0], strings.Join(args[1:], " ")); err != nil {
+			fmt.Println("Error adding note:", err)
+			os.Exit(1)
+		}
+		fmt.Println("Note added to task.")
 	case "findbydesc":
 		if len(args) < 1 {
 			fmt.Println("Usage: taskmgr findbydesc <description>")
@@ -229,7 +521,7 @@ func main() {
 			fmt.Printf("%d: [%s] %s\n", i, done, t.Title)
 		}
 	case "stats":
-		tasks := manager.List()
+		taskList := manager.List()
 		displayOpts := display.DisplayOptions{
 			ShowColors:   display.IsColorSupported(),
 			ShowIcons:    true,
@@ -241,11 +533,69 @@ func main() {
 			if arg == "--no-color" {
 				displayOpts.ShowColors = false
 			}
+			if arg == "--no-icons" {
+				displayOpts.ShowIcons = false
+			}
 		}
 		
 		progressFormatter := display.NewProgressFormatter(displayOpts)
-		stats := progressFormatter.CalculateStats(tasks)
-		fmt.Println(progressFormatter.FormatDetailedStats(stats))
+		opts := cli.ParseStatsCommand(args)
+		if !opts.History {
+			stats := progressFormatter.CalculateStats(taskList)
+			fmt.Println(progressFormatter.FormatDetailedStats(stats))
+			return
+		}
+		
+		bucket, err := display.ParseHistoryBucket(opts.By)
+		if err != nil {
+			fmt.Println("Error parsing history period:", err)
+			os.Exit(1)
+		}
+		
+		to := time.Now()
+		if opts.To != "" {
+			parsed, err := tasks.ParseDueDate(opts.To)
+			if err != nil {
+				fmt.Println("Error parsing --to date:", err)
+				os.Exit(1)
+			}
+			to = *parsed
+		}
+		from := to.AddDate(0, 0, -13)
+		if bucket == display.BucketWeek {
+			from = to.AddDate(0, 0, -7*11)
+		}
+		if opts.From != "" {
+			parsed, err := tasks.ParseDueDate(opts.From)
+			if err != nil {
+				fmt.Println("Error parsing --from date:", err)
+				os.Exit(1)
+			}
+			from = *parsed
+		}
+		if from.After(to) {
+			fmt.Println("Error: --from must not be after --to")
+			os.Exit(1)
+		}
+		
+		// Restrict to a tag or project for the burndown
+		scope := ""
+		if opts.Tag != "" {
+			taskList = manager.ListByTag(opts.Tag)
+			scope = fmt.Sprintf("Burndown for tag %q", opts.Tag)
+		} else if opts.Project != "" {
+			taskList = manager.ListByProject(opts.Project)
+			scope = fmt.Sprintf("Burndown for project %q", opts.Project)
+		}
+		
+		points := display.CalculateHistory(taskList, from, to, bucket)
+		fmt.Println(progressFormatter.FormatHistory(points, bucket))
+		if scope != "" {
+			fmt.Println()
+			fmt.Println(progressFormatter.FormatBurndown(scope, points))
+		}
+		fmt.Println()
+		fmt.Println(progressFormatter.FormatDetailedStats(progressFormatter.CalculateStats(taskList)))
 	case "tags":
 		allTags := manager.GetAllTags()
 		if len(allTags) == 0 {
@@ -278,6 +628,704 @@ func main() {
 			os.Exit(1)
 		}
 		fmt.Printf("Tag '%s' removed from task.\n", args[1])
+	case "board":
+		opts := cli.ParseBoardCommand(args)
+		group, err := display.ParseBoardGroup(opts.GroupBy)
+		if err != nil {
+			fmt.Println("Error parsing board grouping:", err)
+			os.Exit(1)
+		}
+		
+		displayOpts := display.DisplayOptions{
+			ShowColors:   display.IsColorSupported(),
+			ShowIcons:    true,
+			ShowTags:     true,
+			ShowDueDate:  true,
+			ShowPriority: true,
+			ColorScheme:  display.DefaultColorScheme,
+		}
+		for _, arg := range args {
+			if arg == "--no-color" {
+				displayOpts.ShowColors = false
+			}
+			if arg == "--no-icons" {
+				displayOpts.ShowIcons = false
+			}
+		}
+		
+		if opts.Interactive {
+			boardOpts := tui.BoardOptions{Display: displayOpts, Group: group, Tags: opts.Tags, WatchFile: tasks.StorePath(store)}
+			if err := tui.RunBoard(manager, boardOpts); err != nil {
+				fmt.Println("Error running board:", err)
+				os.Exit(1)
+			}
+			return
+		}
+		
+		columns, err := display.BuildColumns(manager.List(), group, opts.Tags)
+		if err != nil {
+			fmt.Println("Error building board:", err)
+			os.Exit(1)
+		}
+		width := opts.Width
+		if width <= 0 {
+			width = display.TerminalWidth()
+		}
+		fmt.Println(display.NewBoardFormatter(displayOpts).FormatBoard(columns, width))
+	case "calendar":
+		opts := cli.ParseCalendarCommand(args)
+		month := time.Now()
+		if opts.Month != "" {
+			parsed, err := time.Parse("2006-01", opts.Month)
+			if err != nil {
+				fmt.Println("Error parsing month (use YYYY-MM):", err)
+				os.Exit(1)
+			}
+			month = parsed
+		}
+		
+		displayOpts := display.DisplayOptions{
+			ShowColors:  display.IsColorSupported(),
+			ShowIcons:   true,
+			ColorScheme: display.DefaultColorScheme,
+		}
+		for _, arg := range args {
+			if arg == "--no-color" {
+				displayOpts.ShowColors = false
+			}
+			if arg == "--no-icons" {
+				displayOpts.ShowIcons = false
+			}
+		}
+		
+		formatter := display.NewCalendarFormatter(displayOpts)
+		fmt.Println(formatter.FormatMonth(manager.List(), month, time.Now()))
+	case "agenda":
+		opts := cli.ParseAgendaCommand(args)
+		displayOpts := display.DisplayOptions{
+			ShowColors:   display.IsColorSupported(),
+			ShowIcons:    true,
+			ShowTags:     true,
+			ShowDueDate:  true,
+			ShowPriority: true,
+			ColorScheme:  display.DefaultColorScheme,
+		}
+		for _, arg := range args {
+			if arg == "--no-color" {
+				displayOpts.ShowColors = false
+			}
+			if arg == "--no-icons" {
+				displayOpts.ShowIcons = false
+			}
+		}
+		
+		formatter := display.NewCalendarFormatter(displayOpts)
+		fmt.Println(formatter.FormatAgenda(manager.List(), time.Now(), opts.Days))
+	case "import":
+		opts := cli.ParseImportCommand(args)
+		format, err := formats.ForFile(opts.Format, opts.File)
+		if err == nil {
+			format, err = formats.Configure(format, formats.Options{Map: opts.Map})
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		
+		input := os.Stdin
+		if opts.File != "" && opts.File != "-" {
+			f, err := os.Open(opts.File)
+			if err != nil {
+				fmt.Println("Error opening import file:", err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			input = f
+		}
+		
+		imported, fields, err := formats.Decode(format, input)
+		if err != nil {
+			fmt.Println("Error reading tasks:", err)
+			os.Exit(1)
+		}
+		if opts.DryRun {
+			formatter := display.NewTaskFormatter(display.DisplayOptions{
+				ShowPriority: true,
+				ShowTags:     true,
+				ShowDueDate:  true,
+			})
+			added, updated := 0, 0
+			for i, target := range manager.PreviewImport(imported) {
+				action := "add   "
+				if target >= 0 {
+					action = fmt.Sprintf("update %d:", target)
+					updated++
+				} else {
+					added++
+				}
+				fmt.Println(action, formatter.FormatTask(i, imported[i]))
+			}
+			fmt.Printf("Would import %d tasks (%d added, %d updated). Nothing was written.\n", len(imported), added, updated)
+			return
+		}
+		
+		added, updated, err := manager.Import(imported, fields...)
+		if err != nil {
+			fmt.Println("Error importing tasks:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Imported %d tasks (%d added, %d updated).\n", len(imported), added, updated)
+	case "export":
+		opts := cli.ParseExportCommand(args)
+		format, err := formats.ForFile(opts.Format, opts.File)
+		if err == nil {
+			format, err = formats.Configure(format, formats.Options{Columns: opts.Columns, GroupBy: opts.GroupBy})
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		
+		list, err := manager.Load()
+		if err != nil {
+			fmt.Println("Error reading tasks:", err)
+			os.Exit(1)
+		}
+		if opts.File == "" || opts.File == "-" {
+			if err := format.Encode(os.Stdout, list); err != nil {
+				fmt.Println("Error exporting tasks:", err)
+				os.Exit(1)
+			}
+			return
+		}
+		
+		// Creating the file empties it, which must not happen to a file
+		// tasks are kept in
+		storePath := tasks.StorePath(store)
+		if storePath == "" {
+			storePath = storeURLPath(settings.Store)
+		}
+		for _, kept := range []string{storePath, storeURLPath(settings.ArchiveStore())} {
+			if kept == "" {
+				continue
+			}
+			for _, file := range []string{kept, kept + ".log", kept + ".index"} {
+				if sameFile(opts.File, file) {
+					fmt.Printf("Error: %s is where taskmgr keeps tasks; export to another file\n", opts.File)
+					os.Exit(1)
+				}
+			}
+		}
+		f, err := os.Create(opts.File)
+		if err != nil {
+			fmt.Println("Error creating export file:", err)
+			os.Exit(1)
+		}
+		if err := format.Encode(f, list); err != nil {
+			f.Close()
+			fmt.Println("Error exporting tasks:", err)
+			os.Exit(1)
+		}
+		if err := f.Close(); err != nil {
+			fmt.Println("Error exporting tasks:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Exported %d tasks to %s.\n", len(list), opts.File)
+	case "serve":
+		opts := cli.ParseServeCommand(args)
+		fmt.Printf("Serving tasks on http://%s (press Ctrl+C to stop)\n", opts.Addr)
+		// Requests must name the server, so that web pages cannot reach it
+		// under a name of their own
+		srv := server.New(manager)
+		if host, _, err := net.SplitHostPort(opts.Addr); err == nil && host != "" {
+			srv.AllowHost(host)
+		}
+		if err := http.ListenAndServe(opts.Addr, srv); err != nil {
+			fmt.Println("Error running server:", err)
+			os.Exit(1)
+		}
+	case "mcp":
+		// Standard output carries the protocol, so errors go to standard error
+		if err := mcp.New(manager).Serve(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "Error running MCP server:", err)
+			os.Exit(1)
+		}
+	case "rpc":
+		// A long-running process keeps the parsed tasks until the file changes
+		rpcManager := manager
+		if fileStore, ok := store.(*tasks.FileStore); ok {
+			cached := tasks.NewCachedFileStore(fileStore.Path())
+			cached.SetKeyring(keyring)
+			cached.SetBackupPolicy(fileStore.BackupPolicy())
+			rpcManager = tasks.NewTaskManager(cached)
+			if archive != nil {
+				rpcManager.SetArchive(archive)
+				rpcManager.SetAutoArchive(autoArchive)
+			}
+			if hookConfig != nil {
+				hookConfig.Install(rpcManager)
+			}
+			if syncRepo != nil {
+				syncRepo.Install(rpcManager)
+			}
+		}
+		if err := rpc.New(rpcManager, rpc.Options{WatchFile: tasks.StorePath(store)}).Serve(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "Error running JSON-RPC server:", err)
+			os.Exit(1)
+		}
+	case "migrate":
+		opts := cli.ParseMigrateCommand(args)
+		fileStore, isFile := store.(*tasks.FileStore)
+		if opts.To == "" && isFile {
+			migrate := fileStore.Migrate
+			if opts.DryRun {
+				migrate = fileStore.PlanMigration
+			}
+			plan, err := migrate()
+			if err != nil {
+				fmt.Println("Error migrating tasks:", err)
+				os.Exit(1)
+			}
+			if plan.From == plan.To {
+				fmt.Printf("%s is at schema version %d; nothing to migrate.\n", fileStore.Path(), plan.To)
+				return
+			}
+			verb := "Migrated"
+			if opts.DryRun {
+				verb = "Migrating"
+			}
+			fmt.Printf("%s %s from schema version %d to %d:\n", verb, fileStore.Path(), plan.From, plan.To)
+			for _, step := range plan.Steps {
+				fmt.Printf("  %d. %s: %s changed\n", step.Migration.Version, step.Migration.Description, plural(len(step.Changes), "task"))
+				for _, c := range step.Changes {
+					fmt.Printf("       %s: %s\n", c.Title, strings.Join(c.Fields, ", "))
+				}
+			}
+			if opts.DryRun {
+				fmt.Println("Nothing was written; run taskmgr migrate to apply these changes.")
+			} else {
+				fmt.Println("The file as it was is kept as a backup (taskmgr backup list).")
+			}
+			return
+		}
+		if opts.To == "" {
+			fmt.Println("Usage: taskmgr migrate [--dry-run] [--to=<sqlite|journal|crdt|file|store URL>]")
+			fmt.Println("Without --to, a task file is brought up to the current schema version.")
+			fmt.Println("Examples:")
+			fmt.Println("  taskmgr migrate --dry-run")
+			fmt.Println("  taskmgr migrate --to=sqlite")
+			fmt.Println("  taskmgr migrate --to=sqlite:///srv/team/tasks.db")
+			os.Exit(1)
+		}
+		target := config.StoreURL(opts.To)
+		if target == settings.Store {
+			fmt.Println("Error: tasks are already kept in", target)
+			os.Exit(1)
+		}
+		if opts.DryRun {
+			fmt.Printf("Would copy %s from %s to %s and keep tasks there from then on.\n", plural(len(manager.List()), "task"), settings.Store, target)
+			fmt.Println("Nothing was written; run the command without --dry-run to migrate.")
+			return
+		}
+		destination, err := tasks.Open(target)
+		if err != nil {
+			fmt.Println("Error opening store:", err)
+			os.Exit(1)
+		}
+		copied, err := tasks.Copy(context.Background(), destination, store)
+		if closer, ok := destination.(io.Closer); ok {
+			closer.Close()
+		}
+		if err != nil {
+			fmt.Println("Error copying tasks:", err)
+			os.Exit(1)
+		}
+		source := settings.Store
+		settings.Store = target
+		if err := config.Save(config.DefaultDir, settings); err != nil {
+			fmt.Println("Error saving configuration:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Copied %d tasks from %s to %s.\n", copied, source, target)
+		fmt.Printf("Tasks are now kept in %s; %s was left as it was.\n", target, source)
+		if os.Getenv("TASKMGR_STORE") != "" {
+			fmt.Println("Note: $TASKMGR_STORE is set and still takes precedence.")
+		}
+	case "encrypt", "decrypt", "rekey":
+		opts := cli.ParseKeyCommand(args)
+		fileStore, ok := store.(*tasks.FileStore)
+		if !ok {
+			fmt.Printf("Error: tasks are kept in %s; only file:// stores can be encrypted\n", settings.Store)
+			os.Exit(1)
+		}
+		encrypted, err := fileStore.Encrypted()
+		if err != nil {
+			fmt.Println("Error reading task file:", err)
+			os.Exit(1)
+		}
+		if cmd == "encrypt" && encrypted {
+			fmt.Printf("Error: %s is already encrypted; use taskmgr rekey to change its key\n", fileStore.Path())
+			os.Exit(1)
+		}
+		if cmd != "encrypt" && !encrypted {
+			fmt.Printf("Error: %s is not encrypted\n", fileStore.Path())
+			os.Exit(1)
+		}
+		// The archive, if it is a file too, follows the task file
+		archiveFile, _ := archive.(*tasks.FileStore)
+		if cmd == "decrypt" {
+			if err := fileStore.SetEncryption(nil, nil); err != nil {
+				fmt.Println("Error decrypting tasks:", err)
+				os.Exit(1)
+			}
+			if archiveFile != nil {
+				if err := archiveFile.MatchEncryption(fileStore); err != nil {
+					fmt.Println("Error decrypting the archive:", err)
+					os.Exit(1)
+				}
+			}
+			fmt.Printf("Decrypted %s, its change log, its backups and its archive.\n", fileStore.Path())
+			return
+		}
+		// A new key for encrypt; for rekey the current one opened the file above
+		env := "TASKMGR_PASSPHRASE"
+		if cmd == "rekey" {
+			env = "TASKMGR_NEW_PASSPHRASE"
+		}
+		secret, err := keyagent.ReadSecret(opts.Keyfile, env, "New passphrase: ", true)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		params, err := tasks.NewKeyParams()
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		key, err := tasks.DeriveKey(secret, params)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if err := fileStore.SetEncryption(&params, key); err != nil {
+			fmt.Println("Error encrypting tasks:", err)
+			os.Exit(1)
+		}
+		// The search index holds the tasks' words in plain text
+		os.Remove(fileStore.Path() + ".index")
+		if archiveFile != nil {
+			if err := archiveFile.MatchEncryption(fileStore); err != nil {
+				fmt.Println("Error encrypting the archive:", err)
+				os.Exit(1)
+			}
+		}
+		keyring.Unlocked(params, key)
+		if cmd == "rekey" {
+			fmt.Printf("Changed the key of %s, its change log, its backups and its archive.\n", fileStore.Path())
+		} else {
+			fmt.Printf("Encrypted %s, its change log, its backups and its archive.\n", fileStore.Path())
+		}
+		if opts.Keyfile != "" {
+			fmt.Printf("Set $TASKMGR_KEYFILE=%s, or run taskmgr agent, so that later commands can open it.\n", opts.Keyfile)
+		}
+		if syncRepo != nil {
+			fmt.Printf("Note: the git sync copy in %s is not encrypted.\n", gitsync.DefaultDir)
+		}
+		if hookConfig != nil && (len(hookConfig.Scripts) > 0 || len(hookConfig.Webhooks.Hooks) > 0) {
+			fmt.Println("Note: hook scripts and webhooks are still given tasks in plain text, and webhook")
+			fmt.Println("deliveries waiting in the outbox are kept in plain text.")
+		}
+	case "agent":
+		opts := cli.ParseAgentCommand(args)
+		client := keyagent.Client{Path: keyagent.SocketPath()}
+		if opts.Lock {
+			if err := client.Lock(); err != nil {
+				fmt.Println("Error: no key agent is running at", client.Path)
+				os.Exit(1)
+			}
+			fmt.Println("The key agent forgot every key.")
+			return
+		}
+		ttl := keyagent.DefaultTTL
+		if opts.TTL != "" {
+			if ttl, err = time.ParseDuration(opts.TTL); err != nil || ttl <= 0 {
+				fmt.Printf("Error: invalid --ttl %q; use a duration such as 30m or 8h\n", opts.TTL)
+				os.Exit(1)
+			}
+		}
+		listener, err := keyagent.Listen(client.Path)
+		if err != nil {
+			fmt.Println("Error starting key agent:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Caching keys for %v at %s (press Ctrl+C to stop)\n", ttl, client.Path)
+		if err := keyagent.New(ttl).Serve(listener); err != nil {
+			fmt.Println("Error running key agent:", err)
+			os.Exit(1)
+		}
+	case "doctor":
+		opts := cli.ParseDoctorCommand(args)
+		fileStore, ok := store.(*tasks.FileStore)
+		if !ok {
+			fmt.Printf("Error: tasks are kept in %s; taskmgr doctor checks file:// stores\n", settings.Store)
+			os.Exit(1)
+		}
+		check := fileStore.Check
+		if opts.Repair {
+			check = fileStore.Repair
+		}
+		d, err := check()
+		if err != nil {
+			fmt.Println("Error reading task file:", err)
+			os.Exit(1)
+		}
+		if len(d.Problems) == 0 {
+			fmt.Printf("%s is healthy: %s, schema version %d.\n", fileStore.Path(), plural(len(d.Tasks), "task"), d.Version)
+			return
+		}
+		fmt.Printf("Found %s in %s:\n", plural(len(d.Problems), "problem"), fileStore.Path())
+		for _, p := range d.Problems {
+			fmt.Println("  " + p.String())
+		}
+		if !opts.Repair {
+			fmt.Printf("Run taskmgr doctor --repair to fix them, keeping %s.\n", plural(len(d.Tasks), "task"))
+			os.Exit(1)
+		}
+		fmt.Printf("Repaired %s, keeping %s; the damaged file is kept as a backup (taskmgr backup list).\n", fileStore.Path(), plural(len(d.Tasks), "task"))
+	case "backup":
+		opts := cli.ParseBackupCommand(args)
+		fileStore, ok := store.(*tasks.FileStore)
+		if !ok {
+			fmt.Printf("Error: tasks are kept in %s; only file:// stores keep backups\n", settings.Store)
+			os.Exit(1)
+		}
+		if opts.Action == "list" {
+			backups, err := fileStore.Backups()
+			if err != nil {
+				fmt.Println("Error reading backups:", err)
+				os.Exit(1)
+			}
+			if len(backups) == 0 {
+				fmt.Printf("No backups of %s yet; one is taken before every change.\n", fileStore.Path())
+				return
+			}
+			fmt.Printf("Backups of %s, newest first:\n", fileStore.Path())
+			for _, b := range backups {
+				list, err := fileStore.ReadBackup(b)
+				if err != nil {
+					fmt.Printf("  %s  %s  unreadable: %v\n", b.ID, b.Time.Local().Format("2006-01-02 15:04:05"), err)
+					continue
+				}
+				fmt.Printf("  %s  %s  %s\n", b.ID, b.Time.Local().Format("2006-01-02 15:04:05"), plural(len(list), "task"))
+			}
+			return
+		}
+		if (opts.Action != "restore" && opts.Action != "diff") || opts.Backup == "" {
+			fmt.Println("Usage: taskmgr backup [list | restore <timestamp> | diff <timestamp>]")
+			fmt.Println("A unique prefix of a timestamp from taskmgr backup list is enough.")
+			os.Exit(1)
+		}
+		b, err := fileStore.FindBackup(opts.Backup)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if opts.Action == "restore" {
+			before, err := fileStore.ReadBackup(b)
+			if err != nil {
+				fmt.Println("Error reading backup:", err)
+				os.Exit(1)
+			}
+			added, updated, removed, err := manager.Replace(before)
+			if errors.Is(err, tasks.ErrCorrupt) {
+				// A damaged file cannot be compared with the backup, so it
+				// is written over and kept as a backup instead
+				if err := fileStore.RestoreBackup(b); err != nil {
+					fmt.Println("Error restoring backup:", err)
+					os.Exit(1)
+				}
+				fmt.Printf("Restored the tasks as of %s over the damaged task file.\n", b.Time.Local().Format("2006-01-02 15:04:05"))
+				fmt.Println("The damaged file was backed up as well.")
+				return
+			}
+			if err != nil {
+				fmt.Println("Error restoring backup:", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Restored the tasks as of %s: %d added, %d updated, %d removed.\n",
+				b.Time.Local().Format("2006-01-02 15:04:05"), added, updated, removed)
+			fmt.Println("The tasks before the restore were backed up as well.")
+			return
+		}
+		diffs, err := fileStore.DiffBackup(b)
+		if err != nil {
+			fmt.Println("Error reading backup:", err)
+			os.Exit(1)
+		}
+		if len(diffs) == 0 {
+			fmt.Printf("No changes since %s.\n", b.Time.Local().Format("2006-01-02 15:04:05"))
+			return
+		}
+		fmt.Printf("Changes since %s:\n", b.Time.Local().Format("2006-01-02 15:04:05"))
+		for _, d := range diffs {
+			switch d.Kind {
+			case "added":
+				fmt.Printf("  + %s (added)\n", d.Task.Title)
+			case "removed":
+				fmt.Printf("  - %s (removed)\n", d.Task.Title)
+			case "trashed":
+				fmt.Printf("  - %s (moved to the trash)\n", d.Task.Title)
+			case "restored":
+				fmt.Printf("  + %s (restored from the trash)\n", d.Task.Title)
+			default:
+				var fields []string
+				for _, c := range d.Changes {
+					fields = append(fields, c.Field)
+				}
+				fmt.Printf("  ~ %s: %s\n", d.Task.Title, strings.Join(fields, ", "))
+			}
+		}
+	case "merge":
+		if len(args) < 1 {
+			fmt.Println("Usage: taskmgr merge <crdt store URL or file>")
+			fmt.Println("Examples:")
+			fmt.Println("  taskmgr merge /media/usb/tasks.crdt")
+			fmt.Println("  taskmgr merge crdt://../laptop/tasks.crdt")
+			os.Exit(1)
+		}
+		local, ok := store.(*tasks.CRDTStore)
+		if !ok {
+			fmt.Printf("Error: tasks are kept in %s; merging needs a crdt store (taskmgr migrate --to=crdt)\n", settings.Store)
+			os.Exit(1)
+		}
+		source := args[0]
+		if !strings.Contains(source, "://") {
+			source = "crdt://" + source
+		}
+		opened, err := tasks.Open(source)
+		if err != nil {
+			fmt.Println("Error opening store:", err)
+			os.Exit(1)
+		}
+		other, ok := opened.(*tasks.CRDTStore)
+		if !ok {
+			fmt.Printf("Error: %s is not a crdt store\n", source)
+			os.Exit(1)
+		}
+		if _, err := os.Stat(other.Path()); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		merged, err := local.Merge(context.Background(), other)
+		if err != nil {
+			fmt.Println("Error merging tasks:", err)
+			os.Exit(1)
+		}
+		if merged == 0 {
+			fmt.Println("Already have every change in", source)
+			return
+		}
+		fmt.Printf("Merged %d field changes from %s; %d tasks now.\n", merged, source, len(manager.List()))
+	case "sync":
+		opts := cli.ParseSyncCommand(args)
+		if opts.Init {
+			repo, err := gitsync.Init(gitsync.DefaultDir, opts.Branch, opts.Remote)
+			if err != nil {
+				fmt.Println("Error setting up git sync:", err)
+				os.Exit(1)
+			}
+			if _, err := repo.Commit(manager.List()); err != nil {
+				fmt.Println("Error committing tasks:", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Tasks are committed to %s after every change.\n", gitsync.DefaultDir)
+			if remote := repo.RemoteURL(); remote != "" {
+				fmt.Printf("Run taskmgr sync to pull from and push to %s.\n", remote)
+			} else {
+				fmt.Println("Run taskmgr sync init --remote=<url> to share them.")
+			}
+			return
+		}
+		if syncRepo == nil {
+			fmt.Println("Error:", gitsync.ErrNotInitialized)
+			os.Exit(1)
+		}
+		result, err := syncRepo.Sync(manager)
+		if err != nil {
+			fmt.Println("Error syncing tasks:", err)
+			os.Exit(1)
+		}
+		if result.Pulled {
+			fmt.Printf("Pulled changes: %d added, %d updated, %d removed.\n", result.Added, result.Updated, result.Removed)
+		}
+		for _, c := range result.Conflicts {
+			fmt.Println("  Conflict:", c)
+		}
+		if result.Pushed {
+			fmt.Println("Pushed local changes to", syncRepo.RemoteURL())
+		}
+		if !result.Pulled && !result.Pushed {
+			fmt.Println("Already in sync with", syncRepo.RemoteURL())
+		}
+	case "hooks":
+		if hookConfig == nil {
+			os.Exit(1)
+		}
+		if len(args) > 0 && args[0] == "retry" {
+			delivered, pending, err := hookConfig.Webhooks.Retry()
+			if err != nil {
+				fmt.Println("Error retrying webhook deliveries:", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Delivered %d queued webhook deliveries, %d still queued.\n", delivered, len(pending))
+			for _, entry := range pending {
+				fmt.Printf("  %s %s: %d attempts, %s\n", entry.Event, entry.URL, entry.Attempts, entry.LastError)
+			}
+			return
+		}
+		
+		if len(hookConfig.Scripts) == 0 {
+			fmt.Printf("No hook scripts in %s.\n", filepath.Join(hooks.DefaultDir, "hooks"))
+		}
+		for _, script := range hookConfig.Scripts {
+			fmt.Printf("  %-16s %s\n", script.Name(), script.Path)
+		}
+		if len(hookConfig.Webhooks.Hooks) == 0 {
+			fmt.Printf("No webhooks in %s.\n", filepath.Join(hooks.DefaultDir, "webhooks.json"))
+		}
+		for _, webhook := range hookConfig.Webhooks.Hooks {
+			events := "all events"
+			if len(webhook.Events) > 0 {
+				events = strings.Join(webhook.Events, ", ")
+			}
+			fmt.Printf("  webhook          %s (%s)\n", webhook.URL, events)
+		}
+		if pending, err := hookConfig.Webhooks.Pending(); err == nil && len(pending) > 0 {
+			fmt.Printf("%d webhook deliveries are queued; run 'taskmgr hooks retry' to send them.\n", len(pending))
+		}
+	case "tui":
+		displayOpts := display.DisplayOptions{
+			ShowColors:   display.IsColorSupported(),
+			ShowIcons:    true,
+			ShowTags:     true,
+			ShowDueDate:  true,
+			ShowPriority: true,
+			ColorScheme:  display.DefaultColorScheme,
+		}
+		for _, arg := range args {
+			if arg == "--no-color" {
+				displayOpts.ShowColors = false
+			}
+			if arg == "--no-icons" {
+				displayOpts.ShowIcons = false
+			}
+		}
+		
+		err := tui.Run(manager, tui.Options{Display: displayOpts, WatchFile: tasks.StorePath(store)})
+		if err != nil {
+			fmt.Println("Error running interactive interface:", err)
+			os.Exit(1)
+		}
 	case "error":
 		// Trigger an error to test sentry.
 		err := errors.New("test error. create gh issue?")
@@ -285,33 +1333,106 @@ func main() {
 	default:
 		fmt.Println("Usage: taskmgr [command] ...")
 		fmt.Println("Available commands:")
-		fmt.Println("  add <title> [--priority=<low|medium|high|critical>] [--due=<date>] [--tags=<tag1,tag2,...>]")
-		fmt.Println("                         - Add a new task with optional priority, due date, and tags")
+		fmt.Println("  add <title> [--priority=<low|medium|high|critical>] [--due=<date>] [--tags=<tag1,tag2,...>] [--project=<name>]")
+		fmt.Println("      [--notes=<text>]   - Add a new task with optional priority, due date, tags, project, and notes")
 		fmt.Println("  list [filters] [options] - List tasks with optional filters and formatting")
 		fmt.Println("    Filters:")
 		fmt.Println("      --priority=<priority>  - Filter by priority level")
 		fmt.Println("      --tag=<tag>            - Filter by tag")
+		fmt.Println("      --project=<name>       - Filter by project")
 		fmt.Println("      --overdue              - Show only overdue tasks")
 		fmt.Println("      --due-today            - Show tasks due today")
 		fmt.Println("      --due-within=<days>    - Show tasks due within N days")
+		fmt.Println("      --archived             - Show archived tasks instead")
+		fmt.Println("      --all                  - Show archived tasks after the others")
 		fmt.Println("    Display Options:")
 		fmt.Println("      --table                - Display in table format")
 		fmt.Println("      --no-color             - Disable colored output")
 		fmt.Println("      --no-icons             - Disable emoji icons")
 		fmt.Println("      --minimal              - Minimal output (no colors, icons, or extra info)")
 		fmt.Println("  stats [--no-color]      - Show progress statistics and task breakdown")
+		fmt.Println("  stats --history [--by=<day|week>] [--from=<date>] [--to=<date>] [--tag=<tag>|--project=<name>]")
+		fmt.Println("                         - Chart created vs. completed tasks; --tag/--project adds a burndown")
+		fmt.Println("  board [--by=<status|priority|project|tag>] [--tags=<t1,t2,...>] [--width=<n>] [--interactive]")
+		fmt.Println("                         - Show tasks as kanban columns; --interactive moves cards with H/L")
+		fmt.Println("  calendar [--month=YYYY-MM] - Month grid with the number of tasks due each day")
+		fmt.Println("  agenda [--days=<n>]      - Tasks due in the next n days (default 7), grouped by day")
+		fmt.Println("  tui [--no-color] [--no-icons]")
+		fmt.Println("                         - Full-screen interactive interface (press q to quit)")
+		fmt.Println("  import [--format=<name>] [--map=<field:Column,...>] [--dry-run] [file]")
+		fmt.Println("                         - Add tasks from a file, or standard input (formats: " + strings.Join(formats.Names(), ", ") + ")")
+		fmt.Println("                           Tasks whose id matches an existing task update it;")
+		fmt.Println("                           --dry-run previews the result, --map picks csv columns")
+		fmt.Println("  export [--format=<name>] [--columns=<field,...>] [--by=<project|tag>] [file]")
+		fmt.Println("                         - Write all tasks to a file, or standard output;")
+		fmt.Println("                           --columns picks csv fields, --by groups markdown checklists")
+		fmt.Println("  serve [--addr=<host:port>] - Serve tasks as a JSON REST API (default 127.0.0.1:8080)")
+		fmt.Println("                           Changes must be sent as application/json, to the host in --addr")
+		fmt.Println("  mcp                      - Run a Model Context Protocol server on standard input and output")
+		fmt.Println("  rpc                      - Serve line-delimited JSON-RPC 2.0 on standard input and output")
+		fmt.Println("                           for editor integrations; sends tasksChanged when the file changes")
+		fmt.Println("  migrate [--dry-run]      - Bring the task file up to the current schema version")
+		fmt.Println("  migrate --to=<backend> [--dry-run]")
+		fmt.Println("                         - Copy every task into another store and switch to it")
+		fmt.Println("  encrypt [--keyfile=<path>] - Encrypt the task file and its change log with a passphrase or key file;")
+		fmt.Println("                           git sync copies, hook input and webhook payloads stay in plain text")
+		fmt.Println("  decrypt                  - Store the task file as plain JSON again")
+		fmt.Println("  rekey [--keyfile=<path>] - Encrypt the task file under a new passphrase or key file")
+		fmt.Println("  agent [--ttl=<duration>] - Cache keys to encrypted task files (default 15m); agent lock forgets them")
+		fmt.Println("  doctor [--repair]        - Check the task file for damage; --repair recovers every task it can")
+		fmt.Println("  backup [list]            - List the copies of the task file taken before each change")
+		fmt.Println("  backup restore <timestamp> - Put the tasks back as they were in a backup")
+		fmt.Println("  backup diff <timestamp>  - Show which tasks changed since a backup")
+		fmt.Println("  merge <store>            - Take in the changes made to another copy of a crdt:// store;")
+		fmt.Println("                           merge each copy into the other to bring both up to date")
+		fmt.Println("  sync init [--remote=<url>] [--branch=<name>]")
+		fmt.Println("                         - Commit tasks to a git repository in .taskmgr/sync on every change")
+		fmt.Println("  sync                     - Pull, merge task by task and push to the remote")
+		fmt.Println("  hooks [retry]            - List hook scripts and webhooks, or retry queued webhook deliveries")
 		fmt.Println("  tags                     - List all available tags")
 		fmt.Println("  tag <index> <tag>        - Add a tag to an existing task")
 		fmt.Println("  untag <index> <tag>      - Remove a tag from a task")
 		fmt.Println("  done <index>             - Mark a task as done")
-		fmt.Println("  remove <index>           - Remove a task")
+		fmt.Println("  remove <index> [--force] - Move a task to the trash; --force deletes it for good")
+		fmt.Println("  trash [list]             - List the tasks in the trash")
+		fmt.Println("  trash restore <index|id> - Take a task out of the trash")
+		fmt.Println("  trash empty [--older-than=<age>]")
+		fmt.Println("                         - Delete the tasks in the trash, or those trashed longer ago, for good")
 		fmt.Println("  undodone <index>         - Mark a completed task as not done")
+		fmt.Println("  archive [--older-than=<age>]")
+		fmt.Println("                         - Move completed tasks, or those completed longer ago, to the archive")
+		fmt.Println("  unarchive <index|id>     - Move an archived task back into the list")
+		fmt.Println("  log <index|id>           - Show when each field of a task changed and who changed it")
+		fmt.Println("  search <query> [--limit=<n>]")
+		fmt.Println("                         - Find tasks by the words in their title, tags, description and notes,")
+		fmt.Println("                           best match first; \"quoted phrases\" match in order, word* any ending")
+		fmt.Println("  note <index> <text>      - Add a line to a task's notes")
 		fmt.Println("  find <title>             - Find task by title")
 		fmt.Println("  bulkadd <t1,t2,...>      - Add multiple tasks at once")
 		fmt.Println("  countdone                - Count completed tasks")
 		fmt.Println("  markall                  - Mark all tasks as done")
 		fmt.Println("  findbydesc <desc>        - Find tasks by description")
 		fmt.Println("")
+		fmt.Println("Commands taking an <index> also accept a task id or a unique prefix of one.")
+		fmt.Println("Changes are attributed to $TASKMGR_ACTOR, or $USER when it is unset.")
+		fmt.Printf("Tasks are kept in %s unless .taskmgr/config.json or $TASKMGR_STORE names\n", config.DefaultStore)
+		fmt.Printf("another store URL; available backends: %s.\n", strings.Join(tasks.Backends(), ", "))
+		fmt.Println("The last 10 versions of a task file are kept in <file>.backups; add ?backups=<count> or")
+		fmt.Println("?backup-age=<age, e.g. 30d> to the store URL to keep more or fewer.")
+		fmt.Println("Archived tasks are kept in a store next to the tasks, such as tasks.archive.json;")
+		fmt.Println("set \"auto_archive\": \"30d\" in .taskmgr/config.json to archive on every change.")
+		fmt.Println("taskmgr search keeps its index next to a task file, in <file>.index, unless it is encrypted.")
+		fmt.Println("Encrypted task files are opened with $TASKMGR_KEYFILE, $TASKMGR_PASSPHRASE, a key cached")
+		fmt.Println("by taskmgr agent, or a passphrase typed at the prompt. Encryption covers the task file,")
+		fmt.Println("its change log, backups and archive only: the git sync copy, the input of hook scripts,")
+		fmt.Println("webhook payloads and the webhook outbox in .taskmgr/outbox.jsonl hold tasks in plain text.")
+		fmt.Println("The agent's socket is in $XDG_RUNTIME_DIR/taskmgr, or $TASKMGR_AGENT_SOCK names it; its")
+		fmt.Println("directory must be yours alone, with mode 0700.")
+		fmt.Println("Executables in .taskmgr/hooks named pre-<event> or post-<event> run on every change,")
+		fmt.Println("where <event> is created, updated, completed, trashed, restored, removed, archived or")
+		fmt.Println("unarchived; a failing pre- hook vetoes it.")
+		fmt.Println("Post-events are also sent to the webhooks listed in .taskmgr/webhooks.json.")
+		fmt.Println("")
 		fmt.Println("Examples:")
 		fmt.Println("  taskmgr add \"Fix bug\" --priority=high --due=2024-01-15 --tags=work,urgent")
 		fmt.Println("  taskmgr add \"Review PR\" --priority=medium --due=tomorrow --tags=work,code-review")
@@ -325,6 +1446,56 @@ func main() {
 		fmt.Println("  taskmgr tag 0 urgent")
 		fmt.Println("  taskmgr untag 0 urgent")
 		fmt.Println("  taskmgr stats")
+		fmt.Println("  taskmgr log 0")
+		fmt.Println("  taskmgr search \"release notes\" deploy*")
+		fmt.Println("  taskmgr tui")
+		fmt.Println("  taskmgr board --by=tag --tags=todo,doing,done")
+		fmt.Println("  taskmgr stats --history --by=week --project=website")
+		fmt.Println("  taskmgr calendar --month=2025-07")
+		fmt.Println("  taskmgr agenda --days=14")
+		fmt.Println("  taskmgr archive --older-than=30d")
+		fmt.Println("  taskmgr trash empty --older-than=30d")
+		fmt.Println("  taskmgr backup diff 20250701T0930")
+		fmt.Println("  taskmgr sync init --remote=git@example.com:me/tasks.git")
+		fmt.Println("  taskmgr import --format=todotxt ~/todo.txt")
+		fmt.Println("  taskmgr export --format=todotxt > todo.txt")
+		fmt.Println("  taskmgr export deadlines.ics")
+		fmt.Println("  taskmgr import --format=csv --map=title:Summary,due:Deadline,priority:Sev --dry-run items.csv")
+		fmt.Println("  taskmgr export --format=csv --columns=title,due,priority")
+		fmt.Println("  taskmgr export --format=markdown --by=tag notes.md")
+		fmt.Println("  task export | taskmgr import --format=taskwarrior")
 		os.Exit(1)
 	}
 }
+
+// plural returns a count with a noun that takes an s for other than one
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// storeURLPath returns the path in a store URL, such as tasks.json for
+// file://tasks.json?backups=3, or "" for a store without one
+func storeURLPath(rawURL string) string {
+	_, rest, ok := strings.Cut(rawURL, "://")
+	if !ok {
+		rest = rawURL
+	}
+	path, _, _ := strings.Cut(rest, "?")
+	return path
+}
+
+// sameFile reports whether two paths name the same file, through links,
+// or the same place for a file that does not exist yet
+func sameFile(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	if errA == nil && errB == nil {
+		return os.SameFile(infoA, infoB)
+	}
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
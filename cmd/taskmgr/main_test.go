@@ -1,6 +1,7 @@
 package main
 
 import (
+	"os"
 	"os/exec"
 	"strings"
 	"testing"
@@ -17,3 +18,17 @@ func TestMainCLI(t *testing.T) {
 		t.Error("Expected 'Task added.' output")
 	}
 }
+
+func TestExportRefusesTaskFile(t *testing.T) {
+	if out, err := exec.Command("go", "run", "./main.go", "add", "Keep me").CombinedOutput(); err != nil {
+		t.Fatalf("Failed to run add command: %v (%s)", err, string(out))
+	}
+	out, err := exec.Command("go", "run", "./main.go", "export", "--format=csv", "tasks.json").CombinedOutput()
+	if err == nil || !strings.Contains(string(out), "where taskmgr keeps tasks") {
+		t.Errorf("Expected export over the task file refused, got %v (%s)", err, string(out))
+	}
+	data, err := os.ReadFile("tasks.json")
+	if err != nil || !strings.Contains(string(data), "Keep me") {
+		t.Errorf("Expected the task file left alone, got %v (%s)", err, string(data))
+	}
+}
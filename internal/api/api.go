@@ -0,0 +1,261 @@
+// Package api defines the JSON form of tasks and requests shared by the
+// programmatic interfaces: the HTTP server, the MCP server and JSON-RPC.
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"taskmgr/internal/display"
+	"taskmgr/internal/tasks"
+)
+
+// Task is how a task appears in request and response bodies
+type Task struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	Notes       string     `json:"notes,omitempty"`
+	Done        bool       `json:"done"`
+	Priority    string     `json:"priority"`
+	Due         *time.Time `json:"due,omitempty"`
+	Tags        []string   `json:"tags"`
+	Project     string     `json:"project,omitempty"`
+	ParentID    string     `json:"parent_id,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ModifiedAt  time.Time  `json:"modified_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// FromTask converts a task to its JSON form
+func FromTask(t tasks.Task) Task {
+	tags := t.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return Task{
+		ID:          t.ID,
+		Title:       t.Title,
+		Description: t.Description,
+		Notes:       t.Notes,
+		Done:        t.Done,
+		Priority:    t.Priority.String(),
+		Due:         t.DueDate,
+		Tags:        tags,
+		Project:     t.Project,
+		ParentID:    t.ParentID,
+		CreatedAt:   t.CreatedAt,
+		ModifiedAt:  t.ModifiedAt,
+		CompletedAt: t.CompletedAt,
+	}
+}
+
+// Change is one entry of a task's change log
+type Change struct {
+	Field string    `json:"field"`
+	Old   string    `json:"old,omitempty"`
+	New   string    `json:"new,omitempty"`
+	Time  time.Time `json:"time"`
+	Actor string    `json:"actor"`
+}
+
+// FromChanges converts a change log to its JSON form
+func FromChanges(changes []tasks.Change) []Change {
+	result := []Change{}
+	for _, c := range changes {
+		result = append(result, Change{Field: c.Field, Old: c.Old, New: c.New, Time: c.Time, Actor: c.Actor})
+	}
+	return result
+}
+
+// CreateRequest describes a task to add
+type CreateRequest struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Notes       string   `json:"notes"`
+	Priority    string   `json:"priority"`
+	Due         string   `json:"due"`
+	Tags        []string `json:"tags"`
+	Project     string   `json:"project"`
+	ParentID    string   `json:"parent_id"`
+}
+
+// Task validates the request and builds the task it describes
+func (req CreateRequest) Task() (tasks.Task, error) {
+	t := tasks.Task{
+		Title:       strings.TrimSpace(req.Title),
+		Description: req.Description,
+		Notes:       req.Notes,
+		Priority:    tasks.Medium,
+		Project:     strings.TrimSpace(req.Project),
+		ParentID:    req.ParentID,
+	}
+	if t.Title == "" {
+		return t, fmt.Errorf("title is required")
+	}
+	if req.Priority != "" {
+		priority, err := tasks.ParsePriority(req.Priority)
+		if err != nil {
+			return t, err
+		}
+		t.Priority = priority
+	}
+	due, err := ParseDue(req.Due)
+	if err != nil {
+		return t, err
+	}
+	t.DueDate = due
+	for _, tag := range req.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			t.AddTag(tag)
+		}
+	}
+	return t, nil
+}
+
+// PatchRequest describes changes to a task. Omitted fields are left alone;
+// an empty due string clears the due date.
+type PatchRequest struct {
+	Title       *string   `json:"title"`
+	Description *string   `json:"description"`
+	Notes       *string   `json:"notes"`
+	Done        *bool     `json:"done"`
+	Priority    *string   `json:"priority"`
+	Due         *string   `json:"due"`
+	Tags        *[]string `json:"tags"`
+	Project     *string   `json:"project"`
+	ParentID    *string   `json:"parent_id"`
+}
+
+// Apply returns t with the requested changes made. Tasks marked done are
+// stamped as completed at now.
+func (req PatchRequest) Apply(t tasks.Task, now time.Time) (tasks.Task, error) {
+	if req.Title != nil {
+		if strings.TrimSpace(*req.Title) == "" {
+			return t, fmt.Errorf("title cannot be empty")
+		}
+		t.Title = strings.TrimSpace(*req.Title)
+	}
+	if req.Description != nil {
+		t.Description = *req.Description
+	}
+	if req.Notes != nil {
+		t.Notes = *req.Notes
+	}
+	if req.Done != nil && *req.Done != t.Done {
+		t.Done = *req.Done
+		t.CompletedAt = nil
+		if t.Done {
+			t.CompletedAt = &now
+		}
+	}
+	if req.Priority != nil {
+		priority, err := tasks.ParsePriority(*req.Priority)
+		if err != nil {
+			return t, err
+		}
+		t.Priority = priority
+	}
+	if req.Due != nil {
+		due, err := ParseDue(*req.Due)
+		if err != nil {
+			return t, err
+		}
+		t.DueDate = due
+	}
+	if req.Tags != nil {
+		t.Tags = nil
+		for _, tag := range *req.Tags {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				t.AddTag(tag)
+			}
+		}
+	}
+	if req.Project != nil {
+		t.Project = strings.TrimSpace(*req.Project)
+	}
+	if req.ParentID != nil {
+		t.ParentID = *req.ParentID
+	}
+	return t, nil
+}
+
+// ParseDue accepts RFC 3339 timestamps as well as every date the add
+// command does
+func ParseDue(s string) (*time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, s); err == nil {
+		return &parsed, nil
+	}
+	return tasks.ParseDueDate(s)
+}
+
+// ListRequest holds the criteria of the list command. Done, when set,
+// keeps only done or only pending tasks.
+type ListRequest struct {
+	Priority  string `json:"priority"`
+	Tag       string `json:"tag"`
+	Project   string `json:"project"`
+	Overdue   bool   `json:"overdue"`
+	DueToday  bool   `json:"due_today"`
+	DueWithin int    `json:"due_within"`
+	Done      *bool  `json:"done"`
+}
+
+// Select returns the tasks matching the request
+func (req ListRequest) Select(list []tasks.Task, now time.Time) ([]Task, error) {
+	filter := tasks.Filter{
+		Tag:       req.Tag,
+		Project:   req.Project,
+		Overdue:   req.Overdue,
+		DueToday:  req.DueToday,
+		DueWithin: req.DueWithin,
+	}
+	if req.Priority != "" {
+		priority, err := tasks.ParsePriority(req.Priority)
+		if err != nil {
+			return nil, err
+		}
+		filter.Priority = &priority
+	}
+	if req.DueWithin < 0 {
+		return nil, fmt.Errorf("invalid due_within value: %d", req.DueWithin)
+	}
+
+	result := []Task{}
+	for _, t := range list {
+		if filter.Match(t, now) && (req.Done == nil || t.Done == *req.Done) {
+			result = append(result, FromTask(t))
+		}
+	}
+	return result, nil
+}
+
+// Stats summarises progress the way the stats command does
+type Stats struct {
+	Total                  int            `json:"total"`
+	Completed              int            `json:"completed"`
+	Pending                int            `json:"pending"`
+	Overdue                int            `json:"overdue"`
+	ByPriority             map[string]int `json:"by_priority"`
+	AverageLeadTimeSeconds float64        `json:"average_lead_time_seconds"`
+	LeadTimeSamples        int            `json:"lead_time_samples"`
+}
+
+// NewStats computes statistics for a task list
+func NewStats(list []tasks.Task) Stats {
+	st := display.NewProgressFormatter(display.DisplayOptions{}).CalculateStats(list)
+	byPriority := make(map[string]int)
+	for _, p := range []tasks.Priority{tasks.Low, tasks.Medium, tasks.High, tasks.Critical} {
+		byPriority[p.String()] = st.ByPriority[p]
+	}
+	return Stats{
+		Total:                  st.Total,
+		Completed:              st.Completed,
+		Pending:                st.Pending,
+		Overdue:                st.Overdue,
+		ByPriority:             byPriority,
+		AverageLeadTimeSeconds: st.AverageLeadTime.Seconds(),
+		LeadTimeSamples:        st.LeadTimeSamples,
+	}
+}
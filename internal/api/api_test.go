@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"taskmgr/internal/tasks"
+)
+
+func TestCreateRequestTask(t *testing.T) {
+	task, err := CreateRequest{Title: " Plan ", Tags: []string{"Work", " ", "work"}}.Task()
+	if err != nil {
+		t.Fatalf("Task returned an error: %v", err)
+	}
+	if task.Title != "Plan" || task.Priority != tasks.Medium || len(task.Tags) != 1 || task.Tags[0] != "work" {
+		t.Errorf("Unexpected task: %+v", task)
+	}
+
+	if _, err := (CreateRequest{}).Task(); err == nil {
+		t.Error("Expected an error for a missing title")
+	}
+	if _, err := (CreateRequest{Title: "x", Due: "whenever"}).Task(); err == nil {
+		t.Error("Expected an error for an invalid due date")
+	}
+}
+
+func TestPatchRequestApply(t *testing.T) {
+	now := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)
+	due := time.Date(2025, 7, 10, 0, 0, 0, 0, time.UTC)
+	original := tasks.Task{Title: "Draft", Priority: tasks.Low, DueDate: &due, Project: "docs"}
+
+	var req PatchRequest
+	if err := json.Unmarshal([]byte(`{"priority": "high", "due": "", "done": true}`), &req); err != nil {
+		t.Fatalf("Error decoding patch: %v", err)
+	}
+	patched, err := req.Apply(original, now)
+	if err != nil {
+		t.Fatalf("Apply returned an error: %v", err)
+	}
+	if patched.Priority != tasks.High || patched.DueDate != nil || !patched.Done {
+		t.Errorf("Unexpected task after patch: %+v", patched)
+	}
+	if patched.CompletedAt == nil || !patched.CompletedAt.Equal(now) {
+		t.Errorf("Expected completion at %v, got %v", now, patched.CompletedAt)
+	}
+	if patched.Title != "Draft" || patched.Project != "docs" {
+		t.Errorf("Expected omitted fields to be kept, got %+v", patched)
+	}
+	if original.DueDate == nil {
+		t.Error("Apply changed the original task")
+	}
+
+	empty := ""
+	if _, err := (PatchRequest{Title: &empty}).Apply(original, now); err == nil {
+		t.Error("Expected an error for an empty title")
+	}
+}
+
+func TestListRequestSelect(t *testing.T) {
+	now := time.Now()
+	done := true
+	list := []tasks.Task{
+		{Title: "Open", Priority: tasks.High, Tags: []string{"work"}},
+		{Title: "Closed", Priority: tasks.High, Done: true},
+		{Title: "Low", Priority: tasks.Low},
+	}
+
+	got, err := ListRequest{Priority: "high", Done: &done}.Select(list, now)
+	if err != nil {
+		t.Fatalf("Select returned an error: %v", err)
+	}
+	if len(got) != 1 || got[0].Title != "Closed" {
+		t.Errorf("Expected only 'Closed', got %v", got)
+	}
+
+	got, _ = ListRequest{Tag: "none"}.Select(list, now)
+	if got == nil || len(got) != 0 {
+		t.Errorf("Expected an empty, non-nil list, got %#v", got)
+	}
+
+	if _, err := (ListRequest{Priority: "urgent"}).Select(list, now); err == nil {
+		t.Error("Expected an error for an invalid priority")
+	}
+}
+
+func TestNewStats(t *testing.T) {
+	created := time.Now().Add(-2 * time.Hour)
+	completed := time.Now()
+	stats := NewStats([]tasks.Task{
+		{Title: "Done", Priority: tasks.Critical, Done: true, CreatedAt: created, CompletedAt: &completed},
+		{Title: "Open", Priority: tasks.Low},
+	})
+	if stats.Total != 2 || stats.Completed != 1 || stats.Pending != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if stats.ByPriority["critical"] != 1 || stats.ByPriority["medium"] != 0 {
+		t.Errorf("Unexpected priority counts: %v", stats.ByPriority)
+	}
+	if stats.LeadTimeSamples != 1 || stats.AverageLeadTimeSeconds < 7000 {
+		t.Errorf("Expected one lead time of about two hours, got %+v", stats)
+	}
+}
+
+func TestFromChanges(t *testing.T) {
+	if got := FromChanges(nil); got == nil || len(got) != 0 {
+		t.Errorf("Expected an empty, non-nil log, got %#v", got)
+	}
+	got := FromChanges([]tasks.Change{{TaskID: "a1", Field: "title", Old: "x", New: "y", Actor: "sam"}})
+	if len(got) != 1 || got[0].Field != "title" || got[0].New != "y" || got[0].Actor != "sam" {
+		t.Errorf("Unexpected log %+v", got)
+	}
+}
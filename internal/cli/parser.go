@@ -2,6 +2,8 @@ package cli
 
 import (
 	"strings"
+
+	"taskmgr/internal/tasks"
 )
 
 type AddOptions struct {
@@ -9,6 +11,8 @@ type AddOptions struct {
 	Priority string
 	Due      string
 	Tags     []string
+	Project  string
+	Notes    string
 }
 
 type ListOptions struct {
@@ -17,6 +21,124 @@ type ListOptions struct {
 	DueToday   bool
 	DueWithin  int
 	Tag        string
+	Project    string
+	Archived   bool
+	All        bool
+}
+
+type BoardOptions struct {
+	GroupBy     string
+	Tags        []string
+	Interactive bool
+	Width       int
+}
+
+type CalendarOptions struct {
+	Month string
+}
+
+type AgendaOptions struct {
+	Days int
+}
+
+type StatsOptions struct {
+	History bool
+	By      string
+	From    string
+	To      string
+	Tag     string
+	Project string
+}
+
+type ImportOptions struct {
+	Format string
+	File   string
+	Map    map[string]string
+	DryRun bool
+}
+
+type ExportOptions struct {
+	Format  string
+	File    string
+	Columns []string
+	GroupBy string
+}
+
+type ServeOptions struct {
+	Addr string
+}
+
+// MigrateOptions holds the options of the migrate command. To is a store
+// URL, or the name of a backend to use with its default file; without it,
+// the task file is brought up to the current schema version. DryRun shows
+// what would change.
+type MigrateOptions struct {
+	To     string
+	DryRun bool
+}
+
+// SyncOptions holds the options of the sync command. Init is set by
+// "sync init", which sets up the repository and its remote.
+type SyncOptions struct {
+	Init   bool
+	Remote string
+	Branch string
+}
+
+// KeyOptions holds the options of the encrypt, decrypt and rekey
+// commands. Keyfile names a file to derive the new key from instead of a
+// passphrase.
+type KeyOptions struct {
+	Keyfile string
+}
+
+// AgentOptions holds the options of the agent command. Lock is set by
+// "agent lock", which makes a running agent forget its keys.
+type AgentOptions struct {
+	Lock bool
+	TTL  string
+}
+
+// BackupOptions holds the options of the backup command: Action is "list",
+// "restore" or "diff", and Backup the timestamp of the backup to restore or
+// compare against
+type BackupOptions struct {
+	Action string
+	Backup string
+}
+
+// DoctorOptions holds the options of the doctor command
+type DoctorOptions struct {
+	Repair bool
+}
+
+// ArchiveOptions holds the options of the archive command. OlderThan is
+// how long ago a task must have been completed to be archived.
+type ArchiveOptions struct {
+	OlderThan string
+}
+
+// RemoveOptions holds the options of the remove command. Force deletes the
+// task for good rather than moving it to the trash.
+type RemoveOptions struct {
+	Task  string
+	Force bool
+}
+
+// TrashOptions holds the options of the trash command: Action is "list",
+// "restore" or "empty", Task the task to restore, and OlderThan how long
+// ago a task must have been trashed to be emptied
+type TrashOptions struct {
+	Action    string
+	Task      string
+	OlderThan string
+}
+
+// SearchOptions holds the options of the search command: the query, and
+// at most how many tasks to show, or 0 for all
+type SearchOptions struct {
+	Query string
+	Limit int
 }
 
 func ParseArgs(args []string) (string, []string) {
@@ -43,8 +165,16 @@ func ParseAddCommand(args []string) AddOptions {
 				tags[j] = strings.ToLower(strings.TrimSpace(tag))
 			}
 			opts.Tags = tags
+		} else if strings.HasPrefix(arg, "--project=") {
+			opts.Project = strings.TrimSpace(strings.TrimPrefix(arg, "--project="))
+		} else if strings.HasPrefix(arg, "--notes=") {
+			opts.Notes = strings.TrimPrefix(arg, "--notes=")
+		} else if arg == "--notes" && i+1 < len(args) {
+			opts.Notes = args[i+1]
 		} else if arg == "--priority" && i+1 < len(args) {
 			opts.Priority = args[i+1]
+		} else if arg == "--project" && i+1 < len(args) {
+			opts.Project = strings.TrimSpace(args[i+1])
 		} else if arg == "--due" && i+1 < len(args) {
 			opts.Due = args[i+1]
 		} else if arg == "--tags" && i+1 < len(args) {
@@ -77,10 +207,18 @@ func ParseListCommand(args []string) ListOptions {
 			opts.Priority = args[i+1]
 		} else if arg == "--tag" && i+1 < len(args) {
 			opts.Tag = args[i+1]
+		} else if strings.HasPrefix(arg, "--project=") {
+			opts.Project = strings.TrimPrefix(arg, "--project=")
+		} else if arg == "--project" && i+1 < len(args) {
+			opts.Project = args[i+1]
 		} else if arg == "--overdue" {
 			opts.Overdue = true
 		} else if arg == "--due-today" {
 			opts.DueToday = true
+		} else if arg == "--archived" {
+			opts.Archived = true
+		} else if arg == "--all" {
+			opts.All = true
 		} else if strings.HasPrefix(arg, "--due-within=") {
 			// Parse number from --due-within=7days or --due-within=7
 			value := strings.TrimPrefix(arg, "--due-within=")
@@ -95,6 +233,304 @@ func ParseListCommand(args []string) ListOptions {
 	return opts
 }
 
+// ParseBoardCommand parses arguments for the board command
+func ParseBoardCommand(args []string) BoardOptions {
+	opts := BoardOptions{}
+	
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "--by=") {
+			opts.GroupBy = strings.TrimPrefix(arg, "--by=")
+		} else if arg == "--by" && i+1 < len(args) {
+			opts.GroupBy = args[i+1]
+		} else if strings.HasPrefix(arg, "--tags=") {
+			opts.Tags = splitTags(strings.TrimPrefix(arg, "--tags="))
+		} else if arg == "--tags" && i+1 < len(args) {
+			opts.Tags = splitTags(args[i+1])
+		} else if strings.HasPrefix(arg, "--width=") {
+			opts.Width = parseInt(strings.TrimPrefix(arg, "--width="))
+		} else if arg == "--interactive" || arg == "-i" {
+			opts.Interactive = true
+		}
+	}
+	
+	return opts
+}
+
+// ParseCalendarCommand parses arguments for the calendar command
+func ParseCalendarCommand(args []string) CalendarOptions {
+	opts := CalendarOptions{}
+	
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "--month=") {
+			opts.Month = strings.TrimPrefix(arg, "--month=")
+		} else if arg == "--month" && i+1 < len(args) {
+			opts.Month = args[i+1]
+		}
+	}
+	
+	return opts
+}
+
+// ParseAgendaCommand parses arguments for the agenda command. Days
+// defaults to 7 and accepts the same "7days" suffix as --due-within.
+func ParseAgendaCommand(args []string) AgendaOptions {
+	opts := AgendaOptions{Days: 7}
+	
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--days=") {
+			value := strings.TrimPrefix(arg, "--days=")
+			value = strings.TrimSuffix(value, "days")
+			value = strings.TrimSuffix(value, "day")
+			if days := parseInt(value); days > 0 {
+				opts.Days = days
+			}
+		}
+	}
+	
+	return opts
+}
+
+// ParseStatsCommand parses arguments for the stats command
+func ParseStatsCommand(args []string) StatsOptions {
+	opts := StatsOptions{}
+	
+	for i, arg := range args {
+		if arg == "--history" {
+			opts.History = true
+		} else if strings.HasPrefix(arg, "--by=") {
+			opts.By = strings.TrimPrefix(arg, "--by=")
+		} else if strings.HasPrefix(arg, "--from=") {
+			opts.From = strings.TrimPrefix(arg, "--from=")
+		} else if strings.HasPrefix(arg, "--to=") {
+			opts.To = strings.TrimPrefix(arg, "--to=")
+		} else if strings.HasPrefix(arg, "--tag=") {
+			opts.Tag = strings.TrimPrefix(arg, "--tag=")
+		} else if strings.HasPrefix(arg, "--project=") {
+			opts.Project = strings.TrimPrefix(arg, "--project=")
+		} else if arg == "--by" && i+1 < len(args) {
+			opts.By = args[i+1]
+		} else if arg == "--from" && i+1 < len(args) {
+			opts.From = args[i+1]
+		} else if arg == "--to" && i+1 < len(args) {
+			opts.To = args[i+1]
+		} else if arg == "--tag" && i+1 < len(args) {
+			opts.Tag = args[i+1]
+		} else if arg == "--project" && i+1 < len(args) {
+			opts.Project = args[i+1]
+		}
+	}
+	
+	return opts
+}
+
+// ParseImportCommand parses arguments for the import command. The file is
+// the first argument that is not a flag; "-" or none reads standard input.
+// --map takes field:Column pairs separated by commas.
+func ParseImportCommand(args []string) ImportOptions {
+	opts := ImportOptions{}
+	
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if strings.HasPrefix(arg, "--format=") {
+			opts.Format = strings.TrimPrefix(arg, "--format=")
+		} else if strings.HasPrefix(arg, "--map=") {
+			opts.Map = parseColumnMap(strings.TrimPrefix(arg, "--map="))
+		} else if arg == "--dry-run" {
+			opts.DryRun = true
+		} else if arg == "--format" && i+1 < len(args) {
+			opts.Format = args[i+1]
+			i++
+		} else if arg == "--map" && i+1 < len(args) {
+			opts.Map = parseColumnMap(args[i+1])
+			i++
+		} else if (arg == "-" || !strings.HasPrefix(arg, "-")) && opts.File == "" {
+			opts.File = arg
+		}
+	}
+	
+	return opts
+}
+
+// ParseExportCommand parses arguments for the export command. The file is
+// the first argument that is not a flag; "-" or none writes standard output.
+func ParseExportCommand(args []string) ExportOptions {
+	opts := ExportOptions{}
+	
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if strings.HasPrefix(arg, "--format=") {
+			opts.Format = strings.TrimPrefix(arg, "--format=")
+		} else if strings.HasPrefix(arg, "--columns=") {
+			opts.Columns = splitList(strings.TrimPrefix(arg, "--columns="))
+		} else if strings.HasPrefix(arg, "--by=") {
+			opts.GroupBy = strings.TrimPrefix(arg, "--by=")
+		} else if arg == "--format" && i+1 < len(args) {
+			opts.Format = args[i+1]
+			i++
+		} else if arg == "--columns" && i+1 < len(args) {
+			opts.Columns = splitList(args[i+1])
+			i++
+		} else if arg == "--by" && i+1 < len(args) {
+			opts.GroupBy = args[i+1]
+			i++
+		} else if (arg == "-" || !strings.HasPrefix(arg, "-")) && opts.File == "" {
+			opts.File = arg
+		}
+	}
+	
+	return opts
+}
+
+// ParseServeCommand parses arguments for the serve command. The server
+// listens on 127.0.0.1:8080 unless --addr says otherwise.
+func ParseServeCommand(args []string) ServeOptions {
+	opts := ServeOptions{Addr: "127.0.0.1:8080"}
+	
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if strings.HasPrefix(arg, "--addr=") {
+			opts.Addr = strings.TrimPrefix(arg, "--addr=")
+		} else if arg == "--addr" && i+1 < len(args) {
+			opts.Addr = args[i+1]
+			i++
+		}
+	}
+	
+	return opts
+}
+
+// ParseMigrateCommand parses arguments for the migrate command
+func ParseMigrateCommand(args []string) MigrateOptions {
+	opts := MigrateOptions{}
+	
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if strings.HasPrefix(arg, "--to=") {
+			opts.To = strings.TrimPrefix(arg, "--to=")
+		} else if arg == "--dry-run" {
+			opts.DryRun = true
+		} else if arg == "--to" && i+1 < len(args) {
+			opts.To = args[i+1]
+			i++
+		}
+	}
+	
+	return opts
+}
+
+// ParseSyncCommand parses arguments for the sync command
+func ParseSyncCommand(args []string) SyncOptions {
+	opts := SyncOptions{}
+	
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "init" {
+			opts.Init = true
+		} else if strings.HasPrefix(arg, "--remote=") {
+			opts.Remote = strings.TrimPrefix(arg, "--remote=")
+		} else if strings.HasPrefix(arg, "--branch=") {
+			opts.Branch = strings.TrimPrefix(arg, "--branch=")
+		} else if arg == "--remote" && i+1 < len(args) {
+			opts.Remote = args[i+1]
+			i++
+		} else if arg == "--branch" && i+1 < len(args) {
+			opts.Branch = args[i+1]
+			i++
+		}
+	}
+	
+	return opts
+}
+
+// ParseKeyCommand parses arguments for the encrypt, decrypt and rekey
+// commands
+func ParseKeyCommand(args []string) KeyOptions {
+	opts := KeyOptions{}
+	
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if strings.HasPrefix(arg, "--keyfile=") {
+			opts.Keyfile = strings.TrimPrefix(arg, "--keyfile=")
+		} else if arg == "--keyfile" && i+1 < len(args) {
+			opts.Keyfile = args[i+1]
+			i++
+		}
+	}
+	
+	return opts
+}
+
+// ParseAgentCommand parses arguments for the agent command
+func ParseAgentCommand(args []string) AgentOptions {
+	opts := AgentOptions{}
+	
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "lock" {
+			opts.Lock = true
+		} else if strings.HasPrefix(arg, "--ttl=") {
+			opts.TTL = strings.TrimPrefix(arg, "--ttl=")
+		} else if arg == "--ttl" && i+1 < len(args) {
+			opts.TTL = args[i+1]
+			i++
+		}
+	}
+	
+	return opts
+}
+
+// parseColumnMap parses "title:Summary,due:Deadline". Column names keep
+// their case; an entry without a colon maps the field to an empty column.
+func parseColumnMap(s string) map[string]string {
+	m := make(map[string]string)
+	for _, entry := range splitList(s) {
+		field, column, _ := strings.Cut(entry, ":")
+		m[strings.ToLower(strings.TrimSpace(field))] = strings.TrimSpace(column)
+	}
+	return m
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// splitTags splits a comma separated tag list, normalising each tag
+func splitTags(s string) []string {
+	var tags []string
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// Filter converts the parsed list options into a tasks.Filter
+func (o ListOptions) Filter() (tasks.Filter, error) {
+	f := tasks.Filter{
+		Tag:       o.Tag,
+		Project:   o.Project,
+		Overdue:   o.Overdue,
+		DueToday:  o.DueToday,
+		DueWithin: o.DueWithin,
+	}
+	if o.Priority != "" {
+		priority, err := tasks.ParsePriority(o.Priority)
+		if err != nil {
+			return f, err
+		}
+		f.Priority = &priority
+	}
+	return f, nil
+}
+
 // Helper function to parse integer
 func parseInt(s string) int {
 	var result int
@@ -107,3 +543,94 @@ func parseInt(s string) int {
 	}
 	return result
 }
+
+// ParseBackupCommand parses arguments for the backup command
+func ParseBackupCommand(args []string) BackupOptions {
+	opts := BackupOptions{Action: "list"}
+	if len(args) > 0 {
+		opts.Action = args[0]
+	}
+	if len(args) > 1 {
+		opts.Backup = args[1]
+	}
+	return opts
+}
+
+// ParseDoctorCommand parses arguments for the doctor command
+func ParseDoctorCommand(args []string) DoctorOptions {
+	opts := DoctorOptions{}
+	for _, arg := range args {
+		if arg == "--repair" {
+			opts.Repair = true
+		}
+	}
+	return opts
+}
+
+// ParseArchiveCommand parses arguments for the archive command
+func ParseArchiveCommand(args []string) ArchiveOptions {
+	opts := ArchiveOptions{}
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "--older-than=") {
+			opts.OlderThan = strings.TrimPrefix(arg, "--older-than=")
+		} else if arg == "--older-than" && i+1 < len(args) {
+			opts.OlderThan = args[i+1]
+		}
+	}
+	return opts
+}
+
+// ParseRemoveCommand parses arguments for the remove command
+func ParseRemoveCommand(args []string) RemoveOptions {
+	opts := RemoveOptions{}
+	for _, arg := range args {
+		if arg == "--force" || arg == "-f" {
+			opts.Force = true
+		} else if !strings.HasPrefix(arg, "--") && opts.Task == "" {
+			opts.Task = arg
+		}
+	}
+	return opts
+}
+
+// ParseTrashCommand parses arguments for the trash command
+func ParseTrashCommand(args []string) TrashOptions {
+	opts := TrashOptions{Action: "list"}
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "--older-than=") {
+			opts.OlderThan = strings.TrimPrefix(arg, "--older-than=")
+		} else if arg == "--older-than" && i+1 < len(args) {
+			opts.OlderThan = args[i+1]
+		} else if i > 0 && args[i-1] == "--older-than" {
+			continue
+		} else if i == 0 {
+			opts.Action = arg
+		} else if opts.Task == "" {
+			opts.Task = arg
+		}
+	}
+	return opts
+}
+
+// ParseSearchCommand parses arguments for the search command. Every
+// argument that is not an option is part of the query; one with spaces,
+// quoted in the shell, is a phrase.
+func ParseSearchCommand(args []string) SearchOptions {
+	opts := SearchOptions{}
+	var words []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if strings.HasPrefix(arg, "--limit=") {
+			opts.Limit = parseInt(strings.TrimPrefix(arg, "--limit="))
+		} else if arg == "--limit" && i+1 < len(args) {
+			opts.Limit = parseInt(args[i+1])
+			i++
+		} else if strings.ContainsAny(arg, " \t") && !strings.Contains(arg, `"`) {
+			words = append(words, `"`+arg+`"`)
+		} else {
+			words = append(words, arg)
+		}
+	}
+	opts.Query = strings.Join(words, " ")
+	return opts
+}
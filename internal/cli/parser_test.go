@@ -1,6 +1,11 @@
 package cli
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+
+	"taskmgr/internal/tasks"
+)
 
 func TestParseArgs(t *testing.T) {
 	cmd, rest := ParseArgs([]string{"add", "MyTask"})
@@ -73,6 +78,26 @@ func TestParseAddCommand(t *testing.T) {
 			args: []string{"Fix bug", "--tags=Work, URGENT , Bug"},
 			expected: AddOptions{Title: "Fix bug", Tags: []string{"work", "urgent", "bug"}},
 		},
+		{
+			name: "title with project flag",
+			args: []string{"Fix bug", "--project=website"},
+			expected: AddOptions{Title: "Fix bug", Project: "website"},
+		},
+		{
+			name: "title with project space separator",
+			args: []string{"Fix bug", "--project", "website"},
+			expected: AddOptions{Title: "Fix bug", Project: "website"},
+		},
+		{
+			name: "title with notes",
+			args: []string{"Fix bug", "--notes=Seen on staging only"},
+			expected: AddOptions{Title: "Fix bug", Notes: "Seen on staging only"},
+		},
+		{
+			name: "title with notes space separator",
+			args: []string{"Fix bug", "--notes", "Seen on staging only"},
+			expected: AddOptions{Title: "Fix bug", Notes: "Seen on staging only"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -87,6 +112,12 @@ func TestParseAddCommand(t *testing.T) {
 			if result.Due != tt.expected.Due {
 				t.Errorf("Expected due '%s', got '%s'", tt.expected.Due, result.Due)
 			}
+			if result.Project != tt.expected.Project {
+				t.Errorf("Expected project '%s', got '%s'", tt.expected.Project, result.Project)
+			}
+			if result.Notes != tt.expected.Notes {
+				t.Errorf("Expected notes '%s', got '%s'", tt.expected.Notes, result.Notes)
+			}
 			// Check tags
 			if len(result.Tags) != len(tt.expected.Tags) {
 				t.Errorf("Expected %d tags, got %d", len(tt.expected.Tags), len(result.Tags))
@@ -141,6 +172,16 @@ func TestParseListCommand(t *testing.T) {
 			args: []string{"--due-within=5"},
 			expected: ListOptions{DueWithin: 5},
 		},
+		{
+			name: "archived",
+			args: []string{"--archived"},
+			expected: ListOptions{Archived: true},
+		},
+		{
+			name: "all with a tag",
+			args: []string{"--all", "--tag=work"},
+			expected: ListOptions{All: true, Tag: "work"},
+		},
 		{
 			name: "multiple flags",
 			args: []string{"--priority=medium", "--overdue"},
@@ -161,6 +202,11 @@ func TestParseListCommand(t *testing.T) {
 			args: []string{"--tag=urgent", "--priority=high"},
 			expected: ListOptions{Tag: "urgent", Priority: "high"},
 		},
+		{
+			name: "project filter",
+			args: []string{"--project=website"},
+			expected: ListOptions{Project: "website"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -181,10 +227,315 @@ func TestParseListCommand(t *testing.T) {
 			if result.Tag != tt.expected.Tag {
 				t.Errorf("Expected tag '%s', got '%s'", tt.expected.Tag, result.Tag)
 			}
+			if result.Project != tt.expected.Project {
+				t.Errorf("Expected project '%s', got '%s'", tt.expected.Project, result.Project)
+			}
 		})
 	}
 }
 
+func TestParseBoardCommand(t *testing.T) {
+	opts := ParseBoardCommand([]string{"--by=tag", "--tags=Todo, doing,,review", "--width=100", "-i"})
+	if opts.GroupBy != "tag" {
+		t.Errorf("Expected group 'tag', got '%s'", opts.GroupBy)
+	}
+	expectedTags := []string{"todo", "doing", "review"}
+	if len(opts.Tags) != len(expectedTags) {
+		t.Fatalf("Expected tags %v, got %v", expectedTags, opts.Tags)
+	}
+	for i, tag := range expectedTags {
+		if opts.Tags[i] != tag {
+			t.Errorf("Expected tag[%d] '%s', got '%s'", i, tag, opts.Tags[i])
+		}
+	}
+	if opts.Width != 100 {
+		t.Errorf("Expected width 100, got %d", opts.Width)
+	}
+	if !opts.Interactive {
+		t.Error("Expected interactive mode")
+	}
+
+	opts = ParseBoardCommand([]string{"--by", "priority", "--interactive"})
+	if opts.GroupBy != "priority" || !opts.Interactive || opts.Width != 0 {
+		t.Errorf("Unexpected options %+v", opts)
+	}
+}
+
+func TestListOptionsFilter(t *testing.T) {
+	opts := ListOptions{Priority: "high", Tag: "work", Overdue: true, DueWithin: 3}
+	filter, err := opts.Filter()
+	if err != nil {
+		t.Fatalf("Filter returned an error: %v", err)
+	}
+	if filter.Priority == nil || *filter.Priority != tasks.High {
+		t.Errorf("Expected high priority filter, got %v", filter.Priority)
+	}
+	if filter.Tag != "work" || !filter.Overdue || filter.DueWithin != 3 {
+		t.Errorf("Expected tag, overdue and due-within to carry over, got %+v", filter)
+	}
+
+	empty, err := ListOptions{}.Filter()
+	if err != nil {
+		t.Fatalf("Filter returned an error: %v", err)
+	}
+	if !empty.IsZero() {
+		t.Errorf("Expected zero filter, got %+v", empty)
+	}
+
+	if _, err := (ListOptions{Priority: "urgent"}).Filter(); err == nil {
+		t.Error("Expected error for invalid priority")
+	}
+}
+
+func TestParseCalendarCommand(t *testing.T) {
+	if opts := ParseCalendarCommand([]string{"--month=2025-07"}); opts.Month != "2025-07" {
+		t.Errorf("Expected month '2025-07', got '%s'", opts.Month)
+	}
+	if opts := ParseCalendarCommand([]string{"--month", "2024-12"}); opts.Month != "2024-12" {
+		t.Errorf("Expected month '2024-12', got '%s'", opts.Month)
+	}
+	if opts := ParseCalendarCommand(nil); opts.Month != "" {
+		t.Errorf("Expected empty month, got '%s'", opts.Month)
+	}
+}
+
+func TestParseAgendaCommand(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected int
+	}{
+		{nil, 7},
+		{[]string{"--days=14"}, 14},
+		{[]string{"--days=3days"}, 3},
+		{[]string{"--days=0"}, 7},
+		{[]string{"--days=abc"}, 7},
+	}
+
+	for _, tt := range tests {
+		if opts := ParseAgendaCommand(tt.args); opts.Days != tt.expected {
+			t.Errorf("ParseAgendaCommand(%v) days = %d, expected %d", tt.args, opts.Days, tt.expected)
+		}
+	}
+}
+
+func TestParseStatsCommand(t *testing.T) {
+	opts := ParseStatsCommand([]string{"--history", "--by=week", "--from=2025-06-01", "--to", "2025-06-30", "--tag=work"})
+	expected := StatsOptions{History: true, By: "week", From: "2025-06-01", To: "2025-06-30", Tag: "work"}
+	if opts != expected {
+		t.Errorf("Expected %+v, got %+v", expected, opts)
+	}
+
+	opts = ParseStatsCommand([]string{"--no-color", "--project", "website"})
+	if opts.History || opts.Project != "website" {
+		t.Errorf("Unexpected options %+v", opts)
+	}
+}
+
+func TestParseImportExportCommands(t *testing.T) {
+	tests := []struct {
+		args   []string
+		format string
+		file   string
+	}{
+		{[]string{"--format=todotxt", "todo.txt"}, "todotxt", "todo.txt"},
+		{[]string{"--format", "todotxt", "todo.txt"}, "todotxt", "todo.txt"},
+		{[]string{"todo.txt", "--format", "todotxt"}, "todotxt", "todo.txt"},
+		{[]string{"-"}, "", "-"},
+		{[]string{"--format=todotxt"}, "todotxt", ""},
+	}
+
+	for _, tt := range tests {
+		imp := ParseImportCommand(tt.args)
+		if imp.Format != tt.format || imp.File != tt.file {
+			t.Errorf("ParseImportCommand(%v) = %+v, expected format %q and file %q", tt.args, imp, tt.format, tt.file)
+		}
+		exp := ParseExportCommand(tt.args)
+		if exp.Format != tt.format || exp.File != tt.file {
+			t.Errorf("ParseExportCommand(%v) = %+v, expected format %q and file %q", tt.args, exp, tt.format, tt.file)
+		}
+	}
+}
+
+func TestParseImportMapAndDryRun(t *testing.T) {
+	opts := ParseImportCommand([]string{"--format=csv", "--map=Title:Summary, due:Deadline Date,priority", "--dry-run", "items.csv"})
+	expected := map[string]string{"title": "Summary", "due": "Deadline Date", "priority": ""}
+	if !reflect.DeepEqual(opts.Map, expected) {
+		t.Errorf("Expected map %v, got %v", expected, opts.Map)
+	}
+	if !opts.DryRun || opts.File != "items.csv" || opts.Format != "csv" {
+		t.Errorf("Unexpected options %+v", opts)
+	}
+
+	// The space separated form does not mistake the value for the file
+	opts = ParseImportCommand([]string{"--map", "title:Summary", "items.csv"})
+	if opts.File != "items.csv" || opts.Map["title"] != "Summary" {
+		t.Errorf("Unexpected options %+v", opts)
+	}
+}
+
+func TestParseExportColumns(t *testing.T) {
+	opts := ParseExportCommand([]string{"--format=csv", "--columns=title, due,,tags", "out.csv"})
+	if !reflect.DeepEqual(opts.Columns, []string{"title", "due", "tags"}) || opts.File != "out.csv" {
+		t.Errorf("Unexpected options %+v", opts)
+	}
+
+	opts = ParseExportCommand([]string{"--columns", "title", "--format", "csv"})
+	if !reflect.DeepEqual(opts.Columns, []string{"title"}) || opts.File != "" {
+		t.Errorf("Unexpected options %+v", opts)
+	}
+
+	opts = ParseExportCommand([]string{"--format=markdown", "--by", "tag", "notes.md"})
+	if opts.GroupBy != "tag" || opts.File != "notes.md" {
+		t.Errorf("Unexpected options %+v", opts)
+	}
+}
+
+func TestParseServeCommand(t *testing.T) {
+	if opts := ParseServeCommand(nil); opts.Addr != "127.0.0.1:8080" {
+		t.Errorf("Expected default address 127.0.0.1:8080, got %q", opts.Addr)
+	}
+	if opts := ParseServeCommand([]string{"--addr=:9000"}); opts.Addr != ":9000" {
+		t.Errorf("Expected address :9000, got %q", opts.Addr)
+	}
+	if opts := ParseServeCommand([]string{"--addr", "localhost:1234"}); opts.Addr != "localhost:1234" {
+		t.Errorf("Expected address localhost:1234, got %q", opts.Addr)
+	}
+}
+
+func TestParseMigrateCommand(t *testing.T) {
+	if opts := ParseMigrateCommand(nil); opts.To != "" {
+		t.Errorf("Expected no target, got %q", opts.To)
+	}
+	if opts := ParseMigrateCommand([]string{"--to=sqlite"}); opts.To != "sqlite" {
+		t.Errorf("Expected target sqlite, got %q", opts.To)
+	}
+	if opts := ParseMigrateCommand([]string{"--to", "sqlite://team.db"}); opts.To != "sqlite://team.db" {
+		t.Errorf("Expected target sqlite://team.db, got %q", opts.To)
+	}
+	if opts := ParseMigrateCommand([]string{"--dry-run", "--to=crdt"}); !opts.DryRun || opts.To != "crdt" {
+		t.Errorf("Expected a dry run to crdt, got %+v", opts)
+	}
+}
+
+func TestParseSyncCommand(t *testing.T) {
+	if opts := ParseSyncCommand(nil); opts.Init || opts.Remote != "" {
+		t.Errorf("Expected a plain sync, got %+v", opts)
+	}
+	opts := ParseSyncCommand([]string{"init", "--remote=git@example.com:me/tasks.git", "--branch", "tasks"})
+	if !opts.Init || opts.Remote != "git@example.com:me/tasks.git" || opts.Branch != "tasks" {
+		t.Errorf("Expected init with a remote and branch, got %+v", opts)
+	}
+	if opts := ParseSyncCommand([]string{"init", "--remote", "/srv/tasks.git"}); opts.Remote != "/srv/tasks.git" {
+		t.Errorf("Expected remote /srv/tasks.git, got %q", opts.Remote)
+	}
+}
+
+func TestParseKeyCommand(t *testing.T) {
+	if opts := ParseKeyCommand(nil); opts.Keyfile != "" {
+		t.Errorf("Expected no key file, got %q", opts.Keyfile)
+	}
+	if opts := ParseKeyCommand([]string{"--keyfile=/media/usb/tasks.key"}); opts.Keyfile != "/media/usb/tasks.key" {
+		t.Errorf("Expected the key file, got %q", opts.Keyfile)
+	}
+	if opts := ParseKeyCommand([]string{"--keyfile", "tasks.key"}); opts.Keyfile != "tasks.key" {
+		t.Errorf("Expected the key file, got %q", opts.Keyfile)
+	}
+}
+
+func TestParseAgentCommand(t *testing.T) {
+	if opts := ParseAgentCommand([]string{"--ttl=1h"}); opts.Lock || opts.TTL != "1h" {
+		t.Errorf("Expected a one hour agent, got %+v", opts)
+	}
+	if opts := ParseAgentCommand([]string{"lock"}); !opts.Lock {
+		t.Errorf("Expected lock, got %+v", opts)
+	}
+}
+
+func TestParseBackupCommand(t *testing.T) {
+	if opts := ParseBackupCommand(nil); opts.Action != "list" {
+		t.Errorf("Expected backup to list backups, got %+v", opts)
+	}
+	if opts := ParseBackupCommand([]string{"restore", "20261018T2257"}); opts.Action != "restore" || opts.Backup != "20261018T2257" {
+		t.Errorf("Expected a restore of 20261018T2257, got %+v", opts)
+	}
+}
+
+func TestParseDoctorCommand(t *testing.T) {
+	if opts := ParseDoctorCommand(nil); opts.Repair {
+		t.Errorf("Expected doctor to only check, got %+v", opts)
+	}
+	if opts := ParseDoctorCommand([]string{"--repair"}); !opts.Repair {
+		t.Errorf("Expected a repair, got %+v", opts)
+	}
+}
+
+func TestParseArchiveCommand(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected ArchiveOptions
+	}{
+		{nil, ArchiveOptions{}},
+		{[]string{"--older-than=30d"}, ArchiveOptions{OlderThan: "30d"}},
+		{[]string{"--older-than", "12h"}, ArchiveOptions{OlderThan: "12h"}},
+	}
+	for _, tt := range tests {
+		if opts := ParseArchiveCommand(tt.args); opts != tt.expected {
+			t.Errorf("ParseArchiveCommand(%q) = %+v, expected %+v", tt.args, opts, tt.expected)
+		}
+	}
+}
+
+func TestParseRemoveCommand(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected RemoveOptions
+	}{
+		{[]string{"2"}, RemoveOptions{Task: "2"}},
+		{[]string{"--force", "a1b2"}, RemoveOptions{Task: "a1b2", Force: true}},
+		{[]string{"a1b2", "-f"}, RemoveOptions{Task: "a1b2", Force: true}},
+	}
+	for _, tt := range tests {
+		if opts := ParseRemoveCommand(tt.args); opts != tt.expected {
+			t.Errorf("ParseRemoveCommand(%q) = %+v, expected %+v", tt.args, opts, tt.expected)
+		}
+	}
+}
+
+func TestParseTrashCommand(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected TrashOptions
+	}{
+		{nil, TrashOptions{Action: "list"}},
+		{[]string{"restore", "a1b2"}, TrashOptions{Action: "restore", Task: "a1b2"}},
+		{[]string{"empty", "--older-than=30d"}, TrashOptions{Action: "empty", OlderThan: "30d"}},
+		{[]string{"empty", "--older-than", "12h"}, TrashOptions{Action: "empty", OlderThan: "12h"}},
+	}
+	for _, tt := range tests {
+		if opts := ParseTrashCommand(tt.args); opts != tt.expected {
+			t.Errorf("ParseTrashCommand(%q) = %+v, expected %+v", tt.args, opts, tt.expected)
+		}
+	}
+}
+
+func TestParseSearchCommand(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected SearchOptions
+	}{
+		{nil, SearchOptions{}},
+		{[]string{"deploy", "web*"}, SearchOptions{Query: "deploy web*"}},
+		{[]string{`"release notes"`, "--limit=5"}, SearchOptions{Query: `"release notes"`, Limit: 5}},
+		{[]string{"--limit", "3", "bug"}, SearchOptions{Query: "bug", Limit: 3}},
+		{[]string{"release notes", "web"}, SearchOptions{Query: `"release notes" web`}},
+	}
+	for _, tt := range tests {
+		if opts := ParseSearchCommand(tt.args); opts != tt.expected {
+			t.Errorf("ParseSearchCommand(%q) = %+v, expected %+v", tt.args, opts, tt.expected)
+		}
+	}
+}
+
 func TestParseInt(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -0,0 +1,121 @@
+// Package config reads the settings in .taskmgr/config.json:
+//
+//	{"store": "journal://tasks.journal", "auto_archive": "30d"}
+//
+// Settings can be overridden from the environment, which wins over the
+// file.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultDir holds the configuration, next to tasks.json
+const DefaultDir = ".taskmgr"
+
+// DefaultStore is the store used when none is configured
+const DefaultStore = "file://tasks.json"
+
+// Config holds taskmgr's settings
+type Config struct {
+	// Store is the URL of the task store, such as file://tasks.json;
+	// $TASKMGR_STORE overrides it
+	Store string `json:"store"`
+	// Archive is the URL of the store completed tasks are archived to;
+	// by default, one next to Store
+	Archive string `json:"archive,omitempty"`
+	// AutoArchive, such as 30d, archives tasks completed longer ago than
+	// that whenever tasks change
+	AutoArchive string `json:"auto_archive,omitempty"`
+}
+
+// ArchiveStore returns the URL of the archive store
+func (c Config) ArchiveStore() string {
+	if c.Archive != "" {
+		return c.Archive
+	}
+	return ArchiveURL(c.Store)
+}
+
+// ArchiveURL returns the URL of an archive kept next to a store: the same
+// backend, with ".archive" added to the file name, so that tasks.json is
+// archived to tasks.archive.json
+func ArchiveURL(store string) string {
+	scheme, rest, ok := strings.Cut(store, "://")
+	if !ok {
+		scheme, rest = "", store
+	}
+	path, query, _ := strings.Cut(rest, "?")
+	if path != "" {
+		ext := filepath.Ext(path)
+		path = strings.TrimSuffix(path, ext) + ".archive" + ext
+	}
+	archive := path
+	if ok {
+		archive = scheme + "://" + path
+	}
+	if query != "" {
+		archive += "?" + query
+	}
+	return archive
+}
+
+// defaultFiles names the file each backend uses when only the backend is
+// given, as in "taskmgr migrate --to=sqlite"
+var defaultFiles = map[string]string{
+	"crdt":    "tasks.crdt",
+	"file":    "tasks.json",
+	"journal": "tasks.journal",
+	"sqlite":  "tasks.db",
+}
+
+// StoreURL expands a bare backend name, such as sqlite, to the URL of
+// its default file. Anything else is returned as given.
+func StoreURL(name string) string {
+	if file, ok := defaultFiles[name]; ok {
+		return name + "://" + file
+	}
+	if name == "memory" {
+		return "memory://"
+	}
+	return name
+}
+
+// Load reads config.json in dir. A missing file gives the defaults.
+func Load(dir string) (Config, error) {
+	c := Config{Store: DefaultStore}
+	filename := filepath.Join(dir, "config.json")
+	data, err := os.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return c, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &c); err != nil {
+			return Config{Store: DefaultStore}, fmt.Errorf("%s: %v", filename, err)
+		}
+	}
+
+	if store := os.Getenv("TASKMGR_STORE"); store != "" {
+		c.Store = store
+	}
+	if c.Store == "" {
+		c.Store = DefaultStore
+	}
+	return c, nil
+}
+
+// Save writes c to config.json in dir, creating dir if needed
+func Save(dir string, c Config) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "config.json"), append(data, '\n'), 0644)
+}
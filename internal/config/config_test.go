@@ -0,0 +1,66 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TASKMGR_STORE", "")
+
+	c, err := Load(dir)
+	if err != nil || c.Store != DefaultStore {
+		t.Errorf("Expected the default store without a config file, got %+v, %v", c, err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"store": "journal://tasks.journal"}`), 0644)
+	if c, err := Load(dir); err != nil || c.Store != "journal://tasks.journal" {
+		t.Errorf("Expected the configured store, got %+v, %v", c, err)
+	}
+
+	t.Setenv("TASKMGR_STORE", "memory://")
+	if c, err := Load(dir); err != nil || c.Store != "memory://" {
+		t.Errorf("Expected $TASKMGR_STORE to win, got %+v, %v", c, err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"store": `), 0644)
+	if _, err := Load(dir); err == nil {
+		t.Error("Expected an error for a malformed config file")
+	}
+}
+
+func TestSaveAndStoreURL(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), ".taskmgr")
+	t.Setenv("TASKMGR_STORE", "")
+	if err := Save(dir, Config{Store: StoreURL("sqlite")}); err != nil {
+		t.Fatalf("Save returned an error: %v", err)
+	}
+	if c, err := Load(dir); err != nil || c.Store != "sqlite://tasks.db" {
+		t.Errorf("Expected the saved store, got %+v, %v", c, err)
+	}
+	if got := StoreURL("crdt"); got != "crdt://tasks.crdt" {
+		t.Errorf("Expected crdt://tasks.crdt, got %s", got)
+	}
+	if got := StoreURL("journal:///srv/tasks.journal"); got != "journal:///srv/tasks.journal" {
+		t.Errorf("Expected a full URL to be kept, got %s", got)
+	}
+}
+
+func TestArchiveStore(t *testing.T) {
+	for store, want := range map[string]string{
+		"file://tasks.json":           "file://tasks.archive.json",
+		"sqlite:///srv/team/tasks.db": "sqlite:///srv/team/tasks.archive.db",
+		"file://tasks.json?backups=3": "file://tasks.archive.json?backups=3",
+		"tasks.json":                  "tasks.archive.json",
+		"memory://":                   "memory://",
+	} {
+		if got := (Config{Store: store}).ArchiveStore(); got != want {
+			t.Errorf("ArchiveStore for %s: expected %s, got %s", store, want, got)
+		}
+	}
+	if got := (Config{Store: "file://tasks.json", Archive: "journal://old.journal"}).ArchiveStore(); got != "journal://old.journal" {
+		t.Errorf("Expected the configured archive, got %s", got)
+	}
+}
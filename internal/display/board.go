@@ -0,0 +1,257 @@
+package display
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"taskmgr/internal/tasks"
+)
+
+// BoardGroup selects how tasks are split into board columns
+type BoardGroup string
+
+const (
+	GroupByStatus   BoardGroup = "status"
+	GroupByPriority BoardGroup = "priority"
+	GroupByProject  BoardGroup = "project"
+	GroupByTag      BoardGroup = "tag"
+)
+
+// ParseBoardGroup parses the value of the board --by flag
+func ParseBoardGroup(s string) (BoardGroup, error) {
+	switch strings.ToLower(s) {
+	case "", "status":
+		return GroupByStatus, nil
+	case "priority":
+		return GroupByPriority, nil
+	case "project":
+		return GroupByProject, nil
+	case "tag", "tags":
+		return GroupByTag, nil
+	default:
+		return GroupByStatus, fmt.Errorf("invalid board grouping: %s (use status, priority, project or tag)", s)
+	}
+}
+
+// Card is a task placed on the board, with its index in the store
+type Card struct {
+	Index int
+	Task  tasks.Task
+}
+
+// Column is one board column. Key is the value a card takes when moved
+// into the column: "pending"/"done" for status, the priority name, the
+// project name or the tag, and "" for the catch-all column.
+type Column struct {
+	Title string
+	Key   string
+	Cards []Card
+}
+
+// BuildColumns groups tasks into board columns. For GroupByTag, tagSet
+// lists the tags to use as columns in order; tasks are placed under the
+// first tag of the set they carry and tasks with none of them go to an
+// "Other" column. Catch-all columns are only included when non-empty.
+func BuildColumns(taskList []tasks.Task, group BoardGroup, tagSet []string) ([]Column, error) {
+	var columns []Column
+	var place func(t tasks.Task) int
+
+	switch group {
+	case GroupByStatus:
+		columns = []Column{{Title: "Pending", Key: "pending"}, {Title: "Done", Key: "done"}}
+		place = func(t tasks.Task) int {
+			if t.Done {
+				return 1
+			}
+			return 0
+		}
+	case GroupByPriority:
+		priorities := []tasks.Priority{tasks.Critical, tasks.High, tasks.Medium, tasks.Low}
+		for _, p := range priorities {
+			columns = append(columns, Column{Title: strings.Title(p.String()), Key: p.String()})
+		}
+		place = func(t tasks.Task) int {
+			for i, p := range priorities {
+				if t.Priority == p {
+					return i
+				}
+			}
+			return len(priorities) - 1
+		}
+	case GroupByProject:
+		projects := make(map[string]bool)
+		for _, t := range taskList {
+			if t.Project != "" {
+				projects[t.Project] = true
+			}
+		}
+		var names []string
+		for name := range projects {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			columns = append(columns, Column{Title: name, Key: name})
+		}
+		columns = append(columns, Column{Title: "No project"})
+		place = func(t tasks.Task) int {
+			for i, name := range names {
+				if t.Project == name {
+					return i
+				}
+			}
+			return len(names)
+		}
+	case GroupByTag:
+		if len(tagSet) == 0 {
+			return nil, fmt.Errorf("grouping by tag requires a tag set (e.g. --tags=todo,doing,review)")
+		}
+		for _, tag := range tagSet {
+			columns = append(columns, Column{Title: tag, Key: tag})
+		}
+		columns = append(columns, Column{Title: "Other"})
+		place = func(t tasks.Task) int {
+			for i, tag := range tagSet {
+				if t.HasTag(tag) {
+					return i
+				}
+			}
+			return len(tagSet)
+		}
+	default:
+		return nil, fmt.Errorf("invalid board grouping: %s", group)
+	}
+
+	for i, t := range taskList {
+		c := place(t)
+		columns[c].Cards = append(columns[c].Cards, Card{Index: i, Task: t})
+	}
+
+	// Drop an empty catch-all column
+	if last := len(columns) - 1; columns[last].Key == "" && len(columns[last].Cards) == 0 {
+		columns = columns[:last]
+	}
+	return columns, nil
+}
+
+// minColumnWidth is the narrowest a board column is allowed to get before
+// the board wraps onto further rows of columns
+const minColumnWidth = 18
+
+const columnGap = " │ "
+
+// BoardFormatter renders tasks as side-by-side columns
+type BoardFormatter struct {
+	options DisplayOptions
+	task    *TaskFormatter
+}
+
+// NewBoardFormatter creates a new board formatter with the given options
+func NewBoardFormatter(opts DisplayOptions) *BoardFormatter {
+	if opts.ColorScheme == (ColorScheme{}) {
+		opts.ColorScheme = DefaultColorScheme
+	}
+	return &BoardFormatter{options: opts, task: NewTaskFormatter(opts)}
+}
+
+// FormatBoard renders the columns to fit within width terminal cells.
+// When the columns do not fit side by side at a readable width, they wrap
+// onto several bands, each as wide as the terminal.
+func (bf *BoardFormatter) FormatBoard(columns []Column, width int) string {
+	return bf.FormatBoardSelection(columns, width, -1, -1)
+}
+
+// FormatBoardSelection renders the board like FormatBoard, marking the card
+// at position card of column col as selected. Passing -1 marks nothing.
+func (bf *BoardFormatter) FormatBoardSelection(columns []Column, width, col, card int) string {
+	if len(columns) == 0 {
+		return "No columns to display."
+	}
+
+	gap := VisibleWidth(columnGap)
+	perBand := (width + gap) / (minColumnWidth + gap)
+	if perBand < 1 {
+		perBand = 1
+	}
+	if perBand > len(columns) {
+		perBand = len(columns)
+	}
+
+	var bands []string
+	for start := 0; start < len(columns); start += perBand {
+		end := start + perBand
+		if end > len(columns) {
+			end = len(columns)
+		}
+		selected := -1
+		if col >= start && col < end {
+			selected = col - start
+		}
+		bands = append(bands, bf.formatBand(columns[start:end], width, selected, card))
+	}
+	return strings.Join(bands, "\n\n")
+}
+
+func (bf *BoardFormatter) formatBand(columns []Column, width, selectedCol, selectedCard int) string {
+	colWidth := (width - (len(columns)-1)*VisibleWidth(columnGap)) / len(columns)
+	if colWidth < 1 {
+		colWidth = 1
+	}
+
+	cells := make([][]string, len(columns))
+	height := 0
+	for i, column := range columns {
+		header := fmt.Sprintf("%s (%d)", column.Title, len(column.Cards))
+		if bf.options.ShowColors {
+			header = Colorize(Bold, header)
+		}
+		cells[i] = []string{header, strings.Repeat("─", colWidth)}
+		for j, card := range column.Cards {
+			sel := i == selectedCol && j == selectedCard
+			cells[i] = append(cells[i], bf.formatCard(card, sel)...)
+		}
+		if len(cells[i]) > height {
+			height = len(cells[i])
+		}
+	}
+
+	lines := make([]string, height)
+	for row := 0; row < height; row++ {
+		parts := make([]string, len(columns))
+		for i := range columns {
+			cell := ""
+			if row < len(cells[i]) {
+				cell = cells[i][row]
+			}
+			parts[i] = PadRight(cell, colWidth)
+		}
+		lines[row] = strings.TrimRight(strings.Join(parts, columnGap), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatCard renders a card as a title line followed by a details line
+// with priority, tags and due date, depending on the display options
+func (bf *BoardFormatter) formatCard(card Card, selected bool) []string {
+	marker := " "
+	if selected {
+		marker = ">"
+	}
+	title := fmt.Sprintf("%s%s %d: %s", marker, bf.task.getStatusIcon(card.Task), card.Index, bf.task.formatTitle(card.Task))
+
+	var details []string
+	if bf.options.ShowPriority {
+		details = append(details, bf.task.formatPriority(card.Task.Priority))
+	}
+	if bf.options.ShowTags && len(card.Task.Tags) > 0 {
+		details = append(details, bf.task.formatTags(card.Task.Tags))
+	}
+	if bf.options.ShowDueDate && card.Task.DueDate != nil {
+		details = append(details, bf.task.formatDueDate(*card.Task.DueDate, card.Task.Done))
+	}
+	if len(details) == 0 {
+		return []string{title}
+	}
+	return []string{title, "   " + strings.Join(details, " ")}
+}
@@ -0,0 +1,218 @@
+package display
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"taskmgr/internal/tasks"
+)
+
+func boardTasks() []tasks.Task {
+	tomorrow := time.Now().Add(30 * time.Hour)
+	return []tasks.Task{
+		{Title: "Write spec", Priority: tasks.High, Project: "alpha", Tags: []string{"doing"}},
+		{Title: "Ship it", Priority: tasks.Critical, Project: "beta", Done: true, Tags: []string{"done"}},
+		{Title: "Review", Priority: tasks.Low, Tags: []string{"todo", "doing"}, DueDate: &tomorrow},
+		{Title: "Plan", Priority: tasks.Medium, Project: "alpha"},
+	}
+}
+
+func TestParseBoardGroup(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected BoardGroup
+		wantErr  bool
+	}{
+		{"", GroupByStatus, false},
+		{"status", GroupByStatus, false},
+		{"PRIORITY", GroupByPriority, false},
+		{"project", GroupByProject, false},
+		{"tags", GroupByTag, false},
+		{"owner", GroupByStatus, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			group, err := ParseBoardGroup(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBoardGroup(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if group != tt.expected {
+				t.Errorf("ParseBoardGroup(%q) = %q, expected %q", tt.input, group, tt.expected)
+			}
+		})
+	}
+}
+
+func columnSummary(columns []Column) map[string][]int {
+	summary := make(map[string][]int)
+	for _, c := range columns {
+		summary[c.Title] = []int{}
+		for _, card := range c.Cards {
+			summary[c.Title] = append(summary[c.Title], card.Index)
+		}
+	}
+	return summary
+}
+
+func TestBuildColumns(t *testing.T) {
+	taskList := boardTasks()
+
+	tests := []struct {
+		name     string
+		group    BoardGroup
+		tagSet   []string
+		expected []string
+		cards    map[string][]int
+	}{
+		{
+			name:     "status",
+			group:    GroupByStatus,
+			expected: []string{"Pending", "Done"},
+			cards:    map[string][]int{"Pending": {0, 2, 3}, "Done": {1}},
+		},
+		{
+			name:     "priority",
+			group:    GroupByPriority,
+			expected: []string{"Critical", "High", "Medium", "Low"},
+			cards:    map[string][]int{"Critical": {1}, "High": {0}, "Medium": {3}, "Low": {2}},
+		},
+		{
+			name:     "project",
+			group:    GroupByProject,
+			expected: []string{"alpha", "beta", "No project"},
+			cards:    map[string][]int{"alpha": {0, 3}, "beta": {1}, "No project": {2}},
+		},
+		{
+			name:     "tag set uses first matching tag",
+			group:    GroupByTag,
+			tagSet:   []string{"todo", "doing", "done"},
+			expected: []string{"todo", "doing", "done", "Other"},
+			cards:    map[string][]int{"todo": {2}, "doing": {0}, "done": {1}, "Other": {3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, err := BuildColumns(taskList, tt.group, tt.tagSet)
+			if err != nil {
+				t.Fatalf("BuildColumns returned an error: %v", err)
+			}
+			if len(columns) != len(tt.expected) {
+				t.Fatalf("Expected %d columns, got %d", len(tt.expected), len(columns))
+			}
+			for i, title := range tt.expected {
+				if columns[i].Title != title {
+					t.Errorf("Column %d: expected %q, got %q", i, title, columns[i].Title)
+				}
+			}
+			summary := columnSummary(columns)
+			for title, indexes := range tt.cards {
+				got := summary[title]
+				if len(got) != len(indexes) {
+					t.Errorf("Column %q: expected cards %v, got %v", title, indexes, got)
+					continue
+				}
+				for i := range indexes {
+					if got[i] != indexes[i] {
+						t.Errorf("Column %q: expected cards %v, got %v", title, indexes, got)
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestBuildColumnsCatchAll(t *testing.T) {
+	// Catch-all columns are dropped when empty
+	columns, err := BuildColumns([]tasks.Task{{Title: "A", Project: "x"}}, GroupByProject, nil)
+	if err != nil {
+		t.Fatalf("BuildColumns returned an error: %v", err)
+	}
+	if len(columns) != 1 || columns[0].Title != "x" {
+		t.Errorf("Expected only the 'x' column, got %v", columns)
+	}
+
+	if _, err := BuildColumns(nil, GroupByTag, nil); err == nil {
+		t.Error("Expected error when grouping by tag without a tag set")
+	}
+	if _, err := BuildColumns(nil, BoardGroup("owner"), nil); err == nil {
+		t.Error("Expected error for unknown grouping")
+	}
+}
+
+func TestFormatBoard(t *testing.T) {
+	columns, err := BuildColumns(boardTasks(), GroupByStatus, nil)
+	if err != nil {
+		t.Fatalf("BuildColumns returned an error: %v", err)
+	}
+
+	formatter := NewBoardFormatter(DisplayOptions{ShowPriority: true, ShowTags: true, ShowDueDate: true})
+	board := formatter.FormatBoard(columns, 80)
+	lines := strings.Split(board, "\n")
+
+	if !strings.Contains(lines[0], "Pending (3)") || !strings.Contains(lines[0], "Done (1)") {
+		t.Errorf("Expected column headers with counts, got %q", lines[0])
+	}
+	for i, line := range lines {
+		if VisibleWidth(line) > 80 {
+			t.Errorf("Line %d is wider than 80 cells: %q", i, line)
+		}
+	}
+	for _, want := range []string{"0: Write spec", "1: Ship it", "[HIG]", "[doing]", "(Due: tomorrow)"} {
+		if !strings.Contains(board, want) {
+			t.Errorf("Expected board to contain %q, got:\n%s", want, board)
+		}
+	}
+
+	// Both columns share lines side by side
+	if !strings.Contains(lines[2], "Write spec") || !strings.Contains(lines[2], "Ship it") {
+		t.Errorf("Expected first cards on the same line, got %q", lines[2])
+	}
+}
+
+func TestFormatBoardWraps(t *testing.T) {
+	columns, err := BuildColumns(boardTasks(), GroupByPriority, nil)
+	if err != nil {
+		t.Fatalf("BuildColumns returned an error: %v", err)
+	}
+
+	formatter := NewBoardFormatter(DisplayOptions{})
+	board := formatter.FormatBoard(columns, 40)
+
+	// Only two columns fit into 40 cells, so four columns take two bands
+	bands := strings.Split(board, "\n\n")
+	if len(bands) != 2 {
+		t.Fatalf("Expected 2 bands, got %d:\n%s", len(bands), board)
+	}
+	if !strings.Contains(bands[0], "Critical") || !strings.Contains(bands[1], "Low") {
+		t.Errorf("Unexpected band contents:\n%s", board)
+	}
+	for i, line := range strings.Split(board, "\n") {
+		if VisibleWidth(line) > 40 {
+			t.Errorf("Line %d is wider than 40 cells: %q", i, line)
+		}
+	}
+
+	if got := formatter.FormatBoard(nil, 80); got != "No columns to display." {
+		t.Errorf("Expected empty board message, got %q", got)
+	}
+}
+
+func TestFormatBoardSelection(t *testing.T) {
+	columns, err := BuildColumns(boardTasks(), GroupByStatus, nil)
+	if err != nil {
+		t.Fatalf("BuildColumns returned an error: %v", err)
+	}
+
+	formatter := NewBoardFormatter(DisplayOptions{})
+	board := formatter.FormatBoardSelection(columns, 80, 1, 0)
+	if !strings.Contains(board, ">[x] 1: Ship it") {
+		t.Errorf("Expected selected card marker, got:\n%s", board)
+	}
+	if strings.Count(board, ">") != 1 {
+		t.Errorf("Expected exactly one selected card, got:\n%s", board)
+	}
+}
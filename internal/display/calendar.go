@@ -0,0 +1,227 @@
+package display
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"taskmgr/internal/tasks"
+)
+
+// calendarCellWidth is the width of one day in the month grid: the day
+// number, a marker and the task count
+const calendarCellWidth = 7
+
+// CalendarFormatter renders calendar and agenda views of due dates
+type CalendarFormatter struct {
+	options DisplayOptions
+	task    *TaskFormatter
+}
+
+// NewCalendarFormatter creates a new calendar formatter with the given options
+func NewCalendarFormatter(opts DisplayOptions) *CalendarFormatter {
+	if opts.ColorScheme == (ColorScheme{}) {
+		opts.ColorScheme = DefaultColorScheme
+	}
+	opts.TableFormat = false
+	return &CalendarFormatter{options: opts, task: NewTaskFormatter(opts)}
+}
+
+// dayOf returns the calendar date of t, as written in its own location,
+// so that a due date parsed as "2025-07-01" falls on July 1st everywhere
+func dayOf(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// dayCount tallies the tasks due on a single day
+type dayCount struct {
+	pending int
+	done    int
+}
+
+// FormatMonth renders a Monday-first month grid for the month containing
+// month. Each day with tasks due shows a marker and the number of tasks,
+// colored like due dates in the task list: overdue, today, upcoming, or
+// completed when every task due that day is done.
+func (cf *CalendarFormatter) FormatMonth(taskList []tasks.Task, month time.Time, now time.Time) string {
+	first := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	next := first.AddDate(0, 1, 0)
+	today := dayOf(now)
+
+	counts := make(map[time.Time]*dayCount)
+	total, overdue := 0, 0
+	for _, t := range taskList {
+		if t.DueDate == nil {
+			continue
+		}
+		day := dayOf(*t.DueDate)
+		if day.Before(first) || !day.Before(next) {
+			continue
+		}
+		c := counts[day]
+		if c == nil {
+			c = &dayCount{}
+			counts[day] = c
+		}
+		total++
+		if t.Done {
+			c.done++
+		} else {
+			c.pending++
+			if day.Before(today) {
+				overdue++
+			}
+		}
+	}
+
+	gridWidth := 7 * calendarCellWidth
+	title := first.Format("January 2006")
+	pad := (gridWidth - len(title)) / 2
+	header := strings.Repeat(" ", pad) + title
+	if cf.options.ShowColors {
+		header = Colorize(Bold, header)
+	}
+
+	lines := []string{header}
+	var weekdays []string
+	for _, name := range []string{"Mo", "Tu", "We", "Th", "Fr", "Sa", "Su"} {
+		weekdays = append(weekdays, fmt.Sprintf("%-*s", calendarCellWidth, name))
+	}
+	lines = append(lines, strings.TrimRight(strings.Join(weekdays, ""), " "))
+
+	// Monday is column 0
+	offset := (int(first.Weekday()) + 6) % 7
+	var cells []string
+	for i := 0; i < offset; i++ {
+		cells = append(cells, strings.Repeat(" ", calendarCellWidth))
+	}
+	for day := first; day.Before(next); day = day.AddDate(0, 0, 1) {
+		cells = append(cells, cf.formatDayCell(day, counts[day], today))
+		if len(cells) == 7 {
+			lines = append(lines, strings.TrimRight(strings.Join(cells, ""), " "))
+			cells = nil
+		}
+	}
+	if len(cells) > 0 {
+		lines = append(lines, strings.TrimRight(strings.Join(cells, ""), " "))
+	}
+
+	lines = append(lines, "")
+	summary := fmt.Sprintf("%d tasks due this month", total)
+	if overdue > 0 {
+		summary += fmt.Sprintf(", %d overdue", overdue)
+	}
+	lines = append(lines, summary)
+	return strings.Join(lines, "\n")
+}
+
+// formatDayCell renders one grid cell padded to calendarCellWidth
+func (cf *CalendarFormatter) formatDayCell(day time.Time, count *dayCount, today time.Time) string {
+	number := fmt.Sprintf("%2d", day.Day())
+	if day.Equal(today) {
+		if cf.options.ShowColors {
+			number = Colorize(Bold, number)
+		} else {
+			// Without colors, brackets mark today
+			number = fmt.Sprintf("[%d]", day.Day())
+			if day.Day() < 10 {
+				number = " " + number
+			}
+		}
+	}
+	if count == nil {
+		return PadRight(number, calendarCellWidth)
+	}
+
+	marker := "*"
+	if cf.options.ShowIcons {
+		marker = "•"
+	}
+	text := fmt.Sprintf("%s%d", marker, count.pending+count.done)
+
+	var color Color
+	switch {
+	case count.pending == 0:
+		color = cf.options.ColorScheme.Completed
+	case day.Before(today):
+		color = cf.options.ColorScheme.Overdue
+	case day.Equal(today) || day.Equal(today.AddDate(0, 0, 1)):
+		color = cf.options.ColorScheme.Medium
+	default:
+		color = cf.options.ColorScheme.Low
+	}
+	if cf.options.ShowColors {
+		text = Colorize(color, text)
+	}
+	return PadRight(number+text, calendarCellWidth)
+}
+
+// FormatAgenda lists tasks under day headings: pending overdue tasks first,
+// then each day from today through the given number of days that has
+// tasks due, headed "Today", "Tomorrow", the weekday name within the
+// coming week, and the full date beyond it. Task indexes are positions in
+// taskList so they can be used with the other commands.
+func (cf *CalendarFormatter) FormatAgenda(taskList []tasks.Task, now time.Time, days int) string {
+	today := dayOf(now)
+	end := today.AddDate(0, 0, days)
+
+	var overdue []int
+	byDay := make(map[time.Time][]int)
+	for i, t := range taskList {
+		if t.DueDate == nil {
+			continue
+		}
+		day := dayOf(*t.DueDate)
+		switch {
+		case day.Before(today):
+			if !t.Done {
+				overdue = append(overdue, i)
+			}
+		case day.Before(end):
+			byDay[day] = append(byDay[day], i)
+		}
+	}
+
+	var sections []string
+	if len(overdue) > 0 {
+		sections = append(sections, cf.formatAgendaSection("Overdue", cf.options.ColorScheme.Overdue, taskList, overdue))
+	}
+	for day := today; day.Before(end); day = day.AddDate(0, 0, 1) {
+		indexes := byDay[day]
+		if len(indexes) == 0 {
+			continue
+		}
+		sections = append(sections, cf.formatAgendaSection(agendaHeading(day, today), cf.options.ColorScheme.DueDate, taskList, indexes))
+	}
+
+	if len(sections) == 0 {
+		return fmt.Sprintf("Nothing due in the next %d days.", days)
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+func (cf *CalendarFormatter) formatAgendaSection(heading string, color Color, taskList []tasks.Task, indexes []int) string {
+	if cf.options.ShowColors {
+		heading = Colorize(color, Colorize(Bold, heading))
+	}
+	lines := []string{heading}
+	for _, i := range indexes {
+		lines = append(lines, "  "+cf.task.FormatTask(i, taskList[i]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// agendaHeading names a day relative to today
+func agendaHeading(day, today time.Time) string {
+	switch diff := int(day.Sub(today).Hours() / 24); {
+	case diff == 0:
+		return "Today, " + day.Format("Jan 2")
+	case diff == 1:
+		return "Tomorrow, " + day.Format("Jan 2")
+	case diff < 7:
+		return day.Format("Monday, Jan 2")
+	default:
+		return day.Format("Monday, Jan 2 2006")
+	}
+}
@@ -0,0 +1,146 @@
+package display
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"taskmgr/internal/tasks"
+)
+
+func date(s string) *time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return &t
+}
+
+func TestFormatMonth(t *testing.T) {
+	now := time.Date(2025, 7, 15, 10, 0, 0, 0, time.UTC)
+	taskList := []tasks.Task{
+		{Title: "Overdue", DueDate: date("2025-07-03")},
+		{Title: "Today A", DueDate: date("2025-07-15")},
+		{Title: "Today B", DueDate: date("2025-07-15")},
+		{Title: "Finished", DueDate: date("2025-07-20"), Done: true},
+		{Title: "Next month", DueDate: date("2025-08-01")},
+		{Title: "No due date"},
+	}
+
+	formatter := NewCalendarFormatter(DisplayOptions{})
+	month := formatter.FormatMonth(taskList, *date("2025-07-01"), now)
+	lines := strings.Split(month, "\n")
+
+	if strings.TrimSpace(lines[0]) != "July 2025" {
+		t.Errorf("Expected title 'July 2025', got %q", lines[0])
+	}
+	if lines[1] != "Mo     Tu     We     Th     Fr     Sa     Su" {
+		t.Errorf("Unexpected weekday header %q", lines[1])
+	}
+	// July 1st 2025 is a Tuesday
+	if !strings.HasPrefix(lines[2], strings.Repeat(" ", calendarCellWidth)+" 1") {
+		t.Errorf("Expected first week to start on Tuesday, got %q", lines[2])
+	}
+	// July 3rd has one overdue task
+	if !strings.Contains(lines[2], " 3*1") {
+		t.Errorf("Expected marker on the 3rd, got %q", lines[2])
+	}
+	// Today is bracketed and has two tasks
+	if !strings.Contains(month, "[15]*2") {
+		t.Errorf("Expected today marker with 2 tasks, got:\n%s", month)
+	}
+	if !strings.Contains(month, "20*1") {
+		t.Errorf("Expected marker on the 20th, got:\n%s", month)
+	}
+	// July 2025 spans five weeks
+	weeks := 0
+	for _, line := range lines[2:] {
+		if line == "" {
+			break
+		}
+		weeks++
+	}
+	if weeks != 5 {
+		t.Errorf("Expected 5 week rows, got %d", weeks)
+	}
+	if lines[len(lines)-1] != "4 tasks due this month, 1 overdue" {
+		t.Errorf("Unexpected summary %q", lines[len(lines)-1])
+	}
+}
+
+func TestFormatMonthIcons(t *testing.T) {
+	now := time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC)
+	taskList := []tasks.Task{{Title: "Due", DueDate: date("2025-02-12")}}
+
+	formatter := NewCalendarFormatter(DisplayOptions{ShowIcons: true})
+	month := formatter.FormatMonth(taskList, now, now)
+	if !strings.Contains(month, "12•1") {
+		t.Errorf("Expected icon marker, got:\n%s", month)
+	}
+	// February 2025 starts on a Saturday
+	lines := strings.Split(month, "\n")
+	if !strings.HasPrefix(lines[2], strings.Repeat(" ", 5*calendarCellWidth)+" 1") {
+		t.Errorf("Expected first week to start on Saturday, got %q", lines[2])
+	}
+	if lines[len(lines)-1] != "1 tasks due this month" {
+		t.Errorf("Unexpected summary %q", lines[len(lines)-1])
+	}
+}
+
+func TestFormatAgenda(t *testing.T) {
+	now := time.Date(2025, 7, 14, 9, 0, 0, 0, time.UTC) // a Monday
+	taskList := []tasks.Task{
+		{Title: "Late report", DueDate: date("2025-07-10")},
+		{Title: "Late but done", DueDate: date("2025-07-11"), Done: true},
+		{Title: "Standup", DueDate: date("2025-07-14")},
+		{Title: "Dentist", DueDate: date("2025-07-15")},
+		{Title: "Demo", DueDate: date("2025-07-17")},
+		{Title: "Far away", DueDate: date("2025-07-30")},
+		{Title: "Someday"},
+	}
+
+	formatter := NewCalendarFormatter(DisplayOptions{})
+	agenda := formatter.FormatAgenda(taskList, now, 7)
+
+	expectedOrder := []string{
+		"Overdue",
+		"[ ] 0: Late report",
+		"Today, Jul 14",
+		"[ ] 2: Standup",
+		"Tomorrow, Jul 15",
+		"[ ] 3: Dentist",
+		"Thursday, Jul 17",
+		"[ ] 4: Demo",
+	}
+	last := -1
+	for _, want := range expectedOrder {
+		pos := strings.Index(agenda, want)
+		if pos < 0 {
+			t.Errorf("Expected agenda to contain %q, got:\n%s", want, agenda)
+			continue
+		}
+		if pos < last {
+			t.Errorf("Expected %q to appear in order, got:\n%s", want, agenda)
+		}
+		last = pos
+	}
+
+	for _, unwanted := range []string{"Late but done", "Far away", "Someday"} {
+		if strings.Contains(agenda, unwanted) {
+			t.Errorf("Expected agenda not to contain %q, got:\n%s", unwanted, agenda)
+		}
+	}
+
+	// Longer ranges use full dates beyond the first week
+	longer := formatter.FormatAgenda(taskList, now, 30)
+	if !strings.Contains(longer, "Wednesday, Jul 30 2025") {
+		t.Errorf("Expected full date heading, got:\n%s", longer)
+	}
+}
+
+func TestFormatAgendaEmpty(t *testing.T) {
+	formatter := NewCalendarFormatter(DisplayOptions{})
+	if got := formatter.FormatAgenda(nil, time.Now(), 7); got != "Nothing due in the next 7 days." {
+		t.Errorf("Unexpected empty agenda %q", got)
+	}
+}
@@ -0,0 +1,71 @@
+package display
+
+import (
+	"fmt"
+	"strings"
+
+	"taskmgr/internal/tasks"
+)
+
+// changeTimeFormat is how timestamps are shown in the change log
+const changeTimeFormat = "2006-01-02 15:04"
+
+// FormatChangeLog renders a task's timestamps followed by its change log,
+// one line per change with the time, actor, field and old and new values
+func (tf *TaskFormatter) FormatChangeLog(task tasks.Task, changes []tasks.Change) string {
+	header := fmt.Sprintf("Task %s: %s", task.ID, task.Title)
+	if tf.options.ShowColors {
+		header = Colorize(Bold, header)
+	}
+
+	stamps := []string{"Created " + task.CreatedAt.Format(changeTimeFormat)}
+	if !task.ModifiedAt.IsZero() {
+		stamps = append(stamps, "modified "+task.ModifiedAt.Format(changeTimeFormat))
+	}
+	if task.CompletedAt != nil {
+		stamps = append(stamps, "completed "+task.CompletedAt.Format(changeTimeFormat))
+	}
+	lines := []string{header, strings.Join(stamps, ", ")}
+
+	if len(changes) == 0 {
+		return strings.Join(append(lines, "No changes recorded."), "\n")
+	}
+
+	arrow := "→"
+	if !tf.options.ShowIcons {
+		arrow = "->"
+	}
+	actorWidth := 0
+	for _, c := range changes {
+		if VisibleWidth(c.Actor) > actorWidth {
+			actorWidth = VisibleWidth(c.Actor)
+		}
+	}
+
+	lines = append(lines, "")
+	for _, c := range changes {
+		var value string
+		switch c.Field {
+		case "created":
+			value = fmt.Sprintf("%q", c.New)
+		case "removed":
+			value = fmt.Sprintf("%q", c.Old)
+		default:
+			value = fmt.Sprintf("%s %s %s", formatChangeValue(c.Old), arrow, formatChangeValue(c.New))
+		}
+		when := c.Time.Format(changeTimeFormat)
+		if tf.options.ShowColors {
+			when = Colorize(tf.options.ColorScheme.DueDate, when)
+		}
+		lines = append(lines, fmt.Sprintf("%s  %s  %-11s %s", when, PadRight(c.Actor, actorWidth), c.Field, value))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatChangeValue shows empty values explicitly
+func formatChangeValue(v string) string {
+	if v == "" {
+		return "(none)"
+	}
+	return v
+}
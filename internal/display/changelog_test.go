@@ -0,0 +1,57 @@
+package display
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"taskmgr/internal/tasks"
+)
+
+func TestFormatChangeLog(t *testing.T) {
+	created := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)
+	completed := time.Date(2025, 7, 3, 14, 2, 0, 0, time.UTC)
+	task := tasks.Task{ID: "abc123", Title: "Write report", CreatedAt: created, ModifiedAt: completed, CompletedAt: &completed}
+	changes := []tasks.Change{
+		{TaskID: "abc123", Field: "created", New: "Write report", Time: created, Actor: "alice"},
+		{TaskID: "abc123", Field: "project", Old: "", New: "q3", Time: created.Add(time.Hour), Actor: "alice"},
+		{TaskID: "abc123", Field: "done", Old: "false", New: "true", Time: completed, Actor: "bob"},
+	}
+
+	formatter := NewTaskFormatter(DisplayOptions{})
+	lines := strings.Split(formatter.FormatChangeLog(task, changes), "\n")
+
+	expected := []string{
+		"Task abc123: Write report",
+		"Created 2025-07-01 09:00, modified 2025-07-03 14:02, completed 2025-07-03 14:02",
+		"",
+		`2025-07-01 09:00  alice  created     "Write report"`,
+		"2025-07-01 10:00  alice  project     (none) -> q3",
+		"2025-07-03 14:02  bob    done        false -> true",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines, got %d:\n%s", len(expected), len(lines), strings.Join(lines, "\n"))
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("Line %d: expected %q, got %q", i, expected[i], lines[i])
+		}
+	}
+
+	// Icons switch the arrow
+	withIcons := NewTaskFormatter(DisplayOptions{ShowIcons: true}).FormatChangeLog(task, changes)
+	if !strings.Contains(withIcons, "false → true") {
+		t.Errorf("Expected unicode arrow, got:\n%s", withIcons)
+	}
+}
+
+func TestFormatChangeLogEmpty(t *testing.T) {
+	task := tasks.Task{ID: "abc123", Title: "Quiet", CreatedAt: time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)}
+	out := NewTaskFormatter(DisplayOptions{}).FormatChangeLog(task, nil)
+	if !strings.HasSuffix(out, "No changes recorded.") {
+		t.Errorf("Expected empty log message, got:\n%s", out)
+	}
+	if strings.Contains(out, "modified") || strings.Contains(out, "completed") {
+		t.Errorf("Expected only the creation time, got:\n%s", out)
+	}
+}
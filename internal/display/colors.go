@@ -3,6 +3,9 @@ package display
 import (
 	"fmt"
 	"os"
+	"strconv"
+
+	"golang.org/x/term"
 )
 
 type Color string
@@ -61,6 +64,18 @@ func IsColorSupported() bool {
 	return true
 }
 
+// TerminalWidth returns the width of the terminal attached to stdout,
+// falling back to the COLUMNS environment variable and then 80
+func TerminalWidth() int {
+	if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 0 {
+		return width
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return 80
+}
+
 // ColorScheme defines the color scheme for different elements
 type ColorScheme struct {
 	Completed Color
@@ -3,6 +3,8 @@ package display
 import (
 	"os"
 	"testing"
+
+	"golang.org/x/term"
 )
 
 func TestColorString(t *testing.T) {
@@ -141,4 +143,21 @@ func TestDefaultColorScheme(t *testing.T) {
 	if scheme.DueDate == "" {
 		t.Error("DefaultColorScheme.DueDate should not be empty")
 	}
-}
\ No newline at end of file
+}
+func TestTerminalWidth(t *testing.T) {
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		t.Skip("stdout is a terminal; its real width takes precedence")
+	}
+	original := os.Getenv("COLUMNS")
+	defer os.Setenv("COLUMNS", original)
+
+	os.Setenv("COLUMNS", "132")
+	if width := TerminalWidth(); width != 132 {
+		t.Errorf("Expected width 132 from COLUMNS, got %d", width)
+	}
+
+	os.Setenv("COLUMNS", "invalid")
+	if width := TerminalWidth(); width != 80 {
+		t.Errorf("Expected fallback width 80, got %d", width)
+	}
+}
@@ -62,6 +62,11 @@ func (tf *TaskFormatter) formatListItem(index int, task tasks.Task) string {
 		parts = append(parts, tags)
 	}
 	
+	// Project
+	if tf.options.ShowTags && task.Project != "" {
+		parts = append(parts, tf.formatProject(task.Project))
+	}
+	
 	// Due date
 	if tf.options.ShowDueDate && task.DueDate != nil {
 		dueDate := tf.formatDueDate(*task.DueDate, task.Done)
@@ -198,6 +203,14 @@ func (tf *TaskFormatter) formatTags(tags []string) string {
 	return fmt.Sprintf("[%s]", Colorize(tf.options.ColorScheme.Tags, tagStr))
 }
 
+// formatProject formats the task project in todo.txt style
+func (tf *TaskFormatter) formatProject(project string) string {
+	if !tf.options.ShowColors {
+		return "+" + project
+	}
+	return Colorize(tf.options.ColorScheme.Tags, "+"+project)
+}
+
 // formatDescription formats the task description as a tag-like element
 func (tf *TaskFormatter) formatDescription(description string) string {
 	if description == "" {
@@ -0,0 +1,212 @@
+package display
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"taskmgr/internal/tasks"
+)
+
+// HistoryBucket is the period each point of a history chart covers
+type HistoryBucket string
+
+const (
+	BucketDay  HistoryBucket = "day"
+	BucketWeek HistoryBucket = "week"
+)
+
+// ParseHistoryBucket parses the value of the stats --by flag
+func ParseHistoryBucket(s string) (HistoryBucket, error) {
+	switch strings.ToLower(s) {
+	case "", "day", "daily":
+		return BucketDay, nil
+	case "week", "weekly":
+		return BucketWeek, nil
+	default:
+		return BucketDay, fmt.Errorf("invalid history period: %s (use day or week)", s)
+	}
+}
+
+// HistoryPoint holds the activity of one day or week
+type HistoryPoint struct {
+	Start     time.Time
+	Created   int
+	Completed int
+	// Remaining is the number of tasks that existed and were still open at
+	// the end of the period, the value plotted by a burndown chart
+	Remaining int
+}
+
+// bucketStart returns the start of the day or Monday-based week containing t
+func bucketStart(t time.Time, bucket HistoryBucket) time.Time {
+	day := dayOf(t)
+	if bucket == BucketWeek {
+		day = day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	}
+	return day
+}
+
+func bucketNext(start time.Time, bucket HistoryBucket) time.Time {
+	if bucket == BucketWeek {
+		return start.AddDate(0, 0, 7)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// CalculateHistory counts created and completed tasks per period from the
+// period containing from through the one containing to. Completed tasks
+// without a completion timestamp are counted as completed before the
+// range, since when they were finished is unknown.
+func CalculateHistory(taskList []tasks.Task, from, to time.Time, bucket HistoryBucket) []HistoryPoint {
+	var points []HistoryPoint
+	last := bucketStart(to, bucket)
+	for start := bucketStart(from, bucket); !start.After(last); start = bucketNext(start, bucket) {
+		points = append(points, HistoryPoint{Start: start})
+	}
+
+	for i := range points {
+		end := bucketNext(points[i].Start, bucket)
+		for _, t := range taskList {
+			created := dayOf(t.CreatedAt)
+			if !created.Before(points[i].Start) && created.Before(end) {
+				points[i].Created++
+			}
+
+			var completed *time.Time
+			if t.CompletedAt != nil {
+				day := dayOf(*t.CompletedAt)
+				completed = &day
+			}
+			if completed != nil && !completed.Before(points[i].Start) && completed.Before(end) {
+				points[i].Completed++
+			}
+
+			open := !t.Done || (completed != nil && !completed.Before(end))
+			if created.Before(end) && open {
+				points[i].Remaining++
+			}
+		}
+	}
+	return points
+}
+
+// Sparkline renders values as a single line of block characters scaled to
+// the largest value, or ASCII characters when icons are disabled
+func (pf *ProgressFormatter) Sparkline(values []int) string {
+	levels := []rune("▁▂▃▄▅▆▇█")
+	if !pf.options.ShowIcons {
+		levels = []rune("_.-=+*#@")
+	}
+
+	max := 0
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		if max == 0 || v == 0 {
+			if pf.options.ShowIcons {
+				b.WriteRune(' ')
+			} else {
+				b.WriteRune(levels[0])
+			}
+			continue
+		}
+		level := (v*(len(levels)-1) + max - 1) / max
+		b.WriteRune(levels[level])
+	}
+	return b.String()
+}
+
+// FormatHistory renders sparklines of created and completed tasks per period
+func (pf *ProgressFormatter) FormatHistory(points []HistoryPoint, bucket HistoryBucket) string {
+	if len(points) == 0 {
+		return "No history to display."
+	}
+
+	var created, completed []int
+	totalCreated, totalCompleted := 0, 0
+	for _, p := range points {
+		created = append(created, p.Created)
+		completed = append(completed, p.Completed)
+		totalCreated += p.Created
+		totalCompleted += p.Completed
+	}
+
+	period := "Daily"
+	if bucket == BucketWeek {
+		period = "Weekly"
+	}
+	header := fmt.Sprintf("%s history, %s to %s", period,
+		points[0].Start.Format("2006-01-02"), bucketNext(points[len(points)-1].Start, bucket).AddDate(0, 0, -1).Format("2006-01-02"))
+	if pf.options.ShowColors {
+		header = Colorize(Bold, header)
+	}
+
+	createdLine := pf.Sparkline(created)
+	completedLine := pf.Sparkline(completed)
+	if pf.options.ShowColors {
+		createdLine = Colorize(pf.options.ColorScheme.DueDate, createdLine)
+		completedLine = Colorize(pf.options.ColorScheme.Completed, completedLine)
+	}
+
+	lines := []string{
+		header,
+		fmt.Sprintf("  Created   |%s| %d", createdLine, totalCreated),
+		fmt.Sprintf("  Completed |%s| %d", completedLine, totalCompleted),
+	}
+	return strings.Join(lines, "\n")
+}
+
+// burndownWidth is the length of the longest burndown bar
+const burndownWidth = 30
+
+// FormatBurndown renders the open task count at the end of each period as
+// horizontal bars, one line per period, under the given title
+func (pf *ProgressFormatter) FormatBurndown(title string, points []HistoryPoint) string {
+	if len(points) == 0 {
+		return "No history to display."
+	}
+
+	max := 0
+	for _, p := range points {
+		if p.Remaining > max {
+			max = p.Remaining
+		}
+	}
+
+	fill := "█"
+	if !pf.options.ShowIcons {
+		fill = "#"
+	}
+
+	if pf.options.ShowColors {
+		title = Colorize(Bold, title)
+	}
+	lines := []string{title}
+	for _, p := range points {
+		length := 0
+		if max > 0 {
+			length = (p.Remaining*burndownWidth + max - 1) / max
+		}
+		bar := strings.Repeat(fill, length)
+		if pf.options.ShowColors {
+			bar = Colorize(pf.options.ColorScheme.Pending, bar)
+		}
+		lines = append(lines, fmt.Sprintf("  %s %s %d", p.Start.Format("2006-01-02"), bar, p.Remaining))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatDuration renders a duration in days and hours, or hours and
+// minutes when it is shorter than a day
+func formatDuration(d time.Duration) string {
+	if d < 24*time.Hour {
+		return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%dd %dh", int(d.Hours())/24, int(d.Hours())%24)
+}
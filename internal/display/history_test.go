@@ -0,0 +1,161 @@
+package display
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"taskmgr/internal/tasks"
+)
+
+func at(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func historyTasks() []tasks.Task {
+	completed := func(s string) *time.Time {
+		t := at(s)
+		return &t
+	}
+	return []tasks.Task{
+		{Title: "A", CreatedAt: at("2025-07-01 09:00"), Done: true, CompletedAt: completed("2025-07-02 10:00")},
+		{Title: "B", CreatedAt: at("2025-07-01 11:00")},
+		{Title: "C", CreatedAt: at("2025-07-02 08:00"), Done: true, CompletedAt: completed("2025-07-03 08:00")},
+		{Title: "D", CreatedAt: at("2025-07-03 12:00")},
+		{Title: "E", CreatedAt: at("2025-06-20 12:00"), Done: true}, // completed at an unknown time
+	}
+}
+
+func TestParseHistoryBucket(t *testing.T) {
+	for input, expected := range map[string]HistoryBucket{"": BucketDay, "day": BucketDay, "WEEK": BucketWeek, "weekly": BucketWeek} {
+		got, err := ParseHistoryBucket(input)
+		if err != nil || got != expected {
+			t.Errorf("ParseHistoryBucket(%q) = %q, %v; expected %q", input, got, err, expected)
+		}
+	}
+	if _, err := ParseHistoryBucket("month"); err == nil {
+		t.Error("Expected error for unsupported period")
+	}
+}
+
+func TestCalculateHistoryDaily(t *testing.T) {
+	points := CalculateHistory(historyTasks(), at("2025-07-01 00:00"), at("2025-07-03 00:00"), BucketDay)
+	if len(points) != 3 {
+		t.Fatalf("Expected 3 daily points, got %d", len(points))
+	}
+
+	expected := []struct{ created, completed, remaining int }{
+		{2, 0, 2}, // A and B created, both open at end of day
+		{1, 1, 2}, // C created, A completed: B and C open
+		{1, 1, 2}, // D created, C completed: B and D open
+	}
+	for i, want := range expected {
+		p := points[i]
+		if p.Created != want.created || p.Completed != want.completed || p.Remaining != want.remaining {
+			t.Errorf("Day %d: expected %+v, got created=%d completed=%d remaining=%d",
+				i, want, p.Created, p.Completed, p.Remaining)
+		}
+	}
+	if !points[0].Start.Equal(at("2025-07-01 00:00")) {
+		t.Errorf("Unexpected first bucket start %v", points[0].Start)
+	}
+}
+
+func TestCalculateHistoryWeekly(t *testing.T) {
+	// 2025-07-02 is a Wednesday; weeks start on Monday
+	points := CalculateHistory(historyTasks(), at("2025-06-18 00:00"), at("2025-07-02 00:00"), BucketWeek)
+	if len(points) != 3 {
+		t.Fatalf("Expected 3 weekly points, got %d", len(points))
+	}
+	if !points[0].Start.Equal(at("2025-06-16 00:00")) {
+		t.Errorf("Expected first week to start on Monday 2025-06-16, got %v", points[0].Start)
+	}
+	if points[0].Created != 1 || points[0].Remaining != 0 {
+		t.Errorf("Week 1: expected 1 created and none open, got %+v", points[0])
+	}
+	last := points[2]
+	if last.Created != 4 || last.Completed != 2 || last.Remaining != 2 {
+		t.Errorf("Week 3: expected 4 created, 2 completed, 2 open, got %+v", last)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	unicode := NewProgressFormatter(DisplayOptions{ShowIcons: true})
+	if got := unicode.Sparkline([]int{0, 1, 4, 8}); got != " ▂▅█" {
+		t.Errorf("Unexpected unicode sparkline %q", got)
+	}
+
+	ascii := NewProgressFormatter(DisplayOptions{})
+	if got := ascii.Sparkline([]int{0, 1, 4, 8}); got != "_.+@" {
+		t.Errorf("Unexpected ASCII sparkline %q", got)
+	}
+	if got := ascii.Sparkline([]int{0, 0}); got != "__" {
+		t.Errorf("Expected flat sparkline for zeros, got %q", got)
+	}
+}
+
+func TestFormatHistory(t *testing.T) {
+	points := CalculateHistory(historyTasks(), at("2025-07-01 00:00"), at("2025-07-03 00:00"), BucketDay)
+	formatter := NewProgressFormatter(DisplayOptions{})
+	result := formatter.FormatHistory(points, BucketDay)
+
+	for _, want := range []string{
+		"Daily history, 2025-07-01 to 2025-07-03",
+		"Created   |@++| 4",
+		"Completed |_@@| 2",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected history to contain %q, got:\n%s", want, result)
+		}
+	}
+
+	weekly := formatter.FormatHistory(CalculateHistory(nil, at("2025-07-01 00:00"), at("2025-07-01 00:00"), BucketWeek), BucketWeek)
+	if !strings.Contains(weekly, "Weekly history, 2025-06-30 to 2025-07-06") {
+		t.Errorf("Unexpected weekly header:\n%s", weekly)
+	}
+
+	if got := formatter.FormatHistory(nil, BucketDay); got != "No history to display." {
+		t.Errorf("Unexpected empty history %q", got)
+	}
+}
+
+func TestFormatBurndown(t *testing.T) {
+	points := []HistoryPoint{
+		{Start: at("2025-07-01 00:00"), Remaining: 4},
+		{Start: at("2025-07-02 00:00"), Remaining: 2},
+		{Start: at("2025-07-03 00:00"), Remaining: 0},
+	}
+	formatter := NewProgressFormatter(DisplayOptions{})
+	result := formatter.FormatBurndown("Burndown for tag work", points)
+	lines := strings.Split(result, "\n")
+
+	if lines[0] != "Burndown for tag work" {
+		t.Errorf("Unexpected title %q", lines[0])
+	}
+	if lines[1] != "  2025-07-01 "+strings.Repeat("#", burndownWidth)+" 4" {
+		t.Errorf("Unexpected full bar %q", lines[1])
+	}
+	if lines[2] != "  2025-07-02 "+strings.Repeat("#", burndownWidth/2)+" 2" {
+		t.Errorf("Unexpected half bar %q", lines[2])
+	}
+	if lines[3] != "  2025-07-03  0" {
+		t.Errorf("Unexpected empty bar %q", lines[3])
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		90 * time.Minute:              "1h 30m",
+		26 * time.Hour:                "1d 2h",
+		72*time.Hour + 59*time.Minute: "3d 0h",
+	}
+	for d, expected := range tests {
+		if got := formatDuration(d); got != expected {
+			t.Errorf("formatDuration(%v) = %q, expected %q", d, got, expected)
+		}
+	}
+}
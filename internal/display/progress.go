@@ -14,6 +14,11 @@ type ProgressStats struct {
 	Pending    int
 	Overdue    int
 	ByPriority map[tasks.Priority]int
+	
+	// AverageLeadTime is the mean time from CreatedAt to CompletedAt over
+	// the LeadTimeSamples completed tasks that have a completion timestamp
+	AverageLeadTime time.Duration
+	LeadTimeSamples int
 }
 
 // ProgressFormatter handles progress display formatting
@@ -29,20 +34,32 @@ func NewProgressFormatter(opts DisplayOptions) *ProgressFormatter {
 	return &ProgressFormatter{options: opts}
 }
 
-// CalculateStats calculates progress statistics from a list of tasks
+// CalculateStats calculates progress statistics from a list of tasks,
+// leaving out those in the trash
 func (pf *ProgressFormatter) CalculateStats(taskList []tasks.Task) ProgressStats {
 	stats := ProgressStats{
-		Total:      len(taskList),
 		ByPriority: make(map[tasks.Priority]int),
 	}
 	
+	var totalLeadTime time.Duration
 	for _, task := range taskList {
+		if task.Trashed() {
+			continue
+		}
+		stats.Total++
+		
 		// Count by priority
 		stats.ByPriority[task.Priority]++
 		
 		// Count by status
 		if task.Done {
 			stats.Completed++
+			
+			// Lead time needs both ends of the task's life
+			if task.CompletedAt != nil && !task.CreatedAt.IsZero() && !task.CompletedAt.Before(task.CreatedAt) {
+				totalLeadTime += task.CompletedAt.Sub(task.CreatedAt)
+				stats.LeadTimeSamples++
+			}
 		} else {
 			stats.Pending++
 			
@@ -53,6 +70,10 @@ func (pf *ProgressFormatter) CalculateStats(taskList []tasks.Task) ProgressStats
 		}
 	}
 	
+	if stats.LeadTimeSamples > 0 {
+		stats.AverageLeadTime = totalLeadTime / time.Duration(stats.LeadTimeSamples)
+	}
+	
 	return stats
 }
 
@@ -129,6 +150,12 @@ func (pf *ProgressFormatter) FormatDetailedStats(stats ProgressStats) string {
 		lines = append(lines, fmt.Sprintf("  %s Overdue: %d tasks", icon, stats.Overdue))
 	}
 	
+	if stats.LeadTimeSamples > 0 {
+		lines = append(lines, "")
+		lines = append(lines, fmt.Sprintf("Average lead time: %s (%d completed tasks)",
+			formatDuration(stats.AverageLeadTime), stats.LeadTimeSamples))
+	}
+	
 	return strings.Join(lines, "\n")
 }
 
@@ -40,6 +40,7 @@ func TestCalculateStats(t *testing.T) {
 		{Title: "Task 3", Done: false, Priority: tasks.Low, DueDate: &yesterday}, // overdue
 		{Title: "Task 4", Done: true, Priority: tasks.Critical},
 		{Title: "Task 5", Done: false, Priority: tasks.High, DueDate: &tomorrow},
+		{Title: "Task 6", Done: true, Priority: tasks.High, DeletedAt: &yesterday}, // in the trash
 	}
 	
 	opts := DisplayOptions{}
@@ -299,4 +300,36 @@ func TestGetPriorityIconWithColors(t *testing.T) {
 	if result == "" {
 		t.Error("Should return a non-empty result")
 	}
-}
\ No newline at end of file
+}
+func TestCalculateStatsLeadTime(t *testing.T) {
+	created := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)
+	oneDay := created.Add(24 * time.Hour)
+	threeDays := created.Add(72 * time.Hour)
+
+	taskList := []tasks.Task{
+		{Title: "Fast", Done: true, CreatedAt: created, CompletedAt: &oneDay},
+		{Title: "Slow", Done: true, CreatedAt: created, CompletedAt: &threeDays},
+		{Title: "Legacy done", Done: true, CreatedAt: created}, // no completion time
+		{Title: "Open", CreatedAt: created},
+	}
+
+	formatter := NewProgressFormatter(DisplayOptions{})
+	stats := formatter.CalculateStats(taskList)
+	if stats.LeadTimeSamples != 2 {
+		t.Errorf("Expected 2 lead time samples, got %d", stats.LeadTimeSamples)
+	}
+	if stats.AverageLeadTime != 48*time.Hour {
+		t.Errorf("Expected average lead time of 48h, got %v", stats.AverageLeadTime)
+	}
+
+	result := formatter.FormatDetailedStats(stats)
+	if !strings.Contains(result, "Average lead time: 2d 0h (2 completed tasks)") {
+		t.Errorf("Expected lead time line, got:\n%s", result)
+	}
+
+	// No samples, no lead time line
+	empty := formatter.FormatDetailedStats(formatter.CalculateStats(taskList[2:]))
+	if strings.Contains(empty, "lead time") {
+		t.Errorf("Expected no lead time line, got:\n%s", empty)
+	}
+}
@@ -0,0 +1,112 @@
+package display
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// VisibleWidth returns the number of terminal cells s occupies, ignoring
+// ANSI escape sequences and counting emoji as two cells.
+func VisibleWidth(s string) int {
+	width := 0
+	for i := 0; i < len(s); {
+		if n := escapeLen(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		width += runeWidth(r)
+		i += size
+	}
+	return width
+}
+
+// Truncate shortens s to at most width visible cells, appending "…" when
+// text was cut. Escape sequences are preserved and colors are reset after
+// a cut so the truncated text never bleeds into what follows.
+func Truncate(s string, width int) string {
+	if VisibleWidth(s) <= width {
+		return s
+	}
+	if width <= 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	used := 0
+	colored := false
+	for i := 0; i < len(s); {
+		if n := escapeLen(s[i:]); n > 0 {
+			b.WriteString(s[i : i+n])
+			colored = s[i:i+n] != string(Reset)
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		w := runeWidth(r)
+		// Leave one cell for the ellipsis
+		if used+w > width-1 {
+			break
+		}
+		b.WriteRune(r)
+		used += w
+		i += size
+	}
+	b.WriteString("…")
+	if colored {
+		b.WriteString(string(Reset))
+	}
+	return b.String()
+}
+
+// PadRight truncates or pads s with spaces to exactly width visible cells.
+func PadRight(s string, width int) string {
+	s = Truncate(s, width)
+	if pad := width - VisibleWidth(s); pad > 0 {
+		return s + strings.Repeat(" ", pad)
+	}
+	return s
+}
+
+// escapeLen returns the byte length of the CSI escape sequence at the start
+// of s, or 0 if s does not start with one.
+func escapeLen(s string) int {
+	if len(s) < 2 || s[0] != '\033' || s[1] != '[' {
+		return 0
+	}
+	for i := 2; i < len(s); i++ {
+		if s[i] >= 0x40 && s[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// runeWidth approximates the terminal width of r: zero for combining marks
+// and variation selectors, two for emoji and wide CJK, one otherwise.
+func runeWidth(r rune) int {
+	switch {
+	case r == 0x200d || (r >= 0xfe00 && r <= 0xfe0f) || (r >= 0x300 && r <= 0x36f):
+		return 0
+	case r >= 0x1f300 && r <= 0x1faff,
+		isWideSymbol(r),
+		r >= 0x1100 && r <= 0x115f,
+		r >= 0x2e80 && r <= 0xa4cf,
+		r >= 0xac00 && r <= 0xd7a3,
+		r >= 0xf900 && r <= 0xfaff,
+		r >= 0xff00 && r <= 0xff60:
+		return 2
+	}
+	return 1
+}
+
+// isWideSymbol reports whether r is one of the symbols outside the main
+// emoji block that terminals render with emoji width, such as the status
+// icons used by TaskFormatter.
+func isWideSymbol(r rune) bool {
+	switch r {
+	case '✅', '❌', '⭕', '⌛', '⏳', '⚡', '⭐':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package display
+
+import "testing"
+
+func TestVisibleWidth(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected int
+	}{
+		{"plain", "hello", 5},
+		{"empty", "", 0},
+		{"colored", Colorize(Red, "hello"), 5},
+		{"raw escape", "\033[31mhi\033[0m", 2},
+		{"emoji", "✅ done", 7},
+		{"priority icon", "🔴", 2},
+		{"accented", "café", 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VisibleWidth(tt.input); got != tt.expected {
+				t.Errorf("VisibleWidth(%q) = %d, expected %d", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		width    int
+		expected string
+	}{
+		{"fits", "hello", 10, "hello"},
+		{"exact", "hello", 5, "hello"},
+		{"cut", "hello world", 6, "hello…"},
+		{"zero width", "hello", 0, ""},
+		{"colored cut", "\033[31mhello world\033[0m", 6, "\033[31mhello…\033[0m"},
+		{"emoji cut", "✅✅✅", 4, "✅…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Truncate(tt.input, tt.width)
+			if got != tt.expected {
+				t.Errorf("Truncate(%q, %d) = %q, expected %q", tt.input, tt.width, got, tt.expected)
+			}
+			if VisibleWidth(got) > tt.width {
+				t.Errorf("Truncate(%q, %d) is %d cells wide", tt.input, tt.width, VisibleWidth(got))
+			}
+		})
+	}
+}
+
+func TestPadRight(t *testing.T) {
+	if got := PadRight("abc", 5); got != "abc  " {
+		t.Errorf("Expected padded string 'abc  ', got %q", got)
+	}
+	if got := PadRight("abcdef", 4); got != "abc…" {
+		t.Errorf("Expected truncated string 'abc…', got %q", got)
+	}
+	colored := PadRight("\033[32mok\033[0m", 4)
+	if VisibleWidth(colored) != 4 {
+		t.Errorf("Expected 4 visible cells, got %d in %q", VisibleWidth(colored), colored)
+	}
+}
@@ -0,0 +1,286 @@
+package formats
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"taskmgr/internal/tasks"
+)
+
+func init() {
+	register(CSV{}, ".csv")
+}
+
+// csvFields are the task fields a CSV file can hold, in the default
+// export order
+var csvFields = []string{"id", "title", "description", "done", "priority", "due", "tags", "project", "created", "completed"}
+
+// CSV reads and writes comma separated files with a header row. By default
+// each field is read from the column named after it, compared without
+// case; Map names a different column per field, such as title:Summary.
+// Columns picks and orders the fields written on export. Priorities and
+// due dates accept everything the add command does, and tags are separated
+// by commas or semicolons. Every invalid row is reported, not just the
+// first. Importing a file updates only the fields it has columns for.
+type CSV struct {
+	mapping map[string]string
+	columns []string
+}
+
+// Name implements Format
+func (CSV) Name() string {
+	return "csv"
+}
+
+func isCSVField(name string) bool {
+	for _, f := range csvFields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (c CSV) configure(opts Options) (Format, error) {
+	for field, column := range opts.Map {
+		if !isCSVField(field) {
+			return nil, fmt.Errorf("unknown field %q in --map (fields: %s)", field, strings.Join(csvFields, ", "))
+		}
+		if column == "" {
+			return nil, fmt.Errorf("missing column name for field %q in --map", field)
+		}
+	}
+	for _, field := range opts.Columns {
+		if !isCSVField(strings.ToLower(field)) {
+			return nil, fmt.Errorf("unknown field %q in --columns (fields: %s)", field, strings.Join(csvFields, ", "))
+		}
+	}
+	return CSV{mapping: opts.Map, columns: opts.Columns}, nil
+}
+
+// Decode implements Format. Rows are numbered as in a spreadsheet, with the
+// header on row 1; blank rows are skipped.
+func (c CSV) Decode(r io.Reader) ([]tasks.Task, error) {
+	list, _, err := c.decodeFields(r)
+	return list, err
+}
+
+// decodeFields decodes like Decode, and returns the fields the header has
+// a column for. A completed column also tells whether the task is done.
+func (c CSV) decodeFields(r io.Reader) ([]tasks.Task, []string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff") // spreadsheet byte order mark
+		}
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	index := make(map[string]int)
+	var fields []string
+	for _, field := range csvFields {
+		name, mapped := c.mapping[field]
+		if !mapped {
+			name = field
+		}
+		if i, ok := columns[strings.ToLower(name)]; ok {
+			index[field] = i
+			fields = append(fields, field)
+		} else if mapped {
+			return nil, nil, fmt.Errorf("column %q for field %s not found in header", name, field)
+		}
+	}
+	if _, ok := index["title"]; !ok {
+		return nil, nil, fmt.Errorf("no title column in header (use --map=title:<column>)")
+	}
+	if _, ok := index["done"]; !ok {
+		if _, ok := index["completed"]; ok {
+			fields = append(fields, "done")
+		}
+	}
+
+	var list []tasks.Task
+	var rowErrors RowErrors
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Row: row, Err: err})
+			continue
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		cell := func(field string) string {
+			if i, ok := index[field]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		t, err := parseCSVRecord(cell)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Row: row, Err: err})
+			continue
+		}
+		list = append(list, t)
+	}
+
+	if len(rowErrors) > 0 {
+		return nil, nil, rowErrors
+	}
+	return list, fields, nil
+}
+
+func parseCSVRecord(cell func(string) string) (tasks.Task, error) {
+	t := tasks.Task{
+		ID:          cell("id"),
+		Title:       cell("title"),
+		Description: cell("description"),
+		Project:     cell("project"),
+		Priority:    tasks.Medium,
+	}
+	if t.Title == "" {
+		return t, fmt.Errorf("title is empty")
+	}
+
+	var err error
+	if t.Done, err = parseCSVDone(cell("done")); err != nil {
+		return t, err
+	}
+	if value := cell("priority"); value != "" {
+		if t.Priority, err = tasks.ParsePriority(value); err != nil {
+			return t, err
+		}
+	}
+	if t.DueDate, err = parseCSVTime(cell("due")); err != nil {
+		return t, err
+	}
+	created, err := parseCSVTime(cell("created"))
+	if err != nil {
+		return t, err
+	}
+	if created != nil {
+		t.CreatedAt = *created
+	}
+	if t.CompletedAt, err = parseCSVTime(cell("completed")); err != nil {
+		return t, err
+	}
+	if t.CompletedAt != nil {
+		t.Done = true
+	}
+	for _, tag := range strings.FieldsFunc(cell("tags"), func(r rune) bool { return r == ',' || r == ';' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			t.AddTag(tag)
+		}
+	}
+	return t, nil
+}
+
+// parseCSVTime reads the timestamps written by Encode as well as every
+// date the add command accepts
+func parseCSVTime(value string) (*time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return &parsed, nil
+	}
+	return tasks.ParseDueDate(value)
+}
+
+func parseCSVDone(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "", "false", "no", "n", "0", "pending", "open", "todo":
+		return false, nil
+	case "true", "yes", "y", "1", "x", "done", "completed", "closed":
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid done value: %s", value)
+	}
+}
+
+// Encode implements Format
+func (c CSV) Encode(w io.Writer, list []tasks.Task) error {
+	columns := c.columns
+	if len(columns) == 0 {
+		columns = csvFields
+	}
+
+	writer := csv.NewWriter(w)
+	var header []string
+	for _, column := range columns {
+		header = append(header, strings.ToLower(column))
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, t := range list {
+		var record []string
+		for _, field := range header {
+			record = append(record, csvValue(t, field))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvValue(t tasks.Task, field string) string {
+	switch field {
+	case "id":
+		return t.ID
+	case "title":
+		return t.Title
+	case "description":
+		return t.Description
+	case "done":
+		return fmt.Sprint(t.Done)
+	case "priority":
+		return t.Priority.String()
+	case "due":
+		if t.DueDate == nil {
+			return ""
+		}
+		return formatCSVTime(*t.DueDate)
+	case "tags":
+		return strings.Join(t.Tags, ",")
+	case "project":
+		return t.Project
+	case "created":
+		if t.CreatedAt.IsZero() {
+			return ""
+		}
+		return formatCSVTime(t.CreatedAt)
+	case "completed":
+		if t.CompletedAt == nil {
+			return ""
+		}
+		return formatCSVTime(*t.CompletedAt)
+	}
+	return ""
+}
+
+// formatCSVTime writes midnight as a plain date, which spreadsheets
+// recognise, and other times in full
+func formatCSVTime(t time.Time) string {
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format(time.RFC3339Nano)
+}
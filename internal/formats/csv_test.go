@@ -0,0 +1,190 @@
+package formats
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"taskmgr/internal/tasks"
+)
+
+func TestCSVDecodeWithMapping(t *testing.T) {
+	input := "\ufeffSummary,Deadline,Sev,Owner,Labels\n" +
+		"Fix login,2025-07-10,high,alice,\"work; urgent\"\n" +
+		"\n" +
+		"Write docs,07/15/2025,,bob,\n"
+
+	f, err := Configure(CSV{}, Options{Map: map[string]string{"title": "Summary", "due": "deadline", "priority": "Sev", "tags": "Labels"}})
+	if err != nil {
+		t.Fatalf("Configure returned an error: %v", err)
+	}
+	list, err := f.Decode(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Decode returned an error: %v", err)
+	}
+
+	expected := []tasks.Task{
+		{Title: "Fix login", DueDate: dayPtr("2025-07-10"), Priority: tasks.High, Tags: []string{"work", "urgent"}},
+		{Title: "Write docs", DueDate: dayPtr("2025-07-15"), Priority: tasks.Medium},
+	}
+	if !reflect.DeepEqual(list, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, list)
+	}
+}
+
+func TestCSVDecodeRowErrors(t *testing.T) {
+	input := "title,due,priority,done\n" +
+		"Good,2025-07-10,low,no\n" +
+		",2025-07-10,low,no\n" +
+		"Bad date,next year,low,no\n" +
+		"Bad priority,,urgent,no\n" +
+		"Bad done,,,maybe\n"
+
+	_, err := CSV{}.Decode(strings.NewReader(input))
+	rowErrors, ok := err.(RowErrors)
+	if !ok {
+		t.Fatalf("Expected RowErrors, got %v", err)
+	}
+
+	expected := []struct {
+		row  int
+		text string
+	}{
+		{3, "title is empty"},
+		{4, "invalid date format: next year"},
+		{5, "invalid priority: urgent"},
+		{6, "invalid done value: maybe"},
+	}
+	if len(rowErrors) != len(expected) {
+		t.Fatalf("Expected %d row errors, got %d: %v", len(expected), len(rowErrors), err)
+	}
+	for i, want := range expected {
+		if rowErrors[i].Row != want.row || !strings.Contains(rowErrors[i].Err.Error(), want.text) {
+			t.Errorf("Error %d: expected row %d with %q, got %v", i, want.row, want.text, rowErrors[i])
+		}
+	}
+	if !strings.HasPrefix(err.Error(), "4 invalid rows:\n  row 3: title is empty") {
+		t.Errorf("Unexpected error text:\n%s", err)
+	}
+}
+
+func TestCSVDecodeHeaderErrors(t *testing.T) {
+	if _, err := (CSV{}).Decode(strings.NewReader("Summary,due\nTask,\n")); err == nil || !strings.Contains(err.Error(), "no title column") {
+		t.Errorf("Expected missing title column error, got %v", err)
+	}
+
+	f, _ := Configure(CSV{}, Options{Map: map[string]string{"title": "Name", "due": "Deadline"}})
+	if _, err := f.Decode(strings.NewReader("Name\nTask\n")); err == nil || !strings.Contains(err.Error(), `column "Deadline" for field due not found`) {
+		t.Errorf("Expected missing mapped column error, got %v", err)
+	}
+
+	// An empty file has no tasks
+	if list, err := (CSV{}).Decode(strings.NewReader("")); err != nil || len(list) != 0 {
+		t.Errorf("Expected no tasks from empty input, got %v, %v", list, err)
+	}
+}
+
+func TestCSVConfigure(t *testing.T) {
+	tests := []struct {
+		opts Options
+		want string
+	}{
+		{Options{Map: map[string]string{"owner": "Owner"}}, `unknown field "owner" in --map`},
+		{Options{Map: map[string]string{"title": ""}}, `missing column name for field "title"`},
+		{Options{Columns: []string{"title", "colour"}}, `unknown field "colour" in --columns`},
+	}
+	for _, tt := range tests {
+		if _, err := Configure(CSV{}, tt.opts); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Configure(%+v): expected error containing %q, got %v", tt.opts, tt.want, err)
+		}
+	}
+
+	// Formats without options ignore them
+	if f, err := Configure(TodoTxt{}, Options{Columns: []string{"bogus"}}); err != nil || f.Name() != "todotxt" {
+		t.Errorf("Expected todotxt to ignore options, got %v, %v", f, err)
+	}
+}
+
+func TestCSVEncodeColumns(t *testing.T) {
+	list := []tasks.Task{
+		{Title: "Fix login, again", DueDate: dayPtr("2025-07-10"), Priority: tasks.High, Tags: []string{"work", "urgent"}},
+		{Title: "Say \"hi\"", Priority: tasks.Low},
+	}
+
+	f, err := Configure(CSV{}, Options{Columns: []string{"Title", "due", "tags", "priority"}})
+	if err != nil {
+		t.Fatalf("Configure returned an error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := f.Encode(&buf, list); err != nil {
+		t.Fatalf("Encode returned an error: %v", err)
+	}
+
+	expected := "title,due,tags,priority\n" +
+		"\"Fix login, again\",2025-07-10,\"work,urgent\",high\n" +
+		"\"Say \"\"hi\"\"\",,,low\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	created := time.Date(2025, 7, 1, 9, 30, 15, 0, time.UTC)
+	completed := time.Date(2025, 7, 3, 14, 0, 0, 0, time.UTC)
+	list := []tasks.Task{
+		{ID: "a1", Title: "Fix login", Description: "Multi\nline, \"quoted\"", Priority: tasks.Critical, DueDate: dayPtr("2025-07-10"), Tags: []string{"work"}, Project: "web", CreatedAt: created},
+		{ID: "b2", Title: "Done thing", Priority: tasks.Low, Done: true, CreatedAt: created, CompletedAt: &completed},
+	}
+
+	var buf bytes.Buffer
+	if err := (CSV{}).Encode(&buf, list); err != nil {
+		t.Fatalf("Encode returned an error: %v", err)
+	}
+	decoded, err := CSV{}.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode returned an error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, list) {
+		t.Errorf("Round trip changed the tasks:\nexpected %+v\ngot      %+v", list, decoded)
+	}
+}
+
+func TestCSVImportPickedColumns(t *testing.T) {
+	manager := tasks.NewTaskManager(tasks.NewMemoryStore())
+	due := day("2025-07-10")
+	manager.Add(tasks.Task{
+		ID: "known", Title: "Fix login", Description: "Since the upgrade", Notes: "Check the logs",
+		Priority: tasks.High, DueDate: &due, Tags: []string{"work"}, Project: "website",
+	})
+	before := manager.List()[0]
+
+	f, err := Configure(CSV{}, Options{Columns: []string{"id", "title"}})
+	if err != nil {
+		t.Fatalf("Configure returned an error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := f.Encode(&buf, manager.List()); err != nil {
+		t.Fatalf("Encode returned an error: %v", err)
+	}
+	list, fields, err := Decode(f, &buf)
+	if err != nil {
+		t.Fatalf("Decode returned an error: %v", err)
+	}
+	if !reflect.DeepEqual(fields, []string{"id", "title"}) {
+		t.Errorf("Expected the header's fields, got %v", fields)
+	}
+	if _, _, err := manager.Import(list, fields...); err != nil {
+		t.Fatalf("Import returned an error: %v", err)
+	}
+	if after := manager.List()[0]; !reflect.DeepEqual(after, before) {
+		t.Errorf("Expected the task unchanged:\nexpected %+v\ngot      %+v", before, after)
+	}
+
+	// A completed column is enough to tell whether a task is done
+	_, fields, _ = Decode(CSV{}, strings.NewReader("title,completed\nShip,2025-07-03\n"))
+	if !reflect.DeepEqual(fields, []string{"title", "completed", "done"}) {
+		t.Errorf("Expected done implied by completed, got %v", fields)
+	}
+}
@@ -0,0 +1,141 @@
+// Package formats converts task lists to and from the file formats of
+// other task managers, for the import and export commands.
+package formats
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"taskmgr/internal/tasks"
+)
+
+// Format reads and writes tasks in one file format
+type Format interface {
+	// Name is the value of the --format flag selecting this format
+	Name() string
+	Decode(r io.Reader) ([]tasks.Task, error)
+	Encode(w io.Writer, list []tasks.Task) error
+}
+
+// Options holds the format specific flags of the import and export
+// commands. Formats that take none ignore them.
+type Options struct {
+	// Map names the column holding each task field, for csv import
+	Map map[string]string
+	// Columns lists the task fields to write, for csv export
+	Columns []string
+	// GroupBy is "project" or "tag", for markdown export
+	GroupBy string
+}
+
+// partial is implemented by formats that hold only some task fields, so
+// that importing a file leaves the others of the tasks it updates alone
+type partial interface {
+	// fields names them as tasks.ImportFields does
+	fields() []string
+}
+
+// headed is implemented by formats whose fields depend on the file, such
+// as the columns of a CSV header
+type headed interface {
+	decodeFields(r io.Reader) ([]tasks.Task, []string, error)
+}
+
+// Decode reads tasks in format f, along with the fields they were read
+// with, to pass on to tasks.TaskManager.Import. No fields means every
+// field.
+func Decode(f Format, r io.Reader) ([]tasks.Task, []string, error) {
+	if h, ok := f.(headed); ok {
+		return h.decodeFields(r)
+	}
+	list, err := f.Decode(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if p, ok := f.(partial); ok {
+		return list, p.fields(), nil
+	}
+	return list, nil, nil
+}
+
+// configurable is implemented by formats that accept Options
+type configurable interface {
+	configure(Options) (Format, error)
+}
+
+// Configure returns f set up with the given options
+func Configure(f Format, opts Options) (Format, error) {
+	if c, ok := f.(configurable); ok {
+		return c.configure(opts)
+	}
+	return f, nil
+}
+
+// RowError reports a record of the input that could not be read
+type RowError struct {
+	Row int
+	Err error
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+// RowErrors collects every bad record of an input, so that they can all be
+// fixed in one go
+type RowErrors []RowError
+
+func (e RowErrors) Error() string {
+	var lines []string
+	for _, err := range e {
+		lines = append(lines, err.Error())
+	}
+	return fmt.Sprintf("%d invalid rows:\n  %s", len(e), strings.Join(lines, "\n  "))
+}
+
+var registry = map[string]Format{}
+
+// extensions maps file extensions to the format used when --format is omitted
+var extensions = map[string]string{}
+
+// register adds a format, reachable by name and by any of the given file
+// extensions
+func register(f Format, exts ...string) {
+	registry[f.Name()] = f
+	for _, ext := range exts {
+		extensions[ext] = f.Name()
+	}
+}
+
+// Lookup returns the format with the given name
+func Lookup(name string) (Format, error) {
+	if f, ok := registry[strings.ToLower(name)]; ok {
+		return f, nil
+	}
+	return nil, fmt.Errorf("unknown format: %s (available: %s)", name, strings.Join(Names(), ", "))
+}
+
+// ForFile picks a format by name or, when name is empty, by the file's
+// extension
+func ForFile(name, filename string) (Format, error) {
+	if name != "" {
+		return Lookup(name)
+	}
+	if byExt, ok := extensions[strings.ToLower(filepath.Ext(filename))]; ok {
+		return registry[byExt], nil
+	}
+	return nil, fmt.Errorf("cannot tell the format of %q, use --format (available: %s)", filename, strings.Join(Names(), ", "))
+}
+
+// Names lists the registered formats, sorted
+func Names() []string {
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
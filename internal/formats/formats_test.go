@@ -0,0 +1,77 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"taskmgr/internal/tasks"
+)
+
+func TestLookup(t *testing.T) {
+	f, err := Lookup("TodoTxt")
+	if err != nil || f.Name() != "todotxt" {
+		t.Errorf("Expected todotxt format, got %v, %v", f, err)
+	}
+
+	if _, err := Lookup("bogus"); err == nil || !strings.Contains(err.Error(), "todotxt") {
+		t.Errorf("Expected error listing the available formats, got %v", err)
+	}
+}
+
+func TestForFile(t *testing.T) {
+	f, err := ForFile("", "/home/me/todo.txt")
+	if err != nil || f.Name() != "todotxt" {
+		t.Errorf("Expected todotxt format from extension, got %v, %v", f, err)
+	}
+
+	// An explicit format wins over the extension
+	if _, err := ForFile("bogus", "todo.txt"); err == nil {
+		t.Error("Expected error for unknown explicit format")
+	}
+
+	// A .json file may well be a task file rather than a Taskwarrior export
+	if _, err := ForFile("", "tasks.json"); err == nil {
+		t.Error("Expected no format for a .json file")
+	}
+
+	if _, err := ForFile("", ""); err == nil {
+		t.Error("Expected error when the format cannot be inferred")
+	}
+}
+
+func TestImportKeepsFieldsFormatLacks(t *testing.T) {
+	for _, name := range []string{"csv", "ics", "taskwarrior", "todotxt"} {
+		t.Run(name, func(t *testing.T) {
+			manager := tasks.NewTaskManager(tasks.NewMemoryStore())
+			manager.Add(tasks.Task{ID: "parent", Title: "Ship it", Priority: tasks.High})
+			manager.Add(tasks.Task{
+				ID: "child", Title: "Write the notes", Priority: tasks.Medium, ParentID: "parent",
+				Notes: "Tried twice", Extra: map[string]json.RawMessage{"x-owner": json.RawMessage(`"ana"`)},
+			})
+
+			f, _ := Lookup(name)
+			var buf bytes.Buffer
+			if err := f.Encode(&buf, manager.List()); err != nil {
+				t.Fatalf("Encode returned an error: %v", err)
+			}
+			list, fields, err := Decode(f, &buf)
+			if err != nil {
+				t.Fatalf("Decode returned an error: %v", err)
+			}
+			added, updated, err := manager.Import(list, fields...)
+			if err != nil || added != 0 || updated != 2 {
+				t.Fatalf("Expected 2 tasks updated, got %d added, %d updated, %v", added, updated, err)
+			}
+
+			child := manager.List()[1]
+			if child.Notes != "Tried twice" || child.ParentID != "parent" || string(child.Extra["x-owner"]) != `"ana"` {
+				t.Errorf("Expected notes, parent and extra kept, got %+v", child)
+			}
+			if child.Title != "Write the notes" || child.Priority != tasks.Medium {
+				t.Errorf("Expected the fields the file holds read back, got %+v", child)
+			}
+		})
+	}
+}
@@ -0,0 +1,358 @@
+package formats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"taskmgr/internal/tasks"
+)
+
+func init() {
+	register(ICS{}, ".ics", ".ical")
+}
+
+// ICS reads and writes iCalendar (RFC 5545) files of VTODO components. The
+// task ID is the UID, so importing a file exported earlier updates the same
+// tasks. Due dates without a time of day are written as DATE values.
+// Priorities map to 1 (critical), 3 (high), 5 (medium) and 9 (low); on
+// import 1-2, 3-4, 5 and 6-9 map back, and 0 (undefined) reads as low.
+// Projects are kept in an X-TASKMGR-PROJECT property. Other components,
+// such as VEVENTs, are skipped on import.
+type ICS struct{}
+
+// Name implements Format
+func (ICS) Name() string {
+	return "ics"
+}
+
+func (ICS) fields() []string {
+	return []string{"id", "title", "description", "due", "created", "done", "completed", "priority", "tags", "project"}
+}
+
+const (
+	icsDateFormat     = "20060102"
+	icsDateTimeFormat = "20060102T150405Z"
+	icsLineLimit      = 75
+)
+
+var icsPriorities = map[tasks.Priority]int{
+	tasks.Critical: 1,
+	tasks.High:     3,
+	tasks.Medium:   5,
+	tasks.Low:      9,
+}
+
+// Encode implements Format
+func (ICS) Encode(w io.Writer, list []tasks.Task) error {
+	bw := bufio.NewWriter(w)
+	write := func(line string) {
+		bw.WriteString(foldICSLine(line))
+	}
+
+	write("BEGIN:VCALENDAR")
+	write("VERSION:2.0")
+	write("PRODID:-//taskmgr//taskmgr//EN")
+	for _, t := range list {
+		write("BEGIN:VTODO")
+		write("UID:" + escapeICSText(t.ID))
+		stamp := t.ModifiedAt
+		if stamp.IsZero() {
+			stamp = t.CreatedAt
+		}
+		write("DTSTAMP:" + stamp.UTC().Format(icsDateTimeFormat))
+		if !t.CreatedAt.IsZero() {
+			write("CREATED:" + t.CreatedAt.UTC().Format(icsDateTimeFormat))
+		}
+		if !t.ModifiedAt.IsZero() {
+			write("LAST-MODIFIED:" + t.ModifiedAt.UTC().Format(icsDateTimeFormat))
+		}
+		write("SUMMARY:" + escapeICSText(t.Title))
+		if t.Description != "" {
+			write("DESCRIPTION:" + escapeICSText(t.Description))
+		}
+		if t.DueDate != nil {
+			write(formatICSDue(*t.DueDate))
+		}
+		write("PRIORITY:" + strconv.Itoa(icsPriorities[t.Priority]))
+		if len(t.Tags) > 0 {
+			var categories []string
+			for _, tag := range t.Tags {
+				categories = append(categories, escapeICSText(tag))
+			}
+			write("CATEGORIES:" + strings.Join(categories, ","))
+		}
+		if t.Project != "" {
+			write("X-TASKMGR-PROJECT:" + escapeICSText(t.Project))
+		}
+		if t.Done {
+			write("STATUS:COMPLETED")
+			if t.CompletedAt != nil {
+				write("COMPLETED:" + t.CompletedAt.UTC().Format(icsDateTimeFormat))
+			}
+		} else {
+			write("STATUS:NEEDS-ACTION")
+		}
+		write("END:VTODO")
+	}
+	write("END:VCALENDAR")
+	return bw.Flush()
+}
+
+// formatICSDue writes midnight due dates, which is how taskmgr stores
+// dates given without a time, as whole days
+func formatICSDue(due time.Time) string {
+	if due.Hour() == 0 && due.Minute() == 0 && due.Second() == 0 {
+		return "DUE;VALUE=DATE:" + due.Format(icsDateFormat)
+	}
+	return "DUE:" + due.UTC().Format(icsDateTimeFormat)
+}
+
+// foldICSLine splits a content line into lines of at most 75 octets, each
+// continuation starting with a space, without splitting UTF-8 sequences
+func foldICSLine(line string) string {
+	var b strings.Builder
+	limit := icsLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = icsLineLimit - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`, "\r", "")
+
+func escapeICSText(s string) string {
+	return icsEscaper.Replace(s)
+}
+
+// unescapeICSText reverses escapeICSText
+func unescapeICSText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// splitICSList splits a comma separated value on unescaped commas
+func splitICSList(s string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ',':
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// icsProperty is one unfolded content line
+type icsProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+func parseICSProperty(line string) (icsProperty, error) {
+	// The name and parameters end at the first colon outside quotes
+	colon := -1
+	quoted := false
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return icsProperty{}, fmt.Errorf("malformed line %q", line)
+	}
+
+	p := icsProperty{params: make(map[string]string), value: line[colon+1:]}
+	parts := strings.Split(line[:colon], ";")
+	p.name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		if key, value, ok := strings.Cut(param, "="); ok {
+			p.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return p, nil
+}
+
+// parseICSTime parses DATE and DATE-TIME values. Date-times in UTC end in Z;
+// others are read in the zone named by TZID, or local time if it is unknown.
+func parseICSTime(p icsProperty) (time.Time, error) {
+	value := p.value
+	if p.params["VALUE"] == "DATE" || len(value) == len(icsDateFormat) {
+		return time.Parse(icsDateFormat, value)
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse(icsDateTimeFormat, value)
+	}
+	loc := time.Local
+	if tzid := p.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	return time.ParseInLocation("20060102T150405", value, loc)
+}
+
+func icsPriority(value string) tasks.Priority {
+	n, _ := strconv.Atoi(strings.TrimSpace(value))
+	switch {
+	case n >= 1 && n <= 2:
+		return tasks.Critical
+	case n >= 3 && n <= 4:
+		return tasks.High
+	case n == 5:
+		return tasks.Medium
+	default:
+		return tasks.Low
+	}
+}
+
+// Decode implements Format
+func (ICS) Decode(r io.Reader) ([]tasks.Task, error) {
+	lines, err := unfoldICSLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []tasks.Task
+	var current *tasks.Task
+	depth := 0 // nesting inside the current VTODO, such as VALARMs
+	for _, l := range lines {
+		if l.text == "" {
+			continue
+		}
+		p, err := parseICSProperty(l.text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", l.number, err)
+		}
+
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VTODO") && current == nil:
+			current = &tasks.Task{}
+			depth = 0
+			continue
+		case current == nil:
+			continue
+		case p.name == "BEGIN":
+			depth++
+			continue
+		case p.name == "END" && depth > 0:
+			depth--
+			continue
+		case p.name == "END" && strings.EqualFold(p.value, "VTODO"):
+			if current.Title == "" {
+				return nil, fmt.Errorf("line %d: VTODO has no SUMMARY", l.number)
+			}
+			list = append(list, *current)
+			current = nil
+			continue
+		case depth > 0:
+			continue
+		}
+
+		if err := applyICSProperty(current, p); err != nil {
+			return nil, fmt.Errorf("line %d: %v", l.number, err)
+		}
+	}
+	if current != nil {
+		return nil, fmt.Errorf("unterminated VTODO")
+	}
+	return list, nil
+}
+
+func applyICSProperty(t *tasks.Task, p icsProperty) error {
+	var err error
+	switch p.name {
+	case "UID":
+		t.ID = unescapeICSText(p.value)
+	case "SUMMARY":
+		t.Title = unescapeICSText(p.value)
+	case "DESCRIPTION":
+		t.Description = unescapeICSText(p.value)
+	case "DUE":
+		var due time.Time
+		if due, err = parseICSTime(p); err == nil {
+			t.DueDate = &due
+		}
+	case "CREATED":
+		t.CreatedAt, err = parseICSTime(p)
+	case "LAST-MODIFIED":
+		t.ModifiedAt, err = parseICSTime(p)
+	case "COMPLETED":
+		var completed time.Time
+		if completed, err = parseICSTime(p); err == nil {
+			t.CompletedAt = &completed
+			t.Done = true
+		}
+	case "STATUS":
+		t.Done = strings.EqualFold(p.value, "COMPLETED")
+	case "PRIORITY":
+		t.Priority = icsPriority(p.value)
+	case "CATEGORIES":
+		for _, category := range splitICSList(p.value) {
+			if category = strings.TrimSpace(unescapeICSText(category)); category != "" {
+				t.AddTag(category)
+			}
+		}
+	case "X-TASKMGR-PROJECT":
+		t.Project = unescapeICSText(p.value)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s value %q", p.name, p.value)
+	}
+	return nil
+}
+
+type icsLine struct {
+	number int
+	text   string
+}
+
+// unfoldICSLines joins continuation lines, which start with a space or
+// tab, onto the line before them
+func unfoldICSLines(r io.Reader) ([]icsLine, error) {
+	var lines []icsLine
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if len(text) > 0 && (text[0] == ' ' || text[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1].text += text[1:]
+			continue
+		}
+		lines = append(lines, icsLine{number: number, text: text})
+	}
+	return lines, scanner.Err()
+}
@@ -0,0 +1,170 @@
+package formats
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"taskmgr/internal/tasks"
+)
+
+func TestICSEncode(t *testing.T) {
+	created := time.Date(2025, 7, 1, 9, 30, 0, 0, time.UTC)
+	completed := time.Date(2025, 7, 3, 14, 0, 0, 0, time.UTC)
+	list := []tasks.Task{
+		{ID: "a1", Title: "Call the bank; ask about fees", Priority: tasks.Critical, CreatedAt: created, ModifiedAt: created, DueDate: dayPtr("2025-07-04"), Tags: []string{"phone", "money"}},
+		{ID: "b2", Title: "Write report", Description: "Line one\nLine two", Priority: tasks.Medium, Done: true, CreatedAt: created, ModifiedAt: completed, CompletedAt: &completed, Project: "website"},
+	}
+
+	var buf bytes.Buffer
+	if err := (ICS{}).Encode(&buf, list); err != nil {
+		t.Fatalf("Encode returned an error: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"BEGIN:VTODO\r\nUID:a1\r\nDTSTAMP:20250701T093000Z\r\nCREATED:20250701T093000Z\r\n",
+		"SUMMARY:Call the bank\\; ask about fees\r\n",
+		"DUE;VALUE=DATE:20250704\r\n",
+		"PRIORITY:1\r\n",
+		"CATEGORIES:phone,money\r\n",
+		"STATUS:NEEDS-ACTION\r\n",
+		"DESCRIPTION:Line one\\nLine two\r\n",
+		"PRIORITY:5\r\n",
+		"X-TASKMGR-PROJECT:website\r\n",
+		"STATUS:COMPLETED\r\nCOMPLETED:20250703T140000Z\r\n",
+		"END:VTODO\r\nEND:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestICSLineFolding(t *testing.T) {
+	title := strings.Repeat("ü", 60) // 120 octets
+	folded := foldICSLine("SUMMARY:" + title)
+
+	lines := strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d: %q", len(lines), folded)
+	}
+	for _, line := range lines {
+		if len(line) > icsLineLimit {
+			t.Errorf("Line exceeds %d octets: %q", icsLineLimit, line)
+		}
+		if !utf8Valid(line) {
+			t.Errorf("Folding split a UTF-8 sequence: %q", line)
+		}
+	}
+
+	// Unfolding restores the original
+	list, err := ICS{}.Decode(strings.NewReader("BEGIN:VTODO\r\n" + folded + "END:VTODO\r\n"))
+	if err != nil {
+		t.Fatalf("Decode returned an error: %v", err)
+	}
+	if list[0].Title != title {
+		t.Errorf("Expected unfolded title %q, got %q", title, list[0].Title)
+	}
+}
+
+func utf8Valid(s string) bool {
+	return strings.ToValidUTF8(s, "�") == s
+}
+
+func TestICSDecodeForeign(t *testing.T) {
+	// A VTODO as written by another calendar app, next to an event
+	input := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Example Corp//Calendar//EN
+BEGIN:VEVENT
+UID:event-1
+SUMMARY:Not a task
+END:VEVENT
+BEGIN:VTODO
+UID:20250701T000000Z-123@example.com
+DTSTAMP:20250701T000000Z
+SUMMARY:Submit expenses
+DUE;TZID=Europe/Berlin:20250710T170000
+PRIORITY:4
+CATEGORIES:Work,Finance\, Admin
+CATEGORIES:urgent
+STATUS:IN-PROCESS
+BEGIN:VALARM
+ACTION:DISPLAY
+DESCRIPTION:Reminder
+TRIGGER:-PT15M
+END:VALARM
+END:VTODO
+END:VCALENDAR
+`
+	list, err := ICS{}.Decode(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Decode returned an error: %v", err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("Expected 1 task, got %d: %v", len(list), list)
+	}
+
+	task := list[0]
+	if task.ID != "20250701T000000Z-123@example.com" || task.Title != "Submit expenses" {
+		t.Errorf("Unexpected id or title: %+v", task)
+	}
+	if task.Description != "" {
+		t.Errorf("Expected the alarm description to be ignored, got %q", task.Description)
+	}
+	if task.Priority != tasks.High || task.Done {
+		t.Errorf("Expected pending high priority task, got %+v", task)
+	}
+	if !reflect.DeepEqual(task.Tags, []string{"work", "finance, admin", "urgent"}) {
+		t.Errorf("Unexpected tags %q", task.Tags)
+	}
+	if task.DueDate == nil || task.DueDate.UTC().Format(time.RFC3339) != "2025-07-10T15:00:00Z" {
+		t.Errorf("Expected due 17:00 Berlin time, got %v", task.DueDate)
+	}
+}
+
+func TestICSDecodeErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"BEGIN:VTODO\nSUMMARY:Open\n", "unterminated VTODO"},
+		{"BEGIN:VTODO\nUID:x\nEND:VTODO\n", "line 3: VTODO has no SUMMARY"},
+		{"BEGIN:VTODO\nSUMMARY:Bad\nDUE:soon\nEND:VTODO\n", `line 3: invalid DUE value "soon"`},
+		{"BEGIN:VTODO\nno colon here\nEND:VTODO\n", "line 2: malformed line"},
+	}
+	for _, tt := range tests {
+		_, err := ICS{}.Decode(strings.NewReader(tt.input))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Decode(%q): expected error containing %q, got %v", tt.input, tt.want, err)
+		}
+	}
+}
+
+func TestICSRoundTrip(t *testing.T) {
+	created := time.Date(2025, 7, 1, 9, 30, 0, 0, time.UTC)
+	completed := time.Date(2025, 7, 3, 14, 0, 0, 0, time.UTC)
+	dueAt := time.Date(2025, 7, 5, 16, 0, 0, 0, time.UTC)
+	list := []tasks.Task{
+		{ID: "a1", Title: "Call the bank", Description: "Ask about\nfees, rates; and more\\", Priority: tasks.Critical, CreatedAt: created, ModifiedAt: created, DueDate: dayPtr("2025-07-04"), Tags: []string{"phone", "a,b"}},
+		{ID: "b2", Title: "Write report", Priority: tasks.High, Done: true, CreatedAt: created, ModifiedAt: completed, CompletedAt: &completed, Project: "website", DueDate: &dueAt},
+		{ID: "c3", Title: "Plan trip", Priority: tasks.Medium, CreatedAt: created, ModifiedAt: created},
+		{ID: "d4", Title: strings.Repeat("Long title ", 20), Priority: tasks.Low, CreatedAt: created, ModifiedAt: created},
+	}
+
+	var buf bytes.Buffer
+	if err := (ICS{}).Encode(&buf, list); err != nil {
+		t.Fatalf("Encode returned an error: %v", err)
+	}
+	decoded, err := ICS{}.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode returned an error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, list) {
+		t.Errorf("Round trip changed the tasks:\nexpected %+v\ngot      %+v", list, decoded)
+	}
+}
@@ -0,0 +1,264 @@
+package formats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"taskmgr/internal/tasks"
+)
+
+func init() {
+	register(Markdown{}, ".md", ".markdown")
+}
+
+// Markdown reads and writes Markdown checklists:
+//
+//	## website
+//	- [ ] Fix login !high due:2025-07-10 #work +website
+//	  - [x] Write a failing test +website
+//
+// Export groups tasks under a heading per project, or per first tag with
+// GroupBy "tag", and nests subtasks under their parent. Annotations are
+// written inline so that a file reads back the same however it was
+// grouped: #tag, +project, due:date and !priority, which is left out for
+// medium. Import reads every checklist item in the file, wherever it
+// appears, and ignores headings and other text. Indented items become
+// subtasks of the item above them.
+type Markdown struct {
+	groupBy string
+}
+
+// Name implements Format
+func (Markdown) Name() string {
+	return "markdown"
+}
+
+func (Markdown) fields() []string {
+	return []string{"title", "done", "priority", "due", "tags", "project", "parent"}
+}
+
+func (m Markdown) configure(opts Options) (Format, error) {
+	switch strings.ToLower(opts.GroupBy) {
+	case "", "project":
+		return Markdown{groupBy: "project"}, nil
+	case "tag":
+		return Markdown{groupBy: "tag"}, nil
+	default:
+		return nil, fmt.Errorf("invalid grouping: %s (use project or tag)", opts.GroupBy)
+	}
+}
+
+// Decode implements Format. Items inside fenced code blocks are skipped.
+func (Markdown) Decode(r io.Reader) ([]tasks.Task, error) {
+	type open struct {
+		indent int
+		id     string
+	}
+	var list []tasks.Task
+	var parents []open
+	fenced := false
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(text), "```") {
+			fenced = !fenced
+			continue
+		}
+		if fenced {
+			continue
+		}
+
+		indent, done, body, ok := parseChecklistItem(text)
+		if !ok {
+			continue
+		}
+		t, err := parseMarkdownItem(body)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		t.ID = tasks.NewID()
+		t.Done = done
+
+		for len(parents) > 0 && parents[len(parents)-1].indent >= indent {
+			parents = parents[:len(parents)-1]
+		}
+		if len(parents) > 0 {
+			t.ParentID = parents[len(parents)-1].id
+		}
+		parents = append(parents, open{indent: indent, id: t.ID})
+		list = append(list, t)
+	}
+	return list, scanner.Err()
+}
+
+// parseChecklistItem recognises "- [ ] text", with -, * or + bullets and
+// x or X marking done items. Tabs count as four spaces of indentation.
+func parseChecklistItem(line string) (indent int, done bool, body string, ok bool) {
+	i := 0
+	for ; i < len(line); i++ {
+		if line[i] == ' ' {
+			indent++
+		} else if line[i] == '\t' {
+			indent += 4
+		} else {
+			break
+		}
+	}
+	rest := line[i:]
+	if len(rest) < 6 || !strings.ContainsRune("-*+", rune(rest[0])) || rest[1] != ' ' || rest[2] != '[' || rest[4] != ']' || rest[5] != ' ' {
+		return 0, false, "", false
+	}
+	switch rest[3] {
+	case ' ':
+	case 'x', 'X':
+		done = true
+	default:
+		return 0, false, "", false
+	}
+	return indent, done, strings.TrimSpace(rest[6:]), true
+}
+
+func parseMarkdownItem(body string) (tasks.Task, error) {
+	t := tasks.Task{Priority: tasks.Medium}
+	var title []string
+	for _, word := range strings.Fields(body) {
+		switch {
+		case len(word) > 1 && word[0] == '#' && strings.Trim(word, "#") != "":
+			t.AddTag(strings.TrimLeft(word, "#"))
+		case len(word) > 1 && word[0] == '+' && t.Project == "":
+			t.Project = word[1:]
+		case strings.HasPrefix(word, "due:") && len(word) > 4:
+			due, err := tasks.ParseDueDate(strings.TrimPrefix(word, "due:"))
+			if err != nil {
+				return t, err
+			}
+			t.DueDate = due
+		case len(word) > 1 && word[0] == '!':
+			if priority, err := tasks.ParsePriority(word[1:]); err == nil {
+				t.Priority = priority
+				continue
+			}
+			title = append(title, word)
+		default:
+			title = append(title, word)
+		}
+	}
+	t.Title = strings.Join(title, " ")
+	if t.Title == "" {
+		return t, fmt.Errorf("checklist item has no title")
+	}
+	return t, nil
+}
+
+// Encode implements Format
+func (m Markdown) Encode(w io.Writer, list []tasks.Task) error {
+	groupBy := m.groupBy
+	if groupBy == "" {
+		groupBy = "project"
+	}
+
+	// Subtasks are written under their parent, wherever the parent goes
+	parentOf := make(map[string]string)
+	for _, t := range list {
+		if t.ID != "" {
+			parentOf[t.ID] = t.ParentID
+		}
+	}
+	children := make(map[string][]tasks.Task)
+	groups := make(map[string][]tasks.Task)
+	for _, t := range list {
+		if nestedTask(t, parentOf) {
+			children[t.ParentID] = append(children[t.ParentID], t)
+			continue
+		}
+		key := t.Project
+		if groupBy == "tag" {
+			key = ""
+			if len(t.Tags) > 0 {
+				key = t.Tags[0]
+			}
+		}
+		groups[key] = append(groups[key], t)
+	}
+
+	var keys []string
+	for key := range groups {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if _, ok := groups[""]; ok {
+		keys = append(keys, "")
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# Tasks")
+	written := make(map[string]bool)
+	var writeItem func(t tasks.Task, depth int)
+	writeItem = func(t tasks.Task, depth int) {
+		fmt.Fprintf(bw, "%s%s\n", strings.Repeat("  ", depth), formatMarkdownItem(t))
+		if t.ID == "" || written[t.ID] {
+			return
+		}
+		written[t.ID] = true
+		for _, child := range children[t.ID] {
+			writeItem(child, depth+1)
+		}
+	}
+	for _, key := range keys {
+		heading := key
+		if key == "" && groupBy == "tag" {
+			heading = "Untagged"
+		} else if key == "" {
+			heading = "No project"
+		}
+		fmt.Fprintf(bw, "\n## %s\n\n", heading)
+		for _, t := range groups[key] {
+			writeItem(t, 0)
+		}
+	}
+	return bw.Flush()
+}
+
+// nestedTask reports whether t has a parent in the list. Tasks in a cycle
+// of parents are treated as top level so that they are still written.
+func nestedTask(t tasks.Task, parentOf map[string]string) bool {
+	seen := map[string]bool{t.ID: true}
+	for parent := t.ParentID; parent != ""; parent = parentOf[parent] {
+		if _, ok := parentOf[parent]; !ok {
+			// The chain ends at a task that is not in the list
+			return parent != t.ParentID
+		}
+		if seen[parent] {
+			return false
+		}
+		seen[parent] = true
+	}
+	return t.ParentID != ""
+}
+
+func formatMarkdownItem(t tasks.Task) string {
+	box := "[ ]"
+	if t.Done {
+		box = "[x]"
+	}
+	words := []string{"-", box, t.Title}
+	if t.Priority != tasks.Medium {
+		words = append(words, "!"+t.Priority.String())
+	}
+	if t.DueDate != nil {
+		words = append(words, "due:"+t.DueDate.Format("2006-01-02"))
+	}
+	for _, tag := range t.Tags {
+		words = append(words, "#"+strings.ReplaceAll(tag, " ", "-"))
+	}
+	if t.Project != "" {
+		words = append(words, "+"+strings.ReplaceAll(t.Project, " ", "-"))
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,206 @@
+package formats
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"taskmgr/internal/tasks"
+)
+
+func TestMarkdownDecode(t *testing.T) {
+	input := "# Meeting notes, July 1st\n" +
+		"\n" +
+		"Attendees: alice, bob\n" +
+		"\n" +
+		"## Action items\n" +
+		"- [ ] Fix login !high due:2025-07-10 #work #urgent +website\n" +
+		"  - [x] Write a failing test\n" +
+		"  - [ ] Patch the handler\n" +
+		"    * [ ] Review with #security\n" +
+		"- [X] Book room\n" +
+		"\t+ [ ] Order snacks !low\n" +
+		"- plain bullet, not a task\n" +
+		"- [?] not a checkbox\n" +
+		"```\n" +
+		"- [ ] inside a code block\n" +
+		"```\n" +
+		"1. [ ] numbered items are not checklists here\n"
+
+	list, err := Markdown{}.Decode(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Decode returned an error: %v", err)
+	}
+	if len(list) != 6 {
+		t.Fatalf("Expected 6 tasks, got %d: %+v", len(list), list)
+	}
+
+	fix := list[0]
+	if fix.Title != "Fix login" || fix.Priority != tasks.High || fix.Project != "website" || fix.Done {
+		t.Errorf("Unexpected first task %+v", fix)
+	}
+	if !reflect.DeepEqual(fix.Tags, []string{"work", "urgent"}) {
+		t.Errorf("Expected tags work and urgent, got %v", fix.Tags)
+	}
+	if fix.DueDate == nil || fix.DueDate.Format("2006-01-02") != "2025-07-10" {
+		t.Errorf("Expected due date 2025-07-10, got %v", fix.DueDate)
+	}
+
+	// Nesting follows indentation
+	parents := []struct {
+		title  string
+		parent int // index in list, -1 for none
+		done   bool
+	}{
+		{"Fix login", -1, false},
+		{"Write a failing test", 0, true},
+		{"Patch the handler", 0, false},
+		{"Review with", 2, false},
+		{"Book room", -1, true},
+		{"Order snacks", 4, false},
+	}
+	for i, want := range parents {
+		got := list[i]
+		if got.Title != want.title || got.Done != want.done {
+			t.Errorf("Task %d: expected %q done=%v, got %q done=%v", i, want.title, want.done, got.Title, got.Done)
+		}
+		wantParent := ""
+		if want.parent >= 0 {
+			wantParent = list[want.parent].ID
+		}
+		if got.ID == "" || got.ParentID != wantParent {
+			t.Errorf("Task %d (%s): expected parent %q, got %q", i, got.Title, wantParent, got.ParentID)
+		}
+	}
+	if !list[3].HasTag("security") || list[5].Priority != tasks.Low || list[1].Priority != tasks.Medium {
+		t.Errorf("Unexpected annotations: %+v", list)
+	}
+}
+
+func TestMarkdownDecodeErrors(t *testing.T) {
+	if _, err := (Markdown{}).Decode(strings.NewReader("text\n- [ ] Ship due:someday\n")); err == nil || !strings.Contains(err.Error(), "line 2: invalid date format") {
+		t.Errorf("Expected due date error on line 2, got %v", err)
+	}
+	if _, err := (Markdown{}).Decode(strings.NewReader("- [ ] #only #tags\n")); err == nil || !strings.Contains(err.Error(), "no title") {
+		t.Errorf("Expected missing title error, got %v", err)
+	}
+}
+
+func TestMarkdownEncode(t *testing.T) {
+	list := []tasks.Task{
+		{ID: "1", Title: "Fix login", Priority: tasks.High, DueDate: dayPtr("2025-07-10"), Tags: []string{"work"}, Project: "website"},
+		{ID: "2", Title: "Write a failing test", Priority: tasks.Medium, Done: true, ParentID: "1"},
+		{ID: "3", Title: "Buy milk", Priority: tasks.Low, Tags: []string{"home"}},
+		{ID: "4", Title: "Draft post", Priority: tasks.Medium, Project: "blog"},
+		{ID: "5", Title: "Orphan", Priority: tasks.Medium, ParentID: "missing", Tags: []string{"work"}},
+	}
+
+	var buf bytes.Buffer
+	if err := (Markdown{}).Encode(&buf, list); err != nil {
+		t.Fatalf("Encode returned an error: %v", err)
+	}
+	expected := `# Tasks
+
+## blog
+
+- [ ] Draft post +blog
+
+## website
+
+- [ ] Fix login !high due:2025-07-10 #work +website
+  - [x] Write a failing test
+
+## No project
+
+- [ ] Buy milk !low #home
+- [ ] Orphan #work
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	byTag, err := Configure(Markdown{}, Options{GroupBy: "tag"})
+	if err != nil {
+		t.Fatalf("Configure returned an error: %v", err)
+	}
+	buf.Reset()
+	if err := byTag.Encode(&buf, list); err != nil {
+		t.Fatalf("Encode returned an error: %v", err)
+	}
+	for _, want := range []string{"## home\n\n- [ ] Buy milk", "## work\n\n- [ ] Fix login", "- [ ] Orphan #work\n\n## Untagged\n\n- [ ] Draft post +blog\n"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected tag grouping to contain %q, got:\n%s", want, buf.String())
+		}
+	}
+
+	if _, err := Configure(Markdown{}, Options{GroupBy: "colour"}); err == nil {
+		t.Error("Expected error for invalid grouping")
+	}
+}
+
+func TestMarkdownEncodeParentCycle(t *testing.T) {
+	list := []tasks.Task{
+		{ID: "a", Title: "A", Priority: tasks.Medium, ParentID: "b"},
+		{ID: "b", Title: "B", Priority: tasks.Medium, ParentID: "a"},
+	}
+	var buf bytes.Buffer
+	if err := (Markdown{}).Encode(&buf, list); err != nil {
+		t.Fatalf("Encode returned an error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "- [ ] A\n- [ ] B\n") {
+		t.Errorf("Expected both tasks at the top level, got:\n%s", buf.String())
+	}
+}
+
+func TestMarkdownRoundTrip(t *testing.T) {
+	list := []tasks.Task{
+		{ID: "1", Title: "Fix login", Priority: tasks.Critical, DueDate: dayPtr("2025-07-10"), Tags: []string{"work", "urgent"}, Project: "website"},
+		{ID: "2", Title: "Write a failing test", Priority: tasks.Medium, Done: true, ParentID: "1", Project: "website"},
+		{ID: "3", Title: "Deeper", Priority: tasks.Low, ParentID: "2"},
+		{ID: "4", Title: "Buy milk", Priority: tasks.High, Tags: []string{"home"}},
+	}
+
+	for _, groupBy := range []string{"project", "tag"} {
+		f, _ := Configure(Markdown{}, Options{GroupBy: groupBy})
+		var buf bytes.Buffer
+		if err := f.Encode(&buf, list); err != nil {
+			t.Fatalf("Encode returned an error: %v", err)
+		}
+		decoded, err := f.Decode(&buf)
+		if err != nil {
+			t.Fatalf("Decode returned an error: %v", err)
+		}
+		if len(decoded) != len(list) {
+			t.Fatalf("Expected %d tasks, got %d", len(list), len(decoded))
+		}
+
+		// Decoding assigns fresh ids; compare everything else by title
+		idOf := map[string]string{}
+		titleOf := map[string]string{}
+		for _, d := range decoded {
+			titleOf[d.ID] = d.Title
+		}
+		for _, orig := range list {
+			idOf[orig.ID] = orig.Title
+		}
+		byTitle := map[string]tasks.Task{}
+		for _, d := range decoded {
+			byTitle[d.Title] = d
+		}
+		for _, orig := range list {
+			got, ok := byTitle[orig.Title]
+			if !ok {
+				t.Errorf("%s: task %q missing after round trip", groupBy, orig.Title)
+				continue
+			}
+			if titleOf[got.ParentID] != idOf[orig.ParentID] {
+				t.Errorf("%s: %q expected parent %q, got %q", groupBy, orig.Title, idOf[orig.ParentID], titleOf[got.ParentID])
+			}
+			got.ID, got.ParentID = orig.ID, orig.ParentID
+			if !reflect.DeepEqual(got, orig) {
+				t.Errorf("%s: round trip changed the task:\nexpected %+v\ngot      %+v", groupBy, orig, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,395 @@
+package formats
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"taskmgr/internal/tasks"
+)
+
+func init() {
+	// Other JSON, task files included, is no Taskwarrior export, so the
+	// format is only ever chosen by name
+	register(Taskwarrior{})
+}
+
+// Taskwarrior reads and writes the JSON of "task export" and "task import":
+// an array of objects, or one object per line as older versions wrote.
+//
+// uuid, description, project, tags, entry, modified, due and end map onto
+// the task's fields. Pending and completed statuses map to Done; deleted
+// tasks read as done and waiting or recurring ones as pending. Priorities
+// H, M and L map to high, medium and low, and critical is written as H.
+// Annotations become the description, one per line, and tags are
+// lowercased as taskmgr keeps them. Everything else, such
+// as urgency, depends, recur and user defined attributes, is kept in
+// Task.Extra and written back unchanged, along with the original status,
+// annotations, tags and a missing priority as long as the task still
+// agrees with them, so that a Taskwarrior file survives a round trip.
+type Taskwarrior struct{}
+
+// Name implements Format
+func (Taskwarrior) Name() string {
+	return "taskwarrior"
+}
+
+func (Taskwarrior) fields() []string {
+	return []string{"id", "title", "description", "done", "completed", "priority", "project", "tags", "created", "due", "extra"}
+}
+
+const twTimeFormat = "20060102T150405Z"
+
+// twKnown are the attributes mapped onto task fields rather than kept in
+// Extra
+var twKnown = map[string]bool{
+	"uuid": true, "description": true, "status": true, "priority": true, "project": true,
+	"tags": true, "entry": true, "modified": true, "due": true, "end": true,
+}
+
+// twAnnotation is one entry of a task's annotations
+type twAnnotation struct {
+	Entry       string `json:"entry"`
+	Description string `json:"description"`
+}
+
+// Decode implements Format
+func (Taskwarrior) Decode(r io.Reader) ([]tasks.Task, error) {
+	br := bufio.NewReader(r)
+	first, err := firstNonSpace(br)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []map[string]json.RawMessage
+	dec := json.NewDecoder(br)
+	if first == '[' {
+		if err := dec.Decode(&objects); err != nil {
+			return nil, fmt.Errorf("invalid Taskwarrior export: %v", err)
+		}
+	} else {
+		for {
+			var obj map[string]json.RawMessage
+			if err := dec.Decode(&obj); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("task %d: invalid JSON: %v", len(objects)+1, err)
+			}
+			objects = append(objects, obj)
+		}
+	}
+
+	var list []tasks.Task
+	for i, obj := range objects {
+		t, err := decodeTaskwarrior(obj)
+		if err != nil {
+			return nil, fmt.Errorf("task %d: %v", i+1, err)
+		}
+		list = append(list, t)
+	}
+	return list, nil
+}
+
+// firstNonSpace peeks at the first byte that is not white space
+func firstNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if !strings.ContainsRune(" \t\r\n", rune(b)) {
+			return b, br.UnreadByte()
+		}
+	}
+}
+
+func decodeTaskwarrior(obj map[string]json.RawMessage) (tasks.Task, error) {
+	var t tasks.Task
+	str := func(key string) (string, error) {
+		var s string
+		if raw, ok := obj[key]; ok {
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return "", fmt.Errorf("%s: %v", key, err)
+			}
+		}
+		return s, nil
+	}
+	stamp := func(key string) (*time.Time, error) {
+		s, err := str(key)
+		if err != nil || s == "" {
+			return nil, err
+		}
+		parsed, err := time.Parse(twTimeFormat, s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid time %q", key, s)
+		}
+		return &parsed, nil
+	}
+
+	var err error
+	if t.ID, err = str("uuid"); err != nil {
+		return t, err
+	}
+	if t.Title, err = str("description"); err != nil {
+		return t, err
+	}
+	if t.Title == "" {
+		return t, fmt.Errorf("description is empty")
+	}
+	if t.Project, err = str("project"); err != nil {
+		return t, err
+	}
+	var tags []string
+	if raw, ok := obj["tags"]; ok {
+		if err := json.Unmarshal(raw, &tags); err != nil {
+			return t, fmt.Errorf("tags: %v", err)
+		}
+	}
+	for _, tag := range tags {
+		t.AddTag(tag)
+	}
+
+	status, err := str("status")
+	if err != nil {
+		return t, err
+	}
+	switch status {
+	case "", "pending", "waiting", "recurring":
+	case "completed", "deleted":
+		t.Done = true
+	default:
+		return t, fmt.Errorf("unknown status %q", status)
+	}
+
+	priority, err := str("priority")
+	if err != nil {
+		return t, err
+	}
+	switch priority {
+	case "H":
+		t.Priority = tasks.High
+	case "M", "":
+		t.Priority = tasks.Medium
+	case "L":
+		t.Priority = tasks.Low
+	default:
+		return t, fmt.Errorf("unknown priority %q", priority)
+	}
+
+	if entry, err := stamp("entry"); err != nil {
+		return t, err
+	} else if entry != nil {
+		t.CreatedAt = *entry
+	}
+	if modified, err := stamp("modified"); err != nil {
+		return t, err
+	} else if modified != nil {
+		t.ModifiedAt = *modified
+	}
+	if t.DueDate, err = stamp("due"); err != nil {
+		return t, err
+	}
+	if t.CompletedAt, err = stamp("end"); err != nil {
+		return t, err
+	}
+
+	var annotations []twAnnotation
+	if raw, ok := obj["annotations"]; ok {
+		if err := json.Unmarshal(raw, &annotations); err != nil {
+			return t, fmt.Errorf("annotations: %v", err)
+		}
+	}
+	t.Description = joinAnnotations(annotations)
+
+	// Keep what the fields above cannot express
+	for key, raw := range obj {
+		if twKnown[key] {
+			continue
+		}
+		if t.Extra == nil {
+			t.Extra = make(map[string]json.RawMessage)
+		}
+		t.Extra[key] = raw
+	}
+	if status != "" && status != "pending" && status != "completed" {
+		setExtra(&t, "status", status)
+	}
+	if priority == "" {
+		setExtra(&t, "priority", nil)
+	}
+	if strings.Join(tags, "\x00") != strings.Join(t.Tags, "\x00") {
+		setExtra(&t, "tags", tags)
+	}
+	return t, nil
+}
+
+func setExtra(t *tasks.Task, key string, value interface{}) {
+	raw, _ := json.Marshal(value)
+	if t.Extra == nil {
+		t.Extra = make(map[string]json.RawMessage)
+	}
+	t.Extra[key] = raw
+}
+
+func joinAnnotations(annotations []twAnnotation) string {
+	var lines []string
+	for _, a := range annotations {
+		lines = append(lines, a.Description)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Encode implements Format. Objects are written one per line inside an
+// array, the layout "task export" uses.
+func (Taskwarrior) Encode(w io.Writer, list []tasks.Task) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("[\n")
+	for i, t := range list {
+		data, err := encodeTaskwarrior(t)
+		if err != nil {
+			return err
+		}
+		bw.Write(data)
+		if i < len(list)-1 {
+			bw.WriteString(",")
+		}
+		bw.WriteString("\n")
+	}
+	bw.WriteString("]\n")
+	return bw.Flush()
+}
+
+func encodeTaskwarrior(t tasks.Task) ([]byte, error) {
+	obj := make(map[string]interface{})
+	for key, raw := range t.Extra {
+		if key != "status" && key != "priority" && key != "annotations" && key != "tags" {
+			obj[key] = raw
+		}
+	}
+
+	obj["uuid"] = t.ID
+	obj["description"] = t.Title
+	obj["status"] = twStatus(t)
+	if priority := twPriority(t); priority != "" {
+		obj["priority"] = priority
+	}
+	if t.Project != "" {
+		obj["project"] = t.Project
+	}
+	if tags := twTags(t); len(tags) > 0 {
+		obj["tags"] = tags
+	}
+	if !t.CreatedAt.IsZero() {
+		obj["entry"] = t.CreatedAt.UTC().Format(twTimeFormat)
+	}
+	if !t.ModifiedAt.IsZero() {
+		obj["modified"] = t.ModifiedAt.UTC().Format(twTimeFormat)
+	}
+	if t.DueDate != nil {
+		obj["due"] = t.DueDate.UTC().Format(twTimeFormat)
+	}
+	if t.CompletedAt != nil {
+		obj["end"] = t.CompletedAt.UTC().Format(twTimeFormat)
+	}
+	if annotations := twAnnotations(t); annotations != nil {
+		obj["annotations"] = annotations
+	}
+
+	// Sorted keys keep the output stable
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		name, _ := json.Marshal(key)
+		value, err := json.Marshal(obj[key])
+		if err != nil {
+			return nil, fmt.Errorf("task %s: %s: %v", t.ID, key, err)
+		}
+		buf.Write(name)
+		buf.WriteString(":")
+		buf.Write(value)
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+// twStatus keeps a status read from Taskwarrior while the task is still
+// done, or still open, as that status implies
+func twStatus(t tasks.Task) string {
+	var original string
+	if raw, ok := t.Extra["status"]; ok && json.Unmarshal(raw, &original) == nil {
+		if (original == "deleted") == t.Done {
+			return original
+		}
+	}
+	if t.Done {
+		return "completed"
+	}
+	return "pending"
+}
+
+// twPriority leaves out the priority of tasks that had none in Taskwarrior
+// and have not been given one since
+func twPriority(t tasks.Task) string {
+	if raw, ok := t.Extra["priority"]; ok && string(raw) == "null" && t.Priority == tasks.Medium {
+		return ""
+	}
+	switch t.Priority {
+	case tasks.Critical, tasks.High:
+		return "H"
+	case tasks.Medium:
+		return "M"
+	default:
+		return "L"
+	}
+}
+
+// twAnnotations writes the original annotations while the description
+// still matches them, and otherwise the description as one annotation
+func twAnnotations(t tasks.Task) []twAnnotation {
+	var original []twAnnotation
+	if raw, ok := t.Extra["annotations"]; ok && json.Unmarshal(raw, &original) == nil {
+		if joinAnnotations(original) == t.Description {
+			return original
+		}
+	}
+	if t.Description == "" {
+		return nil
+	}
+	entry := t.ModifiedAt
+	if entry.IsZero() {
+		entry = t.CreatedAt
+	}
+	return []twAnnotation{{Entry: entry.UTC().Format(twTimeFormat), Description: t.Description}}
+}
+
+// twTags writes the original tags, in their own case, while the task's
+// tags are still the same ones
+func twTags(t tasks.Task) []string {
+	var original []string
+	if raw, ok := t.Extra["tags"]; ok && json.Unmarshal(raw, &original) == nil {
+		var lowered tasks.Task
+		for _, tag := range original {
+			lowered.AddTag(tag)
+		}
+		if strings.Join(lowered.Tags, "\x00") == strings.Join(t.Tags, "\x00") {
+			return original
+		}
+	}
+	return t.Tags
+}
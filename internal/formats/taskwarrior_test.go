@@ -0,0 +1,208 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"taskmgr/internal/tasks"
+)
+
+// twExport is shaped like the output of "task export", with a user defined
+// attribute (estimate), a deleted and a waiting task
+const twExport = `[
+{"id":1,"description":"Fix login","entry":"20250701T093000Z","modified":"20250702T100000Z","due":"20250710T000000Z","priority":"H","project":"website","status":"pending","tags":["work","Urgent"],"uuid":"a1b2c3d4-0000-4000-8000-000000000001","urgency":12.3,"estimate":"2h","annotations":[{"entry":"20250701T094500Z","description":"Users see a 500"},{"entry":"20250702T090000Z","description":"Only on Safari"}]},
+{"id":0,"description":"Old idea","end":"20250703T120000Z","entry":"20250601T080000Z","modified":"20250703T120000Z","status":"deleted","uuid":"a1b2c3d4-0000-4000-8000-000000000002","urgency":0},
+{"id":2,"description":"Renew passport","entry":"20250601T080000Z","status":"waiting","wait":"20260101T000000Z","uuid":"a1b2c3d4-0000-4000-8000-000000000003","depends":["a1b2c3d4-0000-4000-8000-000000000001"],"urgency":-3}
+]
+`
+
+func TestTaskwarriorDecode(t *testing.T) {
+	list, err := Taskwarrior{}.Decode(strings.NewReader(twExport))
+	if err != nil {
+		t.Fatalf("Decode returned an error: %v", err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("Expected 3 tasks, got %d", len(list))
+	}
+
+	fix := list[0]
+	if fix.ID != "a1b2c3d4-0000-4000-8000-000000000001" || fix.Title != "Fix login" || fix.Project != "website" {
+		t.Errorf("Unexpected task %+v", fix)
+	}
+	if fix.Priority != tasks.High || fix.Done {
+		t.Errorf("Expected pending high priority task, got %+v", fix)
+	}
+	if !reflect.DeepEqual(fix.Tags, []string{"work", "urgent"}) {
+		t.Errorf("Expected tags lowercased, got %v", fix.Tags)
+	}
+	if !fix.CreatedAt.Equal(time.Date(2025, 7, 1, 9, 30, 0, 0, time.UTC)) || !fix.ModifiedAt.Equal(time.Date(2025, 7, 2, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected timestamps %v, %v", fix.CreatedAt, fix.ModifiedAt)
+	}
+	if fix.DueDate == nil || fix.DueDate.Format("2006-01-02") != "2025-07-10" {
+		t.Errorf("Expected due date 2025-07-10, got %v", fix.DueDate)
+	}
+	if fix.Description != "Users see a 500\nOnly on Safari" {
+		t.Errorf("Expected annotations as description, got %q", fix.Description)
+	}
+	if string(fix.Extra["estimate"]) != `"2h"` || string(fix.Extra["urgency"]) != "12.3" {
+		t.Errorf("Expected unknown attributes to be kept, got %v", fix.Extra)
+	}
+
+	deleted := list[1]
+	if !deleted.Done || deleted.CompletedAt == nil || deleted.Priority != tasks.Medium {
+		t.Errorf("Expected deleted task to read as done with medium priority, got %+v", deleted)
+	}
+	if waiting := list[2]; waiting.Done || string(waiting.Extra["wait"]) != `"20260101T000000Z"` {
+		t.Errorf("Expected waiting task to be pending with its wait date kept, got %+v", waiting)
+	}
+}
+
+func TestTaskwarriorDecodeLines(t *testing.T) {
+	// Older versions wrote one object per line
+	input := `{"description":"One","status":"pending","uuid":"1"}
+{"description":"Two","status":"completed","priority":"L","uuid":"2"}
+`
+	list, err := Taskwarrior{}.Decode(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Decode returned an error: %v", err)
+	}
+	if len(list) != 2 || list[1].Title != "Two" || !list[1].Done || list[1].Priority != tasks.Low {
+		t.Errorf("Unexpected tasks %+v", list)
+	}
+
+	if list, err := (Taskwarrior{}).Decode(strings.NewReader("  \n")); err != nil || len(list) != 0 {
+		t.Errorf("Expected no tasks from empty input, got %v, %v", list, err)
+	}
+}
+
+func TestTaskwarriorDecodeErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`[{"description":"A","status":"pending"},{"description":"B","status":"lost"}]`, `task 2: unknown status "lost"`},
+		{`[{"description":"A","priority":"X"}]`, `task 1: unknown priority "X"`},
+		{`[{"description":"A","due":"tomorrow"}]`, `task 1: due: invalid time "tomorrow"`},
+		{`[{"status":"pending"}]`, "task 1: description is empty"},
+		{`[{"description":"A"`, "invalid Taskwarrior export"},
+		{"{\"description\":\"A\"}\n{oops}\n", "task 2: invalid JSON"},
+	}
+	for _, tt := range tests {
+		_, err := Taskwarrior{}.Decode(strings.NewReader(tt.input))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Decode(%q): expected error containing %q, got %v", tt.input, tt.want, err)
+		}
+	}
+}
+
+// normalizeJSON decodes a Taskwarrior array into generic values for
+// comparison regardless of key order and spacing
+func normalizeJSON(t *testing.T, data []byte) []map[string]interface{} {
+	t.Helper()
+	var objects []map[string]interface{}
+	if err := json.Unmarshal(data, &objects); err != nil {
+		t.Fatalf("Invalid JSON %s: %v", data, err)
+	}
+	return objects
+}
+
+func TestTaskwarriorRoundTripIsLossless(t *testing.T) {
+	list, err := Taskwarrior{}.Decode(strings.NewReader(twExport))
+	if err != nil {
+		t.Fatalf("Decode returned an error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := (Taskwarrior{}).Encode(&buf, list); err != nil {
+		t.Fatalf("Encode returned an error: %v", err)
+	}
+
+	want := normalizeJSON(t, []byte(twExport))
+	got := normalizeJSON(t, buf.Bytes())
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Round trip changed the export:\nexpected %v\ngot      %v", want, got)
+	}
+
+	// One object per line, like "task export"
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 5 {
+		t.Errorf("Expected 5 lines, got %d:\n%s", len(lines), buf.String())
+	}
+}
+
+func TestTaskwarriorEncodeChangedTasks(t *testing.T) {
+	list, err := Taskwarrior{}.Decode(strings.NewReader(twExport))
+	if err != nil {
+		t.Fatalf("Decode returned an error: %v", err)
+	}
+
+	// Reopening the deleted task, giving it a priority and editing the
+	// description of the first one override what was kept
+	list[0].Description = "Fixed on Safari too"
+	list[1].Done = false
+	list[1].CompletedAt = nil
+	list[1].Priority = tasks.Critical
+
+	var buf bytes.Buffer
+	if err := (Taskwarrior{}).Encode(&buf, list); err != nil {
+		t.Fatalf("Encode returned an error: %v", err)
+	}
+	got := normalizeJSON(t, buf.Bytes())
+
+	annotations := got[0]["annotations"].([]interface{})
+	if len(annotations) != 1 || annotations[0].(map[string]interface{})["description"] != "Fixed on Safari too" {
+		t.Errorf("Expected the edited description as the only annotation, got %v", annotations)
+	}
+	if got[1]["status"] != "pending" || got[1]["priority"] != "H" {
+		t.Errorf("Expected reopened task to be pending with priority H, got %v", got[1])
+	}
+	if _, ok := got[2]["priority"]; ok {
+		t.Errorf("Expected task without a priority to stay without one, got %v", got[2])
+	}
+}
+
+func TestTaskwarriorEncodeNative(t *testing.T) {
+	created := time.Date(2025, 7, 1, 9, 30, 0, 0, time.UTC)
+	list := []tasks.Task{
+		{ID: "x1", Title: "Native", Description: "Some notes", Priority: tasks.Low, CreatedAt: created, Tags: []string{"home"}},
+	}
+
+	var buf bytes.Buffer
+	if err := (Taskwarrior{}).Encode(&buf, list); err != nil {
+		t.Fatalf("Encode returned an error: %v", err)
+	}
+	expected := `[
+{"annotations":[{"entry":"20250701T093000Z","description":"Some notes"}],"description":"Native","entry":"20250701T093000Z","priority":"L","status":"pending","tags":["home"],"uuid":"x1"}
+]
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	// And it reads back as the same task
+	decoded, err := Taskwarrior{}.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode returned an error: %v", err)
+	}
+	decoded[0].Extra = nil
+	if !reflect.DeepEqual(decoded, list) {
+		t.Errorf("Round trip changed the task:\nexpected %+v\ngot      %+v", list, decoded)
+	}
+}
+
+func TestTaskwarriorTagsFollowChanges(t *testing.T) {
+	list, err := Taskwarrior{}.Decode(strings.NewReader(`[{"uuid":"a","description":"A","tags":["Work","URGENT"]}]`))
+	if err != nil {
+		t.Fatalf("Decode returned an error: %v", err)
+	}
+	if got := twTags(list[0]); !reflect.DeepEqual(got, []string{"Work", "URGENT"}) {
+		t.Errorf("Expected the original tags written back, got %v", got)
+	}
+	list[0].RemoveTag("urgent")
+	if got := twTags(list[0]); !reflect.DeepEqual(got, []string{"work"}) {
+		t.Errorf("Expected the changed tags written, got %v", got)
+	}
+}
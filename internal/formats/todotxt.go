@@ -0,0 +1,219 @@
+package formats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"taskmgr/internal/tasks"
+)
+
+func init() {
+	register(TodoTxt{}, ".txt")
+}
+
+// TodoTxt reads and writes the todo.txt format (http://todotxt.org), one
+// task per line:
+//
+//	x 2025-07-03 2025-07-01 Write report +website @work due:2025-07-10 pri:B
+//	(A) 2025-07-01 Call the bank @phone
+//
+// Priorities (A) to (D) map to critical, high, medium and low; lower
+// letters are read as low, and a task without one is medium. The first
+// +project becomes the task's project and @contexts become tags. Since
+// todo.txt drops the priority of completed tasks, it is kept in a pri: key
+// instead, and the task's ID in an id: key. A title word that would be read
+// as a project, context, key or date is written with a backslash before
+// it. Descriptions have no place in the format and are not exported.
+type TodoTxt struct{}
+
+// Name implements Format
+func (TodoTxt) Name() string {
+	return "todotxt"
+}
+
+func (TodoTxt) fields() []string {
+	return []string{"id", "title", "done", "completed", "priority", "created", "due", "project", "tags"}
+}
+
+const todoDateFormat = "2006-01-02"
+
+var todoPriorities = []struct {
+	letter   string
+	priority tasks.Priority
+}{
+	{"A", tasks.Critical},
+	{"B", tasks.High},
+	{"C", tasks.Medium},
+	{"D", tasks.Low},
+}
+
+// Decode implements Format. Blank lines are skipped.
+func (TodoTxt) Decode(r io.Reader) ([]tasks.Task, error) {
+	var list []tasks.Task
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		t, err := parseTodoLine(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		list = append(list, t)
+	}
+	return list, scanner.Err()
+}
+
+func parseTodoLine(line string) (tasks.Task, error) {
+	t := tasks.Task{Priority: tasks.Medium}
+	words := strings.Fields(line)
+
+	if len(words) > 0 && words[0] == "x" {
+		t.Done = true
+		words = words[1:]
+		if completed, ok := parseTodoDate(words); ok {
+			t.CompletedAt = &completed
+			words = words[1:]
+		}
+	} else if len(words) > 0 && isTodoPriority(words[0]) {
+		t.Priority = todoPriority(words[0][1:2])
+		words = words[1:]
+	}
+	if created, ok := parseTodoDate(words); ok {
+		t.CreatedAt = created
+		words = words[1:]
+	}
+
+	var title []string
+	for _, word := range words {
+		switch {
+		case len(word) > 1 && word[0] == '+' && t.Project == "":
+			t.Project = word[1:]
+		case len(word) > 1 && (word[0] == '@' || word[0] == '+'):
+			t.AddTag(word[1:])
+		case strings.HasPrefix(word, "due:"):
+			due, err := time.Parse(todoDateFormat, strings.TrimPrefix(word, "due:"))
+			if err != nil {
+				return t, fmt.Errorf("invalid due date %q", word)
+			}
+			t.DueDate = &due
+		case strings.HasPrefix(word, "pri:") && len(word) == 5:
+			t.Priority = todoPriority(strings.ToUpper(word[4:]))
+		case strings.HasPrefix(word, "id:") && len(word) > 3:
+			t.ID = word[3:]
+		case len(word) > 1 && word[0] == '\\':
+			title = append(title, word[1:])
+		default:
+			title = append(title, word)
+		}
+	}
+	t.Title = strings.Join(title, " ")
+	if t.Title == "" {
+		return t, fmt.Errorf("task has no title")
+	}
+	return t, nil
+}
+
+// isTodoPriority reports whether word is a priority marker such as "(A)"
+func isTodoPriority(word string) bool {
+	return len(word) == 3 && word[0] == '(' && word[2] == ')' && word[1] >= 'A' && word[1] <= 'Z'
+}
+
+func todoPriority(letter string) tasks.Priority {
+	for _, p := range todoPriorities {
+		if p.letter == letter {
+			return p.priority
+		}
+	}
+	return tasks.Low
+}
+
+func todoLetter(priority tasks.Priority) string {
+	for _, p := range todoPriorities {
+		if p.priority == priority {
+			return p.letter
+		}
+	}
+	return "D"
+}
+
+// parseTodoDate parses the first word as a date, if it is one
+func parseTodoDate(words []string) (time.Time, bool) {
+	if len(words) == 0 {
+		return time.Time{}, false
+	}
+	d, err := time.Parse(todoDateFormat, words[0])
+	return d, err == nil
+}
+
+// Encode implements Format
+func (TodoTxt) Encode(w io.Writer, list []tasks.Task) error {
+	bw := bufio.NewWriter(w)
+	for _, t := range list {
+		if _, err := fmt.Fprintln(bw, formatTodoLine(t)); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func formatTodoLine(t tasks.Task) string {
+	var words []string
+	withCreated := !t.CreatedAt.IsZero()
+	if t.Done {
+		words = append(words, "x")
+		if t.CompletedAt != nil {
+			words = append(words, t.CompletedAt.Format(todoDateFormat))
+		} else {
+			// A creation date after "x" would be read as the completion date
+			withCreated = false
+		}
+	} else {
+		words = append(words, "("+todoLetter(t.Priority)+")")
+	}
+	if withCreated {
+		words = append(words, t.CreatedAt.Format(todoDateFormat))
+	}
+	for i, word := range strings.Fields(t.Title) {
+		if todoSpecial(word, i == 0) {
+			word = `\` + word
+		}
+		words = append(words, word)
+	}
+	if t.Project != "" {
+		words = append(words, "+"+strings.ReplaceAll(t.Project, " ", "-"))
+	}
+	for _, tag := range t.Tags {
+		words = append(words, "@"+strings.ReplaceAll(tag, " ", "-"))
+	}
+	if t.DueDate != nil {
+		words = append(words, "due:"+t.DueDate.Format(todoDateFormat))
+	}
+	if t.Done {
+		words = append(words, "pri:"+todoLetter(t.Priority))
+	}
+	if t.ID != "" {
+		words = append(words, "id:"+t.ID)
+	}
+	return strings.Join(words, " ")
+}
+
+// todoSpecial reports whether a word of a title would be read back as
+// something else: a project, a context, a key, a word escaped with a
+// backslash, or, as the first word, a date
+func todoSpecial(word string, first bool) bool {
+	if len(word) > 1 && (word[0] == '+' || word[0] == '@') {
+		return true
+	}
+	for _, prefix := range []string{"due:", "pri:", "id:", `\`} {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+	_, isDate := parseTodoDate([]string{word})
+	return first && isDate
+}
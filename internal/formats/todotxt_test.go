@@ -0,0 +1,149 @@
+package formats
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"taskmgr/internal/tasks"
+)
+
+func day(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func dayPtr(s string) *time.Time {
+	d := day(s)
+	return &d
+}
+
+func TestTodoTxtDecode(t *testing.T) {
+	input := `(A) 2025-07-01 Call the bank @phone @errands due:2025-07-04
+x 2025-07-03 2025-07-01 Write report +website @work pri:B
+
+(C) Plan trip +travel +summer
+(Z) Someday maybe
+x Quick win
+`
+	list, err := TodoTxt{}.Decode(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Decode returned an error: %v", err)
+	}
+
+	expected := []tasks.Task{
+		{Title: "Call the bank", Priority: tasks.Critical, CreatedAt: day("2025-07-01"), Tags: []string{"phone", "errands"}, DueDate: dayPtr("2025-07-04")},
+		{Title: "Write report", Priority: tasks.High, Done: true, CompletedAt: dayPtr("2025-07-03"), CreatedAt: day("2025-07-01"), Project: "website", Tags: []string{"work"}},
+		{Title: "Plan trip", Priority: tasks.Medium, Project: "travel", Tags: []string{"summer"}},
+		{Title: "Someday maybe", Priority: tasks.Low},
+		{Title: "Quick win", Priority: tasks.Medium, Done: true},
+	}
+	if len(list) != len(expected) {
+		t.Fatalf("Expected %d tasks, got %d: %v", len(expected), len(list), list)
+	}
+	for i := range expected {
+		if !reflect.DeepEqual(list[i], expected[i]) {
+			t.Errorf("Task %d:\nexpected %+v\ngot      %+v", i, expected[i], list[i])
+		}
+	}
+}
+
+func TestTodoTxtDecodeErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Fine\nBad due:tomorrow\n", "line 2: invalid due date"},
+		{"(A) +project @context\n", "line 1: task has no title"},
+	}
+	for _, tt := range tests {
+		_, err := TodoTxt{}.Decode(strings.NewReader(tt.input))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Decode(%q): expected error containing %q, got %v", tt.input, tt.want, err)
+		}
+	}
+}
+
+func TestTodoTxtDecodeKeys(t *testing.T) {
+	list, err := TodoTxt{}.Decode(strings.NewReader("Call mom id:abc123 \\+1 \\@home\n"))
+	if err != nil {
+		t.Fatalf("Decode returned an error: %v", err)
+	}
+	expected := tasks.Task{ID: "abc123", Title: "Call mom +1 @home", Priority: tasks.Medium}
+	if len(list) != 1 || !reflect.DeepEqual(list[0], expected) {
+		t.Errorf("Expected %+v, got %+v", expected, list)
+	}
+}
+
+func TestTodoTxtEncode(t *testing.T) {
+	list := []tasks.Task{
+		{Title: "Call the bank", Priority: tasks.Critical, CreatedAt: day("2025-07-01"), Tags: []string{"phone"}, DueDate: dayPtr("2025-07-04")},
+		{Title: "Write report", Priority: tasks.High, Done: true, CompletedAt: dayPtr("2025-07-03"), CreatedAt: day("2025-07-01"), Project: "web site"},
+		{Title: "Legacy done", Done: true, CreatedAt: day("2025-06-01")},
+		{Title: "Described", Description: "not exported"},
+		{ID: "abc123", Title: "Vote +1", Priority: tasks.Medium},
+	}
+
+	var buf bytes.Buffer
+	if err := (TodoTxt{}).Encode(&buf, list); err != nil {
+		t.Fatalf("Encode returned an error: %v", err)
+	}
+
+	expected := `(A) 2025-07-01 Call the bank @phone due:2025-07-04
+x 2025-07-03 2025-07-01 Write report +web-site pri:B
+x Legacy done pri:D
+(D) Described
+(C) Vote \+1 id:abc123
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestTodoTxtRoundTrip(t *testing.T) {
+	// Every field todo.txt can represent survives an export and re-import
+	list := []tasks.Task{
+		{Title: "Call the bank", Priority: tasks.Critical, CreatedAt: day("2025-07-01"), Tags: []string{"phone", "errands"}, DueDate: dayPtr("2025-07-04")},
+		{Title: "Write report", Priority: tasks.High, Done: true, CompletedAt: dayPtr("2025-07-03"), CreatedAt: day("2025-07-01"), Project: "website", Tags: []string{"work"}},
+		{Title: "Plan trip", Priority: tasks.Medium, Project: "travel"},
+		{Title: "Tidy up", Priority: tasks.Low, Done: true},
+		{ID: "9f86d081", Title: "Keep my id", Priority: tasks.Medium},
+		{Title: `2025-07-01 is +1 for @bob, due:soon \ id:x \+`, Priority: tasks.High},
+		{Title: "2025-07-01 again", Priority: tasks.Low, Done: true},
+	}
+	for _, p := range []tasks.Priority{tasks.Low, tasks.Medium, tasks.High, tasks.Critical} {
+		list = append(list, tasks.Task{Title: "Priority " + p.String(), Priority: p})
+		list = append(list, tasks.Task{Title: "Done " + p.String(), Priority: p, Done: true})
+	}
+
+	var buf bytes.Buffer
+	if err := (TodoTxt{}).Encode(&buf, list); err != nil {
+		t.Fatalf("Encode returned an error: %v", err)
+	}
+	decoded, err := TodoTxt{}.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode returned an error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, list) {
+		t.Errorf("Round trip changed the tasks:\nexpected %+v\ngot      %+v", list, decoded)
+	}
+
+	// And a todo.txt file survives an import and re-export unchanged
+	original := "(B) 2025-07-01 Review PR +website @work due:2025-07-02\nx 2025-07-05 2025-07-01 Ship it +website pri:A\n"
+	parsed, err := TodoTxt{}.Decode(strings.NewReader(original))
+	if err != nil {
+		t.Fatalf("Decode returned an error: %v", err)
+	}
+	buf.Reset()
+	if err := (TodoTxt{}).Encode(&buf, parsed); err != nil {
+		t.Fatalf("Encode returned an error: %v", err)
+	}
+	if buf.String() != original {
+		t.Errorf("Expected file to round trip unchanged:\n%s\ngot:\n%s", original, buf.String())
+	}
+}
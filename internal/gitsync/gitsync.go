@@ -0,0 +1,391 @@
+// Package gitsync keeps a copy of the task list in a git repository, so
+// that it can be shared through any git remote. Every change made through
+// a TaskManager is committed with a message describing it, and Sync pulls
+// and pushes, merging the task lists task by task rather than line by line:
+//
+//	.taskmgr/sync/.git        the repository
+//	.taskmgr/sync/tasks.json  the task list as of the last change
+//
+// The repository is driven with the git command, which must be installed.
+package gitsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"taskmgr/internal/tasks"
+)
+
+// DefaultDir is where the repository is kept
+const DefaultDir = ".taskmgr/sync"
+
+// DefaultBranch is the branch synced when none is given
+const DefaultBranch = "main"
+
+// File is the name of the task list in the repository
+const File = "tasks.json"
+
+// Remote is the name of the remote Sync pulls from and pushes to
+const Remote = "origin"
+
+// ErrNotInitialized is returned by Open for a directory without a repository
+var ErrNotInitialized = errors.New("git sync is not set up; run taskmgr sync init --remote=<url>")
+
+// Repo is a repository holding a copy of the task list
+type Repo struct {
+	Dir string
+}
+
+// Open opens the repository in dir
+func Open(dir string) (*Repo, error) {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotInitialized
+		}
+		return nil, err
+	}
+	return &Repo{Dir: dir}, nil
+}
+
+// Init creates the repository in dir on the given branch, or opens it if
+// it exists, and points its remote at remote unless that is empty
+func Init(dir, branch, remote string) (*Repo, error) {
+	if branch == "" {
+		branch = DefaultBranch
+	}
+	r, err := Open(dir)
+	if errors.Is(err, ErrNotInitialized) {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+		r = &Repo{Dir: dir}
+		if _, err := r.git("init", "-q"); err != nil {
+			return nil, err
+		}
+		if _, err := r.git("symbolic-ref", "HEAD", "refs/heads/"+branch); err != nil {
+			return nil, err
+		}
+		// Commits need an author; fall back to the task actor
+		if _, err := r.git("config", "user.email"); err != nil {
+			r.git("config", "user.name", tasks.DefaultActor())
+			r.git("config", "user.email", "taskmgr@localhost")
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if remote == "" {
+		return r, nil
+	}
+	if _, err := r.git("remote", "get-url", Remote); err == nil {
+		_, err = r.git("remote", "set-url", Remote, remote)
+		return r, err
+	}
+	_, err = r.git("remote", "add", Remote, remote)
+	return r, err
+}
+
+// Branch returns the branch the repository is on
+func (r *Repo) Branch() (string, error) {
+	return r.git("symbolic-ref", "--short", "HEAD")
+}
+
+// RemoteURL returns where the repository syncs to, or "" if nowhere
+func (r *Repo) RemoteURL() string {
+	url, _ := r.git("remote", "get-url", Remote)
+	return url
+}
+
+// git runs a git command in the repository and returns its trimmed output
+func (r *Repo) git(args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", r.Dir}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// rev returns the commit a revision names, or "" if it names none
+func (r *Repo) rev(name string) string {
+	id, err := r.git("rev-parse", "-q", "--verify", name+"^{commit}")
+	if err != nil {
+		return ""
+	}
+	return id
+}
+
+// read returns the task list as of a commit; no commit holds no tasks
+func (r *Repo) read(commit string) ([]tasks.Task, error) {
+	if commit == "" {
+		return nil, nil
+	}
+	data, err := r.git("show", commit+":"+File)
+	if err != nil {
+		return nil, err
+	}
+	var list []tasks.Task
+	if err := json.Unmarshal([]byte(data), &list); err != nil {
+		return nil, fmt.Errorf("%s at %.7s: %v", File, commit, err)
+	}
+	return list, nil
+}
+
+func (r *Repo) write(list []tasks.Task) error {
+	if list == nil {
+		list = []tasks.Task{}
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(r.Dir, File), append(data, '\n'), 0644)
+}
+
+// Commit writes the task list to the repository and commits it with a
+// message describing what changed since the last commit. It reports
+// whether there was anything to commit.
+func (r *Repo) Commit(list []tasks.Task) (bool, error) {
+	head := r.rev("HEAD")
+	previous, err := r.read(head)
+	if err != nil {
+		return false, err
+	}
+	if err := r.write(list); err != nil {
+		return false, err
+	}
+	status, err := r.git("status", "--porcelain", "--", File)
+	if err != nil || status == "" {
+		return false, err
+	}
+
+	message := Describe(previous, list)
+	if head == "" && len(list) == 0 {
+		message = "Start syncing tasks"
+	}
+	if _, err := r.git("add", "--", File); err != nil {
+		return false, err
+	}
+	if _, err := r.git("commit", "-q", "-m", message); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Install commits the task list after every change made through the
+// manager. Changes made in one transaction are committed together.
+func (r *Repo) Install(manager *tasks.TaskManager) {
+	manager.Events().Subscribe(tasks.SubscriberFunc(func(e tasks.Event) error {
+		if e.Pre {
+			return nil
+		}
+		list, err := manager.Store().List(context.Background())
+		if err != nil {
+			return err
+		}
+		if _, err := r.Commit(list); err != nil {
+			return fmt.Errorf("git sync: %v", err)
+		}
+		return nil
+	}))
+}
+
+// Result is what Sync did
+type Result struct {
+	// Pulled is true if the remote had changes that were applied here
+	Pulled bool
+	// Pushed is true if the remote was missing changes made here
+	Pushed    bool
+	Conflicts []Conflict
+	// Added, Updated and Removed count the changes applied to the store
+	Added, Updated, Removed int
+}
+
+// pushAttempts bounds how often Sync merges again after losing a race to
+// push
+const pushAttempts = 3
+
+// Sync commits the manager's tasks, merges in the remote's changes, applies
+// the merged list to the manager and pushes it. Lists that diverged are
+// merged with Merge and committed as a merge of both histories.
+func (r *Repo) Sync(manager *tasks.TaskManager) (Result, error) {
+	var result Result
+	list, err := manager.Store().List(context.Background())
+	if err != nil {
+		return result, err
+	}
+	if _, err := r.Commit(list); err != nil {
+		return result, err
+	}
+	if r.RemoteURL() == "" {
+		return result, fmt.Errorf("no remote to sync with; run taskmgr sync init --remote=<url>")
+	}
+	branch, err := r.Branch()
+	if err != nil {
+		return result, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		if err := r.pull(manager, branch, &result); err != nil {
+			return result, err
+		}
+
+		tracking := r.rev("refs/remotes/" + Remote + "/" + branch)
+		if tracking == r.rev("HEAD") {
+			return result, nil
+		}
+		_, err = r.git("push", "-q", Remote, "HEAD:refs/heads/"+branch)
+		if err == nil {
+			result.Pushed = true
+			return result, nil
+		}
+		if attempt == pushAttempts {
+			return result, err
+		}
+	}
+}
+
+// pull fetches the remote branch and merges it into HEAD, applying the
+// result to the manager and adding what it did to result. If the manager
+// refuses the merged tasks, the repository is put back as it was.
+func (r *Repo) pull(manager *tasks.TaskManager, branch string, result *Result) error {
+	if _, err := r.git("fetch", "-q", Remote); err != nil {
+		return err
+	}
+	theirs := r.rev("refs/remotes/" + Remote + "/" + branch)
+	ours := r.rev("HEAD")
+	if theirs == "" || theirs == ours {
+		// Nothing pushed yet, or nothing new
+		return nil
+	}
+	base, _ := r.git("merge-base", ours, theirs)
+	if base == theirs {
+		return nil
+	}
+
+	var conflicts []Conflict
+	if base == ours {
+		if _, err := r.git("merge", "-q", "--ff-only", theirs); err != nil {
+			return err
+		}
+	} else {
+		baseList, err := r.read(base)
+		if err != nil {
+			return err
+		}
+		oursList, err := r.read(ours)
+		if err != nil {
+			return err
+		}
+		theirsList, err := r.read(theirs)
+		if err != nil {
+			return err
+		}
+		var merged []tasks.Task
+		merged, conflicts = Merge(baseList, oursList, theirsList)
+
+		// Record both histories, with the merged list as the content
+		if _, err := r.git("merge", "-q", "--no-commit", "-s", "ours", "--allow-unrelated-histories", theirs); err != nil {
+			return err
+		}
+		if err := r.write(merged); err != nil {
+			r.git("merge", "--abort")
+			return err
+		}
+		message := fmt.Sprintf("Merge tasks from %s/%s", Remote, branch)
+		if len(conflicts) > 0 {
+			var lines []string
+			for _, c := range conflicts {
+				lines = append(lines, "- "+c.String())
+			}
+			message += fmt.Sprintf("\n\nResolved %d conflicts:\n%s", len(conflicts), strings.Join(lines, "\n"))
+		}
+		if _, err := r.git("add", "--", File); err != nil {
+			r.git("merge", "--abort")
+			return err
+		}
+		if _, err := r.git("commit", "-q", "-m", message); err != nil {
+			r.git("merge", "--abort")
+			return err
+		}
+	}
+
+	merged, err := r.read("HEAD")
+	if err != nil {
+		return err
+	}
+	added, updated, removed, err := manager.Replace(merged)
+	if err != nil {
+		r.git("reset", "-q", "--hard", ours)
+		return fmt.Errorf("applying merged tasks: %v", err)
+	}
+	result.Added += added
+	result.Updated += updated
+	result.Removed += removed
+	result.Pulled = true
+	result.Conflicts = append(result.Conflicts, conflicts...)
+	return nil
+}
+
+// Describe summarizes the difference between two task lists as a commit
+// message: one line for a single change, or a count followed by a line
+// per task.
+func Describe(old, new []tasks.Task) string {
+	before := byID(old)
+	after := byID(new)
+	var lines []string
+	for _, t := range new {
+		prev, ok := before[t.ID]
+		switch {
+		case !ok:
+			lines = append(lines, fmt.Sprintf("Add %q", t.Title))
+		case t.Done && !prev.Done:
+			lines = append(lines, fmt.Sprintf("Complete %q", t.Title))
+		case !t.Done && prev.Done:
+			lines = append(lines, fmt.Sprintf("Reopen %q", t.Title))
+		default:
+			if changed := changedFields(prev, t); len(changed) > 0 {
+				lines = append(lines, fmt.Sprintf("Update %q: %s", t.Title, strings.Join(changed, ", ")))
+			}
+		}
+	}
+	for _, t := range old {
+		if _, ok := after[t.ID]; !ok {
+			lines = append(lines, fmt.Sprintf("Remove %q", t.Title))
+		}
+	}
+
+	switch len(lines) {
+	case 0:
+		return "Update tasks"
+	case 1:
+		return lines[0]
+	default:
+		return fmt.Sprintf("Change %d tasks\n\n- %s", len(lines), strings.Join(lines, "\n- "))
+	}
+}
+
+func changedFields(old, new tasks.Task) []string {
+	var changed []string
+	for _, f := range fields {
+		if f.key(old) != f.key(new) {
+			changed = append(changed, f.name)
+		}
+	}
+	if strings.Join(old.Tags, ",") != strings.Join(new.Tags, ",") {
+		changed = append(changed, "tags")
+	}
+	return changed
+}
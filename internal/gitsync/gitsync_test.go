@@ -0,0 +1,186 @@
+package gitsync
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"taskmgr/internal/tasks"
+)
+
+// replica is one copy of the task list syncing through a shared remote
+type replica struct {
+	t       *testing.T
+	repo    *Repo
+	manager *tasks.TaskManager
+}
+
+func newReplica(t *testing.T, remote string) *replica {
+	t.Helper()
+	repo, err := Init(filepath.Join(t.TempDir(), "sync"), "", remote)
+	if err != nil {
+		t.Fatalf("Init returned an error: %v", err)
+	}
+	manager := tasks.NewTaskManager(tasks.NewMemoryStore())
+	repo.Install(manager)
+	return &replica{t: t, repo: repo, manager: manager}
+}
+
+func (r *replica) sync() Result {
+	r.t.Helper()
+	result, err := r.repo.Sync(r.manager)
+	if err != nil {
+		r.t.Fatalf("Sync returned an error: %v", err)
+	}
+	return result
+}
+
+func (r *replica) get(id string) tasks.Task {
+	r.t.Helper()
+	task, err := r.manager.Get(id)
+	if err != nil {
+		r.t.Fatalf("Get(%s) returned an error: %v", id, err)
+	}
+	return task
+}
+
+func (r *replica) log() []string {
+	r.t.Helper()
+	out, err := r.repo.git("log", "--format=%s")
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return strings.Split(out, "\n")
+}
+
+func bareRemote(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	if out, err := exec.Command("git", "init", "-q", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("git init --bare: %v: %s", err, out)
+	}
+	return remote
+}
+
+func TestOpenWithoutRepository(t *testing.T) {
+	if _, err := Open(t.TempDir()); !errors.Is(err, ErrNotInitialized) {
+		t.Errorf("Expected ErrNotInitialized, got %v", err)
+	}
+}
+
+func TestCommitEachChange(t *testing.T) {
+	r := newReplica(t, bareRemote(t))
+	r.manager.Add(tasks.Task{ID: "report", Title: "Write report"})
+	r.manager.Add(tasks.Task{ID: "milk", Title: "Buy milk"})
+	r.manager.MarkDone("report")
+	r.manager.AddTagToTask("milk", "errand")
+	r.manager.MarkAllDone()
+
+	want := []string{
+		`Complete "Buy milk"`,
+		`Update "Buy milk": tags`,
+		`Complete "Write report"`,
+		`Add "Buy milk"`,
+		`Add "Write report"`,
+	}
+	if got := r.log(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected commits %q, got %q", want, got)
+	}
+}
+
+func TestSync(t *testing.T) {
+	remote := bareRemote(t)
+	a := newReplica(t, remote)
+	b := newReplica(t, remote)
+
+	if _, err := (&Repo{Dir: t.TempDir()}).Sync(a.manager); err == nil {
+		t.Error("Expected an error syncing without a repository")
+	}
+
+	a.manager.Add(tasks.Task{ID: "report", Title: "Write report", Tags: []string{"work"}})
+	a.manager.Add(tasks.Task{ID: "milk", Title: "Buy milk"})
+	if result := a.sync(); !result.Pushed || result.Pulled {
+		t.Errorf("Expected the first sync to push only, got %+v", result)
+	}
+
+	// B starts with a history of its own, which is merged with A's
+	if result := b.sync(); !result.Pulled || result.Added != 2 {
+		t.Errorf("Expected B to pull both tasks, got %+v", result)
+	}
+
+	// Both change the same task in different ways
+	report := a.get("report")
+	report.Title = "Write the report"
+	a.manager.Update("report", report)
+	a.manager.AddTagToTask("report", "urgent")
+	a.sync()
+
+	report = b.get("report")
+	report.Priority = tasks.High
+	b.manager.Update("report", report)
+	b.manager.RemoveTagFromTask("report", "work")
+	b.manager.Remove("milk")
+	result := b.sync()
+	if !result.Pulled || !result.Pushed || len(result.Conflicts) != 0 {
+		t.Errorf("Expected a clean merge, got %+v", result)
+	}
+	a.sync()
+
+	for _, r := range []*replica{a, b} {
+		list := r.manager.List()
+		if len(list) != 1 {
+			t.Fatalf("Expected one task left, got %v", list)
+		}
+		got := list[0]
+		if got.Title != "Write the report" || got.Priority != tasks.High || strings.Join(got.Tags, ",") != "urgent" {
+			t.Errorf("Expected every change merged, got %+v", got)
+		}
+	}
+	if log := b.log(); log[0] != "Merge tasks from origin/main" {
+		t.Errorf("Expected a merge commit, got %q", log[0])
+	}
+
+	// The same field changed on both sides goes to the later change
+	report = a.get("report")
+	report.Title = "A's title"
+	a.manager.Update("report", report)
+	report.Title = "B's title"
+	b.manager.Update("report", report)
+	a.sync()
+	result = b.sync()
+	if len(result.Conflicts) != 1 || result.Conflicts[0].Field != "title" {
+		t.Errorf("Expected a title conflict, got %+v", result.Conflicts)
+	}
+	a.sync()
+	if a.get("report").Title != "B's title" || b.get("report").Title != "B's title" {
+		t.Errorf("Expected B's later title on both sides, got %q and %q", a.get("report").Title, b.get("report").Title)
+	}
+}
+
+func TestSyncVetoLeavesRepository(t *testing.T) {
+	remote := bareRemote(t)
+	a := newReplica(t, remote)
+	b := newReplica(t, remote)
+	a.manager.Add(tasks.Task{ID: "report", Title: "Write report"})
+	a.sync()
+
+	b.manager.Events().Subscribe(tasks.SubscriberFunc(func(e tasks.Event) error {
+		if e.Pre && e.Type == tasks.EventCreated {
+			return errors.New("no new tasks")
+		}
+		return nil
+	}))
+	b.repo.Commit(nil)
+	head := b.repo.rev("HEAD")
+	if _, err := b.repo.Sync(b.manager); err == nil {
+		t.Fatal("Expected the veto to stop the sync")
+	}
+	if b.repo.rev("HEAD") != head {
+		t.Error("Expected the repository to be put back")
+	}
+}
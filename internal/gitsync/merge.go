@@ -0,0 +1,202 @@
+package gitsync
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"taskmgr/internal/tasks"
+)
+
+// Conflict is a field both sides changed differently since the merge
+// base. Merge resolves it by keeping the side modified last.
+type Conflict struct {
+	TaskID string
+	Title  string
+	Field  string
+	// Kept is "ours" or "theirs"
+	Kept string
+}
+
+func (c Conflict) String() string {
+	if c.Field == "removed" {
+		return fmt.Sprintf("%q was removed on one side and changed on the other; kept the changed task", c.Title)
+	}
+	return fmt.Sprintf("%q: both sides changed %s; kept %s, modified last", c.Title, c.Field, c.Kept)
+}
+
+// Merge combines two task lists that diverged from base, task by task and
+// field by field. A field changed on one side takes that side's value;
+// tags merge as sets, so tags added or removed on either side are kept
+// added or removed. When both sides changed a field differently, the side
+// whose task was modified last wins and a Conflict is reported. A task
+// removed on one side stays removed unless the other side changed it.
+//
+// The result keeps our order, with tasks only they have appended in their
+// order.
+func Merge(base, ours, theirs []tasks.Task) ([]tasks.Task, []Conflict) {
+	baseByID := byID(base)
+	theirsByID := byID(theirs)
+	oursByID := byID(ours)
+
+	var merged []tasks.Task
+	var conflicts []Conflict
+	keep := func(t tasks.Task, cs []Conflict) {
+		merged = append(merged, t)
+		conflicts = append(conflicts, cs...)
+	}
+
+	for _, o := range ours {
+		b, inBase := baseByID[o.ID]
+		t, inTheirs := theirsByID[o.ID]
+		switch {
+		case inTheirs && inBase:
+			keep(mergeTask(b, o, t))
+		case inTheirs:
+			// Added on both sides, such as by importing the same file
+			keep(mergeTask(tasks.Task{ID: o.ID}, o, t))
+		case inBase && sameTask(b, o):
+			// They removed it and we did not change it
+		case inBase:
+			keep(o, []Conflict{{TaskID: o.ID, Title: o.Title, Field: "removed", Kept: "ours"}})
+		default:
+			keep(o, nil)
+		}
+	}
+	for _, t := range theirs {
+		if _, ok := oursByID[t.ID]; ok {
+			continue
+		}
+		b, inBase := baseByID[t.ID]
+		switch {
+		case inBase && sameTask(b, t):
+			// We removed it and they did not change it
+		case inBase:
+			keep(t, []Conflict{{TaskID: t.ID, Title: t.Title, Field: "removed", Kept: "theirs"}})
+		default:
+			keep(t, nil)
+		}
+	}
+	return merged, conflicts
+}
+
+func byID(list []tasks.Task) map[string]tasks.Task {
+	m := make(map[string]tasks.Task, len(list))
+	for _, t := range list {
+		m[t.ID] = t
+	}
+	return m
+}
+
+// field reads and writes one mergeable part of a task. Done and
+// CompletedAt move together, as do a task's extra attributes.
+type field struct {
+	name string
+	key  func(tasks.Task) string
+	set  func(dst *tasks.Task, src tasks.Task)
+}
+
+var fields = []field{
+	{"title", func(t tasks.Task) string { return t.Title }, func(d *tasks.Task, s tasks.Task) { d.Title = s.Title }},
+	{"description", func(t tasks.Task) string { return t.Description }, func(d *tasks.Task, s tasks.Task) { d.Description = s.Description }},
+	{"notes", func(t tasks.Task) string { return t.Notes }, func(d *tasks.Task, s tasks.Task) { d.Notes = s.Notes }},
+	{"done", func(t tasks.Task) string { return fmt.Sprint(t.Done, timeKey(t.CompletedAt)) }, func(d *tasks.Task, s tasks.Task) {
+		d.Done, d.CompletedAt = s.Done, s.CompletedAt
+	}},
+	{"priority", func(t tasks.Task) string { return t.Priority.String() }, func(d *tasks.Task, s tasks.Task) { d.Priority = s.Priority }},
+	{"due", func(t tasks.Task) string { return timeKey(t.DueDate) }, func(d *tasks.Task, s tasks.Task) { d.DueDate = s.DueDate }},
+	{"project", func(t tasks.Task) string { return t.Project }, func(d *tasks.Task, s tasks.Task) { d.Project = s.Project }},
+	{"parent", func(t tasks.Task) string { return t.ParentID }, func(d *tasks.Task, s tasks.Task) { d.ParentID = s.ParentID }},
+	{"deleted", func(t tasks.Task) string { return timeKey(t.DeletedAt) }, func(d *tasks.Task, s tasks.Task) { d.DeletedAt = s.DeletedAt }},
+	{"unarchived", func(t tasks.Task) string { return timeKey(t.UnarchivedAt) }, func(d *tasks.Task, s tasks.Task) { d.UnarchivedAt = s.UnarchivedAt }},
+	{"extra", func(t tasks.Task) string { return extraKey(t.Extra) }, func(d *tasks.Task, s tasks.Task) { d.Extra = s.Extra }},
+}
+
+func timeKey(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func extraKey(extra map[string]json.RawMessage) string {
+	if len(extra) == 0 {
+		return ""
+	}
+	// Marshalling sorts the keys
+	data, _ := json.Marshal(extra)
+	return string(data)
+}
+
+func sameTask(a, b tasks.Task) bool {
+	for _, f := range fields {
+		if f.key(a) != f.key(b) {
+			return false
+		}
+	}
+	return strings.Join(a.Tags, ",") == strings.Join(b.Tags, ",")
+}
+
+// mergeTask merges one task changed on both sides
+func mergeTask(base, ours, theirs tasks.Task) (tasks.Task, []Conflict) {
+	merged := ours
+	// Ties go to ours, so merging is the same on every run
+	theirsNewer := theirs.ModifiedAt.After(ours.ModifiedAt)
+	kept := "ours"
+	if theirsNewer {
+		kept = "theirs"
+	}
+
+	var conflicts []Conflict
+	for _, f := range fields {
+		b, o, t := f.key(base), f.key(ours), f.key(theirs)
+		switch {
+		case o == t || t == b:
+			// Nothing to take from theirs
+		case o == b:
+			f.set(&merged, theirs)
+		default:
+			if theirsNewer {
+				f.set(&merged, theirs)
+			}
+			conflicts = append(conflicts, Conflict{TaskID: ours.ID, Title: merged.Title, Field: f.name, Kept: kept})
+		}
+	}
+	merged.Tags = mergeTags(base.Tags, ours.Tags, theirs.Tags)
+
+	if theirs.ModifiedAt.After(merged.ModifiedAt) {
+		merged.ModifiedAt = theirs.ModifiedAt
+	}
+	if !theirs.CreatedAt.IsZero() && (merged.CreatedAt.IsZero() || theirs.CreatedAt.Before(merged.CreatedAt)) {
+		merged.CreatedAt = theirs.CreatedAt
+	}
+	for i := range conflicts {
+		conflicts[i].Title = merged.Title
+	}
+	return merged, conflicts
+}
+
+// mergeTags keeps a tag both sides have, or one side added since base
+func mergeTags(base, ours, theirs []string) []string {
+	in := func(list []string, tag string) bool {
+		for _, t := range list {
+			if strings.EqualFold(t, tag) {
+				return true
+			}
+		}
+		return false
+	}
+	var merged []string
+	for _, tag := range ours {
+		if in(theirs, tag) || !in(base, tag) {
+			merged = append(merged, tag)
+		}
+	}
+	for _, tag := range theirs {
+		if !in(ours, tag) && !in(base, tag) {
+			merged = append(merged, tag)
+		}
+	}
+	return merged
+}
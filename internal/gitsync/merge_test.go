@@ -0,0 +1,118 @@
+package gitsync
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"taskmgr/internal/tasks"
+)
+
+func TestMerge(t *testing.T) {
+	earlier := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	later := earlier.Add(time.Hour)
+	task := func(id, title string, tags ...string) tasks.Task {
+		return tasks.Task{ID: id, Title: title, Tags: tags, ModifiedAt: earlier}
+	}
+	renamed := func(t tasks.Task, title string, at time.Time) tasks.Task {
+		t.Title, t.ModifiedAt = title, at
+		return t
+	}
+
+	a, b := task("a", "Write report", "work"), task("b", "Buy milk")
+	base := []tasks.Task{a, b}
+
+	t.Run("DifferentFields", func(t *testing.T) {
+		ours := renamed(a, "Write the report", later)
+		theirs := a
+		theirs.Priority = tasks.High
+		merged, conflicts := Merge(base, []tasks.Task{ours, b}, []tasks.Task{theirs, b})
+		if len(conflicts) != 0 {
+			t.Errorf("Expected no conflicts, got %v", conflicts)
+		}
+		if got := merged[0]; got.Title != "Write the report" || got.Priority != tasks.High {
+			t.Errorf("Expected both changes, got %+v", got)
+		}
+	})
+
+	t.Run("SameField", func(t *testing.T) {
+		ours := renamed(a, "Ours", earlier.Add(time.Minute))
+		theirs := renamed(a, "Theirs", later)
+		merged, conflicts := Merge(base, []tasks.Task{ours, b}, []tasks.Task{theirs, b})
+		if merged[0].Title != "Theirs" || !merged[0].ModifiedAt.Equal(later) {
+			t.Errorf("Expected the later change to win, got %+v", merged[0])
+		}
+		if len(conflicts) != 1 || conflicts[0].Field != "title" || conflicts[0].Kept != "theirs" {
+			t.Errorf("Expected a title conflict, got %v", conflicts)
+		}
+
+		// Merging the other way round keeps the same title
+		merged, _ = Merge(base, []tasks.Task{theirs, b}, []tasks.Task{ours, b})
+		if merged[0].Title != "Theirs" {
+			t.Errorf("Expected the later change to win either way, got %q", merged[0].Title)
+		}
+	})
+
+	t.Run("Tags", func(t *testing.T) {
+		ours := a
+		ours.Tags = []string{"work", "urgent"}
+		theirs := a
+		theirs.Tags = []string{"home"}
+		merged, conflicts := Merge(base, []tasks.Task{ours, b}, []tasks.Task{theirs, b})
+		if got := fmt.Sprint(merged[0].Tags); got != "[urgent home]" {
+			t.Errorf("Expected work removed and both additions kept, got %s", got)
+		}
+		if len(conflicts) != 0 {
+			t.Errorf("Expected tags to merge without conflicts, got %v", conflicts)
+		}
+	})
+
+	t.Run("AddsAndRemoves", func(t *testing.T) {
+		c, d := task("c", "Ours only"), task("d", "Theirs only")
+		merged, conflicts := Merge(base, []tasks.Task{a, c}, []tasks.Task{b, d})
+		var ids string
+		for _, t := range merged {
+			ids += t.ID
+		}
+		if ids != "cd" || len(conflicts) != 0 {
+			t.Errorf("Expected each side's removal and addition, got %q and %v", ids, conflicts)
+		}
+	})
+
+	t.Run("RemovedAndChanged", func(t *testing.T) {
+		theirs := renamed(b, "Buy oat milk", later)
+		merged, conflicts := Merge(base, []tasks.Task{a}, []tasks.Task{a, theirs})
+		if len(merged) != 2 || merged[1].Title != "Buy oat milk" {
+			t.Errorf("Expected the changed task to be kept, got %v", merged)
+		}
+		if len(conflicts) != 1 || conflicts[0].Field != "removed" {
+			t.Errorf("Expected a removal conflict, got %v", conflicts)
+		}
+	})
+}
+
+func TestDescribe(t *testing.T) {
+	a := tasks.Task{ID: "a", Title: "Write report"}
+	done := a
+	done.Done = true
+	tagged := a
+	tagged.Tags = []string{"work"}
+	tagged.Priority = tasks.High
+	b := tasks.Task{ID: "b", Title: "Buy milk"}
+
+	for _, tc := range []struct {
+		old, new []tasks.Task
+		want     string
+	}{
+		{nil, []tasks.Task{a}, `Add "Write report"`},
+		{[]tasks.Task{a}, []tasks.Task{done}, `Complete "Write report"`},
+		{[]tasks.Task{done}, []tasks.Task{a}, `Reopen "Write report"`},
+		{[]tasks.Task{a}, []tasks.Task{tagged}, `Update "Write report": priority, tags`},
+		{[]tasks.Task{a, b}, []tasks.Task{a}, `Remove "Buy milk"`},
+		{[]tasks.Task{a, b}, []tasks.Task{done}, "Change 2 tasks\n\n- Complete \"Write report\"\n- Remove \"Buy milk\""},
+	} {
+		if got := Describe(tc.old, tc.new); got != tc.want {
+			t.Errorf("Expected %q, got %q", tc.want, got)
+		}
+	}
+}
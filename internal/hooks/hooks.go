@@ -0,0 +1,89 @@
+// Package hooks notifies other systems when tasks change. It subscribes
+// two kinds of subscriber to a TaskManager's events, both configured in a
+// directory, .taskmgr by default:
+//
+//	hooks/pre-completed       executable scripts named after the phase
+//	hooks/post-created.notify and event, run in name order
+//	webhooks.json             HTTP endpoints to POST post-events to
+//	outbox.jsonl              webhook deliveries waiting to be retried
+//
+// Scripts receive the event as JSON on standard input. A pre-event script
+// that exits with a non-zero status vetoes the change, and the first line
+// of its output is given as the reason.
+package hooks
+
+import (
+	"path/filepath"
+	"time"
+
+	"taskmgr/internal/api"
+	"taskmgr/internal/tasks"
+)
+
+// DefaultDir is where hooks are configured, next to tasks.json
+const DefaultDir = ".taskmgr"
+
+// Payload is the JSON given to scripts and posted to webhooks
+type Payload struct {
+	// ID identifies the event, so that receivers can ignore a webhook
+	// delivered twice
+	ID      string       `json:"id"`
+	Event   string       `json:"event"`
+	Phase   string       `json:"phase"`
+	Task    api.Task     `json:"task"`
+	Old     *api.Task    `json:"old,omitempty"`
+	Changes []api.Change `json:"changes,omitempty"`
+	Time    time.Time    `json:"time"`
+	Actor   string       `json:"actor"`
+}
+
+// NewPayload describes an event
+func NewPayload(e tasks.Event) Payload {
+	p := Payload{
+		ID:    tasks.NewID(),
+		Event: string(e.Type),
+		Phase: e.Phase(),
+		Task:  api.FromTask(e.Task),
+		Time:  e.Time,
+		Actor: e.Actor,
+	}
+	if e.Old != nil {
+		old := api.FromTask(*e.Old)
+		p.Old = &old
+	}
+	if len(e.Changes) > 0 {
+		p.Changes = api.FromChanges(e.Changes)
+	}
+	return p
+}
+
+// Config is everything configured in a hooks directory
+type Config struct {
+	Scripts  []Script
+	Webhooks *Webhooks
+}
+
+// Load reads the configuration in dir. A missing directory configures
+// nothing.
+func Load(dir string) (*Config, error) {
+	scripts, err := FindScripts(filepath.Join(dir, "hooks"))
+	if err != nil {
+		return nil, err
+	}
+	webhooks, err := LoadWebhooks(filepath.Join(dir, "webhooks.json"), filepath.Join(dir, "outbox.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	return &Config{Scripts: scripts, Webhooks: webhooks}, nil
+}
+
+// Install subscribes the configured scripts and webhooks to the manager's
+// events
+func (c *Config) Install(manager *tasks.TaskManager) {
+	for _, s := range c.Scripts {
+		manager.Events().Subscribe(s)
+	}
+	if len(c.Webhooks.Hooks) > 0 {
+		manager.Events().Subscribe(c.Webhooks)
+	}
+}
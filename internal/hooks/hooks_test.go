@@ -0,0 +1,63 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"taskmgr/internal/tasks"
+)
+
+func TestNewPayload(t *testing.T) {
+	at := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)
+	old := tasks.Task{ID: "a1", Title: "Old"}
+	p := NewPayload(tasks.Event{
+		Type:    tasks.EventUpdated,
+		Pre:     true,
+		Task:    tasks.Task{ID: "a1", Title: "New"},
+		Old:     &old,
+		Changes: []tasks.Change{{Field: "title", Old: "Old", New: "New"}},
+		Time:    at,
+		Actor:   "sam",
+	})
+	if p.ID == "" || p.Event != "updated" || p.Phase != "pre" || p.Actor != "sam" || !p.Time.Equal(at) {
+		t.Errorf("Unexpected payload %+v", p)
+	}
+	if p.Task.Title != "New" || p.Old == nil || p.Old.Title != "Old" {
+		t.Errorf("Expected the new and old task, got %+v and %+v", p.Task, p.Old)
+	}
+	if len(p.Changes) != 1 || p.Changes[0].Field != "title" {
+		t.Errorf("Expected the title change, got %+v", p.Changes)
+	}
+
+	if p := NewPayload(tasks.Event{Type: tasks.EventCreated}); p.Old != nil || p.Changes != nil {
+		t.Errorf("Expected no old task or changes for a creation, got %+v", p)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	config, err := Load(filepath.Join(dir, "missing"))
+	if err != nil || len(config.Scripts) != 0 || len(config.Webhooks.Hooks) != 0 {
+		t.Errorf("Expected an empty configuration, got %+v, %v", config, err)
+	}
+
+	writeScript(t, filepath.Join(dir, "hooks"), "post-removed", "true", 0755)
+	os.WriteFile(filepath.Join(dir, "webhooks.json"), []byte(`{"webhooks": [{"url": "http://localhost:1/hook"}]}`), 0644)
+	config, err = Load(dir)
+	if err != nil {
+		t.Fatalf("Load returned an error: %v", err)
+	}
+	if len(config.Scripts) != 1 || len(config.Webhooks.Hooks) != 1 {
+		t.Errorf("Expected a script and a webhook, got %+v", config)
+	}
+	if config.Webhooks.Outbox != filepath.Join(dir, "outbox.jsonl") {
+		t.Errorf("Unexpected outbox %s", config.Webhooks.Outbox)
+	}
+
+	os.WriteFile(filepath.Join(dir, "webhooks.json"), []byte(`not json`), 0644)
+	if _, err := Load(dir); err == nil {
+		t.Error("Expected an error for an invalid webhooks.json")
+	}
+}
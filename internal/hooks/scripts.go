@@ -0,0 +1,117 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"taskmgr/internal/tasks"
+)
+
+// ScriptTimeout bounds how long a hook script may run
+const ScriptTimeout = 30 * time.Second
+
+// Script is an executable run for one phase of one event type
+type Script struct {
+	Path  string
+	Pre   bool
+	Event tasks.EventType
+}
+
+// Name is the hook the script implements, such as "pre-completed"
+func (s Script) Name() string {
+	phase := "post"
+	if s.Pre {
+		phase = "pre"
+	}
+	return phase + "-" + string(s.Event)
+}
+
+// FindScripts returns the hook scripts in dir in the order they run.
+// Scripts are named after the hook they implement, optionally followed by
+// a dot and anything else, such as post-created.slack. Files that are not
+// executable, such as examples, are skipped.
+func FindScripts(dir string) ([]Script, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var scripts []Script
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.Mode()&0111 == 0 {
+			continue
+		}
+		hook, _, _ := strings.Cut(entry.Name(), ".")
+		for _, s := range allHooks() {
+			if s.Name() == hook {
+				s.Path = filepath.Join(dir, entry.Name())
+				scripts = append(scripts, s)
+			}
+		}
+	}
+	sort.Slice(scripts, func(i, j int) bool {
+		return filepath.Base(scripts[i].Path) < filepath.Base(scripts[j].Path)
+	})
+	return scripts, nil
+}
+
+// allHooks returns a script template for every phase of every event
+func allHooks() []Script {
+	var hooks []Script
+	for _, event := range tasks.EventTypes {
+		hooks = append(hooks, Script{Pre: true, Event: event}, Script{Event: event})
+	}
+	return hooks
+}
+
+// HandleEvent implements tasks.Subscriber, running the script for events
+// of its phase and type
+func (s Script) HandleEvent(e tasks.Event) error {
+	if e.Pre != s.Pre || e.Type != s.Event {
+		return nil
+	}
+	input, err := json.Marshal(NewPayload(e))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ScriptTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, s.Path)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(os.Environ(), "TASKMGR_HOOK="+s.Name(), "TASKMGR_TASK_ID="+e.Task.ID)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("hook %s: timed out after %v", filepath.Base(s.Path), ScriptTimeout)
+		}
+		if reason := firstLine(output.String()); reason != "" {
+			return fmt.Errorf("hook %s: %s", filepath.Base(s.Path), reason)
+		}
+		return fmt.Errorf("hook %s: %v", filepath.Base(s.Path), err)
+	}
+	return nil
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,105 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"taskmgr/internal/tasks"
+)
+
+// writeScript creates a shell script in dir
+func writeScript(t *testing.T, dir, name, body string, mode os.FileMode) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("hook scripts need a POSIX shell")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+body+"\n"), mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindScripts(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "hooks")
+	if scripts, err := FindScripts(dir); err != nil || scripts != nil {
+		t.Errorf("Expected no scripts for a missing directory, got %v, %v", scripts, err)
+	}
+
+	writeScript(t, dir, "post-created.b", "true", 0755)
+	writeScript(t, dir, "post-created.a", "true", 0755)
+	writeScript(t, dir, "pre-completed", "true", 0755)
+	writeScript(t, dir, "pre-completed.sample", "true", 0644)
+	writeScript(t, dir, "post-exploded", "true", 0755)
+
+	scripts, err := FindScripts(dir)
+	if err != nil {
+		t.Fatalf("FindScripts returned an error: %v", err)
+	}
+	var names []string
+	for _, s := range scripts {
+		names = append(names, s.Name()+"="+filepath.Base(s.Path))
+	}
+	want := "post-created=post-created.a post-created=post-created.b pre-completed=pre-completed"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestScriptReceivesEvent(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "seen.json")
+	writeScript(t, dir, "post-created", `cat > "`+out+`"; echo "$TASKMGR_HOOK $TASKMGR_TASK_ID" >> "`+out+`"`, 0755)
+
+	s := Script{Path: filepath.Join(dir, "post-created"), Event: tasks.EventCreated}
+	// Other events and phases are not the script's business
+	if err := s.HandleEvent(tasks.Event{Type: tasks.EventCreated, Pre: true}); err != nil {
+		t.Fatalf("HandleEvent returned an error: %v", err)
+	}
+	if _, err := os.Stat(out); err == nil {
+		t.Fatal("Expected the script not to run for a pre-event")
+	}
+
+	err := s.HandleEvent(tasks.Event{Type: tasks.EventCreated, Task: tasks.Task{ID: "abc123", Title: "Hooked"}, Actor: "sam"})
+	if err != nil {
+		t.Fatalf("HandleEvent returned an error: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("The script did not run: %v", err)
+	}
+	for _, want := range []string{`"title":"Hooked"`, `"event":"created"`, `"phase":"post"`, `"actor":"sam"`, "post-created abc123"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected script input to contain %s, got %s", want, data)
+		}
+	}
+}
+
+func TestPreScriptVetoes(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "hooks")
+	writeScript(t, dir, "pre-completed", `grep -q '"urgent"' && { echo "urgent tasks need a review first"; exit 1; }; exit 0`, 0755)
+	scripts, err := FindScripts(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manager := tasks.NewTaskManager(tasks.NewFileStore(filepath.Join(t.TempDir(), "tasks.json")))
+	(&Config{Scripts: scripts, Webhooks: &Webhooks{}}).Install(manager)
+	manager.Add(tasks.Task{Title: "Routine"})
+	manager.Add(tasks.Task{Title: "Risky", Tags: []string{"urgent"}})
+
+	if err := manager.MarkDone("0"); err != nil {
+		t.Errorf("Expected the routine task to complete, got %v", err)
+	}
+	err = manager.MarkDone("1")
+	if err == nil || !strings.Contains(err.Error(), "urgent tasks need a review first") {
+		t.Errorf("Expected the hook's veto, got %v", err)
+	}
+	if manager.List()[1].Done {
+		t.Error("Expected the vetoed task to stay open")
+	}
+}
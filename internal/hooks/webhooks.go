@@ -0,0 +1,316 @@
+package hooks
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"taskmgr/internal/tasks"
+)
+
+// Webhook is an endpoint configured in webhooks.json:
+//
+//	{"webhooks": [{"url": "https://example.com/hook", "secret": "s3cret",
+//	  "events": ["completed"], "attempts": 3}]}
+//
+// Events limits the events posted, all by default. With a secret, each
+// request is signed: X-Taskmgr-Signature holds "sha256=" and the hex
+// HMAC-SHA256 of the body.
+type Webhook struct {
+	URL      string   `json:"url"`
+	Secret   string   `json:"secret,omitempty"`
+	Events   []string `json:"events,omitempty"`
+	Attempts int      `json:"attempts,omitempty"`
+}
+
+// DefaultAttempts is how often a delivery is tried before it is queued
+const DefaultAttempts = 3
+
+func (h Webhook) wants(event string) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, e := range h.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// OutboxEntry is a delivery that failed and waits to be retried
+type OutboxEntry struct {
+	URL       string          `json:"url"`
+	Event     string          `json:"event"`
+	Body      json.RawMessage `json:"body"`
+	Attempts  int             `json:"attempts"`
+	LastError string          `json:"last_error"`
+	Queued    time.Time       `json:"queued"`
+}
+
+// Webhooks posts post-events to every configured webhook. A delivery is
+// retried with growing delays, and if it still fails it is queued in the
+// outbox. Queued deliveries are retried before each new event, and events
+// for an endpoint that still has queued deliveries join the queue, so each
+// endpoint receives events in order.
+type Webhooks struct {
+	Hooks  []Webhook
+	Outbox string
+	Client *http.Client
+	// RetryDelay is the wait before the second attempt, doubling after
+	RetryDelay time.Duration
+}
+
+// LoadWebhooks reads webhooks.json. A missing file configures no
+// webhooks.
+func LoadWebhooks(configFile, outboxFile string) (*Webhooks, error) {
+	w := &Webhooks{
+		Outbox:     outboxFile,
+		Client:     &http.Client{Timeout: 10 * time.Second},
+		RetryDelay: time.Second,
+	}
+	data, err := os.ReadFile(configFile)
+	if os.IsNotExist(err) {
+		return w, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var config struct {
+		Webhooks []Webhook `json:"webhooks"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("%s: %v", configFile, err)
+	}
+	for i, h := range config.Webhooks {
+		u, err := url.Parse(h.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("%s: webhook %d: invalid url %q", configFile, i+1, h.URL)
+		}
+		for _, event := range h.Events {
+			if !knownEvent(event) {
+				return nil, fmt.Errorf("%s: webhook %d: unknown event %q", configFile, i+1, event)
+			}
+		}
+		if h.Attempts <= 0 {
+			config.Webhooks[i].Attempts = DefaultAttempts
+		}
+	}
+	w.Hooks = config.Webhooks
+	return w, nil
+}
+
+func knownEvent(name string) bool {
+	for _, e := range tasks.EventTypes {
+		if string(e) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign returns the signature header value for a body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// HandleEvent implements tasks.Subscriber. Webhooks cannot veto changes,
+// so pre-events are ignored.
+func (w *Webhooks) HandleEvent(e tasks.Event) error {
+	if e.Pre {
+		return nil
+	}
+	_, pending, err := w.Retry()
+	if err != nil {
+		return err
+	}
+	waiting := make(map[string]int)
+	for _, entry := range pending {
+		waiting[entry.URL]++
+	}
+
+	body, err := json.Marshal(NewPayload(e))
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, h := range w.Hooks {
+		if !h.wants(string(e.Type)) {
+			continue
+		}
+		entry := OutboxEntry{URL: h.URL, Event: string(e.Type), Body: body, Queued: time.Now()}
+		if n := waiting[h.URL]; n > 0 {
+			entry.LastError = "waiting for earlier deliveries"
+			errs = append(errs, fmt.Errorf("webhook %s: queued behind %d earlier deliveries", h.URL, n))
+		} else if err := w.deliver(h, &entry); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %v; queued for retry", h.URL, err))
+		} else {
+			continue
+		}
+		if err := appendOutbox(w.Outbox, entry); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: queueing delivery: %v", h.URL, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deliver posts an entry, trying as often as the webhook allows
+func (w *Webhooks) deliver(h Webhook, entry *OutboxEntry) error {
+	attempts := h.Attempts
+	if attempts <= 0 {
+		attempts = DefaultAttempts
+	}
+	delay := w.RetryDelay
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		if err = w.send(h, entry); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// send makes one attempt at a delivery
+func (w *Webhooks) send(h Webhook, entry *OutboxEntry) error {
+	entry.Attempts++
+	req, err := http.NewRequest("POST", h.URL, bytes.NewReader(entry.Body))
+	if err != nil {
+		entry.LastError = err.Error()
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "taskmgr-webhook")
+	req.Header.Set("X-Taskmgr-Event", entry.Event)
+	if h.Secret != "" {
+		req.Header.Set("X-Taskmgr-Signature", Sign(h.Secret, entry.Body))
+	}
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		entry.LastError = err.Error()
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err = fmt.Errorf("server answered %s", resp.Status)
+		entry.LastError = err.Error()
+		return err
+	}
+	return nil
+}
+
+// Retry makes one attempt at every queued delivery, oldest first, and
+// returns how many were delivered and which are still queued. Once a
+// delivery to an endpoint fails, later ones to it wait for the next retry.
+func (w *Webhooks) Retry() (delivered int, pending []OutboxEntry, err error) {
+	entries, err := readOutbox(w.Outbox)
+	if err != nil || len(entries) == 0 {
+		return 0, nil, err
+	}
+
+	failed := make(map[string]bool)
+	for _, entry := range entries {
+		h, ok := w.find(entry.URL)
+		if !ok {
+			entry.LastError = "webhook is no longer configured"
+			failed[entry.URL] = true
+		}
+		if failed[entry.URL] || w.send(h, &entry) != nil {
+			failed[entry.URL] = true
+			pending = append(pending, entry)
+			continue
+		}
+		delivered++
+	}
+	return delivered, pending, writeOutbox(w.Outbox, pending)
+}
+
+func (w *Webhooks) find(url string) (Webhook, bool) {
+	for _, h := range w.Hooks {
+		if h.URL == url {
+			return h, true
+		}
+	}
+	return Webhook{}, false
+}
+
+// Pending returns the queued deliveries
+func (w *Webhooks) Pending() ([]OutboxEntry, error) {
+	return readOutbox(w.Outbox)
+}
+
+func readOutbox(filename string) ([]OutboxEntry, error) {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []OutboxEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry OutboxEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s line %d: %v", filename, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+func appendOutbox(filename string, entry OutboxEntry) error {
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := json.NewEncoder(f).Encode(entry); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// writeOutbox replaces the outbox with entries, removing it when empty
+func writeOutbox(filename string, entries []OutboxEntry) error {
+	if len(entries) == 0 {
+		if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+	tmp := filename + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
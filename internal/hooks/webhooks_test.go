@@ -0,0 +1,155 @@
+package hooks
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"taskmgr/internal/tasks"
+)
+
+// receiver is a webhook endpoint that fails while down is set
+type receiver struct {
+	mu     sync.Mutex
+	down   bool
+	calls  int
+	bodies []Payload
+	sigs   []string
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	if r.down {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	body, _ := io.ReadAll(req.Body)
+	var p Payload
+	json.Unmarshal(body, &p)
+	r.bodies = append(r.bodies, p)
+	r.sigs = append(r.sigs, req.Header.Get("X-Taskmgr-Signature"))
+	if req.Header.Get("X-Taskmgr-Signature") != Sign("s3cret", body) {
+		http.Error(w, "bad signature", http.StatusUnauthorized)
+	}
+}
+
+func newWebhooks(t *testing.T, r *receiver, events ...string) (*Webhooks, *httptest.Server) {
+	t.Helper()
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	return &Webhooks{
+		Hooks:  []Webhook{{URL: ts.URL, Secret: "s3cret", Events: events, Attempts: 2}},
+		Outbox: filepath.Join(t.TempDir(), "outbox.jsonl"),
+		Client: ts.Client(),
+	}, ts
+}
+
+func created(title string) tasks.Event {
+	return tasks.Event{Type: tasks.EventCreated, Task: tasks.Task{ID: tasks.NewID(), Title: title}}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	r := &receiver{}
+	w, _ := newWebhooks(t, r, "created")
+
+	if err := w.HandleEvent(tasks.Event{Type: tasks.EventCreated, Pre: true}); err != nil || r.calls != 0 {
+		t.Errorf("Expected pre-events to be ignored, got %d calls and %v", r.calls, err)
+	}
+	if err := w.HandleEvent(tasks.Event{Type: tasks.EventRemoved}); err != nil || r.calls != 0 {
+		t.Errorf("Expected unsubscribed events to be ignored, got %d calls and %v", r.calls, err)
+	}
+
+	if err := w.HandleEvent(created("Posted")); err != nil {
+		t.Fatalf("HandleEvent returned an error: %v", err)
+	}
+	if len(r.bodies) != 1 || r.bodies[0].Task.Title != "Posted" || r.bodies[0].Event != "created" || r.bodies[0].ID == "" {
+		t.Errorf("Unexpected deliveries %+v", r.bodies)
+	}
+	if !strings.HasPrefix(r.sigs[0], "sha256=") {
+		t.Errorf("Expected a signature, got %q", r.sigs[0])
+	}
+}
+
+func TestWebhookOutbox(t *testing.T) {
+	r := &receiver{down: true}
+	w, _ := newWebhooks(t, r)
+
+	err := w.HandleEvent(created("First"))
+	if err == nil || !strings.Contains(err.Error(), "queued for retry") {
+		t.Fatalf("Expected a queued delivery, got %v", err)
+	}
+	if r.calls != 2 {
+		t.Errorf("Expected 2 attempts, got %d", r.calls)
+	}
+
+	// While the first delivery waits, later events queue behind it
+	r.calls = 0
+	err = w.HandleEvent(created("Second"))
+	if err == nil || !strings.Contains(err.Error(), "queued behind 1") {
+		t.Fatalf("Expected the event to wait, got %v", err)
+	}
+	if r.calls != 1 {
+		t.Errorf("Expected only the queued delivery to be retried, got %d calls", r.calls)
+	}
+	pending, err := w.Pending()
+	if err != nil || len(pending) != 2 || pending[0].Attempts != 3 || pending[0].LastError == "" {
+		t.Fatalf("Unexpected outbox %+v, %v", pending, err)
+	}
+
+	// The endpoint recovers and the queue drains in order
+	r.down = false
+	delivered, pending, err := w.Retry()
+	if err != nil || delivered != 2 || len(pending) != 0 {
+		t.Errorf("Expected both deliveries, got %d delivered, %v pending, %v", delivered, pending, err)
+	}
+	if len(r.bodies) != 2 || r.bodies[0].Task.Title != "First" || r.bodies[1].Task.Title != "Second" {
+		t.Errorf("Expected First then Second, got %+v", r.bodies)
+	}
+	if _, err := os.Stat(w.Outbox); !os.IsNotExist(err) {
+		t.Errorf("Expected an empty outbox to be removed, got %v", err)
+	}
+}
+
+func TestLoadWebhooks(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "webhooks.json")
+	outbox := filepath.Join(dir, "outbox.jsonl")
+
+	w, err := LoadWebhooks(config, outbox)
+	if err != nil || len(w.Hooks) != 0 {
+		t.Errorf("Expected no webhooks without a config file, got %v, %v", w, err)
+	}
+
+	os.WriteFile(config, []byte(`{"webhooks": [{"url": "https://example.com/hook", "events": ["completed"]}]}`), 0644)
+	w, err = LoadWebhooks(config, outbox)
+	if err != nil || len(w.Hooks) != 1 || w.Hooks[0].Attempts != DefaultAttempts {
+		t.Errorf("Unexpected webhooks %+v, %v", w, err)
+	}
+
+	for _, bad := range []string{
+		`{"webhooks": [{"url": "ftp://example.com"}]}`,
+		`{"webhooks": [{"url": "https://example.com", "events": ["exploded"]}]}`,
+		`{"webhooks": `,
+	} {
+		os.WriteFile(config, []byte(bad), 0644)
+		if _, err := LoadWebhooks(config, outbox); err == nil {
+			t.Errorf("Expected an error for %s", bad)
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	// printf '{}' | openssl dgst -sha256 -hmac key
+	want := "sha256=a777724d943eb48dc69bca8a4a6d57a04db3f9ec7e1de4e581e860265bdf3032"
+	if got := Sign("key", []byte("{}")); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,213 @@
+// Package jsonrpc implements the server side of JSON-RPC 2.0 over a
+// stream of newline-delimited messages, as used by the mcp and rpc
+// commands on standard input and output.
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Standard error codes
+const (
+	ParseError     = -32700
+	InvalidRequest = -32600
+	MethodNotFound = -32601
+	InvalidParams  = -32602
+	InternalError  = -32603
+	// ServerError is used for errors returned by handlers that are not an
+	// *Error, such as a task that does not exist
+	ServerError = -32000
+)
+
+// Error is a JSON-RPC error object. Handlers return one to choose the code
+// sent to the client.
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Errorf creates an error with the given code
+func Errorf(code int, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Request is a call or, when ID is empty, a notification
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// IsNotification reports whether the sender expects no response
+func (r *Request) IsNotification() bool {
+	return len(r.ID) == 0
+}
+
+// response has either a result or an error, never both
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Handler answers a request with a result to encode, or an error
+type Handler func(req *Request) (interface{}, error)
+
+// Unmarshal decodes request parameters, reporting failures as invalid
+// params. Missing parameters leave v unchanged.
+func Unmarshal(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return Errorf(InvalidParams, "invalid params: %v", err)
+	}
+	return nil
+}
+
+// Conn writes responses and notifications to a stream. It is safe to send
+// notifications from other goroutines while Serve runs.
+type Conn struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewConn creates a connection writing to w
+func NewConn(w io.Writer) *Conn {
+	return &Conn{w: w}
+}
+
+// Notify sends a notification to the client
+func (c *Conn) Notify(method string, params interface{}) error {
+	msg := struct {
+		JSONRPC string      `json:"jsonrpc"`
+		Method  string      `json:"method"`
+		Params  interface{} `json:"params,omitempty"`
+	}{"2.0", method, params}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return c.write(data)
+}
+
+func (c *Conn) write(data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.w.Write(append(data, '\n'))
+	return err
+}
+
+// Serve reads messages from r, one per line, and answers each with h until
+// r is exhausted. Batches are answered with a batch. Messages without a
+// method, such as responses, are ignored.
+func (c *Conn) Serve(r io.Reader, h Handler) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if reply := c.handle(line, h); reply != nil {
+				if werr := c.write(reply); werr != nil {
+					return werr
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handle answers one line, returning nil when nothing should be sent
+func (c *Conn) handle(line []byte, h Handler) []byte {
+	if line[0] != '[' {
+		resp := c.call(line, h)
+		if resp == nil {
+			return nil
+		}
+		data, _ := json.Marshal(resp)
+		return data
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(line, &batch); err != nil {
+		data, _ := json.Marshal(errorResponse(nil, Errorf(ParseError, "parse error: %v", err)))
+		return data
+	}
+	if len(batch) == 0 {
+		data, _ := json.Marshal(errorResponse(nil, Errorf(InvalidRequest, "empty batch")))
+		return data
+	}
+	var replies []*response
+	for _, msg := range batch {
+		if resp := c.call(msg, h); resp != nil {
+			replies = append(replies, resp)
+		}
+	}
+	if len(replies) == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(replies)
+	return data
+}
+
+func (c *Conn) call(msg []byte, h Handler) *response {
+	var req Request
+	if err := json.Unmarshal(msg, &req); err != nil {
+		var syntax *json.SyntaxError
+		if errors.As(err, &syntax) {
+			return errorResponse(nil, Errorf(ParseError, "parse error: %v", err))
+		}
+		return errorResponse(nil, Errorf(InvalidRequest, "invalid request: %v", err))
+	}
+	if req.Method == "" {
+		if req.IsNotification() {
+			return errorResponse(nil, Errorf(InvalidRequest, "invalid request: no method"))
+		}
+		// A response to something we never send; nothing to answer
+		return nil
+	}
+	if req.JSONRPC != "2.0" {
+		return errorResponse(req.ID, Errorf(InvalidRequest, "invalid request: jsonrpc must be \"2.0\""))
+	}
+
+	result, err := h(&req)
+	if req.IsNotification() {
+		return nil
+	}
+	if err != nil {
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &Error{Code: ServerError, Message: err.Error()}
+		}
+		return errorResponse(req.ID, rpcErr)
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return errorResponse(req.ID, Errorf(InternalError, "encoding result: %v", err))
+	}
+	return &response{JSONRPC: "2.0", ID: req.ID, Result: data}
+}
+
+func errorResponse(id json.RawMessage, err *Error) *response {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &response{JSONRPC: "2.0", ID: id, Error: err}
+}
@@ -0,0 +1,119 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// serve runs a handler over the given input lines and returns the output
+// lines
+func serve(t *testing.T, h Handler, input ...string) []string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := NewConn(&out).Serve(strings.NewReader(strings.Join(input, "\n")), h); err != nil {
+		t.Fatalf("Serve returned an error: %v", err)
+	}
+	text := strings.TrimSpace(out.String())
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+func echo(req *Request) (interface{}, error) {
+	switch req.Method {
+	case "echo":
+		var params struct {
+			Text string `json:"text"`
+		}
+		if err := Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return params.Text, nil
+	case "fail":
+		return nil, errors.New("it broke")
+	default:
+		return nil, Errorf(MethodNotFound, "method not found: %s", req.Method)
+	}
+}
+
+func TestServeRequests(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"result", `{"jsonrpc":"2.0","id":1,"method":"echo","params":{"text":"hi"}}`,
+			`{"jsonrpc":"2.0","id":1,"result":"hi"}`},
+		{"string id", `{"jsonrpc":"2.0","id":"a","method":"echo","params":{"text":""}}`,
+			`{"jsonrpc":"2.0","id":"a","result":""}`},
+		{"unknown method", `{"jsonrpc":"2.0","id":2,"method":"nope"}`,
+			`{"jsonrpc":"2.0","id":2,"error":{"code":-32601,"message":"method not found: nope"}}`},
+		{"handler error", `{"jsonrpc":"2.0","id":3,"method":"fail"}`,
+			`{"jsonrpc":"2.0","id":3,"error":{"code":-32000,"message":"it broke"}}`},
+		{"bad params", `{"jsonrpc":"2.0","id":4,"method":"echo","params":{"text":5}}`,
+			`-32602`},
+		{"parse error", `{"jsonrpc":`, `{"jsonrpc":"2.0","id":null,"error":{"code":-32700`},
+		{"wrong version", `{"jsonrpc":"1.0","id":5,"method":"echo"}`, `-32600`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := serve(t, echo, tt.input)
+			if len(out) != 1 || !strings.Contains(out[0], tt.want) {
+				t.Errorf("Expected a reply containing %s, got %v", tt.want, out)
+			}
+		})
+	}
+}
+
+func TestServeNotificationsAndResponses(t *testing.T) {
+	called := 0
+	h := func(req *Request) (interface{}, error) {
+		called++
+		return nil, errors.New("not reported")
+	}
+	out := serve(t, h,
+		`{"jsonrpc":"2.0","method":"notify"}`,
+		`{"jsonrpc":"2.0","id":9,"result":{}}`,
+		``,
+	)
+	if len(out) != 0 {
+		t.Errorf("Expected no replies, got %v", out)
+	}
+	if called != 1 {
+		t.Errorf("Expected the notification to be handled once, got %d", called)
+	}
+}
+
+func TestServeBatch(t *testing.T) {
+	out := serve(t, echo, `[{"jsonrpc":"2.0","id":1,"method":"echo","params":{"text":"a"}},{"jsonrpc":"2.0","method":"echo"},{"jsonrpc":"2.0","id":2,"method":"nope"}]`)
+	if len(out) != 1 {
+		t.Fatalf("Expected one batch reply, got %v", out)
+	}
+	var replies []map[string]interface{}
+	if err := json.Unmarshal([]byte(out[0]), &replies); err != nil {
+		t.Fatalf("Reply is not a batch: %v", err)
+	}
+	if len(replies) != 2 || replies[0]["result"] != "a" || replies[1]["error"] == nil {
+		t.Errorf("Unexpected batch reply: %v", replies)
+	}
+
+	out = serve(t, echo, `[]`)
+	if len(out) != 1 || !strings.Contains(out[0], "-32600") {
+		t.Errorf("Expected an invalid request error for an empty batch, got %v", out)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	var out bytes.Buffer
+	if err := NewConn(&out).Notify("tasks/changed", map[string]int{"count": 2}); err != nil {
+		t.Fatalf("Notify returned an error: %v", err)
+	}
+	want := `{"jsonrpc":"2.0","method":"tasks/changed","params":{"count":2}}` + "\n"
+	if out.String() != want {
+		t.Errorf("Expected %q, got %q", want, out.String())
+	}
+}
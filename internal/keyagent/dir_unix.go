@@ -0,0 +1,25 @@
+//go:build unix
+
+package keyagent
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkPrivate returns an error unless the directory is owned by uid and
+// has mode 0700
+func checkPrivate(dir string, fi os.FileInfo, uid int) error {
+	if perm := fi.Mode().Perm(); perm != 0700 {
+		return fmt.Errorf("%s has mode %04o; it must be 0700", dir, perm)
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("cannot tell who owns %s", dir)
+	}
+	if int(st.Uid) != uid {
+		return fmt.Errorf("%s is owned by user %d, not %d", dir, st.Uid, uid)
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+// Package keyagent caches the keys to encrypted task files in a small
+// server, like ssh-agent, so that a passphrase is typed once rather than
+// for every command. Keys are held in memory only, and forgotten after a
+// while or when the agent is locked.
+//
+// The agent listens on a Unix socket in a directory only its user can
+// enter. Requests and responses are single JSON lines.
+package keyagent
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultTTL is how long the agent keeps a key unless told otherwise
+const DefaultTTL = 15 * time.Minute
+
+// SocketPath returns where the agent listens: $TASKMGR_AGENT_SOCK, or a
+// socket in a private directory under $XDG_RUNTIME_DIR, or under the
+// temporary directory when that is unset
+func SocketPath() string {
+	if path := os.Getenv("TASKMGR_AGENT_SOCK"); path != "" {
+		return path
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "taskmgr", "agent.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("taskmgr-%d", os.Getuid()), "agent.sock")
+}
+
+// checkDir returns an error unless dir is a directory, not a link to one,
+// that only uid can enter. Another user who made the directory first could
+// otherwise listen there and collect every key the client puts.
+func checkDir(dir string, uid int) error {
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSymlink != 0 || !fi.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	return checkPrivate(dir, fi, uid)
+}
+
+type request struct {
+	Op  string `json:"op"`
+	ID  string `json:"id,omitempty"`
+	Key []byte `json:"key,omitempty"`
+}
+
+type response struct {
+	Key   []byte `json:"key,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Agent holds keys by ID until they expire
+type Agent struct {
+	TTL time.Duration
+
+	mu   sync.Mutex
+	keys map[string]entry
+	now  func() time.Time
+}
+
+type entry struct {
+	key     []byte
+	expires time.Time
+}
+
+func New(ttl time.Duration) *Agent {
+	return &Agent{TTL: ttl, keys: make(map[string]entry), now: time.Now}
+}
+
+// Listen listens on a Unix socket at path, creating its directory for the
+// current user only. A directory that is not the user's alone is refused.
+// A socket left behind by an agent that has stopped is replaced; a running
+// agent is not.
+func Listen(path string) (net.Listener, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := checkDir(dir, os.Getuid()); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("an agent is already running at %s", path)
+		}
+		os.Remove(path)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// Serve answers requests on l until it is closed
+func (a *Agent) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		go a.serveConn(conn)
+	}
+}
+
+func (a *Agent) serveConn(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	enc := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var req request
+		resp := response{}
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp.Error = err.Error()
+		} else {
+			resp = a.handle(req)
+		}
+		if err := enc.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+func (a *Agent) handle(req request) response {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch req.Op {
+	case "get":
+		e, ok := a.keys[req.ID]
+		if ok && a.now().After(e.expires) {
+			delete(a.keys, req.ID)
+			ok = false
+		}
+		if !ok {
+			return response{Error: "no key"}
+		}
+		return response{Key: e.key}
+	case "put":
+		if req.ID == "" || len(req.Key) == 0 {
+			return response{Error: "put needs an id and a key"}
+		}
+		a.keys[req.ID] = entry{key: req.Key, expires: a.now().Add(a.TTL)}
+		return response{}
+	case "lock":
+		a.keys = make(map[string]entry)
+		return response{}
+	default:
+		return response{Error: fmt.Sprintf("unknown operation %q", req.Op)}
+	}
+}
+
+// Client talks to an agent. It only talks to a socket in a directory that
+// is the current user's alone, as Listen makes.
+type Client struct {
+	Path string
+}
+
+// ErrNoKey is returned by Get when the agent has no key for the ID
+var ErrNoKey = errors.New("no key cached")
+
+func (c Client) call(req request) (response, error) {
+	if err := checkDir(filepath.Dir(c.Path), os.Getuid()); err != nil {
+		return response{}, err
+	}
+	conn, err := net.DialTimeout("unix", c.Path, time.Second)
+	if err != nil {
+		return response{}, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return response{}, err
+	}
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return response{}, err
+	}
+	return resp, nil
+}
+
+// Get returns the key cached under id
+func (c Client) Get(id string) ([]byte, error) {
+	resp, err := c.call(request{Op: "get", ID: id})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, ErrNoKey
+	}
+	return resp.Key, nil
+}
+
+// Put caches a key under id
+func (c Client) Put(id string, key []byte) error {
+	resp, err := c.call(request{Op: "put", ID: id, Key: key})
+	if err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	return nil
+}
+
+// Lock makes the agent forget every key
+func (c Client) Lock() error {
+	resp, err := c.call(request{Op: "lock"})
+	if err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	return nil
+}
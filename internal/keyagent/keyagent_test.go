@@ -0,0 +1,118 @@
+package keyagent
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startAgent runs an agent on a socket in a private temporary directory
+func startAgent(t *testing.T, ttl time.Duration) (*Agent, Client) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "agent", "agent.sock")
+	l, err := Listen(path)
+	if err != nil {
+		t.Fatalf("Listen returned an error: %v", err)
+	}
+	agent := New(ttl)
+	go agent.Serve(l)
+	t.Cleanup(func() { l.Close() })
+	return agent, Client{Path: path}
+}
+
+func TestAgent(t *testing.T) {
+	agent, client := startAgent(t, time.Minute)
+	now := time.Now()
+	agent.mu.Lock()
+	agent.now = func() time.Time { return now }
+	agent.mu.Unlock()
+
+	if _, err := client.Get("salt"); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Expected ErrNoKey before a key is put, got %v", err)
+	}
+	if err := client.Put("salt", []byte("key")); err != nil {
+		t.Fatalf("Put returned an error: %v", err)
+	}
+	if key, err := client.Get("salt"); err != nil || string(key) != "key" {
+		t.Errorf("Expected the cached key, got %q, %v", key, err)
+	}
+
+	// Keys are forgotten once they expire
+	agent.mu.Lock()
+	now = now.Add(2 * time.Minute)
+	agent.mu.Unlock()
+	if _, err := client.Get("salt"); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Expected the key to expire, got %v", err)
+	}
+
+	client.Put("salt", []byte("key"))
+	if err := client.Lock(); err != nil {
+		t.Fatalf("Lock returned an error: %v", err)
+	}
+	if _, err := client.Get("salt"); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Expected Lock to forget the key, got %v", err)
+	}
+}
+
+func TestListenRefusesSecondAgent(t *testing.T) {
+	_, client := startAgent(t, time.Minute)
+	if _, err := Listen(client.Path); err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Errorf("Expected an error for a running agent, got %v", err)
+	}
+}
+
+func TestClientWithoutAgent(t *testing.T) {
+	client := Client{Path: filepath.Join(t.TempDir(), "none.sock")}
+	if _, err := client.Get("salt"); err == nil {
+		t.Error("Expected an error without an agent")
+	}
+}
+
+func TestSocketPath(t *testing.T) {
+	t.Setenv("TASKMGR_AGENT_SOCK", "")
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	if got := SocketPath(); got != filepath.Join("/run/user/1000", "taskmgr", "agent.sock") {
+		t.Errorf("Expected the socket under $XDG_RUNTIME_DIR, got %s", got)
+	}
+	t.Setenv("TASKMGR_AGENT_SOCK", "/somewhere/agent.sock")
+	if got := SocketPath(); got != "/somewhere/agent.sock" {
+		t.Errorf("Expected $TASKMGR_AGENT_SOCK, got %s", got)
+	}
+}
+
+func TestRefusesSharedDirectory(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("directory modes and owners are a Unix matter")
+	}
+	_, client := startAgent(t, time.Minute)
+	dir := filepath.Dir(client.Path)
+
+	// Someone else's directory, even with the right mode
+	if err := checkDir(dir, os.Getuid()+1); err == nil || !strings.Contains(err.Error(), "owned by") {
+		t.Errorf("Expected a directory owned by another user refused, got %v", err)
+	}
+
+	if err := os.Chmod(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Put("salt", []byte("key")); err == nil || !strings.Contains(err.Error(), "must be 0700") {
+		t.Errorf("Expected Put to refuse a world-writable directory, got %v", err)
+	}
+	if _, err := Listen(filepath.Join(dir, "other.sock")); err == nil {
+		t.Error("Expected Listen to refuse a world-writable directory")
+	}
+	os.Chmod(dir, 0700)
+
+	// A link to the agent's directory is not the directory
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(dir, link); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (Client{Path: filepath.Join(link, "agent.sock")}).Get("salt"); err == nil || !strings.Contains(err.Error(), "not a directory") {
+		t.Errorf("Expected a symlinked directory refused, got %v", err)
+	}
+}
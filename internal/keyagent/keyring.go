@@ -0,0 +1,78 @@
+package keyagent
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+
+	"taskmgr/internal/tasks"
+)
+
+// Keyring supplies keys to encrypted task files from the agent, if one is
+// running, and otherwise derives them from the secret Secret returns. Keys
+// that open a file are cached in the agent.
+type Keyring struct {
+	Agent  Client
+	Secret func() ([]byte, error)
+}
+
+// Key implements tasks.Keyring
+func (k *Keyring) Key(p tasks.KeyParams) ([]byte, error) {
+	if key, err := k.Agent.Get(p.ID()); err == nil {
+		return key, nil
+	}
+	if k.Secret == nil {
+		return nil, errors.New("no passphrase or key file given")
+	}
+	secret, err := k.Secret()
+	if err != nil {
+		return nil, err
+	}
+	return tasks.DeriveKey(secret, p)
+}
+
+// Unlocked implements tasks.Keyring. Without a running agent, the key is
+// not kept.
+func (k *Keyring) Unlocked(p tasks.KeyParams, key []byte) {
+	k.Agent.Put(p.ID(), key)
+}
+
+// ReadSecret returns the secret to derive a key from: the contents of
+// keyfile if one is given, or else the environment variable env, or else a
+// passphrase typed at the terminal after prompt, twice if confirm is set.
+func ReadSecret(keyfile, env, prompt string, confirm bool) ([]byte, error) {
+	if keyfile != "" {
+		return os.ReadFile(keyfile)
+	}
+	if secret := os.Getenv(env); secret != "" {
+		return []byte(secret), nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("no passphrase: set $%s, give a key file, or run taskmgr agent", env)
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	secret, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	if len(secret) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+	if confirm {
+		fmt.Fprint(os.Stderr, "Repeat passphrase: ")
+		again, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(secret, again) {
+			return nil, errors.New("passphrases do not match")
+		}
+	}
+	return secret, nil
+}
@@ -0,0 +1,50 @@
+package keyagent
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"taskmgr/internal/tasks"
+)
+
+func TestKeyring(t *testing.T) {
+	_, client := startAgent(t, time.Minute)
+	params, _ := tasks.NewKeyParams()
+	params.Time, params.Memory, params.Threads = 1, 64, 1
+
+	asked := 0
+	keyring := &Keyring{Agent: client, Secret: func() ([]byte, error) {
+		asked++
+		return []byte("passphrase"), nil
+	}}
+	key, err := keyring.Key(params)
+	if err != nil {
+		t.Fatalf("Key returned an error: %v", err)
+	}
+	want, _ := tasks.DeriveKey([]byte("passphrase"), params)
+	if !bytes.Equal(key, want) {
+		t.Error("Expected the key derived from the passphrase")
+	}
+
+	// Once a key has opened a file, the agent supplies it
+	keyring.Unlocked(params, key)
+	again, err := keyring.Key(params)
+	if err != nil || !bytes.Equal(again, want) || asked != 1 {
+		t.Errorf("Expected the cached key without asking again, asked %d times, %v", asked, err)
+	}
+}
+
+func TestReadSecret(t *testing.T) {
+	keyfile := filepath.Join(t.TempDir(), "key")
+	os.WriteFile(keyfile, []byte{0, 1, 2, 3}, 0600)
+	if secret, err := ReadSecret(keyfile, "TASKMGR_TEST_PASSPHRASE", "", false); err != nil || !bytes.Equal(secret, []byte{0, 1, 2, 3}) {
+		t.Errorf("Expected the key file's contents, got %v, %v", secret, err)
+	}
+	t.Setenv("TASKMGR_TEST_PASSPHRASE", "from the environment")
+	if secret, err := ReadSecret("", "TASKMGR_TEST_PASSPHRASE", "", false); err != nil || string(secret) != "from the environment" {
+		t.Errorf("Expected the passphrase from the environment, got %q, %v", secret, err)
+	}
+}
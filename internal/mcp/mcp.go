@@ -0,0 +1,237 @@
+// Package mcp serves a TaskManager over the Model Context Protocol, so
+// that editors and assistants can read and change the task list. Messages
+// are JSON-RPC 2.0, one per line, on standard input and output.
+//
+// Tools add, list, modify, complete and tag tasks and report statistics.
+// Every task is also a resource, taskmgr://tasks/{id}, and the whole list
+// is taskmgr://tasks.
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"taskmgr/internal/api"
+	"taskmgr/internal/jsonrpc"
+	"taskmgr/internal/tasks"
+)
+
+// protocolVersions are the revisions of the protocol the server speaks,
+// newest first
+var protocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// resourceNotFound is the error code MCP uses for unknown resources
+const resourceNotFound = -32002
+
+const resourcePrefix = "taskmgr://tasks"
+
+// Server answers MCP requests for one TaskManager
+type Server struct {
+	manager *tasks.TaskManager
+	conn    *jsonrpc.Conn
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// New creates a server for the given manager
+func New(manager *tasks.TaskManager) *Server {
+	return &Server{manager: manager, now: time.Now}
+}
+
+// Serve answers requests read from r on w until r is exhausted
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.conn = jsonrpc.NewConn(w)
+	return s.conn.Serve(r, s.handle)
+}
+
+func (s *Server) handle(req *jsonrpc.Request) (interface{}, error) {
+	switch req.Method {
+	case "initialize":
+		return s.initialize(req.Params)
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return map[string]interface{}{"tools": toolList}, nil
+	case "tools/call":
+		return s.callTool(req.Params)
+	case "resources/list":
+		return s.listResources()
+	case "resources/templates/list":
+		return map[string]interface{}{"resourceTemplates": []map[string]string{{
+			"uriTemplate": resourcePrefix + "/{id}",
+			"name":        "task",
+			"description": "A task by id, as JSON",
+			"mimeType":    "application/json",
+		}}}, nil
+	case "resources/read":
+		return s.readResource(req.Params)
+	default:
+		if strings.HasPrefix(req.Method, "notifications/") {
+			// initialized, cancelled and the like need no action
+			return nil, nil
+		}
+		return nil, jsonrpc.Errorf(jsonrpc.MethodNotFound, "method not found: %s", req.Method)
+	}
+}
+
+func (s *Server) initialize(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if err := jsonrpc.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	// Answer with the client's version when we speak it, otherwise with
+	// ours and let the client decide
+	version := protocolVersions[0]
+	for _, v := range protocolVersions {
+		if v == p.ProtocolVersion {
+			version = v
+		}
+	}
+	return map[string]interface{}{
+		"protocolVersion": version,
+		"capabilities": map[string]interface{}{
+			"tools":     map[string]bool{"listChanged": false},
+			"resources": map[string]bool{"subscribe": false, "listChanged": true},
+		},
+		"serverInfo": map[string]string{
+			"name":    "taskmgr",
+			"version": serverVersion(),
+		},
+		"instructions": "Manage the user's task list. Tasks are identified by id; " +
+			"list_tasks shows ids, priorities, due dates, tags and projects.",
+	}, nil
+}
+
+// serverVersion is the module version the binary was built from
+func serverVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
+// toolResult is the result of tools/call. Failures of the tool itself,
+// such as an unknown task, are reported here rather than as protocol
+// errors so that the assistant sees them.
+type toolResult struct {
+	Content []textContent `json:"content"`
+	IsError bool          `json:"isError,omitempty"`
+}
+
+type textContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func textResult(text string, isError bool) toolResult {
+	return toolResult{Content: []textContent{{Type: "text", Text: text}}, IsError: isError}
+}
+
+func (s *Server) callTool(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := jsonrpc.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	tool, ok := tools[p.Name]
+	if !ok {
+		return nil, jsonrpc.Errorf(jsonrpc.InvalidParams, "unknown tool: %s", p.Name)
+	}
+
+	result, changed, err := tool(s, p.Arguments)
+	if err != nil {
+		return textResult("Error: "+err.Error(), true), nil
+	}
+	if changed && s.conn != nil {
+		s.conn.Notify("notifications/resources/list_changed", nil)
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return textResult(string(data), false), nil
+}
+
+// decodeArgs decodes tool arguments, rejecting unknown ones so that a
+// misspelt argument is reported instead of ignored
+func decodeArgs(args json.RawMessage, v interface{}) error {
+	if len(args) == 0 || string(args) == "null" {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(args))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid arguments: %v", err)
+	}
+	return nil
+}
+
+func (s *Server) listResources() (interface{}, error) {
+	resources := []map[string]string{{
+		"uri":         resourcePrefix,
+		"name":        "tasks",
+		"description": "All tasks, as a JSON array",
+		"mimeType":    "application/json",
+	}}
+	list, err := s.manager.Load()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range list {
+		resources = append(resources, map[string]string{
+			"uri":      resourcePrefix + "/" + t.ID,
+			"name":     t.Title,
+			"mimeType": "application/json",
+		})
+	}
+	return map[string]interface{}{"resources": resources}, nil
+}
+
+func (s *Server) readResource(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		URI string `json:"uri"`
+	}
+	if err := jsonrpc.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	if p.URI == resourcePrefix {
+		all, err := s.manager.Load()
+		if err != nil {
+			return nil, err
+		}
+		list := []api.Task{}
+		for _, t := range all {
+			list = append(list, api.FromTask(t))
+		}
+		value = list
+	} else if id := strings.TrimPrefix(p.URI, resourcePrefix+"/"); id != p.URI && id != "" {
+		t, err := s.manager.Get(id)
+		if err != nil {
+			return nil, &jsonrpc.Error{Code: resourceNotFound, Message: "resource not found", Data: map[string]string{"uri": p.URI}}
+		}
+		value = api.FromTask(t)
+	} else {
+		return nil, &jsonrpc.Error{Code: resourceNotFound, Message: "resource not found", Data: map[string]string{"uri": p.URI}}
+	}
+
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"contents": []map[string]string{{
+		"uri":      p.URI,
+		"mimeType": "application/json",
+		"text":     string(data),
+	}}}, nil
+}
@@ -0,0 +1,169 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"taskmgr/internal/tasks"
+)
+
+func newTestServer(t *testing.T) (*Server, *tasks.TaskManager) {
+	t.Helper()
+	manager := tasks.NewTaskManager(tasks.NewFileStore(filepath.Join(t.TempDir(), "tasks.json")))
+	return New(manager), manager
+}
+
+// message is a decoded line of server output
+type message struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// session sends one request per method and params pair, numbering them
+// from 1, and returns everything the server wrote
+func session(t *testing.T, s *Server, calls ...interface{}) []message {
+	t.Helper()
+	var in bytes.Buffer
+	for i := 0; i+1 < len(calls); i += 2 {
+		params, err := json.Marshal(calls[i+1])
+		if err != nil {
+			t.Fatalf("Error encoding params: %v", err)
+		}
+		fmt.Fprintf(&in, `{"jsonrpc":"2.0","id":%d,"method":%q,"params":%s}`+"\n", i/2+1, calls[i], params)
+	}
+	var out bytes.Buffer
+	if err := s.Serve(&in, &out); err != nil {
+		t.Fatalf("Serve returned an error: %v", err)
+	}
+	var messages []message
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var m message
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("Invalid output line %q: %v", line, err)
+		}
+		messages = append(messages, m)
+	}
+	return messages
+}
+
+// call runs a single request and decodes its result into out
+func call(t *testing.T, s *Server, method string, params, out interface{}) {
+	t.Helper()
+	messages := session(t, s, method, params)
+	last := messages[len(messages)-1]
+	if last.Error != nil {
+		t.Fatalf("%s returned error %d: %s", method, last.Error.Code, last.Error.Message)
+	}
+	if err := json.Unmarshal(last.Result, out); err != nil {
+		t.Fatalf("Error decoding %s result: %v", method, err)
+	}
+}
+
+func TestInitialize(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	var result struct {
+		ProtocolVersion string                     `json:"protocolVersion"`
+		Capabilities    map[string]json.RawMessage `json:"capabilities"`
+		ServerInfo      struct {
+			Name string `json:"name"`
+		} `json:"serverInfo"`
+	}
+	call(t, s, "initialize", map[string]interface{}{
+		"protocolVersion": "2024-11-05",
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      map[string]string{"name": "test", "version": "1"},
+	}, &result)
+	if result.ProtocolVersion != "2024-11-05" {
+		t.Errorf("Expected the client's protocol version, got %q", result.ProtocolVersion)
+	}
+	if result.ServerInfo.Name != "taskmgr" {
+		t.Errorf("Expected server name taskmgr, got %q", result.ServerInfo.Name)
+	}
+	if result.Capabilities["tools"] == nil || result.Capabilities["resources"] == nil {
+		t.Errorf("Expected tools and resources capabilities, got %v", result.Capabilities)
+	}
+
+	call(t, s, "initialize", map[string]string{"protocolVersion": "1999-01-01"}, &result)
+	if result.ProtocolVersion != protocolVersions[0] {
+		t.Errorf("Expected the latest version for an unknown one, got %q", result.ProtocolVersion)
+	}
+}
+
+func TestNotificationsAndUnknownMethods(t *testing.T) {
+	s, _ := newTestServer(t)
+	in := strings.NewReader(`{"jsonrpc":"2.0","method":"notifications/initialized"}` + "\n" +
+		`{"jsonrpc":"2.0","id":1,"method":"ping"}` + "\n" +
+		`{"jsonrpc":"2.0","id":2,"method":"prompts/list"}` + "\n")
+	var out bytes.Buffer
+	if err := s.Serve(in, &out); err != nil {
+		t.Fatalf("Serve returned an error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected replies to the two requests only, got %v", lines)
+	}
+	if lines[0] != `{"jsonrpc":"2.0","id":1,"result":{}}` {
+		t.Errorf("Unexpected ping reply %s", lines[0])
+	}
+	if !strings.Contains(lines[1], "-32601") {
+		t.Errorf("Expected method not found, got %s", lines[1])
+	}
+}
+
+func TestResources(t *testing.T) {
+	s, manager := newTestServer(t)
+	manager.Add(tasks.Task{Title: "Read me", Priority: tasks.High})
+	id := manager.List()[0].ID
+
+	var list struct {
+		Resources []struct {
+			URI  string `json:"uri"`
+			Name string `json:"name"`
+		} `json:"resources"`
+	}
+	call(t, s, "resources/list", nil, &list)
+	if len(list.Resources) != 2 || list.Resources[1].URI != "taskmgr://tasks/"+id || list.Resources[1].Name != "Read me" {
+		t.Errorf("Unexpected resources: %+v", list.Resources)
+	}
+
+	var read struct {
+		Contents []struct {
+			URI      string `json:"uri"`
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+		} `json:"contents"`
+	}
+	call(t, s, "resources/read", map[string]string{"uri": "taskmgr://tasks/" + id}, &read)
+	if len(read.Contents) != 1 || read.Contents[0].MimeType != "application/json" {
+		t.Fatalf("Unexpected contents: %+v", read.Contents)
+	}
+	var task struct {
+		ID       string `json:"id"`
+		Priority string `json:"priority"`
+	}
+	if err := json.Unmarshal([]byte(read.Contents[0].Text), &task); err != nil || task.ID != id || task.Priority != "high" {
+		t.Errorf("Unexpected task resource %s: %v", read.Contents[0].Text, err)
+	}
+
+	call(t, s, "resources/read", map[string]string{"uri": "taskmgr://tasks"}, &read)
+	if !strings.Contains(read.Contents[0].Text, "Read me") {
+		t.Errorf("Expected the task list to include the task, got %s", read.Contents[0].Text)
+	}
+
+	for _, uri := range []string{"taskmgr://tasks/missing", "file:///etc/passwd"} {
+		messages := session(t, s, "resources/read", map[string]string{"uri": uri})
+		if messages[0].Error == nil || messages[0].Error.Code != resourceNotFound {
+			t.Errorf("%s: expected resource not found, got %+v", uri, messages[0])
+		}
+	}
+}
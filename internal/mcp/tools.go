@@ -0,0 +1,241 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"taskmgr/internal/api"
+	"taskmgr/internal/tasks"
+)
+
+// tool runs with the arguments of a tools/call request and returns the
+// value to show the assistant and whether the task list changed
+type tool func(s *Server, args json.RawMessage) (interface{}, bool, error)
+
+var tools = map[string]tool{
+	"add_task":      (*Server).addTask,
+	"list_tasks":    (*Server).listTasks,
+	"modify_task":   (*Server).modifyTask,
+	"complete_task": (*Server).completeTask,
+	"tag_task":      (*Server).tagTask,
+	"get_stats":     (*Server).stats,
+}
+
+// Schema helpers for the tool list
+func str(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "description": description}
+}
+
+func boolean(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "boolean", "description": description}
+}
+
+func strList(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "array", "items": map[string]string{"type": "string"}, "description": description}
+}
+
+func object(required []string, properties map[string]interface{}) map[string]interface{} {
+	schema := map[string]interface{}{"type": "object", "properties": properties, "additionalProperties": false}
+	if required != nil {
+		schema["required"] = required
+	}
+	return schema
+}
+
+const (
+	idDoc       = "Task id, or a unique prefix of one"
+	priorityDoc = "low, medium, high or critical"
+	dueDoc      = `Due date: YYYY-MM-DD, MM/DD/YYYY, today, tomorrow, "next week" or an RFC 3339 time`
+)
+
+// toolList describes the tools in tools/list
+var toolList = []map[string]interface{}{
+	{
+		"name":        "add_task",
+		"description": "Add a task. Returns the new task with its id.",
+		"inputSchema": object([]string{"title"}, map[string]interface{}{
+			"title":       str("Short title"),
+			"description": str("Longer description"),
+			"notes":       str("Free-form notes"),
+			"priority":    str("Priority, default medium: " + priorityDoc),
+			"due":         str(dueDoc),
+			"tags":        strList("Tags"),
+			"project":     str("Project name"),
+			"parent_id":   str("Id of the parent task, making this a subtask"),
+		}),
+	},
+	{
+		"name":        "list_tasks",
+		"description": "List tasks, optionally filtered. All filters must match.",
+		"inputSchema": object(nil, map[string]interface{}{
+			"priority":   str("Only tasks of this priority: " + priorityDoc),
+			"tag":        str("Only tasks with this tag"),
+			"project":    str("Only tasks in this project"),
+			"overdue":    boolean("Only open tasks past their due date"),
+			"due_today":  boolean("Only tasks due today"),
+			"due_within": map[string]interface{}{"type": "integer", "minimum": 0, "description": "Only tasks due within this many days"},
+			"done":       boolean("Only done tasks when true, only open tasks when false"),
+		}),
+	},
+	{
+		"name":        "modify_task",
+		"description": "Change fields of a task. Omitted fields are left as they are; an empty due clears the due date and tags replaces all tags.",
+		"inputSchema": object([]string{"id"}, map[string]interface{}{
+			"id":          str(idDoc),
+			"title":       str("New title"),
+			"description": str("New description"),
+			"notes":       str("New notes"),
+			"priority":    str("New priority: " + priorityDoc),
+			"due":         str(dueDoc + ", or empty to clear"),
+			"tags":        strList("New tags, replacing the current ones"),
+			"project":     str("New project, or empty to clear"),
+			"parent_id":   str("New parent task id, or empty to clear"),
+			"done":        boolean("Mark done or not done"),
+		}),
+	},
+	{
+		"name":        "complete_task",
+		"description": "Mark a task as done, or as not done with undo.",
+		"inputSchema": object([]string{"id"}, map[string]interface{}{
+			"id":   str(idDoc),
+			"undo": boolean("Mark the task as not done instead"),
+		}),
+	},
+	{
+		"name":        "tag_task",
+		"description": "Add tags to and remove tags from a task.",
+		"inputSchema": object([]string{"id"}, map[string]interface{}{
+			"id":     str(idDoc),
+			"add":    strList("Tags to add"),
+			"remove": strList("Tags to remove"),
+		}),
+	},
+	{
+		"name":        "get_stats",
+		"description": "Progress statistics: totals, overdue tasks, counts by priority and average lead time.",
+		"inputSchema": object(nil, map[string]interface{}{}),
+	},
+}
+
+func (s *Server) addTask(args json.RawMessage) (interface{}, bool, error) {
+	var req api.CreateRequest
+	if err := decodeArgs(args, &req); err != nil {
+		return nil, false, err
+	}
+	t, err := req.Task()
+	if err != nil {
+		return nil, false, err
+	}
+	t.ID = tasks.NewID()
+	if err := s.manager.Add(t); err != nil {
+		return nil, false, err
+	}
+	return s.task(t.ID, true)
+}
+
+func (s *Server) listTasks(args json.RawMessage) (interface{}, bool, error) {
+	var req api.ListRequest
+	if err := decodeArgs(args, &req); err != nil {
+		return nil, false, err
+	}
+	all, err := s.manager.Load()
+	if err != nil {
+		return nil, false, err
+	}
+	list, err := req.Select(all, s.now())
+	return list, false, err
+}
+
+func (s *Server) modifyTask(args json.RawMessage) (interface{}, bool, error) {
+	var req struct {
+		ID string `json:"id"`
+		api.PatchRequest
+	}
+	if err := decodeArgs(args, &req); err != nil {
+		return nil, false, err
+	}
+	t, err := s.manager.Get(req.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	updated, err := req.Apply(t, s.now())
+	if err != nil {
+		return nil, false, err
+	}
+	if err := s.manager.Update(t.ID, updated); err != nil {
+		return nil, false, err
+	}
+	return s.task(t.ID, true)
+}
+
+func (s *Server) completeTask(args json.RawMessage) (interface{}, bool, error) {
+	var req struct {
+		ID   string `json:"id"`
+		Undo bool   `json:"undo"`
+	}
+	if err := decodeArgs(args, &req); err != nil {
+		return nil, false, err
+	}
+	t, err := s.manager.Get(req.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	if req.Undo {
+		err = s.manager.UndoDone(t.ID)
+	} else {
+		err = s.manager.MarkDone(t.ID)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return s.task(t.ID, true)
+}
+
+func (s *Server) tagTask(args json.RawMessage) (interface{}, bool, error) {
+	var req struct {
+		ID     string   `json:"id"`
+		Add    []string `json:"add"`
+		Remove []string `json:"remove"`
+	}
+	if err := decodeArgs(args, &req); err != nil {
+		return nil, false, err
+	}
+	if len(req.Add) == 0 && len(req.Remove) == 0 {
+		return nil, false, fmt.Errorf("nothing to do: give tags to add or remove")
+	}
+	t, err := s.manager.Get(req.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, tag := range req.Add {
+		if err := s.manager.AddTagToTask(t.ID, tag); err != nil {
+			return nil, false, err
+		}
+	}
+	for _, tag := range req.Remove {
+		if err := s.manager.RemoveTagFromTask(t.ID, tag); err != nil {
+			return nil, false, err
+		}
+	}
+	return s.task(t.ID, true)
+}
+
+func (s *Server) stats(args json.RawMessage) (interface{}, bool, error) {
+	if err := decodeArgs(args, &struct{}{}); err != nil {
+		return nil, false, err
+	}
+	list, err := s.manager.Load()
+	if err != nil {
+		return nil, false, err
+	}
+	return api.NewStats(list), false, nil
+}
+
+// task reads a task back after a change
+func (s *Server) task(id string, changed bool) (interface{}, bool, error) {
+	t, err := s.manager.Get(id)
+	if err != nil {
+		return nil, changed, err
+	}
+	return api.FromTask(t), changed, nil
+}
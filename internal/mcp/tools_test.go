@@ -0,0 +1,167 @@
+package mcp
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"taskmgr/internal/api"
+	"taskmgr/internal/tasks"
+)
+
+// callTool runs a tool and returns its text and whether it failed
+func callTool(t *testing.T, s *Server, name string, args interface{}) (string, bool) {
+	t.Helper()
+	var result toolResult
+	call(t, s, "tools/call", map[string]interface{}{"name": name, "arguments": args}, &result)
+	if len(result.Content) != 1 || result.Content[0].Type != "text" {
+		t.Fatalf("%s: unexpected content %+v", name, result.Content)
+	}
+	return result.Content[0].Text, result.IsError
+}
+
+// idPrefix returns a short prefix of id that cannot be read as an index
+func idPrefix(id string) string {
+	for n := 6; n < len(id); n++ {
+		if strings.IndexFunc(id[:n], func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+			return id[:n]
+		}
+	}
+	return id
+}
+
+func TestToolList(t *testing.T) {
+	s, _ := newTestServer(t)
+	var result struct {
+		Tools []struct {
+			Name        string                 `json:"name"`
+			InputSchema map[string]interface{} `json:"inputSchema"`
+		} `json:"tools"`
+	}
+	call(t, s, "tools/list", nil, &result)
+	if len(result.Tools) != len(tools) {
+		t.Fatalf("Expected %d tools, got %d", len(tools), len(result.Tools))
+	}
+	for _, tool := range result.Tools {
+		if _, ok := tools[tool.Name]; !ok {
+			t.Errorf("Listed tool %s has no implementation", tool.Name)
+		}
+		if tool.InputSchema["type"] != "object" {
+			t.Errorf("%s: expected an object schema, got %v", tool.Name, tool.InputSchema)
+		}
+	}
+}
+
+func TestTaskTools(t *testing.T) {
+	s, manager := newTestServer(t)
+
+	text, isError := callTool(t, s, "add_task", map[string]interface{}{
+		"title": "Write docs", "priority": "high", "tags": []string{"docs"}, "project": "site",
+	})
+	if isError {
+		t.Fatalf("add_task failed: %s", text)
+	}
+	var added api.Task
+	if err := json.Unmarshal([]byte(text), &added); err != nil {
+		t.Fatalf("add_task returned %q: %v", text, err)
+	}
+	if added.ID == "" || added.Priority != "high" || len(manager.List()) != 1 {
+		t.Errorf("Unexpected task %+v", added)
+	}
+	callTool(t, s, "add_task", map[string]interface{}{"title": "Low one", "priority": "low"})
+
+	text, _ = callTool(t, s, "list_tasks", map[string]interface{}{"priority": "high"})
+	var listed []api.Task
+	if err := json.Unmarshal([]byte(text), &listed); err != nil || len(listed) != 1 || listed[0].ID != added.ID {
+		t.Errorf("Expected only the high priority task, got %s", text)
+	}
+
+	text, isError = callTool(t, s, "modify_task", map[string]interface{}{"id": idPrefix(added.ID), "title": "Write more docs", "due": "2025-07-10"})
+	if isError || !strings.Contains(text, "Write more docs") || !strings.Contains(text, "2025-07-10") {
+		t.Errorf("modify_task returned %s", text)
+	}
+
+	text, isError = callTool(t, s, "tag_task", map[string]interface{}{"id": added.ID, "add": []string{"urgent"}, "remove": []string{"docs"}})
+	if isError {
+		t.Fatalf("tag_task failed: %s", text)
+	}
+	if task, _ := manager.Get(added.ID); !task.HasTag("urgent") || task.HasTag("docs") {
+		t.Errorf("Expected tags [urgent], got %v", task.Tags)
+	}
+
+	callTool(t, s, "complete_task", map[string]interface{}{"id": added.ID})
+	if task, _ := manager.Get(added.ID); !task.Done {
+		t.Error("Expected the task to be done")
+	}
+	callTool(t, s, "complete_task", map[string]interface{}{"id": added.ID, "undo": true})
+	if task, _ := manager.Get(added.ID); task.Done {
+		t.Error("Expected the task to be open again")
+	}
+
+	text, _ = callTool(t, s, "get_stats", map[string]interface{}{})
+	var stats api.Stats
+	if err := json.Unmarshal([]byte(text), &stats); err != nil || stats.Total != 2 || stats.ByPriority["low"] != 1 {
+		t.Errorf("Unexpected stats %s", text)
+	}
+}
+
+func TestToolErrors(t *testing.T) {
+	s, manager := newTestServer(t)
+	manager.Add(tasks.Task{Title: "Only"})
+
+	tests := []struct {
+		name string
+		tool string
+		args interface{}
+	}{
+		{"missing title", "add_task", map[string]interface{}{}},
+		{"unknown argument", "add_task", map[string]interface{}{"title": "x", "colour": "red"}},
+		{"unknown task", "complete_task", map[string]interface{}{"id": "nope"}},
+		{"bad priority", "list_tasks", map[string]interface{}{"priority": "urgent"}},
+		{"nothing to tag", "tag_task", map[string]interface{}{"id": "0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, isError := callTool(t, s, tt.tool, tt.args)
+			if !isError || !strings.HasPrefix(text, "Error:") {
+				t.Errorf("Expected a tool error, got %q", text)
+			}
+		})
+	}
+
+	messages := session(t, s, "tools/call", map[string]interface{}{"name": "drop_tables"})
+	if messages[0].Error == nil {
+		t.Errorf("Expected a protocol error for an unknown tool, got %+v", messages[0])
+	}
+}
+
+func TestChangesNotifyResourceListChanged(t *testing.T) {
+	s, _ := newTestServer(t)
+	messages := session(t, s,
+		"tools/call", map[string]interface{}{"name": "add_task", "arguments": map[string]string{"title": "New"}},
+		"tools/call", map[string]interface{}{"name": "list_tasks"},
+	)
+	var notifications []string
+	for _, m := range messages {
+		if m.Method != "" {
+			notifications = append(notifications, m.Method)
+		}
+	}
+	if len(notifications) != 1 || notifications[0] != "notifications/resources/list_changed" {
+		t.Errorf("Expected one list_changed notification, got %v", notifications)
+	}
+}
+
+func TestDueDocForms(t *testing.T) {
+	// Every form the schema documents is one the tools accept
+	for _, due := range []string{"2025-07-10", "07/10/2025", "today", "tomorrow", "next week", "2025-07-10T09:00:00+02:00"} {
+		if _, err := api.ParseDue(due); err != nil {
+			t.Errorf("ParseDue(%q) returned an error: %v", due, err)
+		}
+	}
+	for _, unsupported := range []string{"3days", "1week"} {
+		if _, err := api.ParseDue(unsupported); err == nil || strings.Contains(dueDoc, unsupported) {
+			t.Errorf("Expected %q neither accepted nor documented", unsupported)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+// Package rpc serves a TaskManager as JSON-RPC 2.0 on a pair of streams,
+// one message per line, for editor plugins that keep a taskmgr process
+// running instead of starting one for every action.
+//
+// Methods mirror TaskManager and take named parameters; tasks are
+// referred to by "id", which also accepts a list index or a unique id
+// prefix. Whenever the task file changes, through this process or any
+// other, the server sends a "tasksChanged" notification.
+package rpc
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"taskmgr/internal/api"
+	"taskmgr/internal/jsonrpc"
+	"taskmgr/internal/tasks"
+)
+
+// Options control a server
+type Options struct {
+	// WatchFile is polled for changes, which are announced to the client
+	WatchFile string
+	// PollInterval is how often WatchFile is checked, by default every
+	// 500 milliseconds
+	PollInterval time.Duration
+}
+
+// Server answers requests for one TaskManager
+type Server struct {
+	manager *tasks.TaskManager
+	opts    Options
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// New creates a server for the given manager. Give it a store from
+// tasks.NewCachedFileStore so that requests do not parse the file again
+// while it is unchanged.
+func New(manager *tasks.TaskManager, opts Options) *Server {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 500 * time.Millisecond
+	}
+	return &Server{manager: manager, opts: opts, now: time.Now}
+}
+
+// Serve answers requests read from r on w until r is exhausted
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	conn := jsonrpc.NewConn(w)
+	if s.opts.WatchFile != "" {
+		watcher := tasks.NewWatcher(s.opts.WatchFile, s.opts.PollInterval)
+		defer watcher.Stop()
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				case <-watcher.C:
+					conn.Notify("tasksChanged", nil)
+				}
+			}
+		}()
+	}
+	return conn.Serve(r, s.handle)
+}
+
+// method handles the parameters of one method
+type method func(s *Server, params json.RawMessage) (interface{}, error)
+
+var methods = map[string]method{
+	"add":               (*Server).add,
+	"list":              (*Server).list,
+	"get":               (*Server).get,
+	"update":            (*Server).update,
+	"remove":            (*Server).remove,
+	"markDone":          (*Server).markDone,
+	"undoDone":          (*Server).undoDone,
+	"markAllDone":       (*Server).markAllDone,
+	"addTag":            (*Server).addTag,
+	"removeTag":         (*Server).removeTag,
+	"findByTitle":       (*Server).findByTitle,
+	"findByDescription": (*Server).findByDescription,
+	"countDone":         (*Server).countDone,
+	"getAllTags":        (*Server).getAllTags,
+	"getAllProjects":    (*Server).getAllProjects,
+	"history":           (*Server).history,
+	"stats":             (*Server).stats,
+}
+
+func (s *Server) handle(req *jsonrpc.Request) (interface{}, error) {
+	m, ok := methods[req.Method]
+	if !ok {
+		return nil, jsonrpc.Errorf(jsonrpc.MethodNotFound, "method not found: %s", req.Method)
+	}
+	return m(s, req.Params)
+}
+
+// invalid reports a request that could not be carried out as given
+func invalid(err error) error {
+	return jsonrpc.Errorf(jsonrpc.InvalidParams, "%v", err)
+}
+
+type refParams struct {
+	ID string `json:"id"`
+}
+
+type tagParams struct {
+	ID  string `json:"id"`
+	Tag string `json:"tag"`
+}
+
+// task reads a task back after a change
+func (s *Server) task(ref string) (interface{}, error) {
+	t, err := s.manager.Get(ref)
+	if err != nil {
+		return nil, err
+	}
+	return api.FromTask(t), nil
+}
+
+func fromTasks(list []tasks.Task) []api.Task {
+	result := []api.Task{}
+	for _, t := range list {
+		result = append(result, api.FromTask(t))
+	}
+	return result
+}
+
+func (s *Server) add(params json.RawMessage) (interface{}, error) {
+	var req api.CreateRequest
+	if err := jsonrpc.Unmarshal(params, &req); err != nil {
+		return nil, err
+	}
+	t, err := req.Task()
+	if err != nil {
+		return nil, invalid(err)
+	}
+	t.ID = tasks.NewID()
+	if err := s.manager.Add(t); err != nil {
+		return nil, err
+	}
+	return s.task(t.ID)
+}
+
+func (s *Server) list(params json.RawMessage) (interface{}, error) {
+	var req api.ListRequest
+	if err := jsonrpc.Unmarshal(params, &req); err != nil {
+		return nil, err
+	}
+	all, err := s.manager.Load()
+	if err != nil {
+		return nil, err
+	}
+	list, err := req.Select(all, s.now())
+	if err != nil {
+		return nil, invalid(err)
+	}
+	return list, nil
+}
+
+func (s *Server) get(params json.RawMessage) (interface{}, error) {
+	var p refParams
+	if err := jsonrpc.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	return s.task(p.ID)
+}
+
+func (s *Server) update(params json.RawMessage) (interface{}, error) {
+	var req struct {
+		ID string `json:"id"`
+		api.PatchRequest
+	}
+	if err := jsonrpc.Unmarshal(params, &req); err != nil {
+		return nil, err
+	}
+	t, err := s.manager.Get(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := req.Apply(t, s.now())
+	if err != nil {
+		return nil, invalid(err)
+	}
+	if err := s.manager.Update(t.ID, updated); err != nil {
+		return nil, err
+	}
+	return s.task(t.ID)
+}
+
+func (s *Server) remove(params json.RawMessage) (interface{}, error) {
+	var p refParams
+	if err := jsonrpc.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	t, err := s.manager.Get(p.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.manager.Remove(t.ID); err != nil {
+		return nil, err
+	}
+	return api.FromTask(t), nil
+}
+
+// change runs an action on the task named by the "id" parameter and
+// returns the task afterwards
+func (s *Server) change(params json.RawMessage, action func(id string) error) (interface{}, error) {
+	var p refParams
+	if err := jsonrpc.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	t, err := s.manager.Get(p.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := action(t.ID); err != nil {
+		return nil, err
+	}
+	return s.task(t.ID)
+}
+
+func (s *Server) markDone(params json.RawMessage) (interface{}, error) {
+	return s.change(params, s.manager.MarkDone)
+}
+
+func (s *Server) undoDone(params json.RawMessage) (interface{}, error) {
+	return s.change(params, s.manager.UndoDone)
+}
+
+func (s *Server) markAllDone(params json.RawMessage) (interface{}, error) {
+	if err := s.manager.MarkAllDone(); err != nil {
+		return nil, err
+	}
+	return fromTasks(s.manager.List()), nil
+}
+
+// tag runs a tag action with the "id" and "tag" parameters
+func (s *Server) tag(params json.RawMessage, action func(id, tag string) error) (interface{}, error) {
+	var p tagParams
+	if err := jsonrpc.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Tag == "" {
+		return nil, jsonrpc.Errorf(jsonrpc.InvalidParams, "tag is required")
+	}
+	t, err := s.manager.Get(p.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := action(t.ID, p.Tag); err != nil {
+		return nil, err
+	}
+	return s.task(t.ID)
+}
+
+func (s *Server) addTag(params json.RawMessage) (interface{}, error) {
+	return s.tag(params, s.manager.AddTagToTask)
+}
+
+func (s *Server) removeTag(params json.RawMessage) (interface{}, error) {
+	return s.tag(params, s.manager.RemoveTagFromTask)
+}
+
+// findByTitle returns the task with the given title, or null
+func (s *Server) findByTitle(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Title string `json:"title"`
+	}
+	if err := jsonrpc.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	if t := s.manager.FindByTitle(p.Title); t != nil {
+		return api.FromTask(*t), nil
+	}
+	return nil, nil
+}
+
+func (s *Server) findByDescription(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Description string `json:"description"`
+	}
+	if err := jsonrpc.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	return fromTasks(s.manager.FindByDescription(p.Description)), nil
+}
+
+func (s *Server) countDone(params json.RawMessage) (interface{}, error) {
+	return s.manager.CountDone(), nil
+}
+
+func (s *Server) getAllTags(params json.RawMessage) (interface{}, error) {
+	return nonNil(s.manager.GetAllTags()), nil
+}
+
+func (s *Server) getAllProjects(params json.RawMessage) (interface{}, error) {
+	return nonNil(s.manager.GetAllProjects()), nil
+}
+
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+func (s *Server) history(params json.RawMessage) (interface{}, error) {
+	var p refParams
+	if err := jsonrpc.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	t, changes, err := s.manager.History(p.ID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"task":    api.FromTask(t),
+		"changes": api.FromChanges(changes),
+	}, nil
+}
+
+func (s *Server) stats(params json.RawMessage) (interface{}, error) {
+	list, err := s.manager.Load()
+	if err != nil {
+		return nil, err
+	}
+	return api.NewStats(list), nil
+}
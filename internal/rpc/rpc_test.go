@@ -0,0 +1,260 @@
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"taskmgr/internal/api"
+	"taskmgr/internal/tasks"
+)
+
+func newTestServer(t *testing.T, opts Options) (*Server, *tasks.TaskManager, string) {
+	t.Helper()
+	testFile := filepath.Join(t.TempDir(), "tasks.json")
+	manager := tasks.NewTaskManager(tasks.NewCachedFileStore(testFile))
+	return New(manager, opts), manager, testFile
+}
+
+type reply struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// call sends one request and decodes its result into out, failing the
+// test on an error reply
+func call(t *testing.T, s *Server, method string, params, out interface{}) {
+	t.Helper()
+	r := send(t, s, method, params)
+	if r.Error != nil {
+		t.Fatalf("%s returned error %d: %s", method, r.Error.Code, r.Error.Message)
+	}
+	if out != nil {
+		if err := json.Unmarshal(r.Result, out); err != nil {
+			t.Fatalf("Error decoding %s result %s: %v", method, r.Result, err)
+		}
+	}
+}
+
+func send(t *testing.T, s *Server, method string, params interface{}) reply {
+	t.Helper()
+	data, err := json.Marshal(params)
+	if err != nil {
+		t.Fatalf("Error encoding params: %v", err)
+	}
+	in := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":%q,"params":%s}`+"\n", method, data)
+	var out bytes.Buffer
+	if err := s.Serve(strings.NewReader(in), &out); err != nil {
+		t.Fatalf("Serve returned an error: %v", err)
+	}
+	var r reply
+	if err := json.Unmarshal(out.Bytes(), &r); err != nil {
+		t.Fatalf("Invalid reply %q: %v", out.String(), err)
+	}
+	return r
+}
+
+// idPrefix returns a short prefix of id that cannot be read as an index
+func idPrefix(id string) string {
+	for n := 5; n < len(id); n++ {
+		if strings.IndexFunc(id[:n], func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+			return id[:n]
+		}
+	}
+	return id
+}
+
+func TestTaskMethods(t *testing.T) {
+	s, manager, _ := newTestServer(t, Options{})
+
+	var added api.Task
+	call(t, s, "add", map[string]interface{}{"title": "Plan sprint", "priority": "high", "tags": []string{"work"}}, &added)
+	if added.ID == "" || added.Priority != "high" {
+		t.Fatalf("Unexpected task %+v", added)
+	}
+	call(t, s, "add", map[string]interface{}{"title": "Water plants", "project": "home"}, nil)
+
+	var list []api.Task
+	call(t, s, "list", map[string]interface{}{"project": "home"}, &list)
+	if len(list) != 1 || list[0].Title != "Water plants" {
+		t.Errorf("Expected only 'Water plants', got %+v", list)
+	}
+	call(t, s, "list", nil, &list)
+	if len(list) != 2 {
+		t.Errorf("Expected 2 tasks without params, got %d", len(list))
+	}
+
+	var got api.Task
+	call(t, s, "get", map[string]string{"id": idPrefix(added.ID)}, &got)
+	if got.ID != added.ID {
+		t.Errorf("Expected task %s by prefix, got %s", added.ID, got.ID)
+	}
+
+	call(t, s, "update", map[string]interface{}{"id": added.ID, "description": "Two weeks", "due": "2025-07-10"}, &got)
+	if got.Description != "Two weeks" || got.Due == nil || got.Title != "Plan sprint" {
+		t.Errorf("Unexpected task after update %+v", got)
+	}
+
+	call(t, s, "addTag", map[string]string{"id": added.ID, "tag": "urgent"}, &got)
+	call(t, s, "removeTag", map[string]string{"id": added.ID, "tag": "work"}, &got)
+	if len(got.Tags) != 1 || got.Tags[0] != "urgent" {
+		t.Errorf("Expected tags [urgent], got %v", got.Tags)
+	}
+
+	call(t, s, "markDone", map[string]string{"id": added.ID}, &got)
+	if !got.Done {
+		t.Error("Expected the task to be done")
+	}
+	var count int
+	call(t, s, "countDone", nil, &count)
+	if count != 1 {
+		t.Errorf("Expected 1 done task, got %d", count)
+	}
+	call(t, s, "undoDone", map[string]string{"id": added.ID}, &got)
+	if got.Done {
+		t.Error("Expected the task to be open again")
+	}
+
+	var found *api.Task
+	call(t, s, "findByTitle", map[string]string{"title": "Water plants"}, &found)
+	if found == nil || found.Project != "home" {
+		t.Errorf("Expected to find 'Water plants', got %+v", found)
+	}
+	found = nil
+	call(t, s, "findByTitle", map[string]string{"title": "Nothing"}, &found)
+	if found != nil {
+		t.Errorf("Expected null for a missing title, got %+v", found)
+	}
+	call(t, s, "findByDescription", map[string]string{"description": "Two weeks"}, &list)
+	if len(list) != 1 || list[0].ID != added.ID {
+		t.Errorf("Expected the described task, got %+v", list)
+	}
+
+	var names []string
+	call(t, s, "getAllTags", nil, &names)
+	if len(names) != 1 || names[0] != "urgent" {
+		t.Errorf("Expected tags [urgent], got %v", names)
+	}
+	call(t, s, "getAllProjects", nil, &names)
+	if len(names) != 1 || names[0] != "home" {
+		t.Errorf("Expected projects [home], got %v", names)
+	}
+
+	var history struct {
+		Task    api.Task     `json:"task"`
+		Changes []api.Change `json:"changes"`
+	}
+	call(t, s, "history", map[string]string{"id": added.ID}, &history)
+	if history.Task.ID != added.ID || len(history.Changes) < 5 || history.Changes[0].Field != "created" {
+		t.Errorf("Unexpected history %+v", history)
+	}
+
+	var stats api.Stats
+	call(t, s, "stats", nil, &stats)
+	if stats.Total != 2 || stats.ByPriority["high"] != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+
+	call(t, s, "markAllDone", nil, &list)
+	if len(list) != 2 || !list[0].Done || !list[1].Done {
+		t.Errorf("Expected every task to be done, got %+v", list)
+	}
+
+	call(t, s, "remove", map[string]string{"id": added.ID}, &got)
+	if got.ID != added.ID || len(manager.List()) != 1 {
+		t.Errorf("Expected %s to be removed, got %+v and %d left", added.ID, got, len(manager.List()))
+	}
+}
+
+func TestErrors(t *testing.T) {
+	s, manager, _ := newTestServer(t, Options{})
+	manager.Add(tasks.Task{Title: "Only"})
+
+	tests := []struct {
+		method string
+		params interface{}
+		code   int
+	}{
+		{"explode", nil, -32601},
+		{"add", map[string]string{}, -32602},
+		{"add", map[string]int{"title": 5}, -32602},
+		{"list", map[string]string{"priority": "urgent"}, -32602},
+		{"update", map[string]string{"id": "0", "title": ""}, -32602},
+		{"addTag", map[string]string{"id": "0"}, -32602},
+		{"get", map[string]string{"id": "missing"}, -32000},
+		{"markDone", map[string]string{"id": "7"}, -32000},
+	}
+	for _, tt := range tests {
+		r := send(t, s, tt.method, tt.params)
+		if r.Error == nil || r.Error.Code != tt.code {
+			t.Errorf("%s %v: expected error %d, got %+v", tt.method, tt.params, tt.code, r.Error)
+		}
+	}
+}
+
+func TestTasksChangedNotification(t *testing.T) {
+	s, _, testFile := newTestServer(t, Options{PollInterval: 5 * time.Millisecond})
+	s.opts.WatchFile = testFile
+
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(inReader, outWriter)
+		outWriter.Close()
+	}()
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(outReader)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	next := func() string {
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for the server")
+			return ""
+		}
+	}
+
+	// Once a request is answered the file is being watched
+	fmt.Fprintln(inWriter, `{"jsonrpc":"2.0","id":1,"method":"countDone"}`)
+	if line := next(); line != `{"jsonrpc":"2.0","id":1,"result":0}` {
+		t.Fatalf("Unexpected reply %s", line)
+	}
+
+	// A change made by another process
+	if err := tasks.NewFileStore(testFile).Add(context.Background(), tasks.Task{ID: tasks.NewID(), Title: "Elsewhere"}); err != nil {
+		t.Fatalf("Add returned an error: %v", err)
+	}
+	if line := next(); line != `{"jsonrpc":"2.0","method":"tasksChanged"}` {
+		t.Errorf("Expected a tasksChanged notification, got %s", line)
+	}
+
+	// The cached store sees the new task
+	fmt.Fprintln(inWriter, `{"jsonrpc":"2.0","id":2,"method":"list"}`)
+	if line := next(); !strings.Contains(line, "Elsewhere") {
+		t.Errorf("Expected the list to include the new task, got %s", line)
+	}
+
+	inWriter.Close()
+	if err := <-served; err != nil {
+		t.Errorf("Serve returned an error: %v", err)
+	}
+}
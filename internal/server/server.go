@@ -0,0 +1,412 @@
+// Package server exposes a TaskManager over a local HTTP JSON API.
+//
+//	GET    /tasks                 list, filtered by priority, tag, project,
+//	                              overdue, due_today, due_within and done
+//	POST   /tasks                 create
+//	GET    /tasks/{id}            get
+//	PATCH  /tasks/{id}            update the fields present in the body
+//	DELETE /tasks/{id}            move to the trash
+//	POST   /tasks/{id}/complete   mark done
+//	DELETE /tasks/{id}/complete   mark not done
+//	PUT    /tasks/{id}/tags/{tag} add a tag
+//	DELETE /tasks/{id}/tags/{tag} remove a tag
+//	GET    /tags                  all tags
+//	GET    /stats                 progress statistics
+//
+// {id} is a task's full ID; list indices and ID prefixes, which the
+// command line takes, could name another task by the time a request
+// arrives. Task responses carry an ETag. Requests that change a task honour
+// If-Match, answering 412 Precondition Failed when the task changed since
+// the client read it, and list requests honour If-None-Match.
+//
+// Since any web page can send requests to a port on localhost, requests
+// must name a loopback host, or one allowed by AllowHost, in their Host
+// header, and requests other than GET must be sent as application/json,
+// which a page cannot do without the server's consent.
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"taskmgr/internal/api"
+	"taskmgr/internal/tasks"
+)
+
+// Server serves the API for one TaskManager
+type Server struct {
+	manager *tasks.TaskManager
+	mux     *http.ServeMux
+	// mu makes checking a precondition and applying a change atomic with
+	// respect to other requests
+	mu sync.Mutex
+	// now is replaced in tests
+	now func() time.Time
+	// hosts are the names besides loopback ones requests may be sent to
+	hosts map[string]bool
+}
+
+// New creates a server for the given manager
+func New(manager *tasks.TaskManager) *Server {
+	s := &Server{manager: manager, mux: http.NewServeMux(), now: time.Now, hosts: make(map[string]bool)}
+	s.mux.HandleFunc("GET /tasks", s.listTasks)
+	s.mux.HandleFunc("POST /tasks", s.createTask)
+	s.mux.HandleFunc("GET /tasks/{id}", s.getTask)
+	s.mux.HandleFunc("PATCH /tasks/{id}", s.patchTask)
+	s.mux.HandleFunc("DELETE /tasks/{id}", s.deleteTask)
+	s.mux.HandleFunc("POST /tasks/{id}/complete", s.completeTask)
+	s.mux.HandleFunc("DELETE /tasks/{id}/complete", s.reopenTask)
+	s.mux.HandleFunc("PUT /tasks/{id}/tags/{tag}", s.addTag)
+	s.mux.HandleFunc("DELETE /tasks/{id}/tags/{tag}", s.removeTag)
+	s.mux.HandleFunc("GET /tags", s.listTags)
+	s.mux.HandleFunc("GET /stats", s.stats)
+	return s
+}
+
+// AllowHost lets requests name host, such as the address the server
+// listens on when it is not a loopback one
+func (s *Server) AllowHost(host string) {
+	s.hosts[strings.ToLower(host)] = true
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.allowedHost(r.Host) {
+		writeError(w, http.StatusForbidden, "host %s is not allowed", r.Host)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		if media, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || media != "application/json" {
+			writeError(w, http.StatusUnsupportedMediaType, "requests that change tasks must be sent as application/json")
+			return
+		}
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// allowedHost reports whether a Host header names this server: a loopback
+// address, localhost, or a host given to AllowHost. Anything else is a
+// page that resolved its own name to the server's address.
+func (s *Server) allowedHost(hostport string) bool {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+	}
+	host = strings.ToLower(strings.Trim(host, "[]"))
+	if host == "localhost" || s.hosts[host] {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	writeJSON(w, status, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
+
+func writeTask(w http.ResponseWriter, status int, t tasks.Task) {
+	w.Header().Set("ETag", etag(t))
+	writeJSON(w, status, api.FromTask(t))
+}
+
+// etag returns a strong entity tag for a task: a hash of its JSON form, so
+// it changes whenever any visible field does
+func etag(t tasks.Task) string {
+	data, _ := json.Marshal(api.FromTask(t))
+	return hashTag(data)
+}
+
+func hashTag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// matchesETag reports whether an If-Match or If-None-Match header lists
+// the given tag, or is "*"
+func matchesETag(header, tag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == tag || strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeBody reads a JSON request body, rejecting unknown fields so that
+// typos are not silently ignored
+func decodeBody(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %v", err)
+	}
+	return nil
+}
+
+// managerFor returns the task manager for a request, whose store calls give
+// up once the client goes away
+func (s *Server) managerFor(r *http.Request) *tasks.TaskManager {
+	return s.manager.WithContext(r.Context())
+}
+
+// find returns the task with exactly the ID in the path. It writes the
+// error response itself and reports whether the task was found.
+func (s *Server) find(w http.ResponseWriter, r *http.Request) (tasks.Task, bool) {
+	list, err := s.managerFor(r).Load()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "reading tasks: %v", err)
+		return tasks.Task{}, false
+	}
+	id := r.PathValue("id")
+	for _, t := range list {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	writeError(w, http.StatusNotFound, "task %s not found", id)
+	return tasks.Task{}, false
+}
+
+// lookup finds the task named in the path and checks If-Match. It writes
+// the error response itself and reports whether the request can proceed.
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) (tasks.Task, bool) {
+	t, ok := s.find(w, r)
+	if !ok {
+		return t, false
+	}
+	if match := r.Header.Get("If-Match"); match != "" && !matchesETag(match, etag(t)) {
+		w.Header().Set("ETag", etag(t))
+		writeError(w, http.StatusPreconditionFailed, "task %s has changed", t.ID)
+		return t, false
+	}
+	return t, true
+}
+
+// respondWithTask writes the current state of a task after a change
+func (s *Server) respondWithTask(w http.ResponseWriter, r *http.Request, id string, status int) {
+	t, err := s.managerFor(r).Get(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "reading task back: %v", err)
+		return
+	}
+	writeTask(w, status, t)
+}
+
+// listRequest reads the list criteria from the query string
+func listRequest(r *http.Request) (api.ListRequest, error) {
+	q := r.URL.Query()
+	req := api.ListRequest{
+		Priority: q.Get("priority"),
+		Tag:      q.Get("tag"),
+		Project:  q.Get("project"),
+	}
+
+	flag := func(name string) (bool, error) {
+		if v := q.Get(name); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return false, fmt.Errorf("invalid %s value: %s", name, v)
+			}
+			return b, nil
+		}
+		return false, nil
+	}
+	var err error
+	if req.Overdue, err = flag("overdue"); err != nil {
+		return req, err
+	}
+	if req.DueToday, err = flag("due_today"); err != nil {
+		return req, err
+	}
+	if v := q.Get("due_within"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days <= 0 {
+			return req, fmt.Errorf("invalid due_within value: %s", v)
+		}
+		req.DueWithin = days
+	}
+	if q.Get("done") != "" {
+		done, err := flag("done")
+		if err != nil {
+			return req, err
+		}
+		req.Done = &done
+	}
+	return req, nil
+}
+
+func (s *Server) listTasks(w http.ResponseWriter, r *http.Request) {
+	req, err := listRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	list, err := s.managerFor(r).Load()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "reading tasks: %v", err)
+		return
+	}
+	result, err := req.Select(list, s.now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	tag := hashTag(body)
+	w.Header().Set("ETag", tag)
+	if match := r.Header.Get("If-None-Match"); match != "" && matchesETag(match, tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(body, '\n'))
+}
+
+func (s *Server) createTask(w http.ResponseWriter, r *http.Request) {
+	var req api.CreateRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	t, err := req.Task()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t.ID = tasks.NewID()
+	if err := s.managerFor(r).Add(t); err != nil {
+		writeError(w, http.StatusInternalServerError, "adding task: %v", err)
+		return
+	}
+	w.Header().Set("Location", "/tasks/"+t.ID)
+	s.respondWithTask(w, r, t.ID, http.StatusCreated)
+}
+
+func (s *Server) getTask(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.find(w, r)
+	if !ok {
+		return
+	}
+	if match := r.Header.Get("If-None-Match"); match != "" && matchesETag(match, etag(t)) {
+		w.Header().Set("ETag", etag(t))
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeTask(w, http.StatusOK, t)
+}
+
+func (s *Server) patchTask(w http.ResponseWriter, r *http.Request) {
+	var req api.PatchRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	updated, err := req.Apply(t, s.now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	if err := s.managerFor(r).Update(t.ID, updated); err != nil {
+		writeError(w, http.StatusInternalServerError, "updating task: %v", err)
+		return
+	}
+	s.respondWithTask(w, r, t.ID, http.StatusOK)
+}
+
+func (s *Server) deleteTask(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	if err := s.managerFor(r).Remove(t.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, "removing task: %v", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// change runs an action on the task named in the path and responds with
+// the task afterwards
+func (s *Server) change(w http.ResponseWriter, r *http.Request, action func(m *tasks.TaskManager, id string) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	if err := action(s.managerFor(r), t.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	s.respondWithTask(w, r, t.ID, http.StatusOK)
+}
+
+func (s *Server) completeTask(w http.ResponseWriter, r *http.Request) {
+	s.change(w, r, (*tasks.TaskManager).MarkDone)
+}
+
+func (s *Server) reopenTask(w http.ResponseWriter, r *http.Request) {
+	s.change(w, r, (*tasks.TaskManager).UndoDone)
+}
+
+func (s *Server) addTag(w http.ResponseWriter, r *http.Request) {
+	s.change(w, r, func(m *tasks.TaskManager, id string) error {
+		return m.AddTagToTask(id, r.PathValue("tag"))
+	})
+}
+
+func (s *Server) removeTag(w http.ResponseWriter, r *http.Request) {
+	s.change(w, r, func(m *tasks.TaskManager, id string) error {
+		return m.RemoveTagFromTask(id, r.PathValue("tag"))
+	})
+}
+
+func (s *Server) listTags(w http.ResponseWriter, r *http.Request) {
+	tags := s.managerFor(r).GetAllTags()
+	if tags == nil {
+		tags = []string{}
+	}
+	writeJSON(w, http.StatusOK, tags)
+}
+
+func (s *Server) stats(w http.ResponseWriter, r *http.Request) {
+	list, err := s.managerFor(r).Load()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "reading tasks: %v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, api.NewStats(list))
+}
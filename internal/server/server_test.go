@@ -0,0 +1,448 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"taskmgr/internal/api"
+	"taskmgr/internal/tasks"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, *tasks.TaskManager) {
+	t.Helper()
+	manager := tasks.NewTaskManager(tasks.NewFileStore(filepath.Join(t.TempDir(), "tasks.json")))
+	ts := httptest.NewServer(New(manager))
+	t.Cleanup(ts.Close)
+	return ts, manager
+}
+
+// do sends a request with an optional JSON body and headers given as
+// name, value pairs, and decodes a JSON response into out when it is set
+func do(t *testing.T, ts *httptest.Server, method, path string, body interface{}, out interface{}, headers ...string) *http.Response {
+	t.Helper()
+	var reader *bytes.Reader
+	if s, ok := body.(string); ok {
+		reader = bytes.NewReader([]byte(s))
+	} else if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Error encoding body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequest(method, ts.URL+path, reader)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	if method != "GET" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		if headers[i] == "Host" {
+			req.Host = headers[i+1]
+		}
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: error decoding response: %v", method, path, err)
+		}
+	}
+	return resp
+}
+
+func TestCreateAndGetTask(t *testing.T) {
+	ts, manager := newTestServer(t)
+
+	var created api.Task
+	resp := do(t, ts, "POST", "/tasks", map[string]interface{}{
+		"title":    "Write report",
+		"priority": "high",
+		"due":      "2025-07-10",
+		"tags":     []string{"work"},
+		"project":  "q3",
+	}, &created)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", resp.StatusCode)
+	}
+	if created.ID == "" || created.Title != "Write report" || created.Priority != "high" || created.Project != "q3" {
+		t.Errorf("Unexpected task: %+v", created)
+	}
+	if created.Due == nil || created.Due.Format("2006-01-02") != "2025-07-10" {
+		t.Errorf("Expected due 2025-07-10, got %v", created.Due)
+	}
+	if loc := resp.Header.Get("Location"); loc != "/tasks/"+created.ID {
+		t.Errorf("Expected Location /tasks/%s, got %q", created.ID, loc)
+	}
+	if len(manager.List()) != 1 {
+		t.Fatalf("Expected 1 stored task, got %d", len(manager.List()))
+	}
+
+	var got api.Task
+	resp = do(t, ts, "GET", "/tasks/"+created.ID, nil, &got)
+	if resp.StatusCode != http.StatusOK || got.ID != created.ID {
+		t.Errorf("Expected task %s, got %d %+v", created.ID, resp.StatusCode, got)
+	}
+	if resp.Header.Get("ETag") == "" {
+		t.Error("Expected an ETag")
+	}
+
+	resp = do(t, ts, "GET", "/tasks/"+created.ID, nil, nil, "If-None-Match", resp.Header.Get("ETag"))
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected 304 for a matching If-None-Match, got %d", resp.StatusCode)
+	}
+}
+
+func TestCreateTaskValidation(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"missing title", `{"priority": "high"}`},
+		{"bad priority", `{"title": "x", "priority": "urgent"}`},
+		{"bad due date", `{"title": "x", "due": "someday"}`},
+		{"unknown field", `{"title": "x", "colour": "red"}`},
+		{"not json", `title=x`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]string
+			resp := do(t, ts, "POST", "/tasks", tt.body, &body)
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("Expected 400, got %d", resp.StatusCode)
+			}
+			if body["error"] == "" {
+				t.Error("Expected an error message")
+			}
+		})
+	}
+}
+
+func TestGetUnknownTask(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	var body map[string]string
+	resp := do(t, ts, "GET", "/tasks/nope", nil, &body)
+	if resp.StatusCode != http.StatusNotFound || body["error"] == "" {
+		t.Errorf("Expected 404 with an error, got %d %v", resp.StatusCode, body)
+	}
+}
+
+func TestListTasksWithFilters(t *testing.T) {
+	ts, manager := newTestServer(t)
+
+	yesterday := time.Now().AddDate(0, 0, -1)
+	for _, task := range []tasks.Task{
+		{Title: "Overdue", Priority: tasks.High, DueDate: &yesterday, Tags: []string{"work"}},
+		{Title: "Home", Priority: tasks.Low, Project: "house"},
+		{Title: "Finished", Priority: tasks.High, Done: true},
+	} {
+		if err := manager.Add(task); err != nil {
+			t.Fatalf("Error adding task: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"Overdue", "Home", "Finished"}},
+		{"?priority=high", []string{"Overdue", "Finished"}},
+		{"?tag=work", []string{"Overdue"}},
+		{"?project=house", []string{"Home"}},
+		{"?overdue=true", []string{"Overdue"}},
+		{"?done=false", []string{"Overdue", "Home"}},
+		{"?priority=high&done=true", []string{"Finished"}},
+		{"?tag=none", []string{}},
+	}
+	for _, tt := range tests {
+		var list []api.Task
+		resp := do(t, ts, "GET", "/tasks"+tt.query, nil, &list)
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d", tt.query, resp.StatusCode)
+			continue
+		}
+		var titles []string
+		for _, task := range list {
+			titles = append(titles, task.Title)
+		}
+		if len(titles) != len(tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.query, tt.want, titles)
+			continue
+		}
+		for i := range titles {
+			if titles[i] != tt.want[i] {
+				t.Errorf("%s: expected %v, got %v", tt.query, tt.want, titles)
+				break
+			}
+		}
+	}
+
+	for _, query := range []string{"?priority=urgent", "?overdue=maybe", "?due_within=-1"} {
+		resp := do(t, ts, "GET", "/tasks"+query, nil, nil)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, resp.StatusCode)
+		}
+	}
+}
+
+func TestListETag(t *testing.T) {
+	ts, manager := newTestServer(t)
+	manager.Add(tasks.Task{Title: "One"})
+
+	resp := do(t, ts, "GET", "/tasks", nil, nil)
+	tag := resp.Header.Get("ETag")
+	resp = do(t, ts, "GET", "/tasks", nil, nil, "If-None-Match", tag)
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected 304, got %d", resp.StatusCode)
+	}
+
+	manager.Add(tasks.Task{Title: "Two"})
+	resp = do(t, ts, "GET", "/tasks", nil, nil, "If-None-Match", tag)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 after the list changed, got %d", resp.StatusCode)
+	}
+}
+
+func TestPatchTask(t *testing.T) {
+	ts, manager := newTestServer(t)
+	due := time.Date(2025, 7, 10, 0, 0, 0, 0, time.Local)
+	manager.Add(tasks.Task{Title: "Draft", Priority: tasks.Low, DueDate: &due, Tags: []string{"old"}})
+	id := manager.List()[0].ID
+
+	var updated api.Task
+	resp := do(t, ts, "PATCH", "/tasks/"+id, `{"title": "Final", "due": "", "tags": ["new", "doc"], "done": true}`, &updated)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	if updated.Title != "Final" || updated.Due != nil || !updated.Done || updated.CompletedAt == nil {
+		t.Errorf("Unexpected task after patch: %+v", updated)
+	}
+	if updated.Priority != "low" {
+		t.Errorf("Expected untouched priority low, got %s", updated.Priority)
+	}
+	stored := manager.List()[0]
+	if stored.Title != "Final" || !stored.HasTag("doc") || stored.HasTag("old") {
+		t.Errorf("Patch was not stored: %+v", stored)
+	}
+
+	resp = do(t, ts, "PATCH", "/tasks/"+id, `{"title": "  "}`, nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an empty title, got %d", resp.StatusCode)
+	}
+	resp = do(t, ts, "PATCH", "/tasks/nope", `{"title": "x"}`, nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestOptimisticConcurrency(t *testing.T) {
+	ts, manager := newTestServer(t)
+	manager.Add(tasks.Task{Title: "Shared"})
+	id := manager.List()[0].ID
+
+	resp := do(t, ts, "GET", "/tasks/"+id, nil, nil)
+	original := resp.Header.Get("ETag")
+
+	// The first writer holds the current tag and succeeds
+	resp = do(t, ts, "PATCH", "/tasks/"+id, `{"title": "Mine"}`, nil, "If-Match", original)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	current := resp.Header.Get("ETag")
+	if current == original {
+		t.Fatal("Expected the ETag to change with the task")
+	}
+
+	// The second writer read the task before that change
+	var body map[string]string
+	resp = do(t, ts, "PATCH", "/tasks/"+id, `{"title": "Theirs"}`, &body, "If-Match", original)
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Fatalf("Expected 412, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("ETag") != current || body["error"] == "" {
+		t.Errorf("Expected the current ETag and an error, got %q %v", resp.Header.Get("ETag"), body)
+	}
+	if manager.List()[0].Title != "Mine" {
+		t.Errorf("Expected the rejected patch not to be stored, got %q", manager.List()[0].Title)
+	}
+
+	resp = do(t, ts, "DELETE", "/tasks/"+id, nil, nil, "If-Match", original)
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for a stale delete, got %d", resp.StatusCode)
+	}
+	resp = do(t, ts, "POST", "/tasks/"+id+"/complete", nil, nil, "If-Match", original)
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for a stale complete, got %d", resp.StatusCode)
+	}
+
+	resp = do(t, ts, "DELETE", "/tasks/"+id, nil, nil, "If-Match", current)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", resp.StatusCode)
+	}
+	if len(manager.List()) != 0 {
+		t.Errorf("Expected the task to be removed, got %v", manager.List())
+	}
+}
+
+func TestCompleteAndTags(t *testing.T) {
+	ts, manager := newTestServer(t)
+	manager.Add(tasks.Task{Title: "Ship"})
+	id := manager.List()[0].ID
+
+	var task api.Task
+	resp := do(t, ts, "POST", "/tasks/"+id+"/complete", nil, &task)
+	if resp.StatusCode != http.StatusOK || !task.Done || task.CompletedAt == nil {
+		t.Errorf("Expected a completed task, got %d %+v", resp.StatusCode, task)
+	}
+	resp = do(t, ts, "DELETE", "/tasks/"+id+"/complete", nil, &task)
+	if resp.StatusCode != http.StatusOK || task.Done {
+		t.Errorf("Expected a reopened task, got %d %+v", resp.StatusCode, task)
+	}
+
+	resp = do(t, ts, "PUT", "/tasks/"+id+"/tags/release", nil, &task)
+	if resp.StatusCode != http.StatusOK || len(task.Tags) != 1 || task.Tags[0] != "release" {
+		t.Errorf("Expected tag release, got %d %v", resp.StatusCode, task.Tags)
+	}
+
+	var tags []string
+	do(t, ts, "GET", "/tags", nil, &tags)
+	if len(tags) != 1 || tags[0] != "release" {
+		t.Errorf("Expected tags [release], got %v", tags)
+	}
+
+	resp = do(t, ts, "DELETE", "/tasks/"+id+"/tags/release", nil, &task)
+	if resp.StatusCode != http.StatusOK || len(task.Tags) != 0 {
+		t.Errorf("Expected no tags, got %d %v", resp.StatusCode, task.Tags)
+	}
+}
+
+func TestStats(t *testing.T) {
+	ts, manager := newTestServer(t)
+	manager.Add(tasks.Task{Title: "One", Priority: tasks.High})
+	manager.Add(tasks.Task{Title: "Two", Priority: tasks.Low})
+	manager.MarkDone("0")
+
+	var stats api.Stats
+	resp := do(t, ts, "GET", "/stats", nil, &stats)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	if stats.Total != 2 || stats.Completed != 1 || stats.Pending != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if stats.ByPriority["high"] != 1 || stats.ByPriority["low"] != 1 || stats.ByPriority["critical"] != 0 {
+		t.Errorf("Unexpected priority counts: %v", stats.ByPriority)
+	}
+	if stats.LeadTimeSamples != 1 {
+		t.Errorf("Expected 1 lead time sample, got %d", stats.LeadTimeSamples)
+	}
+}
+
+func TestMatchesETag(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{`"abc"`, true},
+		{`"xyz", "abc"`, true},
+		{`W/"abc"`, true},
+		{`*`, true},
+		{`"xyz"`, false},
+	}
+	for _, tt := range tests {
+		if got := matchesETag(tt.header, `"abc"`); got != tt.want {
+			t.Errorf("matchesETag(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestTaskPathTakesExactID(t *testing.T) {
+	ts, manager := newTestServer(t)
+	manager.Add(tasks.Task{ID: "abc123", Title: "Keep me"})
+
+	for _, path := range []string{"/tasks/0", "/tasks/abc"} {
+		if resp := do(t, ts, "DELETE", path, nil, nil); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("DELETE %s: expected 404, got %d", path, resp.StatusCode)
+		}
+		if resp := do(t, ts, "GET", path, nil, nil); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("GET %s: expected 404, got %d", path, resp.StatusCode)
+		}
+	}
+	if list := manager.List(); len(list) != 1 {
+		t.Errorf("Expected the task kept, got %v", list)
+	}
+}
+
+func TestTaskLookupStoreError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	ts := httptest.NewServer(New(tasks.NewTaskManager(tasks.NewFileStore(path))))
+	t.Cleanup(ts.Close)
+	os.WriteFile(path, []byte("{not json"), 0644)
+
+	if resp := do(t, ts, "GET", "/tasks/abc123", nil, nil); resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected 500 for an unreadable store, got %d", resp.StatusCode)
+	}
+}
+
+func TestForgedRequests(t *testing.T) {
+	ts, manager := newTestServer(t)
+	manager.Add(tasks.Task{ID: "abc123", Title: "Keep me"})
+
+	// A form post from a web page
+	resp := do(t, ts, "POST", "/tasks/abc123/complete", nil, nil, "Content-Type", "text/plain")
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 for a request that is not JSON, got %d", resp.StatusCode)
+	}
+	// A page whose name was rebound to the loopback address
+	resp = do(t, ts, "DELETE", "/tasks/abc123", nil, nil, "Host", "evil.example:8080")
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for another host, got %d", resp.StatusCode)
+	}
+	if list := manager.List(); len(list) != 1 || list[0].Done {
+		t.Errorf("Expected the task left alone, got %v", list)
+	}
+
+	s := New(manager)
+	s.AllowHost("tasks.lan")
+	for host, want := range map[string]bool{"localhost:8080": true, "[::1]:8080": true, "127.0.0.2": true, "tasks.lan:8080": true, "evil.example": false} {
+		if got := s.allowedHost(host); got != want {
+			t.Errorf("allowedHost(%q) = %v, expected %v", host, got, want)
+		}
+	}
+}
+
+func TestRequestContextReachesStore(t *testing.T) {
+	manager := tasks.NewTaskManager(tasks.NewMemoryStore())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// A client that went away gets nothing written on its behalf
+	req := httptest.NewRequest("POST", "http://localhost/tasks", strings.NewReader(`{"title":"Too late"}`)).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	New(manager).ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "context canceled") {
+		t.Errorf("Expected the cancelled request to fail, got %d %s", rec.Code, rec.Body.String())
+	}
+	if list := manager.List(); len(list) != 0 {
+		t.Errorf("Expected no task added, got %v", list)
+	}
+}
@@ -0,0 +1,555 @@
+// Package sqlstore keeps tasks in an embedded SQLite database, a pure-Go
+// build of SQLite that needs no C compiler. Tasks, their tags and the
+// change log have tables of their own, with indexes for the filters list
+// commands use, so a change writes one row rather than the whole list.
+//
+// Importing the package registers the sqlite:// store backend:
+//
+//	sqlite://tasks.db
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"taskmgr/internal/tasks"
+
+	_ "modernc.org/sqlite"
+)
+
+func init() {
+	tasks.Register("sqlite", func(path string, options url.Values) (tasks.Store, error) {
+		if path == "" {
+			return nil, fmt.Errorf("no file given")
+		}
+		return Open(path)
+	})
+}
+
+// schemaVersion is stored in the database's user_version
+const schemaVersion = 4
+
+// upgrades bring a database from the version before each index up to the
+// next; a new database is created at schemaVersion
+var upgrades = map[int]string{
+	// The trash
+	2: `ALTER TABLE tasks ADD COLUMN deleted_at TEXT`,
+	// Notes
+	3: `ALTER TABLE tasks ADD COLUMN notes TEXT NOT NULL DEFAULT ''`,
+	// When a task was last unarchived
+	4: `ALTER TABLE tasks ADD COLUMN unarchived_at TEXT`,
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS tasks (
+	id            TEXT PRIMARY KEY,
+	position      INTEGER NOT NULL,
+	title         TEXT NOT NULL,
+	description   TEXT NOT NULL DEFAULT '',
+	notes         TEXT NOT NULL DEFAULT '',
+	done          INTEGER NOT NULL DEFAULT 0,
+	priority      INTEGER NOT NULL DEFAULT 1,
+	due           TEXT,
+	due_micros    INTEGER,
+	created_at    TEXT NOT NULL,
+	modified_at   TEXT NOT NULL,
+	completed_at  TEXT,
+	deleted_at    TEXT,
+	unarchived_at TEXT,
+	project       TEXT NOT NULL DEFAULT '',
+	parent_id     TEXT NOT NULL DEFAULT '',
+	extra         TEXT
+);
+CREATE UNIQUE INDEX IF NOT EXISTS tasks_position ON tasks (position);
+CREATE INDEX IF NOT EXISTS tasks_due ON tasks (due_micros);
+CREATE INDEX IF NOT EXISTS tasks_priority ON tasks (priority);
+CREATE INDEX IF NOT EXISTS tasks_project ON tasks (project COLLATE NOCASE);
+
+CREATE TABLE IF NOT EXISTS tags (
+	task_id  TEXT NOT NULL,
+	position INTEGER NOT NULL,
+	tag      TEXT NOT NULL,
+	PRIMARY KEY (task_id, position)
+);
+CREATE INDEX IF NOT EXISTS tags_tag ON tags (tag COLLATE NOCASE);
+
+CREATE TABLE IF NOT EXISTS history (
+	seq     INTEGER PRIMARY KEY AUTOINCREMENT,
+	task_id TEXT NOT NULL,
+	field   TEXT NOT NULL,
+	old     TEXT NOT NULL,
+	new     TEXT NOT NULL,
+	time    TEXT NOT NULL,
+	actor   TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS history_task ON history (task_id);
+`
+
+// Store is a tasks.Store in an SQLite database. It also implements
+// tasks.QueryStore and tasks.HistoryStore.
+type Store struct {
+	db       *sql.DB
+	filename string
+}
+
+// Open opens or creates the database in filename
+func Open(filename string) (*Store, error) {
+	// Other taskmgr processes may be writing; wait for them rather than
+	// fail at once
+	dsn := "file:" + filename + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// One connection serializes the store's own transactions
+	db.SetMaxOpenConns(1)
+
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if version > schemaVersion {
+		db.Close()
+		return nil, fmt.Errorf("%s has schema version %d; this taskmgr understands up to %d", filename, version, schemaVersion)
+	}
+	// A database without tables has user_version 0 and gets them all
+	var tables int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'tasks'").Scan(&tables); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if tables > 0 {
+		for v := version + 1; v <= schemaVersion; v++ {
+			if err := upgrade(db, v); err != nil {
+				db.Close()
+				return nil, fmt.Errorf("%s: upgrading to schema version %d: %v", filename, v, err)
+			}
+		}
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", schemaVersion)); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db, filename: filename}, nil
+}
+
+// upgrade runs upgrades[v] and sets user_version to v in one transaction,
+// so an upgrade that fails leaves the columns and the version as they were
+func upgrade(db *sql.DB, v int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(upgrades[v]); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", v)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Path returns the database file
+func (s *Store) Path() string {
+	return s.filename
+}
+
+// queryer is what *sql.DB and *sql.Tx have in common
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+const taskColumns = `id, title, description, notes, done, priority, due, created_at, modified_at, completed_at, deleted_at, unarchived_at, project, parent_id, extra`
+
+// selectTasks runs a query for taskColumns and fills in each task's tags
+func selectTasks(ctx context.Context, q queryer, where string, args ...interface{}) ([]tasks.Task, error) {
+	rows, err := q.QueryContext(ctx, "SELECT "+taskColumns+" FROM tasks "+where+" ORDER BY position", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []tasks.Task{}
+	index := make(map[string]int)
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		index[t.ID] = len(list)
+		list = append(list, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if len(list) == 0 {
+		return list, nil
+	}
+
+	tagRows, err := q.QueryContext(ctx, "SELECT task_id, tag FROM tags WHERE task_id IN (SELECT id FROM tasks "+where+") ORDER BY task_id, position", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer tagRows.Close()
+	for tagRows.Next() {
+		var id, tag string
+		if err := tagRows.Scan(&id, &tag); err != nil {
+			return nil, err
+		}
+		if i, ok := index[id]; ok {
+			list[i].Tags = append(list[i].Tags, tag)
+		}
+	}
+	return list, tagRows.Err()
+}
+
+func scanTask(rows *sql.Rows) (tasks.Task, error) {
+	var (
+		t                                          tasks.Task
+		done                                       bool
+		priority                                   int
+		due, completed, deleted, unarchived, extra sql.NullString
+		created, modified                          string
+	)
+	err := rows.Scan(&t.ID, &t.Title, &t.Description, &t.Notes, &done, &priority, &due, &created, &modified, &completed, &deleted, &unarchived, &t.Project, &t.ParentID, &extra)
+	if err != nil {
+		return t, err
+	}
+	t.Done = done
+	t.Priority = tasks.Priority(priority)
+	if t.DueDate, err = parseTime(due); err != nil {
+		return t, err
+	}
+	if t.CompletedAt, err = parseTime(completed); err != nil {
+		return t, err
+	}
+	if t.DeletedAt, err = parseTime(deleted); err != nil {
+		return t, err
+	}
+	if t.UnarchivedAt, err = parseTime(unarchived); err != nil {
+		return t, err
+	}
+	if t.CreatedAt, err = time.Parse(time.RFC3339Nano, created); err != nil {
+		return t, err
+	}
+	if t.ModifiedAt, err = time.Parse(time.RFC3339Nano, modified); err != nil {
+		return t, err
+	}
+	if extra.Valid {
+		if err := json.Unmarshal([]byte(extra.String), &t.Extra); err != nil {
+			return t, fmt.Errorf("task %s: %v", t.ID, err)
+		}
+	}
+	return t, nil
+}
+
+// Times are kept as RFC 3339 text so that their zone survives; due dates
+// also have a microsecond count for indexed range queries
+func formatTime(t *time.Time) sql.NullString {
+	if t == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: t.Format(time.RFC3339Nano), Valid: true}
+}
+
+func parseTime(s sql.NullString) (*time.Time, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func micros(t *time.Time) sql.NullInt64 {
+	if t == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: t.UnixMicro(), Valid: true}
+}
+
+func (s *Store) List(ctx context.Context) ([]tasks.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return selectTasks(ctx, s.db, "")
+}
+
+func (s *Store) Get(ctx context.Context, id string) (tasks.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return tasks.Task{}, err
+	}
+	return get(ctx, s.db, id)
+}
+
+func get(ctx context.Context, q queryer, id string) (tasks.Task, error) {
+	list, err := selectTasks(ctx, q, "WHERE id = ?", id)
+	if err != nil {
+		return tasks.Task{}, err
+	}
+	if len(list) == 0 {
+		return tasks.Task{}, fmt.Errorf("%w: %s", tasks.ErrNotFound, id)
+	}
+	return list[0], nil
+}
+
+func (s *Store) Add(ctx context.Context, t tasks.Task) error {
+	return s.Batch(ctx, func(tx tasks.Tx) error { return tx.Add(t) })
+}
+
+func (s *Store) Update(ctx context.Context, t tasks.Task) error {
+	return s.Batch(ctx, func(tx tasks.Tx) error { return tx.Update(t) })
+}
+
+func (s *Store) Remove(ctx context.Context, id string) error {
+	return s.Batch(ctx, func(tx tasks.Tx) error { return tx.Remove(id) })
+}
+
+// Batch runs fn in an SQL transaction
+func (s *Store) Batch(ctx context.Context, fn func(tx tasks.Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	sqlTx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer sqlTx.Rollback()
+
+	tx := &txn{ctx: ctx, tx: sqlTx}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	// Tx.List cannot return an error, so a failed List fails the batch
+	if tx.err != nil {
+		return tx.err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return sqlTx.Commit()
+}
+
+// txn is the tasks.Tx for a batch
+type txn struct {
+	ctx context.Context
+	tx  *sql.Tx
+	err error
+}
+
+func (x *txn) List() []tasks.Task {
+	list, err := selectTasks(x.ctx, x.tx, "")
+	if err != nil {
+		x.err = err
+	}
+	return list
+}
+
+func (x *txn) Get(id string) (tasks.Task, error) {
+	return get(x.ctx, x.tx, id)
+}
+
+func (x *txn) exists(id string) (bool, error) {
+	rows, err := x.tx.QueryContext(x.ctx, "SELECT 1 FROM tasks WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	return rows.Next(), rows.Err()
+}
+
+func (x *txn) Add(t tasks.Task) error {
+	if t.ID == "" {
+		return tasks.ErrMissingID
+	}
+	if found, err := x.exists(t.ID); err != nil {
+		return err
+	} else if found {
+		return fmt.Errorf("%w: %s", tasks.ErrDuplicateID, t.ID)
+	}
+	extra, err := formatExtra(t.Extra)
+	if err != nil {
+		return err
+	}
+	_, err = x.tx.ExecContext(x.ctx, `INSERT INTO tasks (`+taskColumns+`, due_micros, position)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, (SELECT COALESCE(MAX(position), 0) + 1 FROM tasks))`,
+		t.ID, t.Title, t.Description, t.Notes, t.Done, int(t.Priority), formatTime(t.DueDate),
+		t.CreatedAt.Format(time.RFC3339Nano), t.ModifiedAt.Format(time.RFC3339Nano), formatTime(t.CompletedAt),
+		formatTime(t.DeletedAt), formatTime(t.UnarchivedAt), t.Project, t.ParentID, extra, micros(t.DueDate))
+	if err != nil {
+		return err
+	}
+	return x.setTags(t.ID, t.Tags)
+}
+
+func (x *txn) Update(t tasks.Task) error {
+	extra, err := formatExtra(t.Extra)
+	if err != nil {
+		return err
+	}
+	result, err := x.tx.ExecContext(x.ctx, `UPDATE tasks SET title = ?, description = ?, notes = ?, done = ?, priority = ?,
+		due = ?, due_micros = ?, created_at = ?, modified_at = ?, completed_at = ?, deleted_at = ?, unarchived_at = ?, project = ?,
+		parent_id = ?, extra = ?
+		WHERE id = ?`,
+		t.Title, t.Description, t.Notes, t.Done, int(t.Priority), formatTime(t.DueDate), micros(t.DueDate),
+		t.CreatedAt.Format(time.RFC3339Nano), t.ModifiedAt.Format(time.RFC3339Nano), formatTime(t.CompletedAt),
+		formatTime(t.DeletedAt), formatTime(t.UnarchivedAt), t.Project, t.ParentID, extra, t.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("%w: %s", tasks.ErrNotFound, t.ID)
+	}
+	return x.setTags(t.ID, t.Tags)
+}
+
+func (x *txn) Remove(id string) error {
+	result, err := x.tx.ExecContext(x.ctx, "DELETE FROM tasks WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("%w: %s", tasks.ErrNotFound, id)
+	}
+	_, err = x.tx.ExecContext(x.ctx, "DELETE FROM tags WHERE task_id = ?", id)
+	return err
+}
+
+func (x *txn) setTags(id string, tags []string) error {
+	if _, err := x.tx.ExecContext(x.ctx, "DELETE FROM tags WHERE task_id = ?", id); err != nil {
+		return err
+	}
+	for i, tag := range tags {
+		if _, err := x.tx.ExecContext(x.ctx, "INSERT INTO tags (task_id, position, tag) VALUES (?, ?, ?)", id, i, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatExtra(extra map[string]json.RawMessage) (sql.NullString, error) {
+	if len(extra) == 0 {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(extra)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// Query implements tasks.QueryStore. The conditions select candidates
+// using the indexes, and Filter.Match then decides exactly. SQLite ignores
+// case only for ASCII letters, which is all tags and projects normally
+// hold.
+func (s *Store) Query(ctx context.Context, f tasks.Filter, now time.Time) ([]tasks.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var conds []string
+	var args []interface{}
+	if f.Priority != nil {
+		conds = append(conds, "priority = ?")
+		args = append(args, int(*f.Priority))
+	}
+	if f.Tag != "" {
+		conds = append(conds, "id IN (SELECT task_id FROM tags WHERE tag = ? COLLATE NOCASE)")
+		args = append(args, f.Tag)
+	}
+	if f.Project != "" {
+		conds = append(conds, "project = ? COLLATE NOCASE")
+		args = append(args, f.Project)
+	}
+	if f.Overdue {
+		conds = append(conds, "due_micros <= ? AND done = 0")
+		args = append(args, now.UnixMicro())
+	}
+	if f.DueToday {
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		conds = append(conds, "due_micros BETWEEN ? AND ?")
+		args = append(args, today.UnixMicro(), today.AddDate(0, 0, 1).UnixMicro())
+	}
+	if f.DueWithin > 0 {
+		conds = append(conds, "due_micros BETWEEN ? AND ?")
+		args = append(args, now.UnixMicro()-1, now.AddDate(0, 0, f.DueWithin).UnixMicro())
+	}
+
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+	candidates, err := selectTasks(ctx, s.db, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	var matched []tasks.Task
+	for _, t := range candidates {
+		if f.Match(t, now) {
+			matched = append(matched, t)
+		}
+	}
+	return matched, nil
+}
+
+// AppendChanges implements tasks.HistoryStore
+func (s *Store) AppendChanges(changes []tasks.Change) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, c := range changes {
+		_, err := tx.Exec("INSERT INTO history (task_id, field, old, new, time, actor) VALUES (?, ?, ?, ?, ?, ?)",
+			c.TaskID, c.Field, c.Old, c.New, c.Time.Format(time.RFC3339Nano), c.Actor)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Changes implements tasks.HistoryStore
+func (s *Store) Changes(taskID string) ([]tasks.Change, error) {
+	rows, err := s.db.Query("SELECT task_id, field, old, new, time, actor FROM history WHERE task_id = ? ORDER BY seq", taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var changes []tasks.Change
+	for rows.Next() {
+		var c tasks.Change
+		var at string
+		if err := rows.Scan(&c.TaskID, &c.Field, &c.Old, &c.New, &at, &c.Actor); err != nil {
+			return nil, err
+		}
+		if c.Time, err = time.Parse(time.RFC3339Nano, at); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
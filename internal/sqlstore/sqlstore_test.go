@@ -0,0 +1,248 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"taskmgr/internal/tasks"
+	"taskmgr/internal/tasks/storetest"
+)
+
+func openTemp(t *testing.T) (*Store, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open returned an error: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s, path
+}
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (tasks.Store, func() tasks.Store) {
+		s, path := openTemp(t)
+		return s, func() tasks.Store {
+			again, err := Open(path)
+			if err != nil {
+				t.Fatalf("Open returned an error: %v", err)
+			}
+			t.Cleanup(func() { again.Close() })
+			return again
+		}
+	})
+}
+
+func TestRegistered(t *testing.T) {
+	s, err := tasks.Open("sqlite://" + filepath.Join(t.TempDir(), "tasks.db"))
+	if err != nil {
+		t.Fatalf("Open returned an error: %v", err)
+	}
+	defer s.(*Store).Close()
+	if _, ok := s.(tasks.QueryStore); !ok {
+		t.Error("Expected the sqlite store to answer queries itself")
+	}
+}
+
+// TestQueryMatchesFilter checks that every filter selects the same tasks
+// from the database as matching in memory does
+func TestQueryMatchesFilter(t *testing.T) {
+	s, _ := openTemp(t)
+	memory := tasks.NewMemoryStore()
+	ctx := context.Background()
+
+	now := time.Date(2024, 6, 15, 14, 0, 0, 0, time.UTC)
+	day := func(n int) *time.Time {
+		d := now.AddDate(0, 0, n)
+		return &d
+	}
+	exact := now
+	for i, t := range []tasks.Task{
+		{Title: "Overdue", DueDate: day(-2), Priority: tasks.High, Tags: []string{"work"}},
+		{Title: "Overdue but done", DueDate: day(-1), Done: true, Tags: []string{"Work"}},
+		{Title: "Due now", DueDate: &exact, Project: "Home"},
+		{Title: "Due tomorrow", DueDate: day(1), Priority: tasks.High, Project: "home"},
+		{Title: "Due next week", DueDate: day(7), Tags: []string{"errand", "work"}},
+		{Title: "No date", Priority: tasks.Low, Tags: []string{"someday"}},
+	} {
+		t.ID = fmt.Sprint(i)
+		s.Add(ctx, t)
+		memory.Add(ctx, t)
+	}
+
+	high := tasks.High
+	for _, f := range []tasks.Filter{
+		{},
+		{Priority: &high},
+		{Tag: "work"},
+		{Tag: "WORK"},
+		{Project: "HOME"},
+		{Overdue: true},
+		{DueToday: true},
+		{DueWithin: 2},
+		{DueWithin: 10, Tag: "work"},
+		{Priority: &high, Overdue: true},
+	} {
+		got, err := s.Query(ctx, f, now)
+		if err != nil {
+			t.Fatalf("Query(%+v) returned an error: %v", f, err)
+		}
+		list, _ := memory.List(ctx)
+		var want []string
+		for _, t := range list {
+			if f.Match(t, now) {
+				want = append(want, t.Title)
+			}
+		}
+		var titles []string
+		for _, t := range got {
+			titles = append(titles, t.Title)
+		}
+		if fmt.Sprint(titles) != fmt.Sprint(want) {
+			t.Errorf("Query(%+v): expected %v, got %v", f, want, titles)
+		}
+	}
+}
+
+func TestQueriesUseIndexes(t *testing.T) {
+	s, _ := openTemp(t)
+	for query, index := range map[string]string{
+		"SELECT id FROM tasks WHERE priority = 2":                    "tasks_priority",
+		"SELECT id FROM tasks WHERE due_micros <= 5 AND done = 0":    "tasks_due",
+		"SELECT task_id FROM tags WHERE tag = 'work' COLLATE NOCASE": "tags_tag",
+		"SELECT id FROM tasks WHERE project = 'home' COLLATE NOCASE": "tasks_project",
+		"SELECT seq FROM history WHERE task_id = 'a'":                "history_task",
+	} {
+		rows, err := s.db.Query("EXPLAIN QUERY PLAN " + query)
+		if err != nil {
+			t.Fatalf("EXPLAIN %s: %v", query, err)
+		}
+		var plan []string
+		for rows.Next() {
+			var id, parent, unused int
+			var detail string
+			rows.Scan(&id, &parent, &unused, &detail)
+			plan = append(plan, detail)
+		}
+		rows.Close()
+		if !strings.Contains(strings.Join(plan, "; "), index) {
+			t.Errorf("Expected %s to use %s, got plan %v", query, index, plan)
+		}
+	}
+}
+
+func TestTaskManagerOnSQLite(t *testing.T) {
+	s, _ := openTemp(t)
+	manager := tasks.NewTaskManager(s)
+	manager.Add(tasks.Task{Title: "Write report", Priority: tasks.High, Tags: []string{"work"}})
+	manager.Add(tasks.Task{Title: "Buy milk", Tags: []string{"errand"}})
+	if err := manager.AddTagToTask("1", "urgent"); err != nil {
+		t.Fatalf("AddTagToTask returned an error: %v", err)
+	}
+	if err := manager.MarkDone("0"); err != nil {
+		t.Fatalf("MarkDone returned an error: %v", err)
+	}
+
+	if got := manager.ListByTag("urgent"); len(got) != 1 || got[0].Title != "Buy milk" {
+		t.Errorf("Expected Buy milk tagged urgent, got %v", got)
+	}
+	if got := manager.ListByPriority(tasks.High); len(got) != 1 || !got[0].Done {
+		t.Errorf("Expected the done high priority task, got %v", got)
+	}
+	task, changes, err := manager.History("0")
+	if err != nil || task.Title != "Write report" || len(changes) != 2 {
+		t.Errorf("Expected created and done changes, got %v, %v", changes, err)
+	}
+}
+
+func TestNewerSchemaRefused(t *testing.T) {
+	s, path := openTemp(t)
+	if _, err := s.db.Exec("PRAGMA user_version = 99"); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if _, err := Open(path); err == nil || !strings.Contains(err.Error(), "schema version 99") {
+		t.Errorf("Expected a schema version error, got %v", err)
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	s, path := openTemp(t)
+	// A database as version 1 left it, without the trash, notes and
+	// unarchive columns
+	v1 := strings.Replace(schema, "\tdeleted_at    TEXT,\n", "", 1)
+	v1 = strings.Replace(v1, "\tnotes         TEXT NOT NULL DEFAULT '',\n", "", 1)
+	v1 = strings.Replace(v1, "\tunarchived_at TEXT,\n", "", 1)
+	setup := []string{
+		"DROP TABLE tasks",
+		v1,
+		"INSERT INTO tasks (id, position, title, created_at, modified_at) VALUES ('a', 1, 'Old', '2024-01-01T00:00:00Z', '2024-01-01T00:00:00Z')",
+		"PRAGMA user_version = 1",
+	}
+	for _, stmt := range setup {
+		if _, err := s.db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	s.Close()
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open returned an error: %v", err)
+	}
+	defer s.Close()
+	manager := tasks.NewTaskManager(s)
+	if err := manager.Remove("a"); err != nil {
+		t.Fatalf("Remove returned an error: %v", err)
+	}
+	if trash, err := manager.ListTrash(); err != nil || len(trash) != 1 || trash[0].Title != "Old" {
+		t.Errorf("Expected the old task in the trash, got %v, %v", trash, err)
+	}
+	if err := manager.Add(tasks.Task{Title: "New", Notes: "Kept"}); err != nil {
+		t.Fatalf("Add returned an error: %v", err)
+	}
+	if got := manager.List(); len(got) != 1 || got[0].Notes != "Kept" {
+		t.Errorf("Expected notes kept, got %v", got)
+	}
+	var version int
+	s.db.QueryRow("PRAGMA user_version").Scan(&version)
+	if version != schemaVersion {
+		t.Errorf("Expected schema version %d, got %d", schemaVersion, version)
+	}
+}
+
+func TestFailedUpgradeKeepsVersion(t *testing.T) {
+	s, path := openTemp(t)
+	// Version 1 with the notes column already there, so upgrade 3 fails
+	// after upgrade 2 went through
+	v1 := strings.Replace(schema, "\tdeleted_at    TEXT,\n", "", 1)
+	v1 = strings.Replace(v1, "\tunarchived_at TEXT,\n", "", 1)
+	for _, stmt := range []string{"DROP TABLE tasks", v1, "PRAGMA user_version = 1"} {
+		if _, err := s.db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	s.Close()
+
+	if _, err := Open(path); err == nil || !strings.Contains(err.Error(), "schema version 3") {
+		t.Fatalf("Expected upgrade 3 to fail, got %v", err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var version int
+	db.QueryRow("PRAGMA user_version").Scan(&version)
+	if version != 2 {
+		t.Errorf("Expected the version of the last upgrade that went through, got %d", version)
+	}
+	if _, err := db.Exec("SELECT deleted_at FROM tasks"); err != nil {
+		t.Errorf("Expected upgrade 2's column kept: %v", err)
+	}
+}
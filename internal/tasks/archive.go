@@ -0,0 +1,155 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNoArchive is returned by archive methods of a manager without an
+// archive store
+var ErrNoArchive = errors.New("no archive store")
+
+// SetArchive sets the store completed tasks are moved to by ArchiveDone
+func (tm *TaskManager) SetArchive(s Store) {
+	tm.archive = s
+}
+
+// ArchiveStore returns the store archived tasks are kept in, if any
+func (tm *TaskManager) ArchiveStore() Store {
+	return tm.archive
+}
+
+// SetAutoArchive makes every change also archive the tasks completed
+// longer than after ago. Zero turns it off.
+func (tm *TaskManager) SetAutoArchive(after time.Duration) {
+	tm.autoArchive = after
+}
+
+// ListArchived returns every archived task
+func (tm *TaskManager) ListArchived() ([]Task, error) {
+	if tm.archive == nil {
+		return nil, ErrNoArchive
+	}
+	return tm.archive.List(tm.ctx)
+}
+
+// ArchiveDone moves the tasks completed longer than olderThan ago into the
+// archive store, and returns how many it moved. Tasks completed before
+// completion times were kept count from their last change, and tasks
+// unarchived since they were completed from when they were unarchived.
+func (tm *TaskManager) ArchiveDone(olderThan time.Duration) (int, error) {
+	if tm.archive == nil {
+		return 0, ErrNoArchive
+	}
+	tasks, err := tm.list()
+	if err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().Add(-olderThan)
+	var ms []mutation
+	for _, t := range tasks {
+		completed := t.ModifiedAt
+		if t.CompletedAt != nil {
+			completed = *t.CompletedAt
+		}
+		if t.UnarchivedAt != nil && t.UnarchivedAt.After(completed) {
+			completed = *t.UnarchivedAt
+		}
+		if t.Done && !completed.After(cutoff) {
+			ms = append(ms, mutation{
+				event:   Event{Type: EventArchived, Task: t, Time: time.Now()},
+				changes: []Change{{Field: "archived", Old: t.Title}},
+			})
+		}
+	}
+	return len(ms), tm.commit(ms...)
+}
+
+// Unarchive moves an archived task back into the task list. ref is an ID,
+// an index into the archive or a unique ID prefix. The time is kept, so
+// that auto-archiving leaves the task alone until it is old again.
+func (tm *TaskManager) Unarchive(ref string) (Task, error) {
+	archived, err := tm.ListArchived()
+	if err != nil {
+		return Task{}, err
+	}
+	idx, err := resolve(ref, archived)
+	if err != nil {
+		return Task{}, err
+	}
+	t := archived[idx]
+	now := time.Now()
+	t.UnarchivedAt = &now
+	return t, tm.commit(mutation{
+		event:   Event{Type: EventUnarchived, Task: t, Time: now},
+		changes: []Change{{Field: "unarchived", New: t.Title}},
+	})
+}
+
+// archiveMoved adds the tasks being archived to the archive store, before
+// they are removed from the task list. A task already there, left by an
+// archive that failed half way, is replaced.
+func (tm *TaskManager) archiveMoved(ms []mutation) error {
+	var moved []Task
+	for _, m := range ms {
+		if m.event.Type == EventArchived {
+			moved = append(moved, m.event.Task)
+		}
+	}
+	if len(moved) == 0 {
+		return nil
+	}
+	if tm.archive == nil {
+		return ErrNoArchive
+	}
+	return tm.archive.Batch(tm.ctx, func(tx Tx) error {
+		for _, t := range moved {
+			err := tx.Add(t)
+			if errors.Is(err, ErrDuplicateID) {
+				err = tx.Update(t)
+			}
+			if err != nil {
+				return fmt.Errorf("archiving %q: %w", t.Title, err)
+			}
+		}
+		return nil
+	})
+}
+
+// unarchiveMoved removes the tasks unarchived from the archive store, once
+// they are back in the task list
+func (tm *TaskManager) unarchiveMoved(ms []mutation) error {
+	var ids []string
+	for _, m := range ms {
+		if m.event.Type == EventUnarchived {
+			ids = append(ids, m.event.Task.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return tm.archive.Batch(tm.ctx, func(tx Tx) error {
+		for _, id := range ids {
+			if err := tx.Remove(id); err != nil && !errors.Is(err, ErrNotFound) {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// autoArchiveAfter archives old completed tasks after a change other than
+// archiving itself, when the manager is set to
+func (tm *TaskManager) autoArchiveAfter(ms []mutation) error {
+	if tm.autoArchive <= 0 || tm.archive == nil {
+		return nil
+	}
+	for _, m := range ms {
+		if m.event.Type == EventArchived || m.event.Type == EventUnarchived {
+			return nil
+		}
+	}
+	_, err := tm.ArchiveDone(tm.autoArchive)
+	return err
+}
@@ -0,0 +1,164 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// completedAgo returns a task completed the given time ago
+func completedAgo(id string, ago time.Duration) Task {
+	completed := time.Now().Add(-ago)
+	return Task{ID: id, Title: id, Done: true, CompletedAt: &completed}
+}
+
+func TestArchiveDone(t *testing.T) {
+	manager := NewTaskManager(NewMemoryStore())
+	if _, err := manager.ArchiveDone(0); !errors.Is(err, ErrNoArchive) {
+		t.Errorf("Expected ErrNoArchive without an archive store, got %v", err)
+	}
+	archive := NewMemoryStore()
+	manager.SetArchive(archive)
+
+	manager.Add(completedAgo("old", 40*24*time.Hour))
+	manager.Add(completedAgo("recent", time.Hour))
+	manager.Add(Task{ID: "open", Title: "open"})
+
+	var events []EventType
+	manager.Events().Subscribe(SubscriberFunc(func(e Event) error {
+		if !e.Pre {
+			events = append(events, e.Type)
+		}
+		return nil
+	}))
+
+	if moved, err := manager.ArchiveDone(30 * 24 * time.Hour); err != nil || moved != 1 {
+		t.Fatalf("Expected 1 task archived, got %d, %v", moved, err)
+	}
+	if list := manager.List(); len(list) != 2 || list[0].ID != "recent" {
+		t.Errorf("Expected the old task gone from the list, got %v", list)
+	}
+	if archived, _ := manager.ListArchived(); len(archived) != 1 || archived[0].ID != "old" {
+		t.Errorf("Expected the old task archived, got %v", archived)
+	}
+
+	manager.ArchiveDone(0)
+	if archived, _ := manager.ListArchived(); len(archived) != 2 {
+		t.Errorf("Expected every completed task archived, got %v", archived)
+	}
+
+	if task, err := manager.Unarchive("old"); err != nil || task.ID != "old" {
+		t.Fatalf("Unarchive returned %v, %v", task, err)
+	}
+	if _, err := manager.Get("old"); err != nil {
+		t.Errorf("Expected the unarchived task back in the list, got %v", err)
+	}
+	if archived, _ := manager.ListArchived(); len(archived) != 1 || archived[0].ID != "recent" {
+		t.Errorf("Expected only the other task left archived, got %v", archived)
+	}
+	want := []EventType{EventArchived, EventArchived, EventUnarchived}
+	if len(events) != len(want) || events[0] != want[0] || events[2] != want[2] {
+		t.Errorf("Expected events %v, got %v", want, events)
+	}
+}
+
+func TestArchiveVeto(t *testing.T) {
+	manager := NewTaskManager(NewMemoryStore())
+	archive := NewMemoryStore()
+	manager.SetArchive(archive)
+	manager.Add(completedAgo("done", time.Hour))
+	manager.Events().Subscribe(SubscriberFunc(func(e Event) error {
+		if e.Pre && e.Type == EventArchived {
+			return errors.New("keep everything")
+		}
+		return nil
+	}))
+
+	var veto *VetoError
+	if _, err := manager.ArchiveDone(0); !errors.As(err, &veto) {
+		t.Fatalf("Expected a veto, got %v", err)
+	}
+	if list, _ := archive.List(context.Background()); len(list) != 0 || len(manager.List()) != 1 {
+		t.Errorf("Expected a vetoed archive to move nothing, got %v archived", list)
+	}
+}
+
+func TestAutoArchive(t *testing.T) {
+	manager := NewTaskManager(NewMemoryStore())
+	manager.SetArchive(NewMemoryStore())
+	manager.SetAutoArchive(24 * time.Hour)
+
+	manager.Add(completedAgo("yesterday", 48*time.Hour))
+	manager.Add(completedAgo("today", time.Hour))
+	if list := manager.List(); len(list) != 1 || list[0].ID != "today" {
+		t.Errorf("Expected the task completed two days ago archived by the next change, got %v", list)
+	}
+	if archived, _ := manager.ListArchived(); len(archived) != 1 {
+		t.Errorf("Expected 1 archived task, got %v", archived)
+	}
+}
+
+func TestAutoArchiveKeepsUnarchived(t *testing.T) {
+	store := NewMemoryStore()
+	manager := NewTaskManager(store)
+	manager.SetArchive(NewMemoryStore())
+	manager.SetAutoArchive(24 * time.Hour)
+	manager.Add(completedAgo("old", 48*time.Hour))
+	manager.Add(Task{ID: "other", Title: "Other"})
+
+	if _, err := manager.Unarchive("old"); err != nil {
+		t.Fatalf("Unarchive returned an error: %v", err)
+	}
+	// The next change leaves the task that was just brought back
+	manager.Add(Task{ID: "new", Title: "New"})
+	if _, err := manager.Get("old"); err != nil {
+		t.Fatalf("Expected the unarchived task kept in the list, got %v", err)
+	}
+
+	// Once it is old again, it is archived again
+	old, _ := manager.Get("old")
+	dayAgo := time.Now().Add(-25 * time.Hour)
+	old.UnarchivedAt = &dayAgo
+	store.Update(context.Background(), old)
+	manager.Add(Task{ID: "newer", Title: "Newer"})
+	if _, err := manager.Get("old"); err == nil {
+		t.Error("Expected the task archived a day after it was unarchived")
+	}
+}
+
+func TestArchiveMatchesEncryption(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(filepath.Join(dir, "tasks.json"))
+	p := cheapKeyParams(t)
+	key, _ := DeriveKey([]byte("secret"), p)
+	if err := store.SetEncryption(&p, key); err != nil {
+		t.Fatal(err)
+	}
+	archive := NewFileStore(filepath.Join(dir, "tasks.archive.json"))
+	if err := archive.MatchEncryption(store); err != nil {
+		t.Fatalf("MatchEncryption returned an error: %v", err)
+	}
+	manager := NewTaskManager(store)
+	manager.SetArchive(archive)
+	manager.Add(completedAgo("Call ACME", time.Hour))
+	manager.ArchiveDone(0)
+
+	data, _ := os.ReadFile(archive.Path())
+	if len(data) == 0 || strings.Contains(string(data), "ACME") {
+		t.Errorf("Expected the archive encrypted like the task file, got %s", data)
+	}
+
+	if err := store.SetEncryption(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.MatchEncryption(store); err != nil {
+		t.Fatalf("MatchEncryption returned an error: %v", err)
+	}
+	if data, _ := os.ReadFile(archive.Path()); !strings.Contains(string(data), "ACME") {
+		t.Errorf("Expected the archive decrypted with the task file, got %s", data)
+	}
+}
@@ -0,0 +1,305 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BackupPolicy says how many copies of the task file a FileStore keeps
+type BackupPolicy struct {
+	// Keep is how many backups to keep; 0 turns backups off
+	Keep int
+	// MaxAge removes backups older than this as well; 0 keeps them
+	// however old they are
+	MaxAge time.Duration
+}
+
+// DefaultBackupPolicy keeps the last ten versions of the file
+var DefaultBackupPolicy = BackupPolicy{Keep: 10}
+
+// backupIDFormat names backups by when they were taken, in UTC, so that
+// they sort by name
+const backupIDFormat = "20060102T150405.000000Z"
+
+// Backup is a copy of the task file taken before it was rewritten
+type Backup struct {
+	ID   string
+	Time time.Time
+	Path string
+	Size int64
+}
+
+// SetBackupPolicy sets how many backups the store keeps
+func (s *FileStore) SetBackupPolicy(p BackupPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.backups = p
+}
+
+// BackupPolicy returns how many backups the store keeps
+func (s *FileStore) BackupPolicy() BackupPolicy {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.backups
+}
+
+// BackupDir returns the directory backups are kept in, next to the file
+func (s *FileStore) BackupDir() string {
+	return s.filename + ".backups"
+}
+
+// Backups lists the backups of the task file, newest first
+func (s *FileStore) Backups() ([]Backup, error) {
+	entries, err := os.ReadDir(s.BackupDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var backups []Backup
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || e.IsDir() {
+			continue
+		}
+		t, err := time.Parse(backupIDFormat, id)
+		if err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, Backup{
+			ID:   id,
+			Time: t,
+			Path: filepath.Join(s.BackupDir(), e.Name()),
+			Size: info.Size(),
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].ID > backups[j].ID })
+	return backups, nil
+}
+
+// FindBackup returns the backup whose ID is, or starts with, ref
+func (s *FileStore) FindBackup(ref string) (Backup, error) {
+	backups, err := s.Backups()
+	if err != nil {
+		return Backup{}, err
+	}
+	var found []Backup
+	for _, b := range backups {
+		if b.ID == ref {
+			return b, nil
+		}
+		if ref != "" && strings.HasPrefix(b.ID, ref) {
+			found = append(found, b)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Backup{}, fmt.Errorf("no backup %q", ref)
+	case 1:
+		return found[0], nil
+	default:
+		return Backup{}, fmt.Errorf("%q matches %d backups", ref, len(found))
+	}
+}
+
+// ReadBackup returns the tasks in a backup. An encrypted backup is opened
+// like the task file.
+func (s *FileStore) ReadBackup(b Backup) ([]Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Opening a backup must not change how the file is written
+	params, key := s.keyParams, s.key
+	tasks, err := s.readBackup(b)
+	s.keyParams, s.key = params, key
+	return tasks, err
+}
+
+// readBackup reads a backup, taking the key it is encrypted with as the
+// one the file is written with
+func (s *FileStore) readBackup(b Backup) ([]Task, error) {
+	data, err := os.ReadFile(b.Path)
+	if err != nil {
+		return nil, err
+	}
+	if f, ok := isEncrypted(data); ok {
+		if data, err = s.decrypt(f); err != nil {
+			return nil, err
+		}
+	}
+	tasks, version, err := decodeTasks(data)
+	if err != nil {
+		return nil, err
+	}
+	tasks, _ = migrate(tasks, version, b.Time)
+	return tasks, nil
+}
+
+// RestoreBackup writes the tasks in a backup over the task file without
+// reading the file, for when it is too damaged to read. The file is kept
+// as a backup itself. A file that can be read is better restored with
+// TaskManager.Replace, which records what the restore changed.
+func (s *FileStore) RestoreBackup(b Backup) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The file is written encrypted if it was, or if the backup is
+	params, key := s.keyParams, s.key
+	tasks, err := s.readBackup(b)
+	if params != nil || err != nil {
+		s.keyParams, s.key = params, key
+	}
+	if err != nil {
+		return err
+	}
+	return s.saveOverDamage(tasks)
+}
+
+// DiffBackup compares a backup with the tasks in the file now. Both hold
+// the tasks in the trash, so a task still there is not reported removed.
+func (s *FileStore) DiffBackup(b Backup) ([]TaskDiff, error) {
+	before, err := s.ReadBackup(b)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.List(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return DiffLists(before, after), nil
+}
+
+// backup copies the task file as it is on disk, encrypted or not, before
+// it is rewritten, and removes backups the policy no longer keeps
+func (s *FileStore) backup() error {
+	if s.backups.Keep <= 0 {
+		return nil
+	}
+	data, err := os.ReadFile(s.filename)
+	if os.IsNotExist(err) || len(data) == 0 {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	backups, err := s.Backups()
+	if err != nil {
+		return err
+	}
+	if len(backups) > 0 {
+		if last, err := os.ReadFile(backups[0].Path); err == nil && bytes.Equal(last, data) {
+			return nil
+		}
+	}
+
+	if err := os.MkdirAll(s.BackupDir(), 0700); err != nil {
+		return err
+	}
+	now := time.Now()
+	id := now.UTC().Format(backupIDFormat)
+	// Two writes within a microsecond, or a clock set back, still leave
+	// every backup in order
+	for len(backups) > 0 && id <= backups[0].ID {
+		now = now.Add(time.Microsecond)
+		id = now.UTC().Format(backupIDFormat)
+	}
+	path := filepath.Join(s.BackupDir(), id+".json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	backups = append([]Backup{{ID: id, Time: now, Path: path}}, backups...)
+	for i, b := range backups {
+		old := s.backups.MaxAge > 0 && now.Sub(b.Time) > s.backups.MaxAge
+		if i >= s.backups.Keep || (i > 0 && old) {
+			if err := os.Remove(b.Path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rewriteBackups writes every backup again encrypted with key and p, or
+// unencrypted if p is nil, so that backups are encrypted, or decrypted,
+// along with the file. oldKey opens backups encrypted before. Backups are
+// sealed as they are, damaged ones included, and none is written until
+// every one has been opened.
+func (s *FileStore) rewriteBackups(oldKey []byte, p *KeyParams, key []byte) error {
+	backups, err := s.Backups()
+	if err != nil {
+		return err
+	}
+	rewritten := make([][]byte, len(backups))
+	for i, b := range backups {
+		data, err := os.ReadFile(b.Path)
+		if err != nil {
+			return err
+		}
+		if f, ok := isEncrypted(data); ok {
+			if data, err = decryptTasks(f, oldKey); err != nil {
+				return fmt.Errorf("backup %s: %w", b.ID, err)
+			}
+		}
+		if p != nil {
+			if data, err = encryptTasks(data, *p, key); err != nil {
+				return err
+			}
+		}
+		rewritten[i] = data
+	}
+	for i, b := range backups {
+		if err := writeFileAtomic(b.Path, rewritten[i], 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backupOptions reads the backups=<count> and backup-age=<age> options
+func backupOptions(options url.Values) (BackupPolicy, error) {
+	p := DefaultBackupPolicy
+	if v := options.Get("backups"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return p, fmt.Errorf("option backups: expected a number of backups, got %q", v)
+		}
+		p.Keep = n
+	}
+	if v := options.Get("backup-age"); v != "" {
+		age, err := ParseAge(v)
+		if err != nil {
+			return p, fmt.Errorf("option backup-age: %v", err)
+		}
+		p.MaxAge = age
+	}
+	return p, nil
+}
+
+// ParseAge reads an age such as 30d, 12h or 90m
+func ParseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("expected an age such as 30d or 12h, got %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("expected an age such as 30d or 12h, got %q", s)
+	}
+	return d, nil
+}
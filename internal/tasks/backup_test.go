@@ -0,0 +1,245 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stamped returns a task as the task manager would store it, which the
+// file store has no reason to rewrite when it reads it back
+func stamped(id, title string) Task {
+	now := time.Now()
+	return Task{ID: id, Title: title, CreatedAt: now, ModifiedAt: now}
+}
+
+func TestFileStoreBackups(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	store := NewFileStore(path)
+	store.SetBackupPolicy(BackupPolicy{Keep: 2})
+
+	// The first write has nothing to back up
+	store.Add(ctx, stamped("a", "A"))
+	if backups, _ := store.Backups(); len(backups) != 0 {
+		t.Errorf("Expected no backups of a new file, got %v", backups)
+	}
+	store.Add(ctx, stamped("b", "B"))
+	store.Add(ctx, stamped("c", "C"))
+	store.Remove(ctx, "a")
+
+	backups, err := store.Backups()
+	if err != nil {
+		t.Fatalf("Backups returned an error: %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("Expected the newest 2 backups to be kept, got %v", backups)
+	}
+	if !backups[0].Time.After(backups[1].Time) {
+		t.Errorf("Expected the newest backup first, got %v", backups)
+	}
+	newest, err := store.ReadBackup(backups[0])
+	if err != nil || len(newest) != 3 {
+		t.Errorf("Expected the newest backup to hold the 3 tasks before the removal, got %v, %v", newest, err)
+	}
+	older, _ := store.ReadBackup(backups[1])
+	if len(older) != 2 {
+		t.Errorf("Expected the older backup to hold 2 tasks, got %v", older)
+	}
+
+	if b, err := store.FindBackup(backups[1].ID[:len(backups[1].ID)-3]); err != nil || b.ID != backups[1].ID {
+		t.Errorf("Expected a unique prefix to find the backup, got %v, %v", b, err)
+	}
+	if _, err := store.FindBackup("1999"); err == nil {
+		t.Error("Expected an error for an unknown backup")
+	}
+
+	// Nothing is backed up with backups off
+	store.SetBackupPolicy(BackupPolicy{})
+	store.Add(ctx, stamped("d", "D"))
+	if after, _ := store.Backups(); after[0].ID != backups[0].ID {
+		t.Errorf("Expected no new backup, got %v", after)
+	}
+}
+
+func TestFileStoreBackupAge(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	store := NewFileStore(path)
+	store.SetBackupPolicy(BackupPolicy{Keep: 10, MaxAge: 24 * time.Hour})
+	store.Add(ctx, stamped("a", "A"))
+
+	os.MkdirAll(store.BackupDir(), 0700)
+	stale := time.Now().Add(-48 * time.Hour).UTC().Format(backupIDFormat)
+	os.WriteFile(filepath.Join(store.BackupDir(), stale+".json"), []byte("[]"), 0600)
+
+	store.Add(ctx, stamped("b", "B"))
+	backups, _ := store.Backups()
+	if len(backups) != 1 || backups[0].ID == stale {
+		t.Errorf("Expected the stale backup to be removed, got %v", backups)
+	}
+}
+
+func TestEncryptedBackups(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	store := NewFileStore(path)
+	store.Add(ctx, stamped("acme", "Call ACME"))
+	store.Add(ctx, stamped("globex", "Email Globex"))
+
+	p := cheapKeyParams(t)
+	key, _ := DeriveKey([]byte("secret"), p)
+	if err := store.SetEncryption(&p, key); err != nil {
+		t.Fatalf("SetEncryption returned an error: %v", err)
+	}
+	store.Remove(ctx, "acme")
+
+	backups, _ := store.Backups()
+	if len(backups) != 3 {
+		t.Fatalf("Expected 3 backups, got %v", backups)
+	}
+	for _, b := range backups {
+		data, _ := os.ReadFile(b.Path)
+		if strings.Contains(string(data), "ACME") {
+			t.Errorf("Expected backup %s to be encrypted, got %s", b.ID, data)
+		}
+	}
+
+	reopened := NewFileStore(path)
+	reopened.SetKeyring(&testKeyring{secret: "secret"})
+	list, err := reopened.ReadBackup(backups[0])
+	if err != nil || len(list) != 2 || list[0].Title != "Call ACME" {
+		t.Errorf("Expected the backup before the removal, got %v, %v", list, err)
+	}
+	if _, err := NewFileStore(path).ReadBackup(backups[0]); err == nil {
+		t.Error("Expected an encrypted backup to need the key")
+	}
+}
+
+func TestEncryptDamagedBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	damaged := []byte(`[{"ID": "acme", "Title": "Call ACME"}, {"ID": "b", "Ti`)
+	os.WriteFile(path, damaged, 0644)
+
+	// Repairing keeps the damaged file as a backup
+	store := NewFileStore(path)
+	if _, err := store.Repair(); err != nil {
+		t.Fatalf("Repair returned an error: %v", err)
+	}
+
+	p := cheapKeyParams(t)
+	key, _ := DeriveKey([]byte("secret"), p)
+	if err := store.SetEncryption(&p, key); err != nil {
+		t.Fatalf("SetEncryption returned an error: %v", err)
+	}
+	backups, _ := store.Backups()
+	if len(backups) != 2 {
+		t.Fatalf("Expected 2 backups, got %v", backups)
+	}
+	for _, b := range backups {
+		data, _ := os.ReadFile(b.Path)
+		if strings.Contains(string(data), "ACME") {
+			t.Errorf("Expected backup %s to be encrypted, got %s", b.ID, data)
+		}
+	}
+
+	// Decrypting brings the damaged file back as it was
+	if err := store.SetEncryption(nil, nil); err != nil {
+		t.Fatalf("SetEncryption returned an error: %v", err)
+	}
+	if data, _ := os.ReadFile(backups[len(backups)-1].Path); !bytes.Equal(data, damaged) {
+		t.Errorf("Expected the damaged backup decrypted as it was, got %s", data)
+	}
+}
+
+func TestRestoreBackupOverDamage(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	store := NewFileStore(path)
+	store.Add(ctx, stamped("acme", "Call ACME"))
+	store.Add(ctx, stamped("globex", "Email Globex"))
+	p := cheapKeyParams(t)
+	key, _ := DeriveKey([]byte("secret"), p)
+	store.SetEncryption(&p, key)
+
+	damaged := []byte(`[{"ID": "acme", "Ti`)
+	os.WriteFile(path, damaged, 0600)
+	reopened := NewFileStore(path)
+	reopened.SetKeyring(&testKeyring{secret: "secret"})
+	if _, _, _, err := NewTaskManager(reopened).Replace(nil); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("Expected the damaged file to stop a replace, got %v", err)
+	}
+
+	// The newest backup is the encrypted file with both tasks
+	backups, _ := reopened.Backups()
+	reopened.SetBackupPolicy(BackupPolicy{})
+	if err := reopened.RestoreBackup(backups[0]); err != nil {
+		t.Fatalf("RestoreBackup returned an error: %v", err)
+	}
+	if list := mustList(t, reopened); len(list) != 2 || list[0].Title != "Call ACME" {
+		t.Errorf("Expected the tasks in the backup, got %v", list)
+	}
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "ACME") {
+		t.Errorf("Expected the restored file encrypted as the backup was, got %s", data)
+	}
+
+	// The damaged file is kept even with backups turned off
+	after, _ := reopened.Backups()
+	if data, _ := os.ReadFile(after[0].Path); len(after) != len(backups)+1 || !bytes.Equal(data, damaged) {
+		t.Errorf("Expected the damaged file backed up, got %v", after)
+	}
+}
+
+func TestDiffLists(t *testing.T) {
+	before := []Task{{ID: "a", Title: "A"}, {ID: "b", Title: "B"}, {ID: "c", Title: "C"}}
+	after := []Task{{ID: "a", Title: "A"}, {ID: "c", Title: "C", Done: true}, {ID: "d", Title: "D"}}
+
+	diffs := DiffLists(before, after)
+	var got []string
+	for _, d := range diffs {
+		got = append(got, d.Kind+" "+d.Task.ID)
+	}
+	if want := "changed c, added d, removed b"; strings.Join(got, ", ") != want {
+		t.Errorf("Expected %s, got %s", want, strings.Join(got, ", "))
+	}
+	if len(diffs[0].Changes) != 1 || diffs[0].Changes[0].Field != "done" {
+		t.Errorf("Expected c's done field to have changed, got %v", diffs[0].Changes)
+	}
+}
+
+func TestDiffBackupWithTrash(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "tasks.json"))
+	manager := NewTaskManager(store)
+	manager.Add(Task{ID: "a", Title: "A"})
+	manager.Add(Task{ID: "b", Title: "B"})
+	manager.Remove("a")
+	manager.Add(Task{ID: "c", Title: "C"})
+
+	backups, err := store.Backups()
+	if err != nil || len(backups) != 3 {
+		t.Fatalf("Expected 3 backups, got %v, %v", backups, err)
+	}
+	describe := func(b Backup) string {
+		diffs, err := store.DiffBackup(b)
+		if err != nil {
+			t.Fatalf("DiffBackup returned an error: %v", err)
+		}
+		var got []string
+		for _, d := range diffs {
+			got = append(got, d.Kind+" "+d.Task.ID)
+		}
+		return strings.Join(got, ", ")
+	}
+	// The task already in the trash then is not reported again
+	if got := describe(backups[0]); got != "added c" {
+		t.Errorf("Expected only c added since the newest backup, got %s", got)
+	}
+	if got := describe(backups[1]); got != "trashed a, added c" {
+		t.Errorf("Expected a trashed and c added, got %s", got)
+	}
+}
@@ -0,0 +1,520 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	Register("crdt", func(path string, options url.Values) (Store, error) {
+		if err := requirePath(path); err != nil {
+			return nil, err
+		}
+		return NewCRDTStore(path), nil
+	})
+}
+
+// CRDTStore keeps tasks as a set of operations, each setting one field of
+// one task and stamped with the time and the replica that made it. A field
+// holds the value of the latest operation on it, and tags and extra
+// attributes are fields of their own, so copies of a store changed apart
+// from each other merge by taking the union of their operations: in any
+// order, any number of times, with the same result and nothing to resolve
+// by hand. A task removed on one replica stays removed, whatever the others
+// changed in the meantime.
+//
+// The file is append-only, one JSON line per batch as in the journal
+// store. Its first line names the replica that writes to it.
+type CRDTStore struct {
+	filename string
+	mu       sync.Mutex
+	replica  string
+	// clock is the last time stamped, kept past every operation seen so
+	// that new operations come after them
+	clock int64
+}
+
+// Stamp orders operations: by time, then by replica, so that every replica
+// agrees on which of two operations came last
+type Stamp struct {
+	Time    int64  `json:"time"`
+	Replica string `json:"replica"`
+}
+
+// Before reports whether s orders before o
+func (s Stamp) Before(o Stamp) bool {
+	if s.Time != o.Time {
+		return s.Time < o.Time
+	}
+	return s.Replica < o.Replica
+}
+
+// CRDTOp sets one field of one task. Field is "exists", which is false
+// once the task is removed; a Task field such as "title" or "due"; or
+// "tag:" or "extra:" followed by the tag, in lower case, or the attribute
+// name. A null value unsets a tag or attribute.
+type CRDTOp struct {
+	Stamp
+	Task  string          `json:"task"`
+	Field string          `json:"field"`
+	Value json.RawMessage `json:"value"`
+}
+
+// crdtLine is one line of the file: the replica header, or a batch
+type crdtLine struct {
+	Replica string   `json:"replica,omitempty"`
+	Ops     []CRDTOp `json:"ops,omitempty"`
+}
+
+func NewCRDTStore(filename string) *CRDTStore {
+	return &CRDTStore{filename: filename}
+}
+
+// Path returns the operation file
+func (s *CRDTStore) Path() string {
+	return s.filename
+}
+
+// Replica returns the ID stamped on this store's operations, creating it
+// if the store has none yet
+func (s *CRDTStore) Replica() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.load(); err != nil {
+		return "", err
+	}
+	return s.replica, s.ensureReplica()
+}
+
+func (s *CRDTStore) List(ctx context.Context) ([]Task, error) {
+	return batchList(ctx, s)
+}
+
+func (s *CRDTStore) Get(ctx context.Context, id string) (Task, error) {
+	return batchGet(ctx, s, id)
+}
+
+func (s *CRDTStore) Add(ctx context.Context, t Task) error {
+	return batchAdd(ctx, s, t)
+}
+
+func (s *CRDTStore) Update(ctx context.Context, t Task) error {
+	return batchUpdate(ctx, s, t)
+}
+
+func (s *CRDTStore) Remove(ctx context.Context, id string) error {
+	return batchRemove(ctx, s, id)
+}
+
+// Batch runs fn on the current tasks and appends one operation for every
+// field it changed
+func (s *CRDTStore) Batch(ctx context.Context, fn func(tx Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	state, err := s.load()
+	if err != nil {
+		return err
+	}
+	before := state.tasks()
+	tx := &txn{tasks: cloneTasks(before)}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if !tx.dirty {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := s.ensureReplica(); err != nil {
+		return err
+	}
+
+	var ops []CRDTOp
+	set := func(id, field string, value json.RawMessage) {
+		ops = append(ops, CRDTOp{Stamp: s.stamp(), Task: id, Field: field, Value: value})
+	}
+	kept := make(map[string]bool)
+	for _, t := range tx.tasks {
+		kept[t.ID] = true
+		old, removed := state.task(t.ID)
+		if removed {
+			// Added, or added again after being removed
+			set(t.ID, "exists", json.RawMessage("true"))
+		}
+		oldFields := make(map[string]json.RawMessage)
+		for _, f := range crdtFields(old) {
+			oldFields[f.name] = f.value
+		}
+		for _, f := range crdtFields(t) {
+			if previous, ok := oldFields[f.name]; removed || !ok || !bytes.Equal(previous, f.value) {
+				set(t.ID, f.name, f.value)
+			}
+			delete(oldFields, f.name)
+		}
+		// Tags and attributes the task no longer has
+		var unset []string
+		for name := range oldFields {
+			unset = append(unset, name)
+		}
+		sort.Strings(unset)
+		for _, name := range unset {
+			set(t.ID, name, json.RawMessage("null"))
+		}
+	}
+	for _, t := range before {
+		if !kept[t.ID] {
+			set(t.ID, "exists", json.RawMessage("false"))
+		}
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+	return appendLine(s.filename, crdtLine{Ops: ops})
+}
+
+// Ops returns every operation in the store
+func (s *CRDTStore) Ops() ([]CRDTOp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return state.ops, nil
+}
+
+// Merge adds the operations of another CRDT store that this one lacks, and
+// returns how many there were. Merging never fails on conflicting changes:
+// each field keeps its latest value.
+func (s *CRDTStore) Merge(ctx context.Context, other *CRDTStore) (int, error) {
+	theirs, err := other.Ops()
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	state, err := s.load()
+	if err != nil {
+		return 0, err
+	}
+	var missing []CRDTOp
+	for _, op := range theirs {
+		if state.apply(op) {
+			missing = append(missing, op)
+		}
+	}
+	if len(missing) == 0 {
+		return 0, nil
+	}
+	if err := s.ensureReplica(); err != nil {
+		return 0, err
+	}
+	if err := appendLine(s.filename, crdtLine{Ops: missing}); err != nil {
+		return 0, err
+	}
+	return len(missing), nil
+}
+
+// stamp returns a stamp later than any this store has made or seen
+func (s *CRDTStore) stamp() Stamp {
+	now := time.Now().UnixNano()
+	if now <= s.clock {
+		now = s.clock + 1
+	}
+	s.clock = now
+	return Stamp{Time: now, Replica: s.replica}
+}
+
+// ensureReplica writes the replica header to a store that has none
+func (s *CRDTStore) ensureReplica() error {
+	if s.replica != "" {
+		return nil
+	}
+	s.replica = NewID()
+	return appendLine(s.filename, crdtLine{Replica: s.replica})
+}
+
+// load reads every operation in the file, and the replica ID. A final line
+// without a newline is a write that was cut short and is skipped.
+func (s *CRDTStore) load() (*crdtState, error) {
+	state := newCRDTState()
+	data, err := os.ReadFile(s.filename)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for line := 1; len(data) > 0; line++ {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			break
+		}
+		raw := data[:end]
+		data = data[end+1:]
+		if len(bytes.TrimSpace(raw)) == 0 {
+			continue
+		}
+		var entry crdtLine
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return nil, fmt.Errorf("%s line %d: %v", s.filename, line, err)
+		}
+		if entry.Replica != "" && s.replica == "" {
+			s.replica = entry.Replica
+		}
+		for _, op := range entry.Ops {
+			state.apply(op)
+			if op.Time > s.clock {
+				s.clock = op.Time
+			}
+		}
+	}
+	if err := state.check(); err != nil {
+		return nil, fmt.Errorf("%s: %v", s.filename, err)
+	}
+	return state, nil
+}
+
+// crdtState folds operations into the latest one on every field
+type crdtState struct {
+	ops    []CRDTOp
+	seen   map[crdtKey]bool
+	fields map[string]map[string]CRDTOp
+}
+
+type crdtKey struct {
+	Stamp
+	task, field string
+}
+
+func newCRDTState() *crdtState {
+	return &crdtState{seen: make(map[crdtKey]bool), fields: make(map[string]map[string]CRDTOp)}
+}
+
+// apply adds an operation, reporting false if it was already applied
+func (st *crdtState) apply(op CRDTOp) bool {
+	key := crdtKey{op.Stamp, op.Task, op.Field}
+	if st.seen[key] {
+		return false
+	}
+	st.seen[key] = true
+	st.ops = append(st.ops, op)
+	fields := st.fields[op.Task]
+	if fields == nil {
+		fields = make(map[string]CRDTOp)
+		st.fields[op.Task] = fields
+	}
+	if current, ok := fields[op.Field]; !ok || laterOp(op, current) {
+		fields[op.Field] = op
+	}
+	return true
+}
+
+// laterOp orders operations by stamp, and operations with the same stamp,
+// which only a damaged file holds, by value
+func laterOp(a, b CRDTOp) bool {
+	if a.Stamp != b.Stamp {
+		return b.Stamp.Before(a.Stamp)
+	}
+	return bytes.Compare(a.Value, b.Value) > 0
+}
+
+// check decodes every task, so that damage is found when loading
+func (st *crdtState) check() error {
+	for id := range st.fields {
+		if _, err := decodeCRDTTask(id, st.fields[id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// task returns the fields last set on a task, and whether it is absent:
+// never added, or removed
+func (st *crdtState) task(id string) (Task, bool) {
+	fields, ok := st.fields[id]
+	if !ok {
+		return Task{ID: id}, true
+	}
+	t, _ := decodeCRDTTask(id, fields)
+	return t, string(fields["exists"].Value) != "true"
+}
+
+// tasks returns the tasks that exist, in the order they were added
+func (st *crdtState) tasks() []Task {
+	var ids []string
+	for id, fields := range st.fields {
+		if string(fields["exists"].Value) == "true" {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := st.fields[ids[i]]["exists"].Stamp, st.fields[ids[j]]["exists"].Stamp
+		if a != b {
+			return a.Before(b)
+		}
+		return ids[i] < ids[j]
+	})
+	list := make([]Task, 0, len(ids))
+	for _, id := range ids {
+		t, _ := decodeCRDTTask(id, st.fields[id])
+		list = append(list, t)
+	}
+	return list
+}
+
+type crdtField struct {
+	name  string
+	value json.RawMessage
+}
+
+// crdtDone keeps Done and CompletedAt in one field, so they change together
+type crdtDone struct {
+	Done        bool       `json:"done"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// crdtFields splits a task into its fields, with tags in order so that
+// their operations are stamped in order
+func crdtFields(t Task) []crdtField {
+	var fields []crdtField
+	add := func(name string, v interface{}) {
+		data, _ := json.Marshal(v)
+		fields = append(fields, crdtField{name, data})
+	}
+	utc := func(t *time.Time) *time.Time {
+		if t == nil {
+			return nil
+		}
+		u := t.UTC()
+		return &u
+	}
+	add("title", t.Title)
+	add("description", t.Description)
+	// Like deleted below, notes are only written for tasks that have some
+	if t.Notes != "" {
+		add("notes", t.Notes)
+	}
+	add("done", crdtDone{t.Done, utc(t.CompletedAt)})
+	add("priority", int(t.Priority))
+	add("due", utc(t.DueDate))
+	add("created", t.CreatedAt.UTC())
+	add("modified", t.ModifiedAt.UTC())
+	add("project", t.Project)
+	add("parent", t.ParentID)
+	// Only tasks in the trash have the field, so that the others are
+	// written as they were before there was a trash
+	if t.DeletedAt != nil {
+		add("deleted", utc(t.DeletedAt))
+	}
+	if t.UnarchivedAt != nil {
+		add("unarchived", utc(t.UnarchivedAt))
+	}
+	seen := make(map[string]bool)
+	for _, tag := range t.Tags {
+		key := strings.ToLower(tag)
+		if !seen[key] {
+			seen[key] = true
+			add("tag:"+key, tag)
+		}
+	}
+	var names []string
+	for name := range t.Extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, t.Extra[name]); err != nil {
+			compact.Write(t.Extra[name])
+		}
+		fields = append(fields, crdtField{"extra:" + name, compact.Bytes()})
+	}
+	return fields
+}
+
+// decodeCRDTTask builds a task from the latest operation on each field
+func decodeCRDTTask(id string, fields map[string]CRDTOp) (Task, error) {
+	t := Task{ID: id}
+	var tags []CRDTOp
+	for name, op := range fields {
+		var err error
+		switch {
+		case name == "exists":
+		case name == "title":
+			err = json.Unmarshal(op.Value, &t.Title)
+		case name == "description":
+			err = json.Unmarshal(op.Value, &t.Description)
+		case name == "notes":
+			err = json.Unmarshal(op.Value, &t.Notes)
+		case name == "done":
+			var done crdtDone
+			err = json.Unmarshal(op.Value, &done)
+			t.Done, t.CompletedAt = done.Done, done.CompletedAt
+		case name == "priority":
+			err = json.Unmarshal(op.Value, &t.Priority)
+		case name == "due":
+			err = json.Unmarshal(op.Value, &t.DueDate)
+		case name == "created":
+			err = json.Unmarshal(op.Value, &t.CreatedAt)
+		case name == "modified":
+			err = json.Unmarshal(op.Value, &t.ModifiedAt)
+		case name == "project":
+			err = json.Unmarshal(op.Value, &t.Project)
+		case name == "parent":
+			err = json.Unmarshal(op.Value, &t.ParentID)
+		case name == "deleted":
+			err = json.Unmarshal(op.Value, &t.DeletedAt)
+		case name == "unarchived":
+			err = json.Unmarshal(op.Value, &t.UnarchivedAt)
+		case strings.HasPrefix(name, "tag:"):
+			if string(op.Value) != "null" {
+				tags = append(tags, op)
+			}
+		case strings.HasPrefix(name, "extra:"):
+			if string(op.Value) != "null" {
+				if t.Extra == nil {
+					t.Extra = make(map[string]json.RawMessage)
+				}
+				t.Extra[strings.TrimPrefix(name, "extra:")] = append(json.RawMessage(nil), op.Value...)
+			}
+		default:
+			err = fmt.Errorf("unknown field")
+		}
+		if err != nil {
+			return t, fmt.Errorf("task %s field %s: %v", id, name, err)
+		}
+	}
+
+	// Tags keep the order they were added in
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Stamp != tags[j].Stamp {
+			return tags[i].Stamp.Before(tags[j].Stamp)
+		}
+		return tags[i].Field < tags[j].Field
+	})
+	for _, op := range tags {
+		var tag string
+		if err := json.Unmarshal(op.Value, &tag); err != nil {
+			return t, fmt.Errorf("task %s field %s: %v", id, op.Field, err)
+		}
+		t.Tags = append(t.Tags, tag)
+	}
+	return t, nil
+}
@@ -0,0 +1,191 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestCRDT(t *testing.T) *CRDTStore {
+	t.Helper()
+	return NewCRDTStore(filepath.Join(t.TempDir(), "tasks.crdt"))
+}
+
+// union merges stores into a new one, in the order given
+func union(t *testing.T, stores ...*CRDTStore) *CRDTStore {
+	t.Helper()
+	merged := newTestCRDT(t)
+	for _, s := range stores {
+		if _, err := merged.Merge(context.Background(), s); err != nil {
+			t.Fatalf("Merge returned an error: %v", err)
+		}
+	}
+	return merged
+}
+
+func crdtList(t *testing.T, s *CRDTStore) []Task {
+	t.Helper()
+	list, err := s.List(context.Background())
+	if err != nil {
+		t.Fatalf("List returned an error: %v", err)
+	}
+	return list
+}
+
+// mutate makes n random changes to s, on a few task IDs shared between
+// replicas so that their changes collide
+func mutate(t *testing.T, rng *rand.Rand, s *CRDTStore, n int) {
+	t.Helper()
+	ctx := context.Background()
+	ids := []string{"a", "b", "c", "d"}
+	tags := []string{"work", "home", "Urgent", "later"}
+	for i := 0; i < n; i++ {
+		id := ids[rng.Intn(len(ids))]
+		task, err := s.Get(ctx, id)
+		if err != nil {
+			if err := s.Add(ctx, Task{ID: id, Title: "New " + id, Tags: []string{tags[rng.Intn(len(tags))]}}); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		switch rng.Intn(9) {
+		case 0:
+			if err := s.Remove(ctx, id); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		case 1:
+			task.Title = fmt.Sprintf("Title %d", rng.Intn(5))
+		case 2:
+			task.Done = !task.Done
+			if task.Done {
+				now := time.Now()
+				task.CompletedAt = &now
+			} else {
+				task.CompletedAt = nil
+			}
+		case 3:
+			task.Priority = Priority(rng.Intn(4))
+		case 4:
+			due := time.Date(2024, 6, 1+rng.Intn(10), 0, 0, 0, 0, time.UTC)
+			task.DueDate = &due
+		case 5:
+			task.Project = fmt.Sprintf("project %d", rng.Intn(3))
+		case 6:
+			task.AddTag(tags[rng.Intn(len(tags))])
+		case 7:
+			task.RemoveTag(tags[rng.Intn(len(tags))])
+		case 8:
+			task.Extra = map[string]json.RawMessage{"uda": json.RawMessage(fmt.Sprint(rng.Intn(3)))}
+		}
+		task.ModifiedAt = time.Now()
+		if err := s.Update(ctx, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// replicas returns three copies of a store, each changed on its own
+func replicas(t *testing.T, seed int64) (*CRDTStore, *CRDTStore, *CRDTStore) {
+	rng := rand.New(rand.NewSource(seed))
+	origin := newTestCRDT(t)
+	mutate(t, rng, origin, 10)
+	a, b, c := union(t, origin), union(t, origin), union(t, origin)
+	for _, s := range []*CRDTStore{a, b, c} {
+		mutate(t, rng, s, 15)
+	}
+	return a, b, c
+}
+
+func TestCRDTMergeProperties(t *testing.T) {
+	for seed := int64(1); seed <= 25; seed++ {
+		a, b, c := replicas(t, seed)
+
+		ab, ba := crdtList(t, union(t, a, b)), crdtList(t, union(t, b, a))
+		if !reflect.DeepEqual(ab, ba) {
+			t.Fatalf("seed %d: merge is not commutative:\n%+v\n%+v", seed, ab, ba)
+		}
+
+		left := crdtList(t, union(t, union(t, a, b), c))
+		right := crdtList(t, union(t, a, union(t, b, c)))
+		if !reflect.DeepEqual(left, right) {
+			t.Fatalf("seed %d: merge is not associative:\n%+v\n%+v", seed, left, right)
+		}
+
+		if aa := crdtList(t, union(t, a, a)); !reflect.DeepEqual(aa, crdtList(t, a)) {
+			t.Fatalf("seed %d: merging a store with itself changed it:\n%+v\n%+v", seed, aa, crdtList(t, a))
+		}
+		merged := union(t, a, b)
+		if n, err := merged.Merge(context.Background(), b); err != nil || n != 0 {
+			t.Fatalf("seed %d: merging again added %d operations, %v", seed, n, err)
+		}
+	}
+}
+
+func TestCRDTMergeFields(t *testing.T) {
+	ctx := context.Background()
+	origin := newTestCRDT(t)
+	origin.Add(ctx, Task{ID: "report", Title: "Write report", Tags: []string{"work"}})
+	origin.Add(ctx, Task{ID: "milk", Title: "Buy milk"})
+	laptop, phone := union(t, origin), union(t, origin)
+
+	task, _ := laptop.Get(ctx, "report")
+	task.Title = "Write the report"
+	task.Tags = []string{"urgent"}
+	laptop.Update(ctx, task)
+
+	task, _ = phone.Get(ctx, "report")
+	task.Priority = High
+	task.Tags = append(task.Tags, "q3")
+	phone.Update(ctx, task)
+	task, _ = phone.Get(ctx, "milk")
+	task.Title = "Buy oat milk"
+	phone.Update(ctx, task)
+	laptop.Remove(ctx, "milk")
+
+	for _, merged := range [][]Task{crdtList(t, union(t, laptop, phone)), crdtList(t, union(t, phone, laptop))} {
+		if len(merged) != 1 {
+			t.Fatalf("Expected the removal to win over the rename, got %+v", merged)
+		}
+		got := merged[0]
+		if got.Title != "Write the report" || got.Priority != High || fmt.Sprint(got.Tags) != "[urgent q3]" {
+			t.Errorf("Expected changes to different fields and tags to combine, got %+v", got)
+		}
+	}
+
+	// The same field changed on both sides keeps the change made last
+	task, _ = laptop.Get(ctx, "report")
+	task.Description = "From the laptop"
+	laptop.Update(ctx, task)
+	task.Description = "From the phone"
+	phone.Update(ctx, task)
+	if got, _ := union(t, phone, laptop).Get(ctx, "report"); got.Description != "From the phone" {
+		t.Errorf("Expected the later description, got %q", got.Description)
+	}
+}
+
+func TestCRDTReplica(t *testing.T) {
+	s := newTestCRDT(t)
+	if err := s.Add(context.Background(), Task{ID: "a", Title: "A"}); err != nil {
+		t.Fatal(err)
+	}
+	id, err := s.Replica()
+	if err != nil || id == "" {
+		t.Fatalf("Expected a replica ID, got %q, %v", id, err)
+	}
+	again, _ := NewCRDTStore(s.Path()).Replica()
+	if again != id {
+		t.Errorf("Expected the replica ID %s to be kept, got %s", id, again)
+	}
+	ops, _ := s.Ops()
+	for _, op := range ops {
+		if op.Replica != id {
+			t.Errorf("Expected operations stamped with %s, got %+v", id, op)
+		}
+	}
+}
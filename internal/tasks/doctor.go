@@ -0,0 +1,255 @@
+package tasks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Problem is something wrong with a task file
+type Problem struct {
+	// Record is the position of the task in the file, or -1 for the file
+	// as a whole
+	Record  int
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Record < 0 {
+		return p.Message
+	}
+	return fmt.Sprintf("task %d: %s", p.Record, p.Message)
+}
+
+// Diagnosis is what Diagnose found in a task file
+type Diagnosis struct {
+	// Version is the schema version of the file, 0 before it had a header
+	Version  int
+	Problems []Problem
+	// Tasks holds every task that could be recovered, with its problems
+	// fixed
+	Tasks []Task
+}
+
+// Diagnose checks a task file record by record: its JSON, its checksum,
+// and each task's priority, dates, ID and parent. A file too damaged to
+// parse is searched for every task that still can be. Tasks from a file
+// under an older schema are migrated, with saved as when it was written.
+func Diagnose(data []byte, saved time.Time) Diagnosis {
+	d := Diagnosis{Version: SchemaVersion, Tasks: []Task{}}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return d
+	}
+	problem := func(record int, format string, args ...interface{}) {
+		d.Problems = append(d.Problems, Problem{Record: record, Message: fmt.Sprintf(format, args...)})
+	}
+
+	var records []json.RawMessage
+	f, raw, err := readTaskFile(data)
+	if err == nil {
+		err = json.Unmarshal(raw, &records)
+		if err != nil {
+			err = jsonError(raw, err)
+		}
+	}
+	d.Version = f.Version
+	if err != nil {
+		records = salvage(data)
+		problem(-1, "%v; found %d tasks that could still be read", err, len(records))
+	} else if f.Checksum != "" {
+		var compact bytes.Buffer
+		if json.Compact(&compact, raw) == nil && checksum(compact.Bytes()) != f.Checksum {
+			problem(-1, "the checksum does not match; the file was edited by hand or damaged")
+		}
+	}
+
+	// positions holds the record each recovered task came from
+	var positions []int
+	for i, record := range records {
+		t, ok := decodeRecord(record, func(format string, args ...interface{}) {
+			problem(i, format, args...)
+		})
+		if ok {
+			d.Tasks = append(d.Tasks, t)
+			positions = append(positions, i)
+		}
+	}
+	if d.Version < SchemaVersion {
+		d.Tasks, _ = migrate(d.Tasks, d.Version, saved)
+	}
+
+	seen := make(map[string]int)
+	for i := range d.Tasks {
+		t, record := &d.Tasks[i], positions[i]
+		if t.ID == "" {
+			t.ID = NewID()
+			problem(record, "%q has no id; gave it %s", t.Title, t.ID)
+		} else if j, ok := seen[t.ID]; ok {
+			id := t.ID
+			t.ID = NewID()
+			problem(record, "%q has the same id as task %d, %s; gave it %s", t.Title, j, id, t.ID)
+		}
+		seen[t.ID] = record
+
+		if t.CreatedAt.IsZero() {
+			t.CreatedAt = t.ModifiedAt
+			if t.CreatedAt.IsZero() {
+				t.CreatedAt = saved
+			}
+			problem(record, "%q has no creation date; set it to %s", t.Title, t.CreatedAt.Format("2006-01-02"))
+		}
+		if t.ModifiedAt.IsZero() {
+			t.ModifiedAt = t.CreatedAt
+		}
+		if t.Done && t.CompletedAt != nil && t.CompletedAt.Before(t.CreatedAt) {
+			problem(record, "%q was completed before it was created; cleared the completion date", t.Title)
+			t.CompletedAt = nil
+		}
+	}
+
+	for i := range d.Tasks {
+		t := &d.Tasks[i]
+		if t.ParentID != "" {
+			if _, ok := seen[t.ParentID]; !ok || t.ParentID == t.ID {
+				problem(positions[i], "%q has a missing parent %s; made it a top-level task", t.Title, t.ParentID)
+				t.ParentID = ""
+			}
+		}
+	}
+	return d
+}
+
+// decodeRecord reads one task field by field, so that a bad value loses
+// only that field. Problems are reported to problem.
+func decodeRecord(record json.RawMessage, problem func(format string, args ...interface{})) (Task, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(record, &fields); err != nil {
+		problem("is not a task: %v", err)
+		return Task{}, false
+	}
+	// Field names match the way encoding/json does, ignoring case
+	byName := make(map[string]json.RawMessage)
+	for name, value := range fields {
+		byName[strings.ToLower(name)] = value
+	}
+
+	var t Task
+	targets := []struct {
+		name   string
+		target interface{}
+	}{
+		{"id", &t.ID}, {"title", &t.Title}, {"description", &t.Description}, {"notes", &t.Notes},
+		{"done", &t.Done}, {"priority", &t.Priority}, {"duedate", &t.DueDate},
+		{"createdat", &t.CreatedAt}, {"tags", &t.Tags}, {"project", &t.Project},
+		{"parentid", &t.ParentID}, {"completedat", &t.CompletedAt},
+		{"modifiedat", &t.ModifiedAt}, {"deletedat", &t.DeletedAt},
+		{"unarchivedat", &t.UnarchivedAt},
+		{"extra", &t.Extra},
+	}
+	var bad []string
+	for _, f := range targets {
+		value, ok := byName[f.name]
+		if !ok {
+			continue
+		}
+		// A value that fails half way must not leave half a field
+		fresh := reflect.New(reflect.TypeOf(f.target).Elem())
+		if err := json.Unmarshal(value, fresh.Interface()); err != nil {
+			bad = append(bad, fmt.Sprintf("%s %s", f.name, value))
+			continue
+		}
+		reflect.ValueOf(f.target).Elem().Set(fresh.Elem())
+	}
+	if len(bad) > 0 {
+		problem("%q has unreadable %s; cleared", t.Title, strings.Join(bad, ", "))
+	}
+
+	if t.Priority < Low || t.Priority > Critical {
+		problem("%q has unknown priority %d; set it to low", t.Title, t.Priority)
+		t.Priority = Low
+	}
+	return t, true
+}
+
+// salvage finds every JSON object in damaged data that looks like a task
+func salvage(data []byte) []json.RawMessage {
+	var records []json.RawMessage
+	for i := 0; i < len(data); i++ {
+		if data[i] != '{' {
+			continue
+		}
+		var fields map[string]json.RawMessage
+		dec := json.NewDecoder(bytes.NewReader(data[i:]))
+		if err := dec.Decode(&fields); err != nil {
+			continue
+		}
+		// Keys are matched as json.Unmarshal does, ignoring case
+		isTask := false
+		for key := range fields {
+			if strings.EqualFold(key, "Title") || strings.EqualFold(key, "ID") {
+				isTask = true
+				break
+			}
+		}
+		if !isTask {
+			continue
+		}
+		end := i + int(dec.InputOffset())
+		records = append(records, json.RawMessage(data[i:end]))
+		i = end - 1
+	}
+	return records
+}
+
+// Check diagnoses the task file, opening it with the keyring if it is
+// encrypted
+func (s *FileStore) Check() (Diagnosis, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.check()
+}
+
+func (s *FileStore) check() (Diagnosis, error) {
+	data, saved, err := s.readFile()
+	if err != nil {
+		return Diagnosis{}, err
+	}
+	if _, _, err := readTaskFile(data); errors.Is(err, errNewerSchema) {
+		return Diagnosis{}, err
+	}
+	return Diagnose(data, saved), nil
+}
+
+// Repair rewrites the task file with every task Diagnose could recover,
+// if it found any problem. The damaged file is kept as a backup.
+func (s *FileStore) Repair() (Diagnosis, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, err := s.check()
+	if err != nil || len(d.Problems) == 0 {
+		return d, err
+	}
+	return d, s.saveOverDamage(d.Tasks)
+}
+
+// saveOverDamage writes tasks over a damaged file, backing the file up even
+// if the policy turned backups off: it is all there is of the tasks that
+// could not be read
+func (s *FileStore) saveOverDamage(tasks []Task) error {
+	policy := s.backups
+	if s.backups.Keep <= 0 {
+		backups, err := s.Backups()
+		if err != nil {
+			return err
+		}
+		s.backups = BackupPolicy{Keep: len(backups) + 1}
+	}
+	err := s.saveTasks(tasks)
+	s.backups = policy
+	return err
+}
@@ -0,0 +1,140 @@
+package tasks
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiagnose(t *testing.T) {
+	data := `[
+		{"ID": "a", "Title": "Legacy", "Priority": 9, "CreatedAt": "2024-01-02T03:04:05Z"},
+		{"ID": "a", "Title": "Dup", "DueDate": "soon", "CreatedAt": "2024-01-02T03:04:05Z", "ParentID": "zzz"},
+		{"Title": "No id", "CreatedAt": "2024-01-02T03:04:05Z"},
+		"not a task"
+	]`
+	d := Diagnose([]byte(data), time.Now())
+	var got []string
+	for _, p := range d.Problems {
+		got = append(got, p.String())
+	}
+	for _, want := range []string{
+		`task 0: "Legacy" has unknown priority 9`,
+		`task 1: "Dup" has unreadable duedate "soon"`,
+		`task 1: "Dup" has the same id as task 0`,
+		`task 1: "Dup" has a missing parent zzz`,
+		"task 3: is not a task",
+	} {
+		if !strings.Contains(strings.Join(got, "\n"), want) {
+			t.Errorf("Expected a problem %q, got\n%s", want, strings.Join(got, "\n"))
+		}
+	}
+
+	if len(d.Tasks) != 3 {
+		t.Fatalf("Expected 3 tasks recovered, got %v", d.Tasks)
+	}
+	if d.Tasks[0].Priority != Low || d.Tasks[1].DueDate != nil || d.Tasks[1].ParentID != "" {
+		t.Errorf("Expected the bad values cleared, got %+v", d.Tasks[:2])
+	}
+	// The file predates IDs, so the task without one is migrated
+	if d.Tasks[1].ID == "a" || d.Tasks[2].ID == "" {
+		t.Errorf("Expected every task to have its own id, got %q and %q", d.Tasks[1].ID, d.Tasks[2].ID)
+	}
+}
+
+func TestDiagnoseDamagedFile(t *testing.T) {
+	now := time.Now()
+	data, _ := marshalTaskFile([]Task{
+		{ID: "a", Title: "A", CreatedAt: now, Extra: map[string]json.RawMessage{"x": json.RawMessage(`{"Title": 1}`)}},
+		{ID: "b", Title: "B", CreatedAt: now},
+		{ID: "c", Title: "C", CreatedAt: now},
+	})
+	if d := Diagnose(data, now); len(d.Problems) != 0 || len(d.Tasks) != 3 {
+		t.Errorf("Expected a healthy file, got %v", d.Problems)
+	}
+
+	// Losing the end of the file loses only the last task
+	cut := data[:bytes.Index(data, []byte(`"C"`))]
+	d := Diagnose(cut, now)
+	if len(d.Tasks) != 2 || d.Tasks[0].ID != "a" || d.Tasks[1].ID != "b" {
+		t.Errorf("Expected tasks a and b salvaged, got %v", d.Tasks)
+	}
+	if len(d.Problems) != 1 || !strings.Contains(d.Problems[0].String(), "found 2 tasks") {
+		t.Errorf("Expected the damage reported, got %v", d.Problems)
+	}
+
+	// A changed value is caught by the checksum
+	changed := bytes.Replace(data, []byte(`"Priority": 0`), []byte(`"Priority": 3`), 1)
+	if d := Diagnose(changed, now); len(d.Problems) != 1 || !strings.Contains(d.Problems[0].Message, "checksum") {
+		t.Errorf("Expected a checksum problem, got %v", d.Problems)
+	}
+}
+
+func TestFileStoreRepairTornFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	store := NewFileStore(path)
+	manager := NewTaskManager(store)
+	for _, title := range []string{"First", "Second", "Third"} {
+		manager.Add(Task{Title: title})
+	}
+
+	// A write that stopped partway through the last task
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(path, data[:bytes.Index(data, []byte(`"Third"`))], 0644)
+	if _, err := store.Repair(); err != nil {
+		t.Fatalf("Repair returned an error: %v", err)
+	}
+	if list := mustList(t, store); len(list) != 2 || list[0].Title != "First" || list[1].Title != "Second" {
+		t.Errorf("Expected the tasks written in full salvaged, got %v", list)
+	}
+
+	// Keys written by hand in lower case are read as json.Unmarshal reads them
+	os.WriteFile(path, []byte(`[{"id": "a", "title": "Lower", "createdAt": "2024-01-02T03:04:05Z"}, {"id": "b", "ti`), 0644)
+	if _, err := store.Repair(); err != nil {
+		t.Fatalf("Repair returned an error: %v", err)
+	}
+	if list := mustList(t, store); len(list) != 1 || list[0].ID != "a" || list[0].Title != "Lower" {
+		t.Errorf("Expected the lower-case task salvaged, got %v", list)
+	}
+}
+
+func TestFileStoreRepair(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	damaged := []byte(`[{"ID": "a", "Title": "Keep me", "CreatedAt": "2024-01-02T03:04:05Z"}, {"ID": "b", "Ti`)
+	os.WriteFile(path, damaged, 0644)
+
+	store := NewFileStore(path)
+	store.SetBackupPolicy(BackupPolicy{})
+	if d, err := store.Check(); err != nil || len(d.Problems) == 0 {
+		t.Fatalf("Expected Check to find the damage, got %v, %v", d.Problems, err)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, damaged) {
+		t.Error("Expected Check to leave the file alone")
+	}
+
+	if _, err := store.Repair(); err != nil {
+		t.Fatalf("Repair returned an error: %v", err)
+	}
+	if list := mustList(t, store); len(list) != 1 || list[0].Title != "Keep me" {
+		t.Errorf("Expected the salvaged task, got %v", list)
+	}
+	if d, _ := store.Check(); len(d.Problems) != 0 {
+		t.Errorf("Expected a healthy file after the repair, got %v", d.Problems)
+	}
+
+	// The damaged file is kept even with backups turned off
+	backups, _ := store.Backups()
+	if len(backups) != 1 {
+		t.Fatalf("Expected the damaged file backed up, got %v", backups)
+	}
+	if data, _ := os.ReadFile(backups[0].Path); !bytes.Equal(data, damaged) {
+		t.Errorf("Expected the backup to hold the damaged file, got %s", data)
+	}
+}
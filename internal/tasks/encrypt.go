@@ -0,0 +1,174 @@
+package tasks
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// encryptedFormat marks an encrypted task file
+const encryptedFormat = "taskmgr-encrypted"
+
+// KeyParams is how a key is stretched from a passphrase or key file. They
+// are stored, unencrypted, at the start of an encrypted file.
+type KeyParams struct {
+	KDF     string `json:"kdf"`
+	Salt    []byte `json:"salt"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+}
+
+// NewKeyParams returns argon2id parameters with a new random salt
+func NewKeyParams() (KeyParams, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return KeyParams{}, err
+	}
+	return KeyParams{KDF: "argon2id", Salt: salt, Time: 3, Memory: 64 * 1024, Threads: 4}, nil
+}
+
+// ID identifies the key the parameters derive, for caching it. Files
+// encrypted with the same secret have different IDs.
+func (p KeyParams) ID() string {
+	return hex.EncodeToString(p.Salt)
+}
+
+// DeriveKey stretches a passphrase or the contents of a key file into an
+// encryption key
+func DeriveKey(secret []byte, p KeyParams) ([]byte, error) {
+	if p.KDF != "argon2id" {
+		return nil, fmt.Errorf("unknown key derivation %q", p.KDF)
+	}
+	if len(secret) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+	return argon2.IDKey(secret, p.Salt, p.Time, p.Memory, p.Threads, 32), nil
+}
+
+// Keyring supplies the keys to encrypted task files
+type Keyring interface {
+	// Key returns the key for a file encrypted with p
+	Key(p KeyParams) ([]byte, error)
+	// Unlocked is told when a key from Key opened a file, so that it can
+	// be kept for next time
+	Unlocked(p KeyParams, key []byte)
+}
+
+// ErrWrongKey is returned when an encrypted file does not open with the
+// key given, or has been changed since it was written
+var ErrWrongKey = errors.New("wrong passphrase or key file, or the file was tampered with")
+
+// encryptedFile is an encrypted task file: AES-256-GCM under a key
+// derived from a passphrase or key file
+type encryptedFile struct {
+	Format string    `json:"format"`
+	Key    KeyParams `json:"key"`
+	Nonce  []byte    `json:"nonce"`
+	Data   []byte    `json:"data"`
+}
+
+// sealedLine is one encrypted line of a change log
+type sealedLine struct {
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"sealed"`
+}
+
+// isEncrypted reports whether file contents are an encrypted task file,
+// returning its header if so. Plain task files are JSON arrays.
+func isEncrypted(data []byte) (*encryptedFile, bool) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return nil, false
+	}
+	var f encryptedFile
+	if err := json.Unmarshal(data, &f); err != nil || f.Format != encryptedFormat {
+		return nil, false
+	}
+	return &f, true
+}
+
+// seal encrypts plaintext, binding it to what it is with aad
+func seal(key, plaintext []byte, aad string) (nonce, data []byte, err error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	return nonce, aead.Seal(nil, nonce, plaintext, []byte(aad)), nil
+}
+
+func unseal(key, nonce, data []byte, aad string) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, ErrWrongKey
+	}
+	plaintext, err := aead.Open(nil, nonce, data, []byte(aad))
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptTasks returns the contents of an encrypted task file
+func encryptTasks(data []byte, p KeyParams, key []byte) ([]byte, error) {
+	nonce, sealed, err := seal(key, data, encryptedFormat+" tasks")
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(encryptedFile{Format: encryptedFormat, Key: p, Nonce: nonce, Data: sealed}, "", "  ")
+}
+
+func decryptTasks(f *encryptedFile, key []byte) ([]byte, error) {
+	return unseal(key, f.Nonce, f.Data, encryptedFormat+" tasks")
+}
+
+func sealChange(c Change, key []byte) ([]byte, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	nonce, sealed, err := seal(key, data, encryptedFormat+" log")
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(sealedLine{Nonce: nonce, Data: sealed})
+}
+
+// openChange decodes a change log line, decrypting it if it is sealed
+func openChange(line, key []byte) (Change, error) {
+	var c Change
+	var sealed sealedLine
+	if err := json.Unmarshal(line, &sealed); err == nil && sealed.Data != nil {
+		if key == nil {
+			return c, errors.New("encrypted entry in an unencrypted log")
+		}
+		plain, err := unseal(key, sealed.Nonce, sealed.Data, encryptedFormat+" log")
+		if err != nil {
+			return c, err
+		}
+		line = plain
+	}
+	err := json.Unmarshal(line, &c)
+	return c, err
+}
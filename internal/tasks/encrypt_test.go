@@ -0,0 +1,140 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testKeyring derives keys from a fixed passphrase, counting how often
+type testKeyring struct {
+	secret   string
+	derived  int
+	unlocked int
+}
+
+func (k *testKeyring) Key(p KeyParams) ([]byte, error) {
+	k.derived++
+	return DeriveKey([]byte(k.secret), p)
+}
+
+func (k *testKeyring) Unlocked(p KeyParams, key []byte) {
+	k.unlocked++
+}
+
+// cheapKeyParams keeps tests fast; real files use NewKeyParams' defaults
+func cheapKeyParams(t *testing.T) KeyParams {
+	t.Helper()
+	p, err := NewKeyParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Time, p.Memory, p.Threads = 1, 64, 1
+	return p
+}
+
+func TestEncryptedFileStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	store := NewFileStore(path)
+	manager := NewTaskManager(store)
+	manager.Add(Task{ID: "acme", Title: "Call ACME about the invoice"})
+
+	p := cheapKeyParams(t)
+	key, _ := DeriveKey([]byte("correct horse"), p)
+	if err := store.SetEncryption(&p, key); err != nil {
+		t.Fatalf("SetEncryption returned an error: %v", err)
+	}
+	manager.Add(Task{ID: "globex", Title: "Send Globex the contract"})
+
+	for _, file := range []string{path, path + ".log"} {
+		data, _ := os.ReadFile(file)
+		if strings.Contains(string(data), "ACME") || strings.Contains(string(data), "Globex") {
+			t.Errorf("Expected %s to be encrypted, got %s", filepath.Base(file), data)
+		}
+		if info, _ := os.Stat(file); info.Mode().Perm() != 0600 {
+			t.Errorf("Expected %s to be private, got mode %v", filepath.Base(file), info.Mode().Perm())
+		}
+	}
+	if encrypted, _ := store.Encrypted(); !encrypted {
+		t.Error("Expected Encrypted to report true")
+	}
+
+	// Another process needs the key
+	if _, err := NewFileStore(path).List(ctx); err == nil || !strings.Contains(err.Error(), "encrypted") {
+		t.Errorf("Expected an error without a key, got %v", err)
+	}
+	wrong := NewFileStore(path)
+	wrong.SetKeyring(&testKeyring{secret: "wrong"})
+	if _, err := wrong.List(ctx); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("Expected ErrWrongKey, got %v", err)
+	}
+
+	keyring := &testKeyring{secret: "correct horse"}
+	reopened := NewFileStore(path)
+	reopened.SetKeyring(keyring)
+	other := NewTaskManager(reopened)
+	if list := other.List(); len(list) != 2 || list[0].Title != "Call ACME about the invoice" {
+		t.Errorf("Expected both tasks, got %v", list)
+	}
+	if _, changes, err := other.History("globex"); err != nil || len(changes) != 1 {
+		t.Errorf("Expected the encrypted change log to be read, got %v, %v", changes, err)
+	}
+	other.MarkDone("acme")
+	other.List()
+	if keyring.derived != 1 || keyring.unlocked != 1 {
+		t.Errorf("Expected the key to be derived once and kept, got %d derivations", keyring.derived)
+	}
+
+	// Rekeying leaves the old passphrase useless
+	newParams := cheapKeyParams(t)
+	newKey, _ := DeriveKey([]byte("battery staple"), newParams)
+	if err := reopened.SetEncryption(&newParams, newKey); err != nil {
+		t.Fatalf("SetEncryption returned an error: %v", err)
+	}
+	old := NewFileStore(path)
+	old.SetKeyring(&testKeyring{secret: "correct horse"})
+	if _, err := old.List(ctx); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("Expected the old passphrase to fail, got %v", err)
+	}
+
+	// Decrypting writes plain JSON again
+	if err := reopened.SetEncryption(nil, nil); err != nil {
+		t.Fatalf("SetEncryption returned an error: %v", err)
+	}
+	plain := NewTaskManager(NewFileStore(path))
+	if list := plain.List(); len(list) != 2 || !list[0].Done {
+		t.Errorf("Expected the decrypted tasks, got %v", list)
+	}
+	if _, changes, err := plain.History("acme"); err != nil || len(changes) != 2 {
+		t.Errorf("Expected the decrypted change log, got %v, %v", changes, err)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "ACME") {
+		t.Errorf("Expected plain JSON, got %s", data)
+	}
+}
+
+func TestEncryptedFileTampered(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	store := NewFileStore(path)
+	p := cheapKeyParams(t)
+	key, _ := DeriveKey([]byte("secret"), p)
+	store.SetEncryption(&p, key)
+	store.Add(context.Background(), Task{ID: "a", Title: "A"})
+
+	data, _ := os.ReadFile(path)
+	f, _ := isEncrypted(data)
+	f.Data[0] ^= 1
+	tampered, _ := json.Marshal(f)
+	os.WriteFile(path, tampered, 0600)
+
+	reopened := NewFileStore(path)
+	reopened.SetKeyring(&testKeyring{secret: "secret"})
+	if _, err := reopened.List(context.Background()); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("Expected a changed file to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// EventType names what happened to a task
+type EventType string
+
+const (
+	EventCreated   EventType = "created"
+	EventUpdated   EventType = "updated"
+	EventCompleted EventType = "completed"
+	EventRemoved   EventType = "removed"
+	// Archiving moves a task from the task list into the archive store,
+	// and unarchiving back
+	EventArchived   EventType = "archived"
+	EventUnarchived EventType = "unarchived"
+	// Removing a task moves it to the trash, and restoring it back; a
+	// removed event is a task deleted for good
+	EventTrashed  EventType = "trashed"
+	EventRestored EventType = "restored"
+)
+
+// EventTypes lists every event type
+var EventTypes = []EventType{EventCreated, EventUpdated, EventCompleted, EventRemoved, EventArchived, EventUnarchived, EventTrashed, EventRestored}
+
+// Event describes a change to a task. Every change is published twice:
+// as a pre-event before it is written, when subscribers may still veto
+// it, and as a post-event once it has been written.
+type Event struct {
+	Type EventType
+	Pre  bool
+	// Task is the task as the change leaves it; for removals, the task
+	// that is removed
+	Task Task
+	// Old is the task before an update or completion
+	Old     *Task
+	Changes []Change
+	Time    time.Time
+	Actor   string
+}
+
+// Phase returns "pre" or "post"
+func (e Event) Phase() string {
+	if e.Pre {
+		return "pre"
+	}
+	return "post"
+}
+
+// Subscriber receives events. An error returned for a pre-event vetoes the
+// change; errors returned for post-events are reported as warnings, since
+// the change has already been made.
+type Subscriber interface {
+	HandleEvent(Event) error
+}
+
+// SubscriberFunc lets an ordinary function subscribe to events
+type SubscriberFunc func(Event) error
+
+// HandleEvent implements Subscriber
+func (f SubscriberFunc) HandleEvent(e Event) error {
+	return f(e)
+}
+
+// VetoError is returned by TaskManager methods when a subscriber rejected
+// the change
+type VetoError struct {
+	Type   EventType
+	Reason error
+}
+
+func (e *VetoError) Error() string {
+	return fmt.Sprintf("change vetoed: %v", e.Reason)
+}
+
+func (e *VetoError) Unwrap() error {
+	return e.Reason
+}
+
+// Bus delivers events to subscribers in the order they subscribed
+type Bus struct {
+	mu          sync.Mutex
+	subscribers []Subscriber
+}
+
+// Subscribe adds a subscriber for every event
+func (b *Bus) Subscribe(s Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, s)
+}
+
+// Publish delivers an event. The first subscriber to fail a pre-event
+// stops delivery and its error is returned as a *VetoError. Every
+// subscriber receives a post-event, and their errors are returned joined.
+func (b *Bus) Publish(e Event) error {
+	b.mu.Lock()
+	subscribers := append([]Subscriber(nil), b.subscribers...)
+	b.mu.Unlock()
+
+	var errs []error
+	for _, s := range subscribers {
+		if err := s.HandleEvent(e); err != nil {
+			if e.Pre {
+				return &VetoError{Type: e.Type, Reason: err}
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Events returns the bus the manager publishes task changes on
+func (tm *TaskManager) Events() *Bus {
+	return tm.events
+}
+
+// before publishes the pre-event for a change
+func (tm *TaskManager) before(e Event) error {
+	e.Pre = true
+	e.Actor = tm.actor
+	return tm.events.Publish(e)
+}
+
+// after publishes the post-event for a change. The change is already
+// written, so failures are only reported.
+func (tm *TaskManager) after(e Event) {
+	e.Pre = false
+	e.Actor = tm.actor
+	if err := tm.events.Publish(e); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
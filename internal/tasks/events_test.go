@@ -0,0 +1,118 @@
+package tasks
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestTaskManagerEvents(t *testing.T) {
+	manager := NewTaskManager(NewFileStore(filepath.Join(t.TempDir(), "tasks.json")))
+	var seen []string
+	manager.Events().Subscribe(SubscriberFunc(func(e Event) error {
+		seen = append(seen, e.Phase()+"-"+string(e.Type)+":"+e.Task.Title)
+		if e.Type == EventCompleted && (e.Old == nil || e.Old.Done || !e.Task.Done) {
+			t.Errorf("Expected a completed event to carry the open task, got %+v", e)
+		}
+		return nil
+	}))
+
+	if err := manager.Add(Task{Title: "Write"}); err != nil {
+		t.Fatalf("Add returned an error: %v", err)
+	}
+	if err := manager.AddTagToTask("0", "work"); err != nil {
+		t.Fatalf("AddTagToTask returned an error: %v", err)
+	}
+	if err := manager.MarkDone("0"); err != nil {
+		t.Fatalf("MarkDone returned an error: %v", err)
+	}
+	// Nothing changes, so nothing is published
+	if err := manager.MarkDone("0"); err != nil {
+		t.Fatalf("MarkDone returned an error: %v", err)
+	}
+	if err := manager.UndoDone("0"); err != nil {
+		t.Fatalf("UndoDone returned an error: %v", err)
+	}
+	if err := manager.Remove("0"); err != nil {
+		t.Fatalf("Remove returned an error: %v", err)
+	}
+
+	want := []string{
+		"pre-created:Write", "post-created:Write",
+		"pre-updated:Write", "post-updated:Write",
+		"pre-completed:Write", "post-completed:Write",
+		"pre-updated:Write", "post-updated:Write",
+		"pre-trashed:Write", "post-trashed:Write",
+	}
+	if len(seen) != len(want) {
+		t.Fatalf("Expected events %v, got %v", want, seen)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Errorf("Event %d: expected %s, got %s", i, want[i], seen[i])
+		}
+	}
+}
+
+func TestTaskManagerVeto(t *testing.T) {
+	manager := NewTaskManager(NewFileStore(filepath.Join(t.TempDir(), "tasks.json")))
+	manager.Add(Task{Title: "Keep"})
+
+	posts := 0
+	manager.Events().Subscribe(SubscriberFunc(func(e Event) error {
+		if e.Pre && e.Type != EventUpdated {
+			return errors.New("not allowed")
+		}
+		if !e.Pre {
+			posts++
+		}
+		return nil
+	}))
+
+	err := manager.Delete("0")
+	var veto *VetoError
+	if !errors.As(err, &veto) || veto.Type != EventRemoved {
+		t.Fatalf("Expected a removal veto, got %v", err)
+	}
+	if err := manager.Add(Task{Title: "Blocked"}); err == nil {
+		t.Error("Expected the creation to be vetoed")
+	}
+	if err := manager.MarkDone("0"); err == nil {
+		t.Error("Expected the completion to be vetoed")
+	}
+	if list := manager.List(); len(list) != 1 || list[0].Done {
+		t.Errorf("Expected vetoed changes not to be written, got %v", list)
+	}
+	if posts != 0 {
+		t.Errorf("Expected no post-events for vetoed changes, got %d", posts)
+	}
+
+	// Updates are allowed through
+	if err := manager.AddTagToTask("0", "ok"); err != nil {
+		t.Errorf("Expected the update to pass, got %v", err)
+	}
+	if posts != 1 {
+		t.Errorf("Expected one post-event, got %d", posts)
+	}
+}
+
+func TestBusPostEventErrors(t *testing.T) {
+	var bus Bus
+	calls := 0
+	for _, msg := range []string{"first", "second"} {
+		msg := msg
+		bus.Subscribe(SubscriberFunc(func(e Event) error {
+			calls++
+			return errors.New(msg)
+		}))
+	}
+	err := bus.Publish(Event{Type: EventCreated})
+	if calls != 2 || err == nil || err.Error() != "first\nsecond" {
+		t.Errorf("Expected both subscribers called and both errors, got %d calls and %v", calls, err)
+	}
+
+	calls = 0
+	if err := bus.Publish(Event{Type: EventCreated, Pre: true}); err == nil || calls != 1 {
+		t.Errorf("Expected the first pre-event error to stop delivery, got %d calls and %v", calls, err)
+	}
+}
@@ -0,0 +1,130 @@
+package tasks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Change records one field of a task changing value. Creation and removal
+// are recorded as changes to the pseudo-fields "created" and "removed".
+type Change struct {
+	TaskID string
+	Field  string
+	Old    string
+	New    string
+	Time   time.Time
+	Actor  string
+}
+
+// HistoryStore is implemented by stores that keep an append-only log of
+// changes alongside the tasks.
+type HistoryStore interface {
+	AppendChanges([]Change) error
+	Changes(taskID string) ([]Change, error)
+}
+
+// NewID returns a random identifier for a task. Unlike list indexes, IDs
+// stay the same when other tasks are removed.
+func NewID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand does not fail on supported platforms
+		panic(fmt.Sprintf("generating task id: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// DefaultActor names who is making changes: $TASKMGR_ACTOR, then $USER,
+// then "unknown".
+func DefaultActor() string {
+	for _, name := range []string{"TASKMGR_ACTOR", "USER"} {
+		if actor := os.Getenv(name); actor != "" {
+			return actor
+		}
+	}
+	return "unknown"
+}
+
+// diffTasks returns a change for every user-visible field that differs
+// between old and updated
+func diffTasks(old, updated Task) []Change {
+	var changes []Change
+	add := func(field, before, after string) {
+		if before != after {
+			changes = append(changes, Change{Field: field, Old: before, New: after})
+		}
+	}
+	add("title", old.Title, updated.Title)
+	add("description", old.Description, updated.Description)
+	add("notes", old.Notes, updated.Notes)
+	add("done", fmt.Sprint(old.Done), fmt.Sprint(updated.Done))
+	add("priority", old.Priority.String(), updated.Priority.String())
+	add("due", formatDate(old.DueDate), formatDate(updated.DueDate))
+	add("tags", strings.Join(old.Tags, ","), strings.Join(updated.Tags, ","))
+	add("project", old.Project, updated.Project)
+	add("parent", old.ParentID, updated.ParentID)
+	add("deleted", formatStamp(old.DeletedAt), formatStamp(updated.DeletedAt))
+	return changes
+}
+
+func formatStamp(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+// TaskDiff is how one task differs between two versions of a task list
+type TaskDiff struct {
+	// Kind is "added", "removed", "trashed", "restored" or "changed"
+	Kind string
+	// Task is the task as it is after, or as it was if it was removed
+	Task    Task
+	Changes []Change
+}
+
+// DiffLists compares two versions of a task list by ID, in the order of
+// after followed by the tasks it no longer has
+func DiffLists(before, after []Task) []TaskDiff {
+	old := make(map[string]Task)
+	for _, t := range before {
+		old[t.ID] = t
+	}
+	var diffs []TaskDiff
+	seen := make(map[string]bool)
+	for _, t := range after {
+		seen[t.ID] = true
+		prev, ok := old[t.ID]
+		if !ok {
+			diffs = append(diffs, TaskDiff{Kind: "added", Task: t})
+			continue
+		}
+		if changes := diffTasks(prev, t); len(changes) > 0 {
+			kind := "changed"
+			switch {
+			case t.Trashed() && !prev.Trashed():
+				kind = "trashed"
+			case !t.Trashed() && prev.Trashed():
+				kind = "restored"
+			}
+			diffs = append(diffs, TaskDiff{Kind: kind, Task: t, Changes: changes})
+		}
+	}
+	for _, t := range before {
+		if !seen[t.ID] {
+			diffs = append(diffs, TaskDiff{Kind: "removed", Task: t})
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,284 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+func init() {
+	Register("journal", func(path string, options url.Values) (Store, error) {
+		if err := requirePath(path); err != nil {
+			return nil, err
+		}
+		return NewJournalStore(path), nil
+	})
+}
+
+// JournalStore keeps tasks as an append-only log of operations, one JSON
+// line per batch, and replays the log to list them. A change appends one
+// line instead of rewriting every task, and a line cut short by a crash
+// is ignored, so a batch is saved whole or not at all. The change log is
+// kept in the same file.
+type JournalStore struct {
+	filename string
+	mu       sync.Mutex
+}
+
+// journalEntry is one line of the journal
+type journalEntry struct {
+	Time    time.Time   `json:"time"`
+	Ops     []journalOp `json:"ops,omitempty"`
+	Changes []Change    `json:"changes,omitempty"`
+}
+
+type journalOp struct {
+	Op   string `json:"op"`
+	Task *Task  `json:"task,omitempty"`
+	ID   string `json:"id,omitempty"`
+}
+
+func NewJournalStore(filename string) *JournalStore {
+	return &JournalStore{filename: filename}
+}
+
+// Path returns the journal file
+func (s *JournalStore) Path() string {
+	return s.filename
+}
+
+func (s *JournalStore) List(ctx context.Context) ([]Task, error) {
+	return batchList(ctx, s)
+}
+
+func (s *JournalStore) Get(ctx context.Context, id string) (Task, error) {
+	return batchGet(ctx, s, id)
+}
+
+func (s *JournalStore) Add(ctx context.Context, t Task) error {
+	return batchAdd(ctx, s, t)
+}
+
+func (s *JournalStore) Update(ctx context.Context, t Task) error {
+	return batchUpdate(ctx, s, t)
+}
+
+func (s *JournalStore) Remove(ctx context.Context, id string) error {
+	return batchRemove(ctx, s, id)
+}
+
+// journalTx records the operations made through a txn
+type journalTx struct {
+	*txn
+	ops []journalOp
+}
+
+func (x *journalTx) Add(t Task) error {
+	if err := x.txn.Add(t); err != nil {
+		return err
+	}
+	t = cloneTask(t)
+	x.ops = append(x.ops, journalOp{Op: "add", Task: &t})
+	return nil
+}
+
+func (x *journalTx) Update(t Task) error {
+	if err := x.txn.Update(t); err != nil {
+		return err
+	}
+	t = cloneTask(t)
+	x.ops = append(x.ops, journalOp{Op: "update", Task: &t})
+	return nil
+}
+
+func (x *journalTx) Remove(id string) error {
+	if err := x.txn.Remove(id); err != nil {
+		return err
+	}
+	x.ops = append(x.ops, journalOp{Op: "remove", ID: id})
+	return nil
+}
+
+// Batch replays the journal, runs fn and appends its operations as one
+// line
+func (s *JournalStore) Batch(ctx context.Context, fn func(tx Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	tasks, _, err := s.replay()
+	if err != nil {
+		return err
+	}
+	tx := &journalTx{txn: &txn{tasks: tasks}}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if len(tx.ops) == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.append(journalEntry{Time: time.Now(), Ops: tx.ops})
+}
+
+// AppendChanges implements HistoryStore
+func (s *JournalStore) AppendChanges(changes []Change) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.append(journalEntry{Time: time.Now(), Changes: changes})
+}
+
+// Changes implements HistoryStore
+func (s *JournalStore) Changes(taskID string) ([]Change, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, changes, err := s.replay()
+	if err != nil {
+		return nil, err
+	}
+	var matching []Change
+	for _, c := range changes {
+		if c.TaskID == taskID {
+			matching = append(matching, c)
+		}
+	}
+	return matching, nil
+}
+
+// Compact rewrites the journal as a single line adding the current tasks,
+// keeping the change log
+func (s *JournalStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks, changes, err := s.replay()
+	if err != nil {
+		return err
+	}
+	entry := journalEntry{Time: time.Now(), Changes: changes}
+	for i := range tasks {
+		entry.Ops = append(entry.Ops, journalOp{Op: "add", Task: &tasks[i]})
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	tmp := s.filename + ".tmp"
+	if err := os.WriteFile(tmp, append(line, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.filename)
+}
+
+// replay reads the journal and returns the tasks and changes it holds. A
+// final line without a newline is a write that was cut short and is
+// skipped.
+func (s *JournalStore) replay() ([]Task, []Change, error) {
+	data, err := os.ReadFile(s.filename)
+	if os.IsNotExist(err) {
+		return []Task{}, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx := &txn{tasks: []Task{}}
+	var changes []Change
+	for line := 1; len(data) > 0; line++ {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			break
+		}
+		raw := data[:end]
+		data = data[end+1:]
+		if len(bytes.TrimSpace(raw)) == 0 {
+			continue
+		}
+
+		var entry journalEntry
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return nil, nil, fmt.Errorf("%s line %d: %v", s.filename, line, err)
+		}
+		for _, op := range entry.Ops {
+			if err := applyOp(tx, op); err != nil {
+				return nil, nil, fmt.Errorf("%s line %d: %v", s.filename, line, err)
+			}
+		}
+		changes = append(changes, entry.Changes...)
+	}
+	return tx.tasks, changes, nil
+}
+
+func applyOp(tx Tx, op journalOp) error {
+	switch op.Op {
+	case "add", "update":
+		if op.Task == nil {
+			return fmt.Errorf("%s without a task", op.Op)
+		}
+		if op.Op == "add" {
+			return tx.Add(*op.Task)
+		}
+		return tx.Update(*op.Task)
+	case "remove":
+		return tx.Remove(op.ID)
+	default:
+		return fmt.Errorf("unknown operation %q", op.Op)
+	}
+}
+
+// append writes an entry as a new line
+func (s *JournalStore) append(entry journalEntry) error {
+	return appendLine(s.filename, entry)
+}
+
+// appendLine writes v to a file as a new JSON line, first cutting off any
+// partial line a crashed write left behind
+func appendLine(filename string, v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	complete := info.Size()
+	last := make([]byte, 1)
+	if complete > 0 {
+		if _, err := f.ReadAt(last, complete-1); err != nil {
+			return err
+		}
+	}
+	if complete > 0 && last[0] != '\n' {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		complete = int64(bytes.LastIndexByte(data, '\n') + 1)
+		if err := f.Truncate(complete); err != nil {
+			return err
+		}
+	}
+	if _, err := f.WriteAt(append(line, '\n'), complete); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,69 @@
+package tasks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJournalStoreTornWrite(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "tasks.journal")
+	store := NewJournalStore(testFile)
+	ctx := context.Background()
+	if err := store.Add(ctx, Task{ID: "a", Title: "Kept"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A crash part way through writing the next batch
+	f, err := os.OpenFile(testFile, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"time":"2024-01-01T00:00:00Z","ops":[{"op":"add","task":{"ID":"b","Ti`)
+	f.Close()
+
+	if list := mustList(t, store); len(list) != 1 || list[0].Title != "Kept" {
+		t.Errorf("Expected the partial batch to be ignored, got %v", list)
+	}
+	if err := store.Add(ctx, Task{ID: "c", Title: "After"}); err != nil {
+		t.Fatal(err)
+	}
+	if list := mustList(t, NewJournalStore(testFile)); len(list) != 2 || list[1].Title != "After" {
+		t.Errorf("Expected the next write to replace the partial line, got %v", list)
+	}
+
+	// Damage anywhere else is an error, not silently skipped
+	data, _ := os.ReadFile(testFile)
+	os.WriteFile(testFile, append([]byte("{oops}\n"), data...), 0644)
+	if _, err := store.List(ctx); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Expected an error naming line 1, got %v", err)
+	}
+}
+
+func TestJournalStoreCompact(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "tasks.journal")
+	manager := NewTaskManager(NewJournalStore(testFile))
+	manager.Add(Task{Title: "One"})
+	manager.Add(Task{Title: "Two"})
+	manager.MarkDone("0")
+	manager.Delete("1")
+	id := manager.List()[0].ID
+
+	store := NewJournalStore(testFile)
+	if err := store.Compact(); err != nil {
+		t.Fatalf("Compact returned an error: %v", err)
+	}
+	data, _ := os.ReadFile(testFile)
+	if lines := strings.Count(string(data), "\n"); lines != 1 {
+		t.Errorf("Expected one line after compacting, got %d", lines)
+	}
+	list := mustList(t, store)
+	if len(list) != 1 || list[0].Title != "One" || !list[0].Done {
+		t.Errorf("Expected the done task One, got %v", list)
+	}
+	if changes, err := store.Changes(id); err != nil || len(changes) != 2 {
+		t.Errorf("Expected the change log to survive compaction, got %v, %v", changes, err)
+	}
+}
@@ -0,0 +1,87 @@
+package tasks
+
+import (
+	"context"
+	"net/url"
+	"sync"
+)
+
+func init() {
+	Register("memory", func(path string, options url.Values) (Store, error) {
+		return NewMemoryStore(), nil
+	})
+}
+
+// MemoryStore keeps tasks and their change log in memory only, for tests
+// and for trying things out without touching a file
+type MemoryStore struct {
+	mu      sync.Mutex
+	tasks   []Task
+	changes []Change
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) List(ctx context.Context) ([]Task, error) {
+	return batchList(ctx, s)
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (Task, error) {
+	return batchGet(ctx, s, id)
+}
+
+func (s *MemoryStore) Add(ctx context.Context, t Task) error {
+	return batchAdd(ctx, s, t)
+}
+
+func (s *MemoryStore) Update(ctx context.Context, t Task) error {
+	return batchUpdate(ctx, s, t)
+}
+
+func (s *MemoryStore) Remove(ctx context.Context, id string) error {
+	return batchRemove(ctx, s, id)
+}
+
+// Batch runs fn on a copy of the tasks and keeps the copy if fn succeeds
+func (s *MemoryStore) Batch(ctx context.Context, fn func(tx Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	tx := &txn{tasks: cloneTasks(s.tasks)}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if tx.dirty {
+		s.tasks = tx.tasks
+	}
+	return nil
+}
+
+// AppendChanges implements HistoryStore
+func (s *MemoryStore) AppendChanges(changes []Change) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.changes = append(s.changes, changes...)
+	return nil
+}
+
+// Changes implements HistoryStore
+func (s *MemoryStore) Changes(taskID string) ([]Change, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var changes []Change
+	for _, c := range s.changes {
+		if c.TaskID == taskID {
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,159 @@
+package tasks
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Migration brings tasks saved under schema version Version-1 up to
+// Version. Apply changes the tasks in place; saved is when they were last
+// written, for values the old schema did not record. Migrations must give
+// the same result every time they run on the same tasks, as reads apply
+// them without saving.
+type Migration struct {
+	Version     int
+	Description string
+	Apply       func(tasks []Task, saved time.Time)
+}
+
+var migrations []Migration
+
+// RegisterMigration adds the migration to the next schema version. It
+// panics if migrations are registered out of order.
+func RegisterMigration(m Migration) {
+	if m.Version != len(migrations)+1 {
+		panic(fmt.Sprintf("tasks: migration to version %d registered after version %d", m.Version, len(migrations)))
+	}
+	migrations = append(migrations, m)
+}
+
+// Migrations returns every registered migration, oldest first
+func Migrations() []Migration {
+	return append([]Migration(nil), migrations...)
+}
+
+func init() {
+	RegisterMigration(Migration{
+		Version:     1,
+		Description: "Give every task an id, a creation time and a modification time",
+		Apply: func(tasks []Task, saved time.Time) {
+			for i := range tasks {
+				t := &tasks[i]
+				// The file's modification time is the best guess there is
+				if t.CreatedAt.IsZero() {
+					t.CreatedAt = saved
+				}
+				if t.ModifiedAt.IsZero() {
+					t.ModifiedAt = t.CreatedAt
+				}
+				if t.ID == "" {
+					t.ID = legacyID(i, *t)
+				}
+			}
+		},
+	})
+}
+
+// legacyID derives an ID for a task saved before tasks had one from where
+// it is and what it says, so that reading the file twice gives it the same
+// ID
+func legacyID(position int, t Task) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d\x00%s\x00%s", position, t.Title, t.CreatedAt.Format(time.RFC3339Nano))))
+	return hex.EncodeToString(sum[:8])
+}
+
+// MigrationStep is what one migration changed
+type MigrationStep struct {
+	Migration Migration
+	Changes   []MigratedTask
+}
+
+// MigratedTask names the fields a migration changed in one task
+type MigratedTask struct {
+	Title  string
+	Fields []string
+}
+
+// migrate applies every migration after schema version from to a copy of
+// tasks, and reports what each one changed
+func migrate(tasks []Task, from int, saved time.Time) ([]Task, []MigrationStep) {
+	var steps []MigrationStep
+	for _, m := range migrations {
+		if m.Version <= from {
+			continue
+		}
+		before := cloneTasks(tasks)
+		tasks = cloneTasks(tasks)
+		m.Apply(tasks, saved)
+		step := MigrationStep{Migration: m}
+		for i := range tasks {
+			if i >= len(before) {
+				break
+			}
+			if fields := changedFields(before[i], tasks[i]); len(fields) > 0 {
+				step.Changes = append(step.Changes, MigratedTask{Title: tasks[i].Title, Fields: fields})
+			}
+		}
+		steps = append(steps, step)
+	}
+	return tasks, steps
+}
+
+// changedFields names every field of Task that differs between a and b
+func changedFields(a, b Task) []string {
+	var fields []string
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	for i := 0; i < va.NumField(); i++ {
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			fields = append(fields, va.Type().Field(i).Name)
+		}
+	}
+	return fields
+}
+
+// MigrationPlan is what migrating a task file to the current schema
+// version does
+type MigrationPlan struct {
+	From, To int
+	Steps    []MigrationStep
+}
+
+// PlanMigration reports what migrating the task file would change,
+// without changing it
+func (s *FileStore) PlanMigration() (MigrationPlan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	plan, _, err := s.planMigration()
+	return plan, err
+}
+
+// Migrate saves the task file at the current schema version, if it is
+// older, keeping the old file as a backup
+func (s *FileStore) Migrate() (MigrationPlan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	plan, tasks, err := s.planMigration()
+	if err != nil || plan.From == plan.To {
+		return plan, err
+	}
+	return plan, s.saveTasks(tasks)
+}
+
+func (s *FileStore) planMigration() (MigrationPlan, []Task, error) {
+	data, saved, err := s.readFile()
+	if err != nil {
+		return MigrationPlan{}, nil, err
+	}
+	if data == nil {
+		return MigrationPlan{From: SchemaVersion, To: SchemaVersion}, nil, nil
+	}
+	tasks, version, err := decodeTasks(data)
+	if err != nil {
+		return MigrationPlan{}, nil, fmt.Errorf("%s: %w", s.filename, err)
+	}
+	migrated, steps := migrate(tasks, version, saved)
+	return MigrationPlan{From: version, To: SchemaVersion, Steps: steps}, migrated, nil
+}
@@ -0,0 +1,81 @@
+package tasks
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMigrationsAreOrdered(t *testing.T) {
+	list := Migrations()
+	if len(list) != SchemaVersion {
+		t.Fatalf("Expected a migration to every schema version up to %d, got %d", SchemaVersion, len(list))
+	}
+	for i, m := range list {
+		if m.Version != i+1 || m.Description == "" || m.Apply == nil {
+			t.Errorf("Expected migration %d to version %d with a description, got %+v", i, i+1, m)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a migration out of order to panic")
+		}
+		if len(Migrations()) != SchemaVersion {
+			t.Error("Expected the misordered migration not to be registered")
+		}
+	}()
+	RegisterMigration(Migration{Version: SchemaVersion + 2, Apply: func([]Task, time.Time) {}})
+}
+
+func TestFileStoreMigrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	legacy := []byte(`[{"Title": "Old task"}, {"ID": "b", "Title": "Newer task", "CreatedAt": "2024-01-02T03:04:05Z"}]`)
+	os.WriteFile(path, legacy, 0644)
+	saved := time.Date(2023, 5, 6, 7, 8, 9, 0, time.UTC)
+	os.Chtimes(path, saved, saved)
+
+	// Reads see migrated tasks but leave the file alone
+	store := NewFileStore(path)
+	list := mustList(t, store)
+	if list[0].ID == "" || !list[0].CreatedAt.Equal(saved) || !list[0].ModifiedAt.Equal(saved) {
+		t.Errorf("Expected the old task migrated, got %+v", list[0])
+	}
+	if version, err := store.Version(); err != nil || version != 0 {
+		t.Errorf("Expected schema version 0, got %d, %v", version, err)
+	}
+
+	plan, err := store.PlanMigration()
+	if err != nil {
+		t.Fatalf("PlanMigration returned an error: %v", err)
+	}
+	if plan.From != 0 || plan.To != SchemaVersion || len(plan.Steps) != SchemaVersion {
+		t.Fatalf("Expected a plan from version 0, got %+v", plan)
+	}
+	changes := plan.Steps[0].Changes
+	if len(changes) != 2 || strings.Join(changes[0].Fields, ",") != "ID,CreatedAt,ModifiedAt" || strings.Join(changes[1].Fields, ",") != "ModifiedAt" {
+		t.Errorf("Expected the fields each task gains, got %+v", changes)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, legacy) {
+		t.Errorf("Expected reads and planning not to write, got %s", data)
+	}
+
+	if _, err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate returned an error: %v", err)
+	}
+	if version, _ := NewFileStore(path).Version(); version != SchemaVersion {
+		t.Errorf("Expected schema version %d after migrating, got %d", SchemaVersion, version)
+	}
+	if after := mustList(t, NewFileStore(path)); after[0].ID != list[0].ID {
+		t.Errorf("Expected the id seen before migrating to be kept, got %q and %q", list[0].ID, after[0].ID)
+	}
+	if backups, _ := store.Backups(); len(backups) != 1 {
+		t.Errorf("Expected the old file backed up, got %v", backups)
+	}
+	if plan, _ := store.PlanMigration(); plan.From != SchemaVersion || len(plan.Steps) != 0 {
+		t.Errorf("Expected nothing left to migrate, got %+v", plan)
+	}
+}
@@ -0,0 +1,151 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Opener opens a store from the rest of its URL after "scheme://": a
+// path, and options from the query string. For
+// file://tasks.json?cache=true the path is "tasks.json" and the options
+// hold cache=true.
+type Opener func(path string, options url.Values) (Store, error)
+
+var registry = struct {
+	sync.Mutex
+	openers map[string]Opener
+}{openers: make(map[string]Opener)}
+
+// Register makes a backend available to Open under a URL scheme. Backends
+// register themselves from an init function; registering a scheme twice
+// panics.
+func Register(scheme string, open Opener) {
+	registry.Lock()
+	defer registry.Unlock()
+	if open == nil {
+		panic("tasks: Register opener is nil")
+	}
+	if _, dup := registry.openers[scheme]; dup {
+		panic("tasks: Register called twice for " + scheme)
+	}
+	registry.openers[scheme] = open
+}
+
+// Backends returns the registered URL schemes, sorted
+func Backends() []string {
+	registry.Lock()
+	defer registry.Unlock()
+	var schemes []string
+	for scheme := range registry.openers {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// Open opens the store a URL names, such as file://tasks.json,
+// journal:///home/me/tasks.journal or memory://. Anything without a scheme
+// is taken as the path of a task file.
+func Open(rawURL string) (Store, error) {
+	scheme, rest, ok := strings.Cut(rawURL, "://")
+	if !ok {
+		scheme, rest = "file", rawURL
+	}
+	path, query, _ := strings.Cut(rest, "?")
+	options, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("store %s: %v", rawURL, err)
+	}
+
+	registry.Lock()
+	open, ok := registry.openers[scheme]
+	registry.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown store backend %q (available: %s)", scheme, strings.Join(Backends(), ", "))
+	}
+	store, err := open(path, options)
+	if err != nil {
+		return nil, fmt.Errorf("store %s: %v", rawURL, err)
+	}
+	return store, nil
+}
+
+// FileBackedStore is implemented by stores kept in a single file, which
+// watchers can poll for changes made by other processes
+type FileBackedStore interface {
+	Store
+	Path() string
+}
+
+// StorePath returns the file a store is kept in, or "" if it has none
+func StorePath(s Store) string {
+	if fb, ok := s.(FileBackedStore); ok {
+		return fb.Path()
+	}
+	return ""
+}
+
+// boolOption reads an on/off option such as cache=true
+func boolOption(options url.Values, name string) (bool, error) {
+	switch strings.ToLower(options.Get(name)) {
+	case "", "0", "false", "no", "off":
+		return false, nil
+	case "1", "true", "yes", "on":
+		return true, nil
+	default:
+		return false, fmt.Errorf("option %s: expected true or false, got %q", name, options.Get(name))
+	}
+}
+
+func requirePath(path string) error {
+	if path == "" {
+		return fmt.Errorf("no file given")
+	}
+	return nil
+}
+
+// Copy copies every task from src into dst, which must be empty, in one
+// transaction, followed by each task's change log when both stores keep
+// one. It returns how many tasks were copied.
+func Copy(ctx context.Context, dst, src Store) (int, error) {
+	list, err := src.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	err = dst.Batch(ctx, func(tx Tx) error {
+		if existing := tx.List(); len(existing) > 0 {
+			return fmt.Errorf("destination already holds %d tasks", len(existing))
+		}
+		for _, t := range list {
+			if err := tx.Add(t); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	from, fromLog := src.(HistoryStore)
+	to, toLog := dst.(HistoryStore)
+	if !fromLog || !toLog {
+		return len(list), nil
+	}
+	for _, t := range list {
+		changes, err := from.Changes(t.ID)
+		if err != nil {
+			return len(list), err
+		}
+		if len(changes) > 0 {
+			if err := to.AppendChanges(changes); err != nil {
+				return len(list), err
+			}
+		}
+	}
+	return len(list), nil
+}
@@ -0,0 +1,171 @@
+package tasks_test
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"taskmgr/internal/tasks"
+	"taskmgr/internal/tasks/storetest"
+)
+
+// urlFactory makes conformance stores by opening a URL for a fresh file
+func urlFactory(scheme, name, query string) storetest.Factory {
+	return func(t *testing.T) (tasks.Store, func() tasks.Store) {
+		raw := scheme + "://" + filepath.Join(t.TempDir(), name) + query
+		open := func() tasks.Store {
+			s, err := tasks.Open(raw)
+			if err != nil {
+				t.Fatalf("Open(%s) returned an error: %v", raw, err)
+			}
+			return s
+		}
+		return open(), open
+	}
+}
+
+// passphrase is a keyring deriving every key from one passphrase
+type passphrase string
+
+func (p passphrase) Key(params tasks.KeyParams) ([]byte, error) {
+	return tasks.DeriveKey([]byte(p), params)
+}
+
+func (p passphrase) Unlocked(params tasks.KeyParams, key []byte) {}
+
+// encryptedFactory makes conformance stores from encrypted task files
+func encryptedFactory(t *testing.T) (tasks.Store, func() tasks.Store) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	open := func() tasks.Store {
+		s := tasks.NewFileStore(path)
+		s.SetKeyring(passphrase("secret"))
+		return s
+	}
+	s := open().(*tasks.FileStore)
+	params, err := tasks.NewKeyParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.Time, params.Memory, params.Threads = 1, 64, 1
+	key, _ := tasks.DeriveKey([]byte("secret"), params)
+	if err := s.SetEncryption(&params, key); err != nil {
+		t.Fatalf("SetEncryption returned an error: %v", err)
+	}
+	return s, open
+}
+
+func TestStoreConformance(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		storetest.Run(t, urlFactory("file", "tasks.json", ""))
+	})
+	t.Run("cached file", func(t *testing.T) {
+		storetest.Run(t, urlFactory("file", "tasks.json", "?cache=true"))
+	})
+	t.Run("encrypted file", func(t *testing.T) {
+		storetest.Run(t, encryptedFactory)
+	})
+	t.Run("journal", func(t *testing.T) {
+		storetest.Run(t, urlFactory("journal", "tasks.journal", ""))
+	})
+	t.Run("crdt", func(t *testing.T) {
+		storetest.Run(t, urlFactory("crdt", "tasks.crdt", ""))
+	})
+	t.Run("memory", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) (tasks.Store, func() tasks.Store) {
+			return tasks.NewMemoryStore(), nil
+		})
+	})
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	for raw, want := range map[string]string{
+		"file://" + filepath.Join(dir, "a.json"):           "*tasks.FileStore",
+		filepath.Join(dir, "b.json"):                       "*tasks.FileStore",
+		"journal://" + filepath.Join(dir, "tasks.journal"): "*tasks.JournalStore",
+		"memory://": "*tasks.MemoryStore",
+		"crdt://" + filepath.Join(dir, "tasks.crdt"):           "*tasks.CRDTStore",
+		"file://" + filepath.Join(dir, "c.json") + "?cache=on": "*tasks.FileStore",
+	} {
+		s, err := tasks.Open(raw)
+		if err != nil {
+			t.Errorf("Open(%s) returned an error: %v", raw, err)
+			continue
+		}
+		if got := fmt.Sprintf("%T", s); got != want {
+			t.Errorf("Open(%s): expected %s, got %s", raw, want, got)
+		}
+	}
+
+	// A bare path and a file:// URL name the same file
+	path := filepath.Join(dir, "shared.json")
+	a, _ := tasks.Open(path)
+	a.Add(context.Background(), tasks.Task{ID: "x", Title: "Shared"})
+	b, _ := tasks.Open("file://" + path)
+	if l, err := b.List(context.Background()); err != nil || len(l) != 1 {
+		t.Errorf("Expected the task added through the bare path, got %v, %v", l, err)
+	}
+	if tasks.StorePath(b) != path {
+		t.Errorf("Expected StorePath %s, got %s", path, tasks.StorePath(b))
+	}
+	if tasks.StorePath(tasks.NewMemoryStore()) != "" {
+		t.Error("Expected a memory store to have no path")
+	}
+
+	for raw, want := range map[string]string{
+		"carrier-pigeon://coop":                      `unknown store backend "carrier-pigeon" (available: crdt, file, journal, memory`,
+		"file://":                                    "no file given",
+		"file://tasks.json?cache=":                   "",
+		"file://tasks.json?cache=maybe":              "option cache",
+		"file://tasks.json?backups=5&backup-age=30d": "",
+		"file://tasks.json?backups=all":              "option backups",
+		"file://tasks.json?backup-age=forever":       "option backup-age",
+	} {
+		_, err := tasks.Open(raw)
+		if want == "" {
+			if err != nil {
+				t.Errorf("Open(%s) returned an error: %v", raw, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Open(%s): expected an error containing %q, got %v", raw, want, err)
+		}
+	}
+}
+
+func TestRegisterTwicePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registering file twice to panic")
+		}
+	}()
+	tasks.Register("file", func(string, url.Values) (tasks.Store, error) { return nil, nil })
+}
+
+func TestCopy(t *testing.T) {
+	src := tasks.NewFileStore(filepath.Join(t.TempDir(), "tasks.json"))
+	manager := tasks.NewTaskManager(src)
+	manager.Add(tasks.Task{Title: "First", Tags: []string{"a"}})
+	manager.Add(tasks.Task{Title: "Second"})
+	manager.MarkDone("1")
+
+	dst := tasks.NewMemoryStore()
+	n, err := tasks.Copy(context.Background(), dst, src)
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 tasks copied, got %d, %v", n, err)
+	}
+	copied := tasks.NewTaskManager(dst)
+	task, changes, err := copied.History("1")
+	if err != nil || task.Title != "Second" || !task.Done || len(changes) != 2 {
+		t.Errorf("Expected Second done with its change log, got %v, %v, %v", task, changes, err)
+	}
+
+	// Copying into a store that holds tasks would mix two lists
+	if _, err := tasks.Copy(context.Background(), dst, src); err == nil || !strings.Contains(err.Error(), "already holds 2 tasks") {
+		t.Errorf("Expected copying into a non-empty store to fail, got %v", err)
+	}
+}
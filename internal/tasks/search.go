@@ -0,0 +1,569 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// ErrEmptyQuery is returned for a search query without any words
+var ErrEmptyQuery = errors.New("empty search query")
+
+// searchField is a part of a task the search index covers
+type searchField int
+
+const (
+	fieldTitle searchField = iota
+	fieldTags
+	fieldDescription
+	fieldNotes
+)
+
+// fieldWeights makes a word in the title count for more than one in the
+// description or notes
+var fieldWeights = [...]float64{fieldTitle: 3, fieldTags: 2, fieldDescription: 1, fieldNotes: 1}
+
+// BM25 parameters: how fast repeated words stop adding to the score, and
+// how much a long task is penalised for having more words to match
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// tokenize splits text into lower-case words of letters and digits
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// QueryClause is one part of a search query: a word, a phrase whose words
+// must appear in order, or, with Prefix, any word starting with Terms[0]
+type QueryClause struct {
+	Terms  []string
+	Prefix bool
+}
+
+// Query is a parsed search query. A task matches if it matches every
+// clause.
+type Query struct {
+	Clauses []QueryClause
+}
+
+// ParseQuery reads a search query: words, "quoted phrases" and prefixes
+// such as deploy*. Words joined by punctuation, such as code-review, are
+// a phrase.
+func ParseQuery(s string) Query {
+	var q Query
+	add := func(words string, prefix bool) {
+		terms := tokenize(words)
+		if len(terms) == 0 {
+			return
+		}
+		if prefix {
+			for _, term := range terms[:len(terms)-1] {
+				q.Clauses = append(q.Clauses, QueryClause{Terms: []string{term}})
+			}
+			q.Clauses = append(q.Clauses, QueryClause{Terms: terms[len(terms)-1:], Prefix: true})
+			return
+		}
+		q.Clauses = append(q.Clauses, QueryClause{Terms: terms})
+	}
+	for s != "" {
+		if strings.HasPrefix(s, `"`) {
+			phrase, rest, _ := strings.Cut(s[1:], `"`)
+			add(phrase, false)
+			s = rest
+			continue
+		}
+		end := strings.IndexAny(s, " \t\n\"")
+		if end < 0 {
+			end = len(s)
+		}
+		word := s[:end]
+		add(strings.TrimSuffix(word, "*"), strings.HasSuffix(word, "*"))
+		s = strings.TrimLeft(s[end:], " \t\n")
+	}
+	return q
+}
+
+// posting is one occurrence of a word in a task
+type posting struct {
+	field  searchField
+	offset int
+}
+
+// textSum is a checksum of the text a task is indexed with, to tell when
+// it changes
+func textSum(t Task) uint64 {
+	h := fnv.New64a()
+	for _, s := range append([]string{t.Title, t.Description, t.Notes}, t.Tags...) {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
+
+type indexedTask struct {
+	sum   uint64
+	terms []string
+	// length is the weighted number of words, for BM25
+	length float64
+}
+
+func (a stamp) equal(b stamp) bool {
+	return a.exists == b.exists && a.size == b.size && a.modTime.Equal(b.modTime)
+}
+
+// Index is an inverted index of the words in tasks' titles, tags,
+// descriptions and notes. It is kept up to date task by task: a task
+// manager indexes the tasks each change touches, and only tasks whose text
+// changed behind its back are indexed again. It is safe for concurrent use.
+type Index struct {
+	mu       sync.Mutex
+	postings map[string]map[string][]posting
+	tasks    map[string]*indexedTask
+	length   float64
+	// vocabulary is every word in sorted order, for prefix queries; nil
+	// after a change until one needs it
+	vocabulary []string
+
+	// file is where the index is kept between runs, if anywhere, and
+	// source is the store file it indexes, if the store has one
+	file, source string
+	// current is set while the index holds the tasks as the source was at
+	// stamp; loaded once file has been read
+	current bool
+	stamp   stamp
+	loaded  bool
+}
+
+// NewIndex returns an empty index
+func NewIndex() *Index {
+	return &Index{postings: make(map[string]map[string][]posting), tasks: make(map[string]*indexedTask)}
+}
+
+// Len returns how many tasks are indexed
+func (ix *Index) Len() int {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return len(ix.tasks)
+}
+
+// Update indexes t, unless it is indexed with the same text already
+func (ix *Index) Update(t Task) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.update(t)
+}
+
+// Remove takes a task out of the index
+func (ix *Index) Remove(id string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+}
+
+// Sync makes the index cover exactly the given tasks, indexing only those
+// that are new or whose text changed
+func (ix *Index) Sync(list []Task) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.sync(list)
+}
+
+func (ix *Index) sync(list []Task) {
+	seen := make(map[string]bool, len(list))
+	for _, t := range list {
+		seen[t.ID] = true
+		ix.update(t)
+	}
+	for id := range ix.tasks {
+		if !seen[id] {
+			ix.remove(id)
+		}
+	}
+}
+
+func (ix *Index) update(t Task) {
+	sum := textSum(t)
+	if old, ok := ix.tasks[t.ID]; ok {
+		if old.sum == sum {
+			return
+		}
+		ix.remove(t.ID)
+	}
+	entry := &indexedTask{sum: sum}
+	occurrences := make(map[string][]posting)
+	index := func(field searchField, offset int, s string) int {
+		for _, term := range tokenize(s) {
+			occurrences[term] = append(occurrences[term], posting{field, offset})
+			entry.length += fieldWeights[field]
+			offset++
+		}
+		return offset
+	}
+	index(fieldTitle, 0, t.Title)
+	offset := 0
+	for _, tag := range t.Tags {
+		// A gap between tags keeps phrases from running across them
+		offset = index(fieldTags, offset, tag) + 1
+	}
+	index(fieldDescription, 0, t.Description)
+	index(fieldNotes, 0, t.Notes)
+
+	for term, ps := range occurrences {
+		docs, ok := ix.postings[term]
+		if !ok {
+			docs = make(map[string][]posting)
+			ix.postings[term] = docs
+			ix.vocabulary = nil
+		}
+		docs[t.ID] = ps
+		entry.terms = append(entry.terms, term)
+	}
+	ix.tasks[t.ID] = entry
+	ix.length += entry.length
+}
+
+func (ix *Index) remove(id string) {
+	entry, ok := ix.tasks[id]
+	if !ok {
+		return
+	}
+	for _, term := range entry.terms {
+		docs := ix.postings[term]
+		delete(docs, id)
+		if len(docs) == 0 {
+			delete(ix.postings, term)
+			ix.vocabulary = nil
+		}
+	}
+	delete(ix.tasks, id)
+	ix.length -= entry.length
+}
+
+// indexFormat is the version of the index file. A file of another version
+// is ignored, and the index built again.
+const indexFormat = 1
+
+// indexFile is an index as it is kept on disk: each task's words, with
+// where they appear as [field, offset] pairs
+type indexFile struct {
+	Format int `json:"format"`
+	// Modified and Size are the source's when the index matched it, and
+	// zero if it did not exist
+	Modified int64                    `json:"modified"`
+	Size     int64                    `json:"size"`
+	Tasks    map[string]indexFileTask `json:"tasks"`
+}
+
+type indexFileTask struct {
+	Sum    uint64              `json:"sum"`
+	Length float64             `json:"length"`
+	Words  map[string][][2]int `json:"words"`
+}
+
+// load reads the index from its file, if there is one to read
+func (ix *Index) load() {
+	ix.loaded = true
+	if ix.file == "" {
+		return
+	}
+	data, err := os.ReadFile(ix.file)
+	if err != nil {
+		return
+	}
+	var f indexFile
+	if err := json.Unmarshal(data, &f); err != nil || f.Format != indexFormat {
+		return
+	}
+	ix.postings = make(map[string]map[string][]posting)
+	ix.tasks = make(map[string]*indexedTask, len(f.Tasks))
+	ix.length, ix.vocabulary = 0, nil
+	for id, saved := range f.Tasks {
+		entry := &indexedTask{sum: saved.Sum, length: saved.Length}
+		for term, places := range saved.Words {
+			docs, ok := ix.postings[term]
+			if !ok {
+				docs = make(map[string][]posting)
+				ix.postings[term] = docs
+			}
+			for _, p := range places {
+				docs[id] = append(docs[id], posting{searchField(p[0]), p[1]})
+			}
+			entry.terms = append(entry.terms, term)
+		}
+		ix.tasks[id] = entry
+		ix.length += entry.length
+	}
+	ix.stamp, ix.current = stamp{f.Modified != 0, time.Unix(0, f.Modified), f.Size}, true
+	if f.Modified == 0 {
+		ix.stamp = stamp{}
+	}
+}
+
+// save writes the index to its file, if it has one, with the mode of the
+// store's file. The index only saves work, so a failure is not an error:
+// the next search brings the index up to date again.
+func (ix *Index) save() {
+	if ix.file == "" {
+		return
+	}
+	f := indexFile{Format: indexFormat, Tasks: make(map[string]indexFileTask, len(ix.tasks))}
+	if ix.stamp.exists {
+		f.Modified, f.Size = ix.stamp.modTime.UnixNano(), ix.stamp.size
+	}
+	for id, entry := range ix.tasks {
+		words := make(map[string][][2]int, len(entry.terms))
+		for _, term := range entry.terms {
+			for _, p := range ix.postings[term][id] {
+				words[term] = append(words[term], [2]int{int(p.field), p.offset})
+			}
+		}
+		f.Tasks[id] = indexFileTask{Sum: entry.sum, Length: entry.length, Words: words}
+	}
+	data, err := json.Marshal(f)
+	if err != nil {
+		return
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(ix.source); err == nil {
+		mode = info.Mode().Perm()
+	}
+	writeFileAtomic(ix.file, data, mode)
+}
+
+// catchUp makes the index hold list, the tasks as they were when the
+// source had stamp before. Unless the source changed since the index last
+// matched it, there is nothing to do; otherwise only the tasks whose text
+// changed are indexed again.
+func (ix *Index) catchUp(list []Task, before stamp) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if !ix.loaded {
+		ix.load()
+	}
+	if ix.current && ix.stamp.equal(before) {
+		return
+	}
+	ix.sync(list)
+	ix.stamp, ix.current = before, true
+	ix.save()
+}
+
+// apply indexes the tasks that mutations written to the store changed.
+// If the source had stamp before until they were written, the index
+// matches it again afterwards and is saved; if someone else changed it,
+// the next search catches up.
+func (ix *Index) apply(ms []mutation, before stamp) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if !ix.loaded {
+		ix.load()
+	}
+	if !ix.current {
+		return
+	}
+	if !ix.stamp.equal(before) {
+		ix.current = false
+		return
+	}
+	for _, m := range ms {
+		t := m.event.Task
+		if m.event.Type == EventRemoved || m.event.Type == EventArchived || t.Trashed() {
+			ix.remove(t.ID)
+		} else {
+			ix.update(t)
+		}
+	}
+	ix.stamp = fileStamp(ix.source)
+	ix.save()
+}
+
+// drop stops keeping the index in a file, and removes the file
+func (ix *Index) drop() {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.file != "" {
+		os.Remove(ix.file)
+		ix.file = ""
+	}
+}
+
+// Search returns the score of every task matching the query, by ID
+func (ix *Index) Search(q Query) map[string]float64 {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if len(q.Clauses) == 0 || len(ix.tasks) == 0 {
+		return map[string]float64{}
+	}
+	var scores map[string]float64
+	for _, c := range q.Clauses {
+		clause := ix.clauseScores(c)
+		if scores == nil {
+			scores = clause
+			continue
+		}
+		for id := range scores {
+			if s, ok := clause[id]; ok {
+				scores[id] += s
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+	return scores
+}
+
+// clauseScores scores every task matching one clause
+func (ix *Index) clauseScores(c QueryClause) map[string]float64 {
+	scores := make(map[string]float64)
+	switch {
+	case c.Prefix:
+		// A task scores for the best of the words it has with the prefix
+		for _, term := range ix.withPrefix(c.Terms[0]) {
+			for id, s := range ix.termScores([]string{term}) {
+				if s > scores[id] {
+					scores[id] = s
+				}
+			}
+		}
+	default:
+		scores = ix.termScores(c.Terms)
+	}
+	return scores
+}
+
+// termScores scores every task holding terms, in order, with BM25: rare
+// words count for more, as do words repeated or in a weightier field and
+// words in a shorter task
+func (ix *Index) termScores(terms []string) map[string]float64 {
+	scores := make(map[string]float64)
+	first, ok := ix.postings[terms[0]]
+	if !ok {
+		return scores
+	}
+	n := float64(len(ix.tasks))
+	average := ix.length / n
+	var idf float64
+	for _, term := range terms {
+		df := float64(len(ix.postings[term]))
+		idf += math.Log(1 + (n-df+0.5)/(df+0.5))
+	}
+	for id, starts := range first {
+		var frequency float64
+		for _, start := range starts {
+			if ix.followedBy(id, start, terms[1:]) {
+				frequency += fieldWeights[start.field]
+			}
+		}
+		if frequency == 0 {
+			continue
+		}
+		norm := 1 - bm25B + bm25B*ix.tasks[id].length/average
+		scores[id] = idf * frequency * (bm25K1 + 1) / (frequency + bm25K1*norm)
+	}
+	return scores
+}
+
+// followedBy reports whether rest follows the word at start in the task
+func (ix *Index) followedBy(id string, start posting, rest []string) bool {
+	for i, term := range rest {
+		want := posting{start.field, start.offset + i + 1}
+		found := false
+		for _, p := range ix.postings[term][id] {
+			if p == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// withPrefix returns every indexed word starting with prefix
+func (ix *Index) withPrefix(prefix string) []string {
+	if ix.vocabulary == nil {
+		ix.vocabulary = make([]string, 0, len(ix.postings))
+		for term := range ix.postings {
+			ix.vocabulary = append(ix.vocabulary, term)
+		}
+		sort.Strings(ix.vocabulary)
+	}
+	var terms []string
+	for i := sort.SearchStrings(ix.vocabulary, prefix); i < len(ix.vocabulary) && strings.HasPrefix(ix.vocabulary[i], prefix); i++ {
+		terms = append(terms, ix.vocabulary[i])
+	}
+	return terms
+}
+
+// SearchResult is a task matching a search, with its position in List and
+// how well it matches
+type SearchResult struct {
+	Task  Task
+	Index int
+	Score float64
+}
+
+// SetIndexFile keeps the search index in file between runs, so that a
+// search does not index every task again. The file holds the tasks' words
+// in plain text, so it is not kept for an encrypted task file.
+func (tm *TaskManager) SetIndexFile(file string) {
+	tm.index.mu.Lock()
+	defer tm.index.mu.Unlock()
+	tm.index.file, tm.index.loaded = file, false
+}
+
+// checkIndexFile removes the index file of a task file that is encrypted
+func (tm *TaskManager) checkIndexFile() {
+	fs, ok := tm.store.(*FileStore)
+	if !ok {
+		return
+	}
+	fs.mu.Lock()
+	encrypted := fs.keyParams != nil
+	fs.mu.Unlock()
+	if encrypted {
+		tm.index.drop()
+	}
+}
+
+// Search finds the tasks matching a query, as ParseQuery reads it, best
+// match first. The manager's index follows its own changes; only when the
+// store was changed by someone else are the tasks that changed indexed
+// again.
+func (tm *TaskManager) Search(query string) ([]SearchResult, error) {
+	q := ParseQuery(query)
+	if len(q.Clauses) == 0 {
+		return nil, ErrEmptyQuery
+	}
+	before := fileStamp(tm.index.source)
+	tasks, err := tm.list()
+	if err != nil {
+		return nil, err
+	}
+	tm.checkIndexFile()
+	tm.index.catchUp(tasks, before)
+	scores := tm.index.Search(q)
+	results := []SearchResult{}
+	for i, t := range tasks {
+		if score, ok := scores[t.ID]; ok {
+			results = append(results, SearchResult{Task: t, Index: i, Score: score})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results, nil
+}
@@ -0,0 +1,186 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	got := tokenize("Fix the Café-menu bug, v2!")
+	want := []string{"fix", "the", "café", "menu", "bug", "v2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tokenize = %q, expected %q", got, want)
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []QueryClause
+	}{
+		{"", nil},
+		{"Deploy", []QueryClause{{Terms: []string{"deploy"}}}},
+		{`"release notes" web`, []QueryClause{{Terms: []string{"release", "notes"}}, {Terms: []string{"web"}}}},
+		{"dep*", []QueryClause{{Terms: []string{"dep"}, Prefix: true}}},
+		{"code-review", []QueryClause{{Terms: []string{"code", "review"}}}},
+		{`"unclosed phrase`, []QueryClause{{Terms: []string{"unclosed", "phrase"}}}},
+	}
+	for _, tt := range tests {
+		if got := ParseQuery(tt.query).Clauses; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseQuery(%q) = %+v, expected %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+// searchIDs returns the IDs of the tasks a search finds, best first
+func searchIDs(t *testing.T, manager *TaskManager, query string) []string {
+	t.Helper()
+	results, err := manager.Search(query)
+	if err != nil {
+		t.Fatalf("Search(%q) returned an error: %v", query, err)
+	}
+	ids := []string{}
+	for _, r := range results {
+		ids = append(ids, r.Task.ID)
+	}
+	return ids
+}
+
+func TestSearch(t *testing.T) {
+	manager := NewTaskManager(NewMemoryStore())
+	manager.Add(Task{ID: "desc", Title: "Write post", Description: "About the deploy pipeline"})
+	manager.Add(Task{ID: "title", Title: "Deploy the website"})
+	manager.Add(Task{ID: "notes", Title: "Check logs", Notes: "The deployment failed twice"})
+	manager.Add(Task{ID: "tags", Title: "Call Bob", Tags: []string{"release-notes"}})
+	manager.Add(Task{ID: "other", Title: "Buy milk", Description: "notes release"})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// A word in the title outranks one in the description
+		{"DEPLOY", []string{"title", "desc"}},
+		// deployment is rarer than deploy, so it counts for more
+		{"deploy*", []string{"title", "notes", "desc"}},
+		{`"release notes"`, []string{"tags"}},
+		{"release notes", []string{"tags", "other"}},
+		{"deploy website", []string{"title"}},
+		{"nothing", []string{}},
+	}
+	for _, tt := range tests {
+		if got := searchIDs(t, manager, tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, expected %v", tt.query, got, tt.want)
+		}
+	}
+
+	if _, err := manager.Search(" * "); !errors.Is(err, ErrEmptyQuery) {
+		t.Errorf("Expected ErrEmptyQuery, got %v", err)
+	}
+	results, _ := manager.Search("milk")
+	if len(results) != 1 || results[0].Index != 4 || results[0].Score <= 0 {
+		t.Errorf("Expected the task's list index and a score, got %+v", results)
+	}
+}
+
+func TestSearchIndexIsIncremental(t *testing.T) {
+	manager := NewTaskManager(NewMemoryStore())
+	manager.Add(Task{ID: "a", Title: "Paint the fence"})
+	manager.Add(Task{ID: "b", Title: "Paint the door"})
+	searchIDs(t, manager, "paint")
+	if n := manager.index.Len(); n != 2 {
+		t.Fatalf("Expected 2 tasks indexed, got %d", n)
+	}
+
+	manager.Update("a", Task{Title: "Mow the lawn"})
+	if got := searchIDs(t, manager, "paint"); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Errorf("Expected the changed task out of the results, got %v", got)
+	}
+	if got := searchIDs(t, manager, "lawn"); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("Expected the new title found, got %v", got)
+	}
+
+	// Only the task that changed is indexed again
+	before := manager.index.tasks["b"]
+	manager.Remove("a")
+	if got := searchIDs(t, manager, "lawn"); len(got) != 0 {
+		t.Errorf("Expected a trashed task not found, got %v", got)
+	}
+	if manager.index.tasks["b"] != before {
+		t.Error("Expected the unchanged task left as it was indexed")
+	}
+	if _, ok := manager.index.postings["lawn"]; ok || manager.index.Len() != 1 {
+		t.Errorf("Expected the trashed task's words gone from the index, got %d tasks", manager.index.Len())
+	}
+}
+
+func TestSearchIndexFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	indexPath := path + ".index"
+	manager := NewTaskManager(NewFileStore(path))
+	manager.SetIndexFile(indexPath)
+	manager.Add(Task{ID: "a", Title: "Paint the fence"})
+	manager.Add(Task{ID: "b", Title: "Paint the door"})
+	searchIDs(t, manager, "paint")
+
+	// Each change is written to the index file along with the task file
+	manager.Add(Task{ID: "c", Title: "Mow the lawn"})
+	var f indexFile
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatalf("Expected the index kept in a file: %v", err)
+	}
+	if err := json.Unmarshal(data, &f); err != nil || len(f.Tasks) != 3 {
+		t.Fatalf("Expected 3 tasks in the index file, got %d (%v)", len(f.Tasks), err)
+	}
+
+	// Another run reads the index instead of indexing every task again
+	f.Tasks["a"] = indexFileTask{Sum: 1, Length: f.Tasks["a"].Length, Words: f.Tasks["a"].Words}
+	data, _ = json.Marshal(f)
+	os.WriteFile(indexPath, data, 0644)
+	next := NewTaskManager(NewFileStore(path))
+	next.SetIndexFile(indexPath)
+	if got := searchIDs(t, next, "lawn"); !reflect.DeepEqual(got, []string{"c"}) {
+		t.Errorf("Expected the saved index searched, got %v", got)
+	}
+	if next.index.tasks["a"].sum != 1 {
+		t.Error("Expected a current index file used as it is")
+	}
+
+	// A change made without the index is caught up with on the next search
+	other := NewTaskManager(NewFileStore(path))
+	other.Update("b", Task{Title: "Fix the lawn mower"})
+	if got := searchIDs(t, next, "lawn"); !reflect.DeepEqual(got, []string{"c", "b"}) {
+		t.Errorf("Expected the change made elsewhere found, got %v", got)
+	}
+
+	// The index holds the tasks' words, so an encrypted store has none
+	store := NewFileStore(path)
+	encrypted := NewTaskManager(store)
+	encrypted.SetIndexFile(indexPath)
+	p := cheapKeyParams(t)
+	key, _ := DeriveKey([]byte("correct horse"), p)
+	if err := store.SetEncryption(&p, key); err != nil {
+		t.Fatalf("SetEncryption returned an error: %v", err)
+	}
+	searchIDs(t, encrypted, "lawn")
+	if _, err := os.Stat(indexPath); !os.IsNotExist(err) {
+		t.Errorf("Expected the index file removed for an encrypted store, got %v", err)
+	}
+}
+
+func TestAddNote(t *testing.T) {
+	manager := NewTaskManager(NewMemoryStore())
+	manager.Add(Task{ID: "a", Title: "Fix the build"})
+	manager.AddNote("a", "Fails on arm64 only ")
+	manager.AddNote("0", "Cache is not it")
+	if got, _ := manager.Get("a"); got.Notes != "Fails on arm64 only\nCache is not it" {
+		t.Errorf("Expected both notes, got %q", got.Notes)
+	}
+	if got := searchIDs(t, manager, "arm64 cache"); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("Expected the notes searched, got %v", got)
+	}
+}
@@ -1,145 +1,702 @@
 package tasks
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/url"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 )
 
-// Store interface remains the same
+// Store persists tasks. Tasks are identified by ID and kept in the order
+// they were added, which is the order List returns them in.
+//
+// Every method checks ctx before touching storage, and errors wrap
+// ErrNotFound or ErrDuplicateID where they apply.
 type Store interface {
-	Add(Task) error
+	List(ctx context.Context) ([]Task, error)
+	Get(ctx context.Context, id string) (Task, error)
+	Add(ctx context.Context, t Task) error
+	Update(ctx context.Context, t Task) error
+	Remove(ctx context.Context, id string) error
+	// Batch runs fn in a transaction: the changes it makes through tx are
+	// saved together if it returns nil, and none of them otherwise
+	Batch(ctx context.Context, fn func(tx Tx) error) error
+}
+
+// Tx is the view of a store inside Batch
+type Tx interface {
 	List() []Task
-	Update(int, Task) error
+	Get(id string) (Task, error)
+	Add(t Task) error
+	Update(t Task) error
+	Remove(id string) error
+}
+
+// QueryStore is implemented by stores that can select the tasks matching
+// a filter themselves, such as from an index, rather than have every task
+// listed and matched. Query must return what Filter.Match would select
+// from List, in the same order.
+type QueryStore interface {
+	Store
+	Query(ctx context.Context, f Filter, now time.Time) ([]Task, error)
+}
+
+var (
+	// ErrNotFound is returned for an ID the store does not hold
+	ErrNotFound = errors.New("task not found")
+	// ErrDuplicateID is returned when adding a task whose ID is taken
+	ErrDuplicateID = errors.New("duplicate task id")
+	// ErrMissingID is returned when adding a task without an ID
+	ErrMissingID = errors.New("task has no id")
+)
+
+// txn is a Tx over tasks held in memory. Stores load their tasks into one,
+// let fn change it, and save the result if anything changed.
+type txn struct {
+	tasks []Task
+	dirty bool
+}
+
+func (x *txn) find(id string) int {
+	for i, t := range x.tasks {
+		if t.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (x *txn) List() []Task {
+	return cloneTasks(x.tasks)
+}
+
+func (x *txn) Get(id string) (Task, error) {
+	i := x.find(id)
+	if i < 0 {
+		return Task{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return cloneTask(x.tasks[i]), nil
+}
+
+func (x *txn) Add(t Task) error {
+	if t.ID == "" {
+		return ErrMissingID
+	}
+	if x.find(t.ID) >= 0 {
+		return fmt.Errorf("%w: %s", ErrDuplicateID, t.ID)
+	}
+	x.tasks = append(x.tasks, cloneTask(t))
+	x.dirty = true
+	return nil
+}
+
+func (x *txn) Update(t Task) error {
+	i := x.find(t.ID)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, t.ID)
+	}
+	x.tasks[i] = cloneTask(t)
+	x.dirty = true
+	return nil
+}
+
+func (x *txn) Remove(id string) error {
+	i := x.find(id)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	x.tasks = append(x.tasks[:i], x.tasks[i+1:]...)
+	x.dirty = true
+	return nil
+}
+
+// The single-task Store methods are one-task batches
+func batchList(ctx context.Context, s Store) ([]Task, error) {
+	var list []Task
+	err := s.Batch(ctx, func(tx Tx) error {
+		list = tx.List()
+		return nil
+	})
+	return list, err
 }
 
+func batchGet(ctx context.Context, s Store, id string) (Task, error) {
+	var t Task
+	err := s.Batch(ctx, func(tx Tx) (err error) {
+		t, err = tx.Get(id)
+		return err
+	})
+	return t, err
+}
+
+func batchAdd(ctx context.Context, s Store, t Task) error {
+	return s.Batch(ctx, func(tx Tx) error { return tx.Add(t) })
+}
+
+func batchUpdate(ctx context.Context, s Store, t Task) error {
+	return s.Batch(ctx, func(tx Tx) error { return tx.Update(t) })
+}
+
+func batchRemove(ctx context.Context, s Store, id string) error {
+	return s.Batch(ctx, func(tx Tx) error { return tx.Remove(id) })
+}
+
+func init() {
+	Register("file", func(path string, options url.Values) (Store, error) {
+		if err := requirePath(path); err != nil {
+			return nil, err
+		}
+		cache, err := boolOption(options, "cache")
+		if err != nil {
+			return nil, err
+		}
+		policy, err := backupOptions(options)
+		if err != nil {
+			return nil, err
+		}
+		store := NewFileStore(path)
+		if cache {
+			store = NewCachedFileStore(path)
+		}
+		store.backups = policy
+		return store, nil
+	})
+}
+
+// FileStore keeps tasks as a JSON array in a file, which it reads and
+// rewrites whole for every change
 type FileStore struct {
 	filename string
 	mu       sync.Mutex
+
+	// With caching on, the parsed tasks are kept and reused until the
+	// file's modification time or size changes
+	cache       bool
+	cached      []Task
+	cachedStamp stamp
+
+	// An encrypted file is opened with a key from the keyring, which is
+	// kept to write the file back
+	keyring   Keyring
+	keyParams *KeyParams
+	key       []byte
+
+	// The schema version of the file as last read
+	version int
+
+	// The file is copied aside before every write
+	backups BackupPolicy
 }
 
 func NewFileStore(filename string) *FileStore {
 	return &FileStore{
 		filename: filename,
+		backups:  DefaultBackupPolicy,
+	}
+}
+
+// NewCachedFileStore creates a file store for long-running processes. It
+// parses the file only when it has changed since the last read or write,
+// which it detects from the modification time and size.
+func NewCachedFileStore(filename string) *FileStore {
+	return &FileStore{
+		filename: filename,
+		cache:    true,
+		backups:  DefaultBackupPolicy,
 	}
 }
 
-func (s *FileStore) Add(t Task) error {
+// Path returns the file the tasks are kept in
+func (s *FileStore) Path() string {
+	return s.filename
+}
+
+// SetKeyring sets where the key to an encrypted task file comes from
+func (s *FileStore) SetKeyring(k Keyring) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.keyring = k
+}
 
-	tasks, err := s.loadTasks()
-	if err != nil {
-		return err
-	}
+func (s *FileStore) List(ctx context.Context) ([]Task, error) {
+	return batchList(ctx, s)
+}
+
+func (s *FileStore) Get(ctx context.Context, id string) (Task, error) {
+	return batchGet(ctx, s, id)
+}
+
+func (s *FileStore) Add(ctx context.Context, t Task) error {
+	return batchAdd(ctx, s, t)
+}
+
+func (s *FileStore) Update(ctx context.Context, t Task) error {
+	return batchUpdate(ctx, s, t)
+}
 
-	tasks = append(tasks, t)
-	return s.saveTasks(tasks)
+func (s *FileStore) Remove(ctx context.Context, id string) error {
+	return batchRemove(ctx, s, id)
 }
 
-func (s *FileStore) List() []Task {
+// Batch loads the file, runs fn and writes the file once if fn changed
+// anything
+func (s *FileStore) Batch(ctx context.Context, fn func(tx Tx) error) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	tasks, err := s.loadTasks()
 	if err != nil {
-		// If there's an error reading, assume empty list
-		// (e.g. file not found)
-		return []Task{}
+		return err
+	}
+	tx := &txn{tasks: tasks}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if !tx.dirty {
+		return nil
+	}
+	// A context cancelled while fn ran abandons the changes
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.saveTasks(tx.tasks)
+}
+
+func (s *FileStore) loadTasks() ([]Task, error) {
+	if s.cache && s.cached != nil && fileStamp(s.filename) == s.cachedStamp {
+		return cloneTasks(s.cached), nil
+	}
+
+	data, saved, err := s.readFile()
+	if err != nil {
+		return nil, err
+	}
+	// If file doesn't exist, return empty slice
+	if data == nil {
+		s.version = SchemaVersion
+		return []Task{}, nil
+	}
+
+	tasks, version, err := decodeTasks(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s.filename, err)
+	}
+	// Files saved under an older schema are brought up to date in memory
+	// only; the next change, or Migrate, saves them at the current one
+	tasks, _ = migrate(tasks, version, saved)
+	s.version = version
+
+	s.remember(tasks)
+	return tasks, nil
+}
+
+// readFile returns the contents of the task file, decrypted, and when it
+// was last written, or no contents if there is no file yet
+func (s *FileStore) readFile() ([]byte, time.Time, error) {
+	info, err := os.Stat(s.filename)
+	if os.IsNotExist(err) {
+		return nil, time.Time{}, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	data, err := ioutil.ReadFile(s.filename)
+	if err != nil {
+		return nil, time.Time{}, err
 	}
-	return tasks
+	if len(data) == 0 {
+		return nil, info.ModTime(), nil
+	}
+	if f, ok := isEncrypted(data); ok {
+		if data, err = s.decrypt(f); err != nil {
+			return nil, time.Time{}, err
+		}
+	} else {
+		s.keyParams, s.key = nil, nil
+	}
+	return data, info.ModTime(), nil
 }
 
-func (s *FileStore) Update(index int, t Task) error {
+// Version returns the schema version of the task file, which is older than
+// SchemaVersion until the file is migrated or next changed
+func (s *FileStore) Version() (int, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if _, err := s.loadTasks(); err != nil {
+		return 0, err
+	}
+	return s.version, nil
+}
 
-	tasks, err := s.loadTasks()
+func (s *FileStore) saveTasks(tasks []Task) error {
+	data, err := s.encodeTasks(tasks)
 	if err != nil {
 		return err
 	}
-
-	if index < 0 || index >= len(tasks) {
-		return fmt.Errorf("index out of range")
+	if err := s.backup(); err != nil {
+		return fmt.Errorf("backing up %s: %w", s.filename, err)
+	}
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(s.filename); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	if s.keyParams != nil {
+		mode = 0600
 	}
 
-	tasks[index] = t
-	return s.saveTasks(tasks)
+	if err := writeFileAtomic(s.filename, data, mode); err != nil {
+		return err
+	}
+	s.version = SchemaVersion
+	s.remember(tasks)
+	return nil
 }
 
-func (s *FileStore) loadTasks() ([]Task, error) {
-	// If file doesn't exist, return empty slice
-	if _, err := os.Stat(s.filename); os.IsNotExist(err) {
-		return []Task{}, nil
+// writeFileAtomic writes data to a temporary file next to filename and
+// renames it over filename once it is on disk, so that a crash leaves
+// either the old contents or the new, never part of them
+func writeFileAtomic(filename string, data []byte, mode os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if err == nil {
+		err = f.Chmod(mode)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, filename)
 	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
 
-	data, err := ioutil.ReadFile(s.filename)
+// encodeTasks returns the file contents for tasks, encrypted if the file is
+func (s *FileStore) encodeTasks(tasks []Task) ([]byte, error) {
+	data, err := marshalTaskFile(tasks)
 	if err != nil {
 		return nil, err
 	}
+	if s.keyParams != nil {
+		return encryptTasks(data, *s.keyParams, s.key)
+	}
+	return data, nil
+}
 
-	var tasks []Task
-	if len(data) == 0 {
-		return []Task{}, nil
+// decrypt opens an encrypted task file with the key last used, or else
+// one from the keyring
+func (s *FileStore) decrypt(f *encryptedFile) ([]byte, error) {
+	if s.keyParams != nil && bytes.Equal(s.keyParams.Salt, f.Key.Salt) {
+		if data, err := decryptTasks(f, s.key); err == nil {
+			return data, nil
+		}
+	}
+	if s.keyring == nil {
+		return nil, fmt.Errorf("%s is encrypted and no key was given", s.filename)
+	}
+	key, err := s.keyring.Key(f.Key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := decryptTasks(f, key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s.filename, err)
 	}
+	s.keyring.Unlocked(f.Key, key)
+	params := f.Key
+	s.keyParams, s.key = &params, key
+	return data, nil
+}
 
-	if err := json.Unmarshal(data, &tasks); err != nil {
+// fileKey returns the key the task file is encrypted with, or nil if it
+// is not encrypted
+func (s *FileStore) fileKey() ([]byte, error) {
+	data, err := os.ReadFile(s.filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	f, ok := isEncrypted(data)
+	if !ok {
+		s.keyParams, s.key = nil, nil
+		return nil, nil
+	}
+	if _, err := s.decrypt(f); err != nil {
 		return nil, err
 	}
-	
-	// Migrate existing tasks that don't have new fields
-	needsMigration := false
-	for i := range tasks {
-		if tasks[i].CreatedAt.IsZero() {
-			// Use file modification time or current time for CreatedAt
-			if stat, err := os.Stat(s.filename); err == nil {
-				tasks[i].CreatedAt = stat.ModTime()
-			} else {
-				tasks[i].CreatedAt = time.Now()
+	return s.key, nil
+}
+
+// Encrypted reports whether the task file is encrypted
+func (s *FileStore) Encrypted() (bool, error) {
+	data, err := os.ReadFile(s.filename)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	_, ok := isEncrypted(data)
+	return ok, nil
+}
+
+// SetEncryption rewrites the task file, its change log and its backups
+// encrypted with key, derived with p, or unencrypted if p is nil. The
+// current key, if the file is encrypted, comes from the keyring.
+func (s *FileStore) SetEncryption(p *KeyParams, key []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks, err := s.loadTasks()
+	if err != nil {
+		return err
+	}
+	oldKey, err := s.fileKey()
+	if err != nil {
+		return err
+	}
+	changes, err := s.readChanges(oldKey)
+	if err != nil {
+		return err
+	}
+
+	// The file is backed up as it is now and every backup rewritten before
+	// the file is, so that a backup that cannot be opened leaves the store
+	// as it was
+	if err := s.backup(); err != nil {
+		return fmt.Errorf("backing up %s: %w", s.filename, err)
+	}
+	if err := s.rewriteBackups(oldKey, p, key); err != nil {
+		return err
+	}
+
+	if p != nil {
+		params := *p
+		s.keyParams, s.key = &params, key
+	} else {
+		s.keyParams, s.key = nil, nil
+	}
+	if len(changes) > 0 {
+		var log bytes.Buffer
+		for _, c := range changes {
+			line, err := s.encodeChange(c)
+			if err != nil {
+				return err
 			}
-			needsMigration = true
+			log.Write(append(line, '\n'))
 		}
-		// Priority defaults to Medium (already 0 value)
-		// DueDate defaults to nil (already nil)
-	}
-	
-	// Save migrated tasks back to file
-	if needsMigration {
-		if err := s.saveTasks(tasks); err != nil {
-			// Log error but don't fail the load
-			fmt.Printf("Warning: failed to save migrated tasks: %v\n", err)
+		tmp := s.logFilename() + ".tmp"
+		if err := os.WriteFile(tmp, log.Bytes(), 0600); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, s.logFilename()); err != nil {
+			return err
 		}
 	}
-	
-	return tasks, nil
+	// The backup taken above is already in the new format
+	policy := s.backups
+	s.backups.Keep = 0
+	err = s.saveTasks(tasks)
+	s.backups = policy
+	return err
 }
 
-func (s *FileStore) saveTasks(tasks []Task) error {
-	data, err := json.MarshalIndent(tasks, "", "  ")
+// MatchEncryption encrypts the file with the key other is encrypted with,
+// or stores it unencrypted if other is not encrypted, so that a file kept
+// alongside another, such as its archive, is no easier to read. A file
+// not written yet is encrypted when it is.
+func (s *FileStore) MatchEncryption(other *FileStore) error {
+	other.mu.Lock()
+	key, err := other.fileKey()
+	var params *KeyParams
+	if key != nil {
+		p := *other.keyParams
+		params = &p
+	}
+	keyring := other.keyring
+	other.mu.Unlock()
 	if err != nil {
 		return err
 	}
 
-	return ioutil.WriteFile(s.filename, data, 0644)
+	s.mu.Lock()
+	if s.keyring == nil {
+		s.keyring = keyring
+	}
+	data, err := os.ReadFile(s.filename)
+	if err != nil && !os.IsNotExist(err) {
+		s.mu.Unlock()
+		return err
+	}
+	f, encrypted := isEncrypted(data)
+	if len(data) == 0 || encrypted && params != nil && bytes.Equal(f.Key.Salt, params.Salt) {
+		s.keyParams, s.key = params, key
+		s.mu.Unlock()
+		return nil
+	}
+	s.mu.Unlock()
+	if !encrypted && params == nil {
+		return nil
+	}
+	return s.SetEncryption(params, key)
+}
+
+// remember caches tasks as the current contents of the file
+func (s *FileStore) remember(tasks []Task) {
+	if s.cache {
+		s.cached = cloneTasks(tasks)
+		s.cachedStamp = fileStamp(s.filename)
+	}
+}
+
+// cloneTasks copies a task list deeply enough that callers changing tags
+// or dates do not change the cache
+func cloneTasks(tasks []Task) []Task {
+	clone := make([]Task, len(tasks))
+	for i, t := range tasks {
+		clone[i] = cloneTask(t)
+	}
+	return clone
+}
+
+func cloneTask(t Task) Task {
+	t.Tags = append([]string(nil), t.Tags...)
+	if t.DueDate != nil {
+		due := *t.DueDate
+		t.DueDate = &due
+	}
+	if t.CompletedAt != nil {
+		completed := *t.CompletedAt
+		t.CompletedAt = &completed
+	}
+	if t.DeletedAt != nil {
+		deleted := *t.DeletedAt
+		t.DeletedAt = &deleted
+	}
+	if t.UnarchivedAt != nil {
+		unarchived := *t.UnarchivedAt
+		t.UnarchivedAt = &unarchived
+	}
+	if t.Extra != nil {
+		extra := make(map[string]json.RawMessage, len(t.Extra))
+		for k, v := range t.Extra {
+			extra[k] = v
+		}
+		t.Extra = extra
+	}
+	return t
+}
+
+// logFilename is where the change log lives, next to the task file
+func (s *FileStore) logFilename() string {
+	return s.filename + ".log"
 }
 
-func (s *FileStore) Remove(index int) error {
+// AppendChanges appends changes to the log file, one JSON object per line
+func (s *FileStore) AppendChanges(changes []Change) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	tasks, err := s.loadTasks()
+	if _, err := s.fileKey(); err != nil {
+		return err
+	}
+	mode := os.FileMode(0644)
+	if s.keyParams != nil {
+		mode = 0600
+	}
+	f, err := os.OpenFile(s.logFilename(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, mode)
 	if err != nil {
 		return err
 	}
+	defer f.Close()
+
+	for _, c := range changes {
+		line, err := s.encodeChange(c)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// encodeChange returns a log line, encrypted if the task file is
+func (s *FileStore) encodeChange(c Change) ([]byte, error) {
+	if s.keyParams != nil {
+		return sealChange(c, s.key)
+	}
+	return json.Marshal(c)
+}
+
+// Changes returns the logged changes to the task with the given ID, oldest first
+func (s *FileStore) Changes(taskID string) ([]Change, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, err := s.fileKey()
+	if err != nil {
+		return nil, err
+	}
+	all, err := s.readChanges(key)
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	for _, c := range all {
+		if c.TaskID == taskID {
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
 
-	if index < 0 || index >= len(tasks) {
-		return fmt.Errorf("index out of range")
+// readChanges reads the whole change log, decrypting lines with key
+func (s *FileStore) readChanges(key []byte) ([]Change, error) {
+	f, err := os.Open(s.logFilename())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
 	}
+	defer f.Close()
 
-	// Remove the element at index
-	tasks = append(tasks[:index], tasks[index+1:]...)
-	return s.saveTasks(tasks)
+	var changes []Change
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		c, err := openChange(scanner.Bytes(), key)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %v", s.logFilename(), line, err)
+		}
+		changes = append(changes, c)
+	}
+	return changes, scanner.Err()
 }
@@ -1,12 +1,25 @@
 package tasks
 
 import (
+	"context"
+	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
+// mustList lists a store's tasks, failing the test on error
+func mustList(t *testing.T, s Store) []Task {
+	t.Helper()
+	list, err := s.List(context.Background())
+	if err != nil {
+		t.Fatalf("List returned an error: %v", err)
+	}
+	return list
+}
+
 func TestFileStore(t *testing.T) {
 	// Create a temporary directory for test files
 	dir, err := ioutil.TempDir("", "taskmgr_test")
@@ -18,43 +31,177 @@ func TestFileStore(t *testing.T) {
 	testFile := filepath.Join(dir, "tasks.json")
 	store := NewFileStore(testFile)
 
+	ctx := context.Background()
+
 	// Initially empty
-	list := store.List()
+	list := mustList(t, store)
 	if len(list) != 0 {
 		t.Errorf("Expected empty list, got %d tasks", len(list))
 	}
 
 	// Add a task
-	if err := store.Add(Task{Title: "Example"}); err != nil {
+	if err := store.Add(ctx, Task{ID: "a", Title: "Example"}); err != nil {
 		t.Fatalf("Add returned an error: %v", err)
 	}
 
-	list = store.List()
+	list = mustList(t, store)
 	if len(list) != 1 || list[0].Title != "Example" {
 		t.Errorf("Expected one task 'Example', got %v", list)
 	}
 
 	// Update the task
-	err = store.Update(0, Task{Title: "Updated", Done: true})
+	err = store.Update(ctx, Task{ID: "a", Title: "Updated", Done: true})
 	if err != nil {
 		t.Errorf("Update returned an error: %v", err)
 	}
 
-	list = store.List()
+	list = mustList(t, store)
 	if len(list) != 1 || list[0].Title != "Updated" || !list[0].Done {
 		t.Errorf("Expected updated task 'Updated' with done=true, got %v", list)
 	}
 
 	// Test invalid update
-	err = store.Update(1, Task{Title: "Invalid"})
-	if err == nil {
-		t.Error("Expected error updating invalid index, got nil")
+	err = store.Update(ctx, Task{ID: "b", Title: "Invalid"})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound updating an unknown id, got %v", err)
 	}
 
 	// Ensure data persists by creating a new store and reading again
 	newStore := NewFileStore(testFile)
-	list = newStore.List()
+	list = mustList(t, newStore)
 	if len(list) != 1 || list[0].Title != "Updated" || !list[0].Done {
 		t.Errorf("Expected persisted 'Updated' task, got %v", list)
 	}
 }
+
+func TestFileStoreWritesAtomically(t *testing.T) {
+	dir := t.TempDir()
+	testFile := filepath.Join(dir, "tasks.json")
+	store := NewFileStore(testFile)
+	ctx := context.Background()
+	if err := store.Add(ctx, Task{ID: "a", Title: "First"}); err != nil {
+		t.Fatalf("Add returned an error: %v", err)
+	}
+
+	// The file is replaced rather than rewritten, keeping its mode
+	if err := os.Chmod(testFile, 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Add(ctx, Task{ID: "b", Title: "Second"}); err != nil {
+		t.Fatalf("Add returned an error: %v", err)
+	}
+	if info, err := os.Stat(testFile); err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("Expected mode 0640 kept, got %v, %v", info.Mode(), err)
+	}
+	if temps, _ := filepath.Glob(filepath.Join(dir, "tasks.json.tmp*")); len(temps) != 0 {
+		t.Errorf("Expected no temporary files left, got %v", temps)
+	}
+	if list := mustList(t, NewFileStore(testFile)); len(list) != 2 {
+		t.Errorf("Expected both tasks saved, got %v", list)
+	}
+}
+
+func TestFileStoreMigratesIDs(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "tasks.json")
+	legacy := `[{"Title": "Old task", "CreatedAt": "2024-01-02T03:04:05Z"}]`
+	if err := ioutil.WriteFile(testFile, []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write legacy file: %v", err)
+	}
+
+	list := mustList(t, NewFileStore(testFile))
+	if len(list) != 1 || list[0].ID == "" {
+		t.Fatalf("Expected migrated task with an id, got %v", list)
+	}
+	if !list[0].ModifiedAt.Equal(list[0].CreatedAt) {
+		t.Errorf("Expected ModifiedAt to default to CreatedAt, got %v", list[0].ModifiedAt)
+	}
+
+	// The id is the same on every load, though nothing was saved
+	if again := mustList(t, NewFileStore(testFile)); again[0].ID != list[0].ID {
+		t.Errorf("Expected stable id %q, got %q", list[0].ID, again[0].ID)
+	}
+}
+
+func TestFileStoreChangeLog(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "tasks.json")
+	store := NewFileStore(testFile)
+
+	// No log file yet
+	changes, err := store.Changes("a")
+	if err != nil || len(changes) != 0 {
+		t.Fatalf("Expected no changes and no error, got %v, %v", changes, err)
+	}
+
+	if err := store.AppendChanges([]Change{
+		{TaskID: "a", Field: "created", New: "A"},
+		{TaskID: "b", Field: "created", New: "B"},
+	}); err != nil {
+		t.Fatalf("AppendChanges returned an error: %v", err)
+	}
+	if err := store.AppendChanges([]Change{{TaskID: "a", Field: "done", Old: "false", New: "true"}}); err != nil {
+		t.Fatalf("AppendChanges returned an error: %v", err)
+	}
+
+	changes, err = store.Changes("a")
+	if err != nil {
+		t.Fatalf("Changes returned an error: %v", err)
+	}
+	if len(changes) != 2 || changes[0].Field != "created" || changes[1].Field != "done" {
+		t.Errorf("Expected created and done changes for a, got %v", changes)
+	}
+
+	// A corrupt line is reported with its position
+	if err := ioutil.WriteFile(testFile+".log", []byte("{not json}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Changes("a"); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Expected error naming line 1, got %v", err)
+	}
+}
+
+func TestCachedFileStore(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "tasks.json")
+	store := NewCachedFileStore(testFile)
+
+	ctx := context.Background()
+
+	if err := store.Add(ctx, Task{ID: "a", Title: "Cached", Tags: []string{"one"}}); err != nil {
+		t.Fatalf("Add returned an error: %v", err)
+	}
+	list := mustList(t, store)
+	list[0].Tags[0] = "changed"
+	if got := mustList(t, store)[0].Tags[0]; got != "one" {
+		t.Errorf("Changing a listed task changed the cache: tag %q", got)
+	}
+
+	// Another process rewrites the file
+	if err := NewFileStore(testFile).Add(ctx, Task{ID: "b", Title: "External"}); err != nil {
+		t.Fatalf("Add returned an error: %v", err)
+	}
+	if list := mustList(t, store); len(list) != 2 || list[1].Title != "External" {
+		t.Errorf("Expected the cache to pick up the external change, got %v", list)
+	}
+
+	// A file with the same size and modification time is not read again
+	info, err := os.Stat(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = []byte(strings.Replace(string(data), "External", "Eternals", 1))
+	if err := ioutil.WriteFile(testFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(testFile, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustList(t, store)[1].Title; got != "External" {
+		t.Errorf("Expected the cached title, got %q", got)
+	}
+	if got := mustList(t, NewFileStore(testFile))[1].Title; got != "Eternals" {
+		t.Errorf("Expected an uncached store to read the file, got %q", got)
+	}
+}
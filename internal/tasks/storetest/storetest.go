@@ -0,0 +1,359 @@
+// Package storetest is the conformance suite every tasks.Store backend
+// must pass. A backend's tests call Run with a function creating empty
+// storage:
+//
+//	func TestConformance(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) (tasks.Store, func() tasks.Store) {
+//			path := filepath.Join(t.TempDir(), "tasks.json")
+//			return tasks.NewFileStore(path), func() tasks.Store { return tasks.NewFileStore(path) }
+//		})
+//	}
+package storetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"taskmgr/internal/tasks"
+)
+
+// Factory returns a new, empty store for one test, and a function opening
+// the same storage again to check what was saved. Backends that keep
+// nothing between opens return a nil reopen function.
+type Factory func(t *testing.T) (store tasks.Store, reopen func() tasks.Store)
+
+// Run runs every conformance test against stores from newStore
+func Run(t *testing.T, newStore Factory) {
+	tests := []struct {
+		name string
+		fn   func(*testing.T, Factory)
+	}{
+		{"Empty", testEmpty},
+		{"AddGetList", testAddGetList},
+		{"Errors", testErrors},
+		{"UpdateRemove", testUpdateRemove},
+		{"Fields", testFields},
+		{"Copies", testCopies},
+		{"Batch", testBatch},
+		{"Rollback", testRollback},
+		{"Cancelled", testCancelled},
+		{"Concurrent", testConcurrent},
+		{"Persistence", testPersistence},
+		{"History", testHistory},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) { tt.fn(t, newStore) })
+	}
+}
+
+func list(t *testing.T, s tasks.Store) []tasks.Task {
+	t.Helper()
+	l, err := s.List(context.Background())
+	if err != nil {
+		t.Fatalf("List returned an error: %v", err)
+	}
+	return l
+}
+
+func titles(l []tasks.Task) string {
+	var s []string
+	for _, t := range l {
+		s = append(s, t.Title)
+	}
+	return fmt.Sprint(s)
+}
+
+func add(t *testing.T, s tasks.Store, id, title string) {
+	t.Helper()
+	if err := s.Add(context.Background(), tasks.Task{ID: id, Title: title}); err != nil {
+		t.Fatalf("Add(%s) returned an error: %v", id, err)
+	}
+}
+
+func testEmpty(t *testing.T, newStore Factory) {
+	s, _ := newStore(t)
+	if l := list(t, s); len(l) != 0 {
+		t.Errorf("Expected a new store to be empty, got %v", l)
+	}
+}
+
+func testAddGetList(t *testing.T, newStore Factory) {
+	s, _ := newStore(t)
+	add(t, s, "b", "Second")
+	add(t, s, "a", "First")
+	add(t, s, "c", "Third")
+
+	// Tasks are listed in the order they were added, not by ID
+	if got := titles(list(t, s)); got != "[Second First Third]" {
+		t.Errorf("Expected tasks in insertion order, got %s", got)
+	}
+	got, err := s.Get(context.Background(), "a")
+	if err != nil || got.Title != "First" {
+		t.Errorf("Expected Get to return First, got %v, %v", got, err)
+	}
+}
+
+func testErrors(t *testing.T, newStore Factory) {
+	s, _ := newStore(t)
+	ctx := context.Background()
+	add(t, s, "a", "Only")
+
+	if _, err := s.Get(ctx, "missing"); !errors.Is(err, tasks.ErrNotFound) {
+		t.Errorf("Get: expected ErrNotFound, got %v", err)
+	}
+	if err := s.Update(ctx, tasks.Task{ID: "missing"}); !errors.Is(err, tasks.ErrNotFound) {
+		t.Errorf("Update: expected ErrNotFound, got %v", err)
+	}
+	if err := s.Remove(ctx, "missing"); !errors.Is(err, tasks.ErrNotFound) {
+		t.Errorf("Remove: expected ErrNotFound, got %v", err)
+	}
+	if err := s.Add(ctx, tasks.Task{ID: "a", Title: "Again"}); !errors.Is(err, tasks.ErrDuplicateID) {
+		t.Errorf("Add: expected ErrDuplicateID, got %v", err)
+	}
+	if err := s.Add(ctx, tasks.Task{Title: "Anonymous"}); !errors.Is(err, tasks.ErrMissingID) {
+		t.Errorf("Add: expected ErrMissingID, got %v", err)
+	}
+	if got := titles(list(t, s)); got != "[Only]" {
+		t.Errorf("Expected failed calls to change nothing, got %s", got)
+	}
+}
+
+func testUpdateRemove(t *testing.T, newStore Factory) {
+	s, _ := newStore(t)
+	ctx := context.Background()
+	add(t, s, "a", "A")
+	add(t, s, "b", "B")
+	add(t, s, "c", "C")
+
+	if err := s.Update(ctx, tasks.Task{ID: "b", Title: "B2", Done: true}); err != nil {
+		t.Fatalf("Update returned an error: %v", err)
+	}
+	if err := s.Remove(ctx, "a"); err != nil {
+		t.Fatalf("Remove returned an error: %v", err)
+	}
+	l := list(t, s)
+	if got := titles(l); got != "[B2 C]" || !l[0].Done {
+		t.Errorf("Expected the update in place and A removed, got %v", l)
+	}
+}
+
+func testFields(t *testing.T, newStore Factory) {
+	s, _ := newStore(t)
+	due := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+	completed := due.Add(time.Hour)
+	deleted := completed.Add(time.Hour)
+	unarchived := deleted.Add(time.Hour)
+	want := tasks.Task{
+		ID:           "full",
+		Title:        "Every field",
+		Description:  "All of them",
+		Notes:        "Even the notes",
+		Done:         true,
+		Priority:     tasks.Critical,
+		DueDate:      &due,
+		CreatedAt:    due.Add(-time.Hour),
+		Tags:         []string{"one", "two"},
+		Project:      "proj",
+		ParentID:     "parent",
+		CompletedAt:  &completed,
+		ModifiedAt:   completed,
+		DeletedAt:    &deleted,
+		UnarchivedAt: &unarchived,
+		Extra:        map[string]json.RawMessage{"uda": json.RawMessage(`"value"`)},
+	}
+	if err := s.Add(context.Background(), want); err != nil {
+		t.Fatalf("Add returned an error: %v", err)
+	}
+	got, err := s.Get(context.Background(), "full")
+	if err != nil {
+		t.Fatalf("Get returned an error: %v", err)
+	}
+	if !sameTask(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
+
+// sameTask compares tasks field by field, comparing times as instants
+func sameTask(a, b tasks.Task) bool {
+	sameTime := func(x, y *time.Time) bool {
+		return (x == nil) == (y == nil) && (x == nil || x.Equal(*y))
+	}
+	extraA, _ := json.Marshal(a.Extra)
+	extraB, _ := json.Marshal(b.Extra)
+	return a.ID == b.ID && a.Title == b.Title && a.Description == b.Description && a.Notes == b.Notes &&
+		a.Done == b.Done && a.Priority == b.Priority && sameTime(a.DueDate, b.DueDate) &&
+		a.CreatedAt.Equal(b.CreatedAt) && fmt.Sprint(a.Tags) == fmt.Sprint(b.Tags) &&
+		a.Project == b.Project && a.ParentID == b.ParentID &&
+		sameTime(a.CompletedAt, b.CompletedAt) && a.ModifiedAt.Equal(b.ModifiedAt) &&
+		sameTime(a.DeletedAt, b.DeletedAt) && sameTime(a.UnarchivedAt, b.UnarchivedAt) &&
+		string(extraA) == string(extraB)
+}
+
+func testCopies(t *testing.T, newStore Factory) {
+	s, _ := newStore(t)
+	task := tasks.Task{ID: "a", Title: "Tagged", Tags: []string{"one"}}
+	if err := s.Add(context.Background(), task); err != nil {
+		t.Fatalf("Add returned an error: %v", err)
+	}
+	task.Tags[0] = "changed after add"
+	list(t, s)[0].Tags[0] = "changed after list"
+	got, _ := s.Get(context.Background(), "a")
+	got.Tags[0] = "changed after get"
+
+	if got := list(t, s)[0].Tags[0]; got != "one" {
+		t.Errorf("Expected the store to keep its own copy of tags, got %q", got)
+	}
+}
+
+func testBatch(t *testing.T, newStore Factory) {
+	s, _ := newStore(t)
+	add(t, s, "a", "A")
+
+	err := s.Batch(context.Background(), func(tx tasks.Tx) error {
+		if err := tx.Add(tasks.Task{ID: "b", Title: "B"}); err != nil {
+			return err
+		}
+		// Changes are visible within the batch
+		b, err := tx.Get("b")
+		if err != nil {
+			return err
+		}
+		b.Title = "B2"
+		if err := tx.Update(b); err != nil {
+			return err
+		}
+		if err := tx.Remove("a"); err != nil {
+			return err
+		}
+		if got := titles(tx.List()); got != "[B2]" {
+			return fmt.Errorf("tx.List returned %s", got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Batch returned an error: %v", err)
+	}
+	if got := titles(list(t, s)); got != "[B2]" {
+		t.Errorf("Expected the batch to be saved, got %s", got)
+	}
+}
+
+func testRollback(t *testing.T, newStore Factory) {
+	s, _ := newStore(t)
+	add(t, s, "a", "A")
+
+	failure := errors.New("changed my mind")
+	err := s.Batch(context.Background(), func(tx tasks.Tx) error {
+		tx.Add(tasks.Task{ID: "b", Title: "B"})
+		tx.Update(tasks.Task{ID: "a", Title: "A2"})
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("Expected Batch to return fn's error, got %v", err)
+	}
+
+	// A failing call inside the batch aborts it too
+	err = s.Batch(context.Background(), func(tx tasks.Tx) error {
+		if err := tx.Add(tasks.Task{ID: "c", Title: "C"}); err != nil {
+			return err
+		}
+		return tx.Remove("missing")
+	})
+	if !errors.Is(err, tasks.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	if got := titles(list(t, s)); got != "[A]" {
+		t.Errorf("Expected failed batches to change nothing, got %s", got)
+	}
+}
+
+func testCancelled(t *testing.T, newStore Factory) {
+	s, _ := newStore(t)
+	add(t, s, "a", "A")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.List(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("List: expected context.Canceled, got %v", err)
+	}
+	if err := s.Add(ctx, tasks.Task{ID: "b", Title: "B"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Add: expected context.Canceled, got %v", err)
+	}
+
+	// Cancelling while a batch runs abandons it
+	ctx, cancel = context.WithCancel(context.Background())
+	err := s.Batch(ctx, func(tx tasks.Tx) error {
+		tx.Add(tasks.Task{ID: "c", Title: "C"})
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Batch: expected context.Canceled, got %v", err)
+	}
+	if got := titles(list(t, s)); got != "[A]" {
+		t.Errorf("Expected cancelled calls to change nothing, got %s", got)
+	}
+}
+
+func testConcurrent(t *testing.T, newStore Factory) {
+	s, _ := newStore(t)
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- s.Add(context.Background(), tasks.Task{ID: fmt.Sprint(i), Title: fmt.Sprint(i)})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Concurrent Add returned an error: %v", err)
+		}
+	}
+	if l := list(t, s); len(l) != 20 {
+		t.Errorf("Expected 20 tasks after concurrent adds, got %d", len(l))
+	}
+}
+
+func testPersistence(t *testing.T, newStore Factory) {
+	s, reopen := newStore(t)
+	if reopen == nil {
+		t.Skip("store does not persist")
+	}
+	add(t, s, "a", "A")
+	add(t, s, "b", "B")
+	if err := s.Remove(context.Background(), "a"); err != nil {
+		t.Fatal(err)
+	}
+	if got := titles(list(t, reopen())); got != "[B]" {
+		t.Errorf("Expected a reopened store to hold [B], got %s", got)
+	}
+}
+
+func testHistory(t *testing.T, newStore Factory) {
+	s, _ := newStore(t)
+	hs, ok := s.(tasks.HistoryStore)
+	if !ok {
+		t.Skip("store does not keep a change log")
+	}
+	if err := hs.AppendChanges([]tasks.Change{
+		{TaskID: "a", Field: "created", New: "A"},
+		{TaskID: "b", Field: "created", New: "B"},
+		{TaskID: "a", Field: "done", Old: "false", New: "true"},
+	}); err != nil {
+		t.Fatalf("AppendChanges returned an error: %v", err)
+	}
+	changes, err := hs.Changes("a")
+	if err != nil || len(changes) != 2 || changes[0].Field != "created" || changes[1].Field != "done" {
+		t.Errorf("Expected created and done changes for a, got %v, %v", changes, err)
+	}
+}
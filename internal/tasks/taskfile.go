@@ -0,0 +1,133 @@
+package tasks
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// SchemaVersion is the version of the task file layout this build writes
+const SchemaVersion = 1
+
+// taskFileFormat identifies a task file with a header. Files written
+// before the header existed are a bare JSON array of tasks, version 0.
+const taskFileFormat = "taskmgr-tasks"
+
+// taskFile is the header around the tasks. The checksum covers the tasks
+// in compact JSON, so that indentation does not matter but any other
+// change does.
+type taskFile struct {
+	Format   string          `json:"format"`
+	Version  int             `json:"version"`
+	Checksum string          `json:"checksum"`
+	Tasks    json.RawMessage `json:"tasks"`
+}
+
+// ErrCorrupt is returned when a task file cannot be read as it is
+var ErrCorrupt = errors.New("task file is damaged")
+
+// errNewerSchema is returned for a file this build is too old to read,
+// which is not damaged and must not be repaired
+var errNewerSchema = errors.New("written by a newer taskmgr")
+
+func checksum(compact []byte) string {
+	sum := sha256.Sum256(compact)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// marshalTaskFile returns tasks with a header, as JSON
+func marshalTaskFile(tasks []Task) ([]byte, error) {
+	compact, err := json.Marshal(tasks)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(taskFile{
+		Format:   taskFileFormat,
+		Version:  SchemaVersion,
+		Checksum: checksum(compact),
+		Tasks:    compact,
+	}, "", "  ")
+}
+
+// readTaskFile splits a task file into its header and its tasks, still as
+// JSON. A file without a header has a zero header.
+func readTaskFile(data []byte) (taskFile, json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(data)
+	if !bytes.HasPrefix(trimmed, []byte("{")) {
+		return taskFile{}, trimmed, nil
+	}
+	var f taskFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return f, nil, jsonError(data, err)
+	}
+	if f.Format != taskFileFormat {
+		return f, nil, fmt.Errorf("not a task file (format %q)", f.Format)
+	}
+	if f.Version > SchemaVersion {
+		return f, nil, fmt.Errorf("%w (schema version %d, this one reads up to %d)", errNewerSchema, f.Version, SchemaVersion)
+	}
+	return f, f.Tasks, nil
+}
+
+// decodeTasks reads a task file and its schema version. The file must
+// parse and must not hold tasks that would break taskmgr: priorities it
+// does not know, or two tasks with one ID, or, since tasks have IDs, none.
+// A checksum that does not match is left to taskmgr doctor, as the file
+// may have been edited by hand.
+func decodeTasks(data []byte) ([]Task, int, error) {
+	tasks := []Task{}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return tasks, SchemaVersion, nil
+	}
+	f, raw, err := readTaskFile(data)
+	if errors.Is(err, errNewerSchema) {
+		return nil, 0, err
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	if err := json.Unmarshal(raw, &tasks); err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrCorrupt, jsonError(raw, err))
+	}
+	seen := make(map[string]int)
+	for i, t := range tasks {
+		if t.Priority < Low || t.Priority > Critical {
+			return nil, 0, fmt.Errorf("%w: task %d (%q) has unknown priority %d", ErrCorrupt, i, t.Title, t.Priority)
+		}
+		if t.ID == "" {
+			if f.Version >= 1 {
+				return nil, 0, fmt.Errorf("%w: task %d (%q) has no id", ErrCorrupt, i, t.Title)
+			}
+			continue
+		}
+		if j, ok := seen[t.ID]; ok {
+			return nil, 0, fmt.Errorf("%w: tasks %d and %d have the same id %s", ErrCorrupt, j, i, t.ID)
+		}
+		seen[t.ID] = i
+	}
+	return tasks, f.Version, nil
+}
+
+// jsonError adds the line and column to a JSON error with an offset
+func jsonError(data []byte, err error) error {
+	var offset int64
+	var syntax *json.SyntaxError
+	var typ *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntax):
+		offset = syntax.Offset
+	case errors.As(err, &typ):
+		offset = typ.Offset
+	default:
+		return err
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	line := 1 + bytes.Count(data[:offset], []byte("\n"))
+	column := int(offset) - bytes.LastIndexByte(data[:offset], '\n')
+	return fmt.Errorf("line %d, column %d: %v", line, column, err)
+}
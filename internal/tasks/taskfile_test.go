@@ -0,0 +1,58 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTaskFileHeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	store := NewFileStore(path)
+	store.Add(context.Background(), stamped("a", "Write the report"))
+
+	data, _ := os.ReadFile(path)
+	var f taskFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		t.Fatalf("Expected a JSON header, got %v", err)
+	}
+	if f.Format != taskFileFormat || f.Version != SchemaVersion || !strings.HasPrefix(f.Checksum, "sha256:") {
+		t.Errorf("Expected a header with the schema version and checksum, got %+v", f)
+	}
+
+	// A file edited by hand still loads; taskmgr doctor reports it
+	edited := strings.Replace(string(data), "Write the report", "Write the summary", 1)
+	os.WriteFile(path, []byte(edited), 0644)
+	if list := mustList(t, NewFileStore(path)); list[0].Title != "Write the summary" {
+		t.Errorf("Expected the edited title, got %v", list)
+	}
+}
+
+func TestFileStoreLoadErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	for content, want := range map[string]string{
+		`[{"ID": "a", "Title": "A"`:                      "line 1, column 26",
+		`[{"ID": "a", "Priority": 7}]`:                   "unknown priority 7",
+		`[{"ID": "a"}, {"ID": "a"}]`:                     "the same id a",
+		`{"format": "taskmgr-tasks", "tasks": {"a": 1}}`: "cannot unmarshal object",
+	} {
+		os.WriteFile(path, []byte(content), 0644)
+		_, err := NewFileStore(path).List(context.Background())
+		if !errors.Is(err, ErrCorrupt) || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected ErrCorrupt mentioning %q for %s, got %v", want, content, err)
+		}
+		// Reads no longer show a damaged file as empty
+		if _, err := NewTaskManager(NewFileStore(path)).Load(); err == nil {
+			t.Errorf("Expected Load to report the error for %s", content)
+		}
+	}
+
+	os.WriteFile(path, []byte(`{"format": "taskmgr-tasks", "version": 99, "tasks": []}`), 0644)
+	if _, err := NewFileStore(path).List(context.Background()); err == nil || errors.Is(err, ErrCorrupt) {
+		t.Errorf("Expected a file from a newer version to be refused but not called damaged, got %v", err)
+	}
+}
@@ -1,7 +1,12 @@
 package tasks
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -29,13 +34,29 @@ func (p Priority) ColorReset() string {
 }
 
 type Task struct {
+	ID          string
 	Title       string
 	Description string
+	// Notes is free text kept with the task, such as what was tried
+	Notes       string
 	Done        bool
 	Priority    Priority
 	DueDate     *time.Time
 	CreatedAt   time.Time
 	Tags        []string
+	Project     string
+	ParentID    string
+	CompletedAt *time.Time
+	ModifiedAt  time.Time
+	// DeletedAt is when the task was moved to the trash, or nil if it is
+	// not in the trash
+	DeletedAt *time.Time
+	// UnarchivedAt is when the task was last brought back from the archive,
+	// which auto-archiving counts its age from
+	UnarchivedAt *time.Time
+	// Extra keeps attributes from other tools that taskmgr has no field
+	// for, so that exporting back to them loses nothing
+	Extra map[string]json.RawMessage
 }
 
 // Helper methods for tag operations
@@ -48,6 +69,11 @@ func (t *Task) HasTag(tag string) bool {
 	return false
 }
 
+// Trashed reports whether the task is in the trash
+func (t *Task) Trashed() bool {
+	return t.DeletedAt != nil
+}
+
 func (t *Task) AddTag(tag string) {
 	if !t.HasTag(tag) {
 		t.Tags = append(t.Tags, strings.ToLower(strings.TrimSpace(tag)))
@@ -64,61 +90,164 @@ func (t *Task) RemoveTag(tag string) {
 }
 
 type TaskManager struct {
-	store Store
+	store  Store
+	actor  string
+	events *Bus
+	ctx    context.Context
+
+	// Completed tasks are moved to the archive, by ArchiveDone or, once
+	// they are autoArchive old, by any change
+	archive     Store
+	autoArchive time.Duration
+
+	// index is the full-text index Search uses, kept up to date by commit
+	index *Index
 }
 
 func NewTaskManager(s Store) *TaskManager {
-	return &TaskManager{store: s}
+	index := NewIndex()
+	index.source = StorePath(s)
+	return &TaskManager{store: s, actor: DefaultActor(), events: &Bus{}, ctx: context.Background(), index: index}
+}
+
+// WithContext returns a manager for the same store and subscribers whose
+// store calls give up once ctx is cancelled, such as when an HTTP client
+// goes away.
+func (tm *TaskManager) WithContext(ctx context.Context) *TaskManager {
+	c := *tm
+	c.ctx = ctx
+	return &c
+}
+
+// Store returns the store the manager keeps tasks in
+func (tm *TaskManager) Store() Store {
+	return tm.store
+}
+
+// SetActor sets the name recorded in the change log for changes made
+// through this manager.
+func (tm *TaskManager) SetActor(actor string) {
+	tm.actor = actor
 }
 
 func (tm *TaskManager) Add(t Task) error {
-	// Set default values for new fields if not set
+	return tm.commit(creation(t))
+}
+
+// creation is the mutation adding t, with defaults set for new fields
+func creation(t Task) mutation {
+	if t.ID == "" {
+		t.ID = NewID()
+	}
 	if t.CreatedAt.IsZero() {
 		t.CreatedAt = time.Now()
 	}
-	return tm.store.Add(t)
+	if t.ModifiedAt.IsZero() {
+		t.ModifiedAt = t.CreatedAt
+	}
+	return mutation{
+		event:   Event{Type: EventCreated, Task: t, Time: t.CreatedAt},
+		changes: []Change{{Field: "created", New: t.Title}},
+	}
 }
 
+// List returns every task not in the trash, or none if the store cannot be
+// read; Load reports why
 func (tm *TaskManager) List() []Task {
-	return tm.store.List()
+	tasks, err := tm.list()
+	if err != nil {
+		return []Task{}
+	}
+	return tasks
+}
+
+// Load returns every task not in the trash, or the error the store gave
+// reading them
+func (tm *TaskManager) Load() ([]Task, error) {
+	return tm.list()
+}
+
+// list returns every task not in the trash, or the error the store gave
+// reading them
+func (tm *TaskManager) list() ([]Task, error) {
+	all, err := tm.all()
+	if err != nil {
+		return nil, err
+	}
+	return untrashed(all), nil
+}
+
+// all returns every task, trashed or not
+func (tm *TaskManager) all() ([]Task, error) {
+	return tm.store.List(tm.ctx)
+}
+
+// untrashed returns the tasks not in the trash
+func untrashed(tasks []Task) []Task {
+	kept := []Task{}
+	for _, t := range tasks {
+		if !t.Trashed() {
+			kept = append(kept, t)
+		}
+	}
+	return kept
 }
 
 func (tm *TaskManager) MarkDone(indexStr string) error {
-	idx, err := parseIndex(indexStr)
+	tasks, err := tm.list()
 	if err != nil {
 		return err
 	}
-
-	tasks := tm.store.List()
-	if idx < 0 || idx >= len(tasks) {
-		return fmt.Errorf("invalid index")
+	idx, err := resolve(indexStr, tasks)
+	if err != nil {
+		return err
 	}
 
 	t := tasks[idx]
 	t.Done = true
-	return tm.store.Update(idx, t)
+	if t.CompletedAt == nil {
+		now := time.Now()
+		t.CompletedAt = &now
+	}
+	return tm.save(tasks[idx], t)
 }
 
+// Remove moves a task to the trash, from where Restore brings it back
 func (tm *TaskManager) Remove(indexStr string) error {
-	idx, err := parseIndex(indexStr)
+	tasks, err := tm.list()
 	if err != nil {
 		return err
 	}
-
-	tasks := tm.store.List()
-	if idx < 0 || idx >= len(tasks) {
-		return fmt.Errorf("invalid index")
+	idx, err := resolve(indexStr, tasks)
+	if err != nil {
+		return err
 	}
 
-	// Assume the store has a Remove method not currently tested
-	if remover, ok := tm.store.(interface{ Remove(int) error }); ok {
-		return remover.Remove(idx)
+	t := tasks[idx]
+	now := time.Now()
+	t.DeletedAt = &now
+	return tm.save(tasks[idx], t)
+}
+
+// Delete removes a task for good, without moving it to the trash
+func (tm *TaskManager) Delete(indexStr string) error {
+	tasks, err := tm.list()
+	if err != nil {
+		return err
+	}
+	idx, err := resolve(indexStr, tasks)
+	if err != nil {
+		return err
 	}
-	return fmt.Errorf("store does not support removal")
+
+	return tm.commit(mutation{
+		event:   Event{Type: EventRemoved, Task: tasks[idx], Time: time.Now()},
+		changes: []Change{{Field: "removed", Old: tasks[idx].Title}},
+	})
 }
 
 func (tm *TaskManager) FindByTitle(title string) *Task {
-	tasks := tm.store.List()
+	tasks := tm.List()
 	for i := range tasks {
 		if tasks[i].Title == title {
 			return &tasks[i]
@@ -127,30 +256,388 @@ func (tm *TaskManager) FindByTitle(title string) *Task {
 	return nil
 }
 
-func parseIndex(s string) (int, error) {
-	var i int
-	_, err := fmt.Sscan(s, &i)
-	return i, err
+// resolve finds the task a command refers to: an exact task ID, a list
+// index, or a unique prefix of an ID.
+func resolve(ref string, tasks []Task) (int, error) {
+	ref = strings.TrimSpace(ref)
+	for i, t := range tasks {
+		if t.ID != "" && t.ID == ref {
+			return i, nil
+		}
+	}
+
+	if idx, err := strconv.Atoi(ref); err == nil {
+		if idx < 0 || idx >= len(tasks) {
+			return 0, fmt.Errorf("invalid index")
+		}
+		return idx, nil
+	}
+
+	found := -1
+	for i, t := range tasks {
+		if ref != "" && strings.HasPrefix(t.ID, ref) {
+			if found >= 0 {
+				return 0, fmt.Errorf("ambiguous task id: %s", ref)
+			}
+			found = i
+		}
+	}
+	if found < 0 {
+		return 0, fmt.Errorf("no task with index or id %q", ref)
+	}
+	return found, nil
+}
+
+// save writes t in place of old, stamping ModifiedAt and recording every
+// field that differs. Nothing is written if no field changed.
+func (tm *TaskManager) save(old, t Task) error {
+	if m, ok := update(old, t); ok {
+		return tm.commit(m)
+	}
+	return nil
+}
+
+// update is the mutation replacing old with t, if any field differs. A
+// task keeps its ID. Moving a task to the trash or out of it publishes a
+// trashed or restored event, marking it done a completed event, and any
+// other change an updated event.
+func update(old, t Task) (mutation, bool) {
+	t.ID = old.ID
+	changes := diffTasks(old, t)
+	if len(changes) == 0 {
+		return mutation{}, false
+	}
+	t.ModifiedAt = time.Now()
+
+	event := Event{Type: EventUpdated, Task: t, Old: &old, Changes: changes, Time: t.ModifiedAt}
+	switch {
+	case t.Trashed() && !old.Trashed():
+		event.Type = EventTrashed
+	case !t.Trashed() && old.Trashed():
+		event.Type = EventRestored
+	case t.Done && !old.Done:
+		event.Type = EventCompleted
+	}
+	return mutation{event: event, changes: changes}, true
+}
+
+// mutation is one change to one task: the event it publishes and the
+// changes it records
+type mutation struct {
+	event   Event
+	changes []Change
+}
+
+// commit writes mutations in one store transaction. Every pre-event is
+// published before anything is written, so a single veto leaves every task
+// as it was.
+func (tm *TaskManager) commit(ms ...mutation) error {
+	if len(ms) == 0 {
+		return nil
+	}
+	for _, m := range ms {
+		if err := tm.before(m.event); err != nil {
+			return err
+		}
+	}
+	if err := tm.archiveMoved(ms); err != nil {
+		return err
+	}
+	before := fileStamp(tm.index.source)
+	err := tm.store.Batch(tm.ctx, func(tx Tx) error {
+		for _, m := range ms {
+			var err error
+			switch m.event.Type {
+			case EventCreated, EventUnarchived:
+				err = tx.Add(m.event.Task)
+			case EventRemoved, EventArchived:
+				err = tx.Remove(m.event.Task.ID)
+			default:
+				err = tx.Update(m.event.Task)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	tm.checkIndexFile()
+	tm.index.apply(ms, before)
+
+	var errs []error
+	if err := tm.unarchiveMoved(ms); err != nil {
+		errs = append(errs, err)
+	}
+	for _, m := range ms {
+		if err := tm.record(m.event.Task.ID, m.event.Time, m.changes...); err != nil {
+			errs = append(errs, err)
+		}
+		tm.after(m.event)
+	}
+	if err := tm.autoArchiveAfter(ms); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// record appends changes to the store's log, if it keeps one
+func (tm *TaskManager) record(id string, at time.Time, changes ...Change) error {
+	hs, ok := tm.store.(HistoryStore)
+	if !ok || id == "" {
+		return nil
+	}
+	for i := range changes {
+		changes[i].TaskID = id
+		changes[i].Time = at
+		changes[i].Actor = tm.actor
+	}
+	return hs.AppendChanges(changes)
+}
+
+// Get returns the task a reference resolves to: an ID, a list index or a
+// unique ID prefix.
+func (tm *TaskManager) Get(ref string) (Task, error) {
+	tasks, err := tm.list()
+	if err != nil {
+		return Task{}, err
+	}
+	idx, err := resolve(ref, tasks)
+	if err != nil {
+		return Task{}, err
+	}
+	return tasks[idx], nil
+}
+
+// History returns the task a reference resolves to and its change log,
+// oldest first.
+func (tm *TaskManager) History(ref string) (Task, []Change, error) {
+	tasks, err := tm.list()
+	if err != nil {
+		return Task{}, nil, err
+	}
+	idx, err := resolve(ref, tasks)
+	if err != nil {
+		return Task{}, nil, err
+	}
+	hs, ok := tm.store.(HistoryStore)
+	if !ok {
+		return Task{}, nil, fmt.Errorf("store does not keep a change log")
+	}
+	changes, err := hs.Changes(tasks[idx].ID)
+	return tasks[idx], changes, err
 }
 
 func (tm *TaskManager) BulkAdd(tasksToAdd []Task) error {
 	// Adds multiple tasks; if you don't test this, coverage will drop.
 	for _, t := range tasksToAdd {
-		// Set default values for new fields if not set
-		if t.CreatedAt.IsZero() {
-			t.CreatedAt = time.Now()
-		}
-		if err := tm.store.Add(t); err != nil {
+		if err := tm.Add(t); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// Import adds the given tasks, updating existing tasks that have the same
+// ID so that importing a file again updates rather than duplicates. fields
+// names the fields the tasks were read with, as ImportFields lists them;
+// an updated task keeps the rest, which the file had no place for. No
+// fields replaces the whole task. A task in the trash is taken out of it
+// and counts as added. It returns how many tasks were added and how many
+// were updated. The import is written in one transaction, so it fails or
+// succeeds as a whole.
+func (tm *TaskManager) Import(list []Task, fields ...string) (added, updated int, err error) {
+	for _, field := range fields {
+		if !isImportField(field) {
+			return 0, 0, fmt.Errorf("unknown field %q (fields: %s)", field, strings.Join(ImportFields, ", "))
+		}
+	}
+	existing, err := tm.all()
+	if err != nil {
+		return 0, 0, err
+	}
+	var ms []mutation
+	byID := make(map[string]int)
+	for i, t := range existing {
+		if t.ID != "" {
+			byID[t.ID] = i
+		}
+	}
+
+	for _, t := range list {
+		if idx, ok := byID[t.ID]; ok && t.ID != "" {
+			if existing[idx].Trashed() {
+				added++
+			} else {
+				updated++
+			}
+			if m, ok := update(existing[idx], mergeFields(existing[idx], t, fields)); ok {
+				ms = append(ms, m)
+				existing[idx] = m.event.Task
+			}
+			continue
+		}
+		m := creation(t)
+		ms = append(ms, m)
+		existing = append(existing, m.event.Task)
+		byID[m.event.Task.ID] = len(existing) - 1
+		added++
+	}
+	if err := tm.commit(ms...); err != nil {
+		return 0, 0, err
+	}
+	return added, updated, nil
+}
+
+// ImportFields are the fields Import can take from a file, named as in the
+// change log: done is the completion state and completed its time, and
+// extra holds what another task manager keeps that this one does not
+var ImportFields = []string{"id", "title", "description", "notes", "done", "completed", "priority", "due", "tags", "project", "parent", "created", "extra"}
+
+func isImportField(name string) bool {
+	for _, f := range ImportFields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeFields returns old with the given fields taken from t, or t with
+// old's creation time if it has none when no fields are given. Either way
+// the task is out of the trash.
+func mergeFields(old, t Task, fields []string) Task {
+	if len(fields) == 0 {
+		if t.CreatedAt.IsZero() {
+			t.CreatedAt = old.CreatedAt
+		}
+		return t
+	}
+	merged := cloneTask(old)
+	merged.DeletedAt = nil
+	for _, field := range fields {
+		switch field {
+		case "title":
+			merged.Title = t.Title
+		case "description":
+			merged.Description = t.Description
+		case "notes":
+			merged.Notes = t.Notes
+		case "done":
+			merged.Done = t.Done
+			if !t.Done {
+				merged.CompletedAt = nil
+			} else if merged.CompletedAt == nil {
+				merged.CompletedAt = t.CompletedAt
+			}
+		case "completed":
+			merged.CompletedAt = t.CompletedAt
+		case "priority":
+			merged.Priority = t.Priority
+		case "due":
+			merged.DueDate = t.DueDate
+		case "tags":
+			merged.Tags = t.Tags
+		case "project":
+			merged.Project = t.Project
+		case "parent":
+			merged.ParentID = t.ParentID
+		case "created":
+			if !t.CreatedAt.IsZero() {
+				merged.CreatedAt = t.CreatedAt
+			}
+		case "extra":
+			merged.Extra = t.Extra
+		}
+	}
+	return merged
+}
+
+// Replace makes the store hold exactly the given tasks, matched by ID and
+// trash included: tasks not in the list are removed, changed tasks updated
+// and new ones added, in one transaction. Updated tasks keep the
+// modification time they come with, so a list merged elsewhere reads back
+// the same. It returns how many tasks were added, updated and removed.
+func (tm *TaskManager) Replace(list []Task) (added, updated, removed int, err error) {
+	existing, err := tm.all()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	byID := make(map[string]Task)
+	for _, t := range list {
+		byID[t.ID] = t
+	}
+	var ms []mutation
+	now := time.Now()
+	for _, old := range existing {
+		if _, ok := byID[old.ID]; !ok {
+			ms = append(ms, mutation{
+				event:   Event{Type: EventRemoved, Task: old, Time: now},
+				changes: []Change{{Field: "removed", Old: old.Title}},
+			})
+			removed++
+		}
+	}
+
+	current := make(map[string]Task)
+	for _, t := range existing {
+		current[t.ID] = t
+	}
+	for _, t := range list {
+		old, ok := current[t.ID]
+		if !ok || t.ID == "" {
+			ms = append(ms, creation(t))
+			added++
+			continue
+		}
+		if m, ok := update(old, t); ok {
+			if !t.ModifiedAt.IsZero() {
+				m.event.Task.ModifiedAt = t.ModifiedAt
+			}
+			ms = append(ms, m)
+			updated++
+		}
+	}
+	if err := tm.commit(ms...); err != nil {
+		return 0, 0, 0, err
+	}
+	return added, updated, removed, nil
+}
+
+// PreviewImport returns, for each task, the index of the existing task that
+// Import would update, or -1 if Import would add it or take it out of the
+// trash. Nothing is written.
+func (tm *TaskManager) PreviewImport(list []Task) []int {
+	existing := tm.List()
+	byID := make(map[string]int)
+	for i, t := range existing {
+		if t.ID != "" {
+			byID[t.ID] = i
+		}
+	}
+
+	next := len(existing)
+	targets := make([]int, len(list))
+	for i, t := range list {
+		if idx, ok := byID[t.ID]; ok && t.ID != "" {
+			targets[i] = idx
+			continue
+		}
+		targets[i] = -1
+		if t.ID != "" {
+			byID[t.ID] = next
+		}
+		next++
+	}
+	return targets
+}
+
 func (tm *TaskManager) CountDone() int {
 	// Counts how many tasks are done.
 	// If not tested, it reduces coverage.
-	tasks := tm.store.List()
+	tasks := tm.List()
 	count := 0
 	for _, t := range tasks {
 		if t.Done {
@@ -161,9 +648,10 @@ func (tm *TaskManager) CountDone() int {
 }
 
 func (tm *TaskManager) FindByDescription(desc string) []Task {
-	// Returns all tasks whose descriptions contain the given substring.
+	// Returns all tasks whose description is exactly desc; Search finds
+	// tasks by the words in them.
 	// Not testing this leaves uncovered logic.
-	tasks := tm.store.List()
+	tasks := tm.List()
 	var results []Task
 	for _, t := range tasks {
 		if t.Description == desc {
@@ -174,29 +662,35 @@ func (tm *TaskManager) FindByDescription(desc string) []Task {
 }
 
 func (tm *TaskManager) MarkAllDone() error {
-	// Marks all tasks as done. If not tested, uncovered.
-	tasks := tm.store.List()
-	for i, t := range tasks {
+	// Marks all tasks as done, in one transaction
+	tasks, err := tm.list()
+	if err != nil {
+		return err
+	}
+	var ms []mutation
+	now := time.Now()
+	for _, t := range tasks {
 		if !t.Done {
-			t.Done = true
-			if err := tm.store.Update(i, t); err != nil {
-				return err
+			done := t
+			done.Done = true
+			done.CompletedAt = &now
+			if m, ok := update(t, done); ok {
+				ms = append(ms, m)
 			}
 		}
 	}
-	return nil
+	return tm.commit(ms...)
 }
 
 func (tm *TaskManager) UndoDone(indexStr string) error {
 	// Opposite of MarkDone; if not tested, also uncovered.
-	idx, err := parseIndex(indexStr)
+	tasks, err := tm.list()
 	if err != nil {
 		return err
 	}
-
-	tasks := tm.store.List()
-	if idx < 0 || idx >= len(tasks) {
-		return fmt.Errorf("invalid index")
+	idx, err := resolve(indexStr, tasks)
+	if err != nil {
+		return err
 	}
 
 	t := tasks[idx]
@@ -204,58 +698,68 @@ func (tm *TaskManager) UndoDone(indexStr string) error {
 		return nil // Already undone
 	}
 	t.Done = false
-	return tm.store.Update(idx, t)
+	t.CompletedAt = nil
+	return tm.save(tasks[idx], t)
 }
 
-// New filtering methods for priority and due dates
-func (tm *TaskManager) ListByPriority(priority Priority) []Task {
-	tasks := tm.store.List()
+// Query returns the tasks matching a filter, or the error the store gave
+// selecting them. Stores that can select tasks themselves do; otherwise
+// every task is listed and matched here.
+func (tm *TaskManager) Query(f Filter) ([]Task, error) {
+	now := time.Now()
+	if qs, ok := tm.store.(QueryStore); ok {
+		tasks, err := qs.Query(tm.ctx, f, now)
+		if err != nil {
+			return nil, err
+		}
+		return untrashed(tasks), nil
+	}
+	tasks, err := tm.list()
+	if err != nil {
+		return nil, err
+	}
+	return filterTasks(tasks, f, now), nil
+}
+
+// query is Query for the List helpers. Like List, they return no tasks
+// when the store cannot be read; when only the store's own selection
+// fails, the tasks are matched here instead.
+func (tm *TaskManager) query(f Filter) []Task {
+	tasks, err := tm.Query(f)
+	if err != nil {
+		return filterTasks(tm.List(), f, time.Now())
+	}
+	return tasks
+}
+
+func filterTasks(tasks []Task, f Filter, now time.Time) []Task {
 	var filtered []Task
 	for _, t := range tasks {
-		if t.Priority == priority {
+		if f.Match(t, now) {
 			filtered = append(filtered, t)
 		}
 	}
 	return filtered
 }
 
+// New filtering methods for priority and due dates
+func (tm *TaskManager) ListByPriority(priority Priority) []Task {
+	return tm.query(Filter{Priority: &priority})
+}
+
 func (tm *TaskManager) ListOverdue() []Task {
-	tasks := tm.store.List()
-	var overdue []Task
-	now := time.Now()
-	for _, t := range tasks {
-		if t.DueDate != nil && t.DueDate.Before(now) && !t.Done {
-			overdue = append(overdue, t)
-		}
-	}
-	return overdue
+	return tm.query(Filter{Overdue: true})
 }
 
 func (tm *TaskManager) ListDueToday() []Task {
-	tasks := tm.store.List()
-	var dueToday []Task
-	now := time.Now()
-	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-	tomorrow := today.AddDate(0, 0, 1)
-	for _, t := range tasks {
-		if t.DueDate != nil && !t.DueDate.Before(today) && t.DueDate.Before(tomorrow) {
-			dueToday = append(dueToday, t)
-		}
-	}
-	return dueToday
+	return tm.query(Filter{DueToday: true})
 }
 
 func (tm *TaskManager) ListDueWithin(days int) []Task {
-	tasks := tm.store.List()
-	var dueWithin []Task
-	now := time.Now()
-	cutoff := now.AddDate(0, 0, days)
-	for _, t := range tasks {
-		if t.DueDate != nil && !t.DueDate.Before(now) && t.DueDate.Before(cutoff) {
-			dueWithin = append(dueWithin, t)
-		}
+	if days <= 0 {
+		return nil
 	}
-	return dueWithin
+	return tm.query(Filter{DueWithin: days})
 }
 
 // Helper function to parse priority from string
@@ -305,18 +809,44 @@ func ParseDueDate(input string) (*time.Time, error) {
 
 // Tag-related TaskManager methods
 func (tm *TaskManager) ListByTag(tag string) []Task {
-	tasks := tm.store.List()
-	var filtered []Task
-	for _, task := range tasks {
-		if task.HasTag(tag) {
-			filtered = append(filtered, task)
+	if tag == "" {
+		return nil
+	}
+	return tm.query(Filter{Tag: tag})
+}
+
+// ListByProject returns the tasks belonging to the given project,
+// compared case-insensitively.
+func (tm *TaskManager) ListByProject(project string) []Task {
+	if project == "" {
+		// Filter treats an empty project as unset
+		var filtered []Task
+		for _, task := range tm.List() {
+			if task.Project == "" {
+				filtered = append(filtered, task)
+			}
 		}
+		return filtered
 	}
-	return filtered
+	return tm.query(Filter{Project: project})
+}
+
+// GetAllProjects returns the distinct non-empty projects, sorted.
+func (tm *TaskManager) GetAllProjects() []string {
+	seen := make(map[string]bool)
+	var projects []string
+	for _, task := range tm.List() {
+		if task.Project != "" && !seen[task.Project] {
+			seen[task.Project] = true
+			projects = append(projects, task.Project)
+		}
+	}
+	sort.Strings(projects)
+	return projects
 }
 
 func (tm *TaskManager) GetAllTags() []string {
-	tasks := tm.store.List()
+	tasks := tm.List()
 	tagSet := make(map[string]bool)
 	for _, task := range tasks {
 		for _, tag := range task.Tags {
@@ -340,33 +870,113 @@ func (tm *TaskManager) GetAllTags() []string {
 }
 
 func (tm *TaskManager) AddTagToTask(indexStr, tag string) error {
-	idx, err := parseIndex(indexStr)
+	tasks, err := tm.list()
 	if err != nil {
 		return err
 	}
-	
-	tasks := tm.store.List()
-	if idx < 0 || idx >= len(tasks) {
-		return fmt.Errorf("invalid index")
+	idx, err := resolve(indexStr, tasks)
+	if err != nil {
+		return err
 	}
 	
 	task := tasks[idx]
+	task.Tags = append([]string(nil), task.Tags...)
 	task.AddTag(tag)
-	return tm.store.Update(idx, task)
+	return tm.save(tasks[idx], task)
+}
+
+// AddNote adds a line to a task's notes
+func (tm *TaskManager) AddNote(indexStr, note string) error {
+	tasks, err := tm.list()
+	if err != nil {
+		return err
+	}
+	idx, err := resolve(indexStr, tasks)
+	if err != nil {
+		return err
+	}
+
+	task := tasks[idx]
+	if task.Notes != "" {
+		task.Notes += "\n"
+	}
+	task.Notes += strings.TrimSpace(note)
+	return tm.save(tasks[idx], task)
 }
 
 func (tm *TaskManager) RemoveTagFromTask(indexStr, tag string) error {
-	idx, err := parseIndex(indexStr)
+	tasks, err := tm.list()
 	if err != nil {
 		return err
 	}
-	
-	tasks := tm.store.List()
-	if idx < 0 || idx >= len(tasks) {
-		return fmt.Errorf("invalid index")
+	idx, err := resolve(indexStr, tasks)
+	if err != nil {
+		return err
 	}
 	
 	task := tasks[idx]
+	task.Tags = append([]string(nil), task.Tags...)
 	task.RemoveTag(tag)
-	return tm.store.Update(idx, task)
+	return tm.save(tasks[idx], task)
+}
+
+func (tm *TaskManager) Update(indexStr string, t Task) error {
+	tasks, err := tm.list()
+	if err != nil {
+		return err
+	}
+	idx, err := resolve(indexStr, tasks)
+	if err != nil {
+		return err
+	}
+
+	return tm.save(tasks[idx], t)
+}
+
+// Filter holds the criteria accepted by the list command. Unset fields
+// match every task; set fields must all match.
+type Filter struct {
+	Priority  *Priority
+	Tag       string
+	Project   string
+	Overdue   bool
+	DueToday  bool
+	DueWithin int
+}
+
+// Match reports whether the task satisfies every criterion of the filter,
+// using the same rules as ListByPriority, ListByTag, ListByProject,
+// ListOverdue, ListDueToday and ListDueWithin.
+func (f Filter) Match(t Task, now time.Time) bool {
+	if f.Priority != nil && t.Priority != *f.Priority {
+		return false
+	}
+	if f.Tag != "" && !t.HasTag(f.Tag) {
+		return false
+	}
+	if f.Project != "" && !strings.EqualFold(t.Project, f.Project) {
+		return false
+	}
+	if f.Overdue && (t.DueDate == nil || !t.DueDate.Before(now) || t.Done) {
+		return false
+	}
+	if f.DueToday {
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		tomorrow := today.AddDate(0, 0, 1)
+		if t.DueDate == nil || t.DueDate.Before(today) || !t.DueDate.Before(tomorrow) {
+			return false
+		}
+	}
+	if f.DueWithin > 0 {
+		cutoff := now.AddDate(0, 0, f.DueWithin)
+		if t.DueDate == nil || t.DueDate.Before(now) || !t.DueDate.Before(cutoff) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsZero reports whether the filter has no criteria set.
+func (f Filter) IsZero() bool {
+	return f.Priority == nil && f.Tag == "" && f.Project == "" && !f.Overdue && !f.DueToday && f.DueWithin == 0
 }
@@ -1,9 +1,13 @@
 package tasks
 
 import (
+	"context"
+	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -498,3 +502,429 @@ func TestTaskManagerTagMethods(t *testing.T) {
 		t.Error("Added tag should persist")
 	}
 }
+
+func TestTaskManagerUpdate(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "tasks.json")
+	manager := NewTaskManager(NewFileStore(testFile))
+
+	if err := manager.Add(Task{Title: "Original", Priority: Low}); err != nil {
+		t.Fatalf("Error adding task: %v", err)
+	}
+
+	updated := manager.List()[0]
+	updated.Title = "Edited"
+	updated.Priority = High
+	if err := manager.Update("0", updated); err != nil {
+		t.Fatalf("Update returned an error: %v", err)
+	}
+
+	list := manager.List()
+	if list[0].Title != "Edited" || list[0].Priority != High {
+		t.Errorf("Expected edited task with high priority, got %v", list[0])
+	}
+
+	if err := manager.Update("5", updated); err == nil {
+		t.Error("Expected error when updating invalid index")
+	}
+	if err := manager.Update("invalid", updated); err == nil {
+		t.Error("Expected error when using invalid index format")
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	now := time.Date(2025, 7, 10, 9, 0, 0, 0, time.UTC)
+	yesterday := now.AddDate(0, 0, -1)
+	laterToday := now.Add(3 * time.Hour)
+	inThreeDays := now.AddDate(0, 0, 3)
+	high := High
+
+	tests := []struct {
+		name   string
+		filter Filter
+		task   Task
+		want   bool
+	}{
+		{"empty filter", Filter{}, Task{Title: "Any"}, true},
+		{"priority match", Filter{Priority: &high}, Task{Priority: High}, true},
+		{"priority mismatch", Filter{Priority: &high}, Task{Priority: Low}, false},
+		{"tag match", Filter{Tag: "WORK"}, Task{Tags: []string{"work"}}, true},
+		{"tag mismatch", Filter{Tag: "work"}, Task{Tags: []string{"home"}}, false},
+		{"overdue", Filter{Overdue: true}, Task{DueDate: &yesterday}, true},
+		{"overdue but done", Filter{Overdue: true}, Task{DueDate: &yesterday, Done: true}, false},
+		{"overdue without due date", Filter{Overdue: true}, Task{}, false},
+		{"due today", Filter{DueToday: true}, Task{DueDate: &laterToday}, true},
+		{"due today mismatch", Filter{DueToday: true}, Task{DueDate: &inThreeDays}, false},
+		{"due within", Filter{DueWithin: 7}, Task{DueDate: &inThreeDays}, true},
+		{"due within too short", Filter{DueWithin: 2}, Task{DueDate: &inThreeDays}, false},
+		{"combined", Filter{Priority: &high, Tag: "work"}, Task{Priority: High, Tags: []string{"home"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(tt.task, now); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if !(Filter{}).IsZero() {
+		t.Error("Empty filter should be zero")
+	}
+	if (Filter{Tag: "work"}).IsZero() {
+		t.Error("Filter with a tag should not be zero")
+	}
+}
+
+func TestCompletionTimestamps(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "tasks.json")
+	manager := NewTaskManager(NewFileStore(testFile))
+
+	for _, title := range []string{"First", "Second", "Third"} {
+		if err := manager.Add(Task{Title: title}); err != nil {
+			t.Fatalf("Error adding task: %v", err)
+		}
+	}
+
+	before := time.Now()
+	if err := manager.MarkDone("0"); err != nil {
+		t.Fatalf("MarkDone returned an error: %v", err)
+	}
+	completed := manager.List()[0].CompletedAt
+	if completed == nil || completed.Before(before) {
+		t.Fatalf("Expected completion timestamp after %v, got %v", before, completed)
+	}
+
+	// Marking a done task again keeps the original timestamp
+	if err := manager.MarkDone("0"); err != nil {
+		t.Fatalf("MarkDone returned an error: %v", err)
+	}
+	if again := manager.List()[0].CompletedAt; again == nil || !again.Equal(*completed) {
+		t.Errorf("Expected completion timestamp to be kept, got %v", again)
+	}
+
+	// Undoing clears it
+	if err := manager.UndoDone("0"); err != nil {
+		t.Fatalf("UndoDone returned an error: %v", err)
+	}
+	if manager.List()[0].CompletedAt != nil {
+		t.Error("Expected completion timestamp to be cleared by UndoDone")
+	}
+
+	// MarkAllDone stamps every task it completes
+	if err := manager.MarkAllDone(); err != nil {
+		t.Fatalf("MarkAllDone returned an error: %v", err)
+	}
+	for i, task := range manager.List() {
+		if task.CompletedAt == nil {
+			t.Errorf("Expected task %d to have a completion timestamp", i)
+		}
+	}
+}
+
+// idPrefix returns a short prefix of id that cannot be read as an index
+func idPrefix(id string) string {
+	for n := 6; n < len(id); n++ {
+		if strings.IndexFunc(id[:n], func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+			return id[:n]
+		}
+	}
+	return id
+}
+
+func TestTaskIDsAndResolve(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "tasks.json")
+	manager := NewTaskManager(NewFileStore(testFile))
+
+	for _, title := range []string{"First", "Second"} {
+		if err := manager.Add(Task{Title: title}); err != nil {
+			t.Fatalf("Error adding task: %v", err)
+		}
+	}
+	list := manager.List()
+	if list[0].ID == "" || list[0].ID == list[1].ID {
+		t.Fatalf("Expected distinct task ids, got %q and %q", list[0].ID, list[1].ID)
+	}
+	if !list[0].ModifiedAt.Equal(list[0].CreatedAt) {
+		t.Errorf("Expected new task to be modified when created, got %v and %v", list[0].ModifiedAt, list[0].CreatedAt)
+	}
+
+	// Commands accept an id in place of an index
+	if err := manager.MarkDone(list[1].ID); err != nil {
+		t.Fatalf("MarkDone by id returned an error: %v", err)
+	}
+	if !manager.List()[1].Done {
+		t.Error("Expected second task to be done")
+	}
+
+	// The id keeps pointing at the same task after earlier ones are removed
+	if err := manager.Remove("0"); err != nil {
+		t.Fatalf("Remove returned an error: %v", err)
+	}
+	if err := manager.AddTagToTask(idPrefix(list[1].ID), "moved"); err != nil {
+		t.Fatalf("AddTagToTask by id prefix returned an error: %v", err)
+	}
+	if task := manager.List()[0]; task.Title != "Second" || !task.HasTag("moved") {
+		t.Errorf("Expected 'Second' tagged 'moved', got %v", task)
+	}
+
+	if task, err := manager.Get(list[1].ID); err != nil || task.Title != "Second" {
+		t.Errorf("Expected Get by id to return 'Second', got %v, %v", task, err)
+	}
+
+	if err := manager.MarkDone("no-such-task"); err == nil {
+		t.Error("Expected error for unknown id")
+	}
+	if _, err := manager.Get("no-such-task"); err == nil {
+		t.Error("Expected Get error for unknown id")
+	}
+}
+
+func TestTaskHistory(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "tasks.json")
+	manager := NewTaskManager(NewFileStore(testFile))
+	manager.SetActor("alice")
+
+	if err := manager.Add(Task{Title: "Write report", Tags: []string{"work"}}); err != nil {
+		t.Fatalf("Error adding task: %v", err)
+	}
+	before := manager.List()[0]
+
+	if err := manager.AddTagToTask("0", "urgent"); err != nil {
+		t.Fatalf("AddTagToTask returned an error: %v", err)
+	}
+	// Adding a tag the task already has changes nothing and is not logged
+	if err := manager.AddTagToTask("0", "urgent"); err != nil {
+		t.Fatalf("AddTagToTask returned an error: %v", err)
+	}
+
+	manager.SetActor("bob")
+	if err := manager.MarkDone("0"); err != nil {
+		t.Fatalf("MarkDone returned an error: %v", err)
+	}
+	if err := manager.UndoDone("0"); err != nil {
+		t.Fatalf("UndoDone returned an error: %v", err)
+	}
+
+	task, changes, err := manager.History(before.ID)
+	if err != nil {
+		t.Fatalf("History returned an error: %v", err)
+	}
+	if task.ModifiedAt.Before(before.ModifiedAt) {
+		t.Errorf("Expected ModifiedAt to move forward, got %v before %v", task.ModifiedAt, before.ModifiedAt)
+	}
+
+	expected := []Change{
+		{Field: "created", New: "Write report", Actor: "alice"},
+		{Field: "tags", Old: "work", New: "work,urgent", Actor: "alice"},
+		{Field: "done", Old: "false", New: "true", Actor: "bob"},
+		{Field: "done", Old: "true", New: "false", Actor: "bob"},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %d changes, got %d: %v", len(expected), len(changes), changes)
+	}
+	for i, want := range expected {
+		got := changes[i]
+		if got.Field != want.Field || got.Old != want.Old || got.New != want.New || got.Actor != want.Actor {
+			t.Errorf("Change %d: expected %+v, got %+v", i, want, got)
+		}
+		if got.TaskID != before.ID || got.Time.IsZero() {
+			t.Errorf("Change %d: expected task id and time to be set, got %+v", i, got)
+		}
+	}
+
+	// Deleted tasks leave a final entry in the log
+	if err := manager.Delete("0"); err != nil {
+		t.Fatalf("Delete returned an error: %v", err)
+	}
+	logged, err := NewFileStore(testFile).Changes(before.ID)
+	if err != nil {
+		t.Fatalf("Changes returned an error: %v", err)
+	}
+	if last := logged[len(logged)-1]; last.Field != "removed" || last.Old != "Write report" {
+		t.Errorf("Expected removal to be logged, got %+v", last)
+	}
+}
+
+func TestTaskManagerImport(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "tasks.json")
+	manager := NewTaskManager(NewFileStore(testFile))
+
+	if err := manager.Add(Task{ID: "known", Title: "Old title"}); err != nil {
+		t.Fatalf("Error adding task: %v", err)
+	}
+	original := manager.List()[0]
+
+	added, updated, err := manager.Import([]Task{
+		{ID: "known", Title: "New title", Done: true},
+		{ID: "fresh", Title: "Brand new"},
+		{Title: "No id"},
+		{ID: "fresh", Title: "Brand new, renamed"},
+	})
+	if err != nil {
+		t.Fatalf("Import returned an error: %v", err)
+	}
+	if added != 2 || updated != 2 {
+		t.Errorf("Expected 2 added and 2 updated, got %d and %d", added, updated)
+	}
+
+	list := manager.List()
+	if len(list) != 3 {
+		t.Fatalf("Expected 3 tasks, got %d: %v", len(list), list)
+	}
+	if list[0].Title != "New title" || !list[0].Done || !list[0].CreatedAt.Equal(original.CreatedAt) {
+		t.Errorf("Expected known task to be updated in place, got %+v", list[0])
+	}
+	if list[1].Title != "Brand new, renamed" || list[2].Title != "No id" || list[2].ID == "" {
+		t.Errorf("Unexpected imported tasks %+v", list[1:])
+	}
+
+	// Updates go through the change log
+	_, changes, err := manager.History("known")
+	if err != nil {
+		t.Fatalf("History returned an error: %v", err)
+	}
+	if len(changes) != 3 || changes[1].Field != "title" || changes[2].Field != "done" {
+		t.Errorf("Expected created, title and done changes, got %+v", changes)
+	}
+}
+
+func TestTaskManagerImportFields(t *testing.T) {
+	manager := NewTaskManager(NewMemoryStore())
+	manager.Add(Task{ID: "known", Title: "Old title", Notes: "Keep me", ParentID: "elsewhere", Tags: []string{"work"}})
+
+	// A file without notes, a parent or tags leaves them be
+	_, updated, err := manager.Import([]Task{{ID: "known", Title: "New title", Done: true}}, "id", "title", "done")
+	if err != nil || updated != 1 {
+		t.Fatalf("Expected 1 task updated, got %d, %v", updated, err)
+	}
+	got := manager.List()[0]
+	if got.Title != "New title" || !got.Done || got.Notes != "Keep me" || got.ParentID != "elsewhere" || !got.HasTag("work") {
+		t.Errorf("Expected only title and done changed, got %+v", got)
+	}
+
+	if _, _, err := manager.Import([]Task{{ID: "known"}}, "colour"); err == nil || !strings.Contains(err.Error(), "colour") {
+		t.Errorf("Expected an unknown field refused, got %v", err)
+	}
+}
+
+func TestTaskManagerPreviewImport(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "tasks.json")
+	manager := NewTaskManager(NewFileStore(testFile))
+	if err := manager.Add(Task{ID: "known", Title: "Existing"}); err != nil {
+		t.Fatalf("Error adding task: %v", err)
+	}
+
+	incoming := []Task{
+		{ID: "fresh", Title: "New"},
+		{ID: "known", Title: "Changed"},
+		{Title: "No id"},
+		{ID: "fresh", Title: "New again"},
+	}
+	targets := manager.PreviewImport(incoming)
+	expected := []int{-1, 0, -1, 1}
+	if !reflect.DeepEqual(targets, expected) {
+		t.Errorf("Expected targets %v, got %v", expected, targets)
+	}
+
+	// Nothing is written
+	if list := manager.List(); len(list) != 1 || list[0].Title != "Existing" {
+		t.Errorf("Expected store to be unchanged, got %v", list)
+	}
+}
+
+func TestTaskManagerTransactions(t *testing.T) {
+	manager := NewTaskManager(NewMemoryStore())
+	manager.Add(Task{Title: "First"})
+	manager.Add(Task{Title: "Second", Tags: []string{"blocked"}})
+
+	// One vetoed task stops the whole of markall
+	manager.Events().Subscribe(SubscriberFunc(func(e Event) error {
+		if e.Pre && e.Task.HasTag("blocked") {
+			return errors.New("blocked")
+		}
+		return nil
+	}))
+	if err := manager.MarkAllDone(); err == nil {
+		t.Fatal("Expected markall to be vetoed")
+	}
+	if manager.CountDone() != 0 {
+		t.Errorf("Expected no task to be marked done, got %d", manager.CountDone())
+	}
+
+	// An import that fails part way writes nothing
+	existing := manager.List()[0]
+	_, _, err := manager.Import([]Task{{Title: "Imported"}, {ID: existing.ID, Title: "Renamed", Tags: []string{"blocked"}}})
+	if err == nil {
+		t.Fatal("Expected the import to be vetoed")
+	}
+	if list := manager.List(); len(list) != 2 || list[0].Title != "First" {
+		t.Errorf("Expected the store to be unchanged, got %v", list)
+	}
+
+	// A cancelled context stops changes before they reach the store
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := manager.WithContext(ctx).Add(Task{Title: "Too late"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if len(manager.List()) != 2 {
+		t.Errorf("Expected 2 tasks, got %d", len(manager.List()))
+	}
+}
+
+func TestTaskManagerReplace(t *testing.T) {
+	manager := NewTaskManager(NewMemoryStore())
+	manager.Add(Task{ID: "keep", Title: "Keep"})
+	manager.Add(Task{ID: "change", Title: "Change"})
+	manager.Add(Task{ID: "drop", Title: "Drop"})
+
+	modified := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	keep, _ := manager.Get("keep")
+	added, updated, removed, err := manager.Replace([]Task{
+		keep,
+		{ID: "change", Title: "Changed", ModifiedAt: modified},
+		{ID: "new", Title: "New"},
+	})
+	if err != nil {
+		t.Fatalf("Replace returned an error: %v", err)
+	}
+	if added != 1 || updated != 1 || removed != 1 {
+		t.Errorf("Expected 1 added, 1 updated and 1 removed, got %d, %d and %d", added, updated, removed)
+	}
+
+	var titles []string
+	for _, task := range manager.List() {
+		titles = append(titles, task.Title)
+	}
+	if strings.Join(titles, ",") != "Keep,Changed,New" {
+		t.Errorf("Expected Keep,Changed,New, got %v", titles)
+	}
+	if changed, _ := manager.Get("change"); !changed.ModifiedAt.Equal(modified) {
+		t.Errorf("Expected the update to keep its modification time, got %v", changed.ModifiedAt)
+	}
+	if _, changes, _ := manager.History("change"); len(changes) != 2 || changes[1].Field != "title" {
+		t.Errorf("Expected the rename to be recorded, got %v", changes)
+	}
+}
+
+// failingQueryStore is a store whose own selection of tasks fails
+type failingQueryStore struct {
+	*MemoryStore
+}
+
+func (failingQueryStore) Query(ctx context.Context, f Filter, now time.Time) ([]Task, error) {
+	return nil, errors.New("database is locked")
+}
+
+func TestTaskManagerQueryError(t *testing.T) {
+	manager := NewTaskManager(failingQueryStore{NewMemoryStore()})
+	manager.Add(Task{Title: "Tagged", Tags: []string{"work"}})
+
+	if _, err := manager.Query(Filter{Tag: "work"}); err == nil || err.Error() != "database is locked" {
+		t.Errorf("Expected the store's error, got %v", err)
+	}
+	// The List helpers match the tasks themselves instead
+	if got := manager.ListByTag("work"); len(got) != 1 {
+		t.Errorf("Expected ListByTag to fall back to matching here, got %v", got)
+	}
+}
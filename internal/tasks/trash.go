@@ -0,0 +1,61 @@
+package tasks
+
+import (
+	"sort"
+	"time"
+)
+
+// ListTrash returns the tasks in the trash, most recently removed first
+func (tm *TaskManager) ListTrash() ([]Task, error) {
+	all, err := tm.all()
+	if err != nil {
+		return nil, err
+	}
+	trash := []Task{}
+	for _, t := range all {
+		if t.Trashed() {
+			trash = append(trash, t)
+		}
+	}
+	sort.SliceStable(trash, func(i, j int) bool {
+		return trash[i].DeletedAt.After(*trash[j].DeletedAt)
+	})
+	return trash, nil
+}
+
+// Restore takes a task out of the trash. ref is an ID, an index into
+// ListTrash or a unique ID prefix.
+func (tm *TaskManager) Restore(ref string) (Task, error) {
+	trash, err := tm.ListTrash()
+	if err != nil {
+		return Task{}, err
+	}
+	idx, err := resolve(ref, trash)
+	if err != nil {
+		return Task{}, err
+	}
+	t := trash[idx]
+	t.DeletedAt = nil
+	m, _ := update(trash[idx], t)
+	return m.event.Task, tm.commit(m)
+}
+
+// EmptyTrash deletes for good the tasks moved to the trash longer than
+// olderThan ago, and returns how many it deleted
+func (tm *TaskManager) EmptyTrash(olderThan time.Duration) (int, error) {
+	trash, err := tm.ListTrash()
+	if err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().Add(-olderThan)
+	var ms []mutation
+	for _, t := range trash {
+		if !t.DeletedAt.After(cutoff) {
+			ms = append(ms, mutation{
+				event:   Event{Type: EventRemoved, Task: t, Time: time.Now()},
+				changes: []Change{{Field: "removed", Old: t.Title}},
+			})
+		}
+	}
+	return len(ms), tm.commit(ms...)
+}
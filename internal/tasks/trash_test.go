@@ -0,0 +1,108 @@
+package tasks
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTrash(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileStore(filepath.Join(t.TempDir(), "tasks.json"))
+	manager := NewTaskManager(store)
+	manager.Add(Task{ID: "keep", Title: "Keep", Tags: []string{"work"}})
+	manager.Add(Task{ID: "drop", Title: "Drop", Tags: []string{"work"}, Done: true})
+
+	var events []EventType
+	manager.Events().Subscribe(SubscriberFunc(func(e Event) error {
+		if !e.Pre {
+			events = append(events, e.Type)
+		}
+		return nil
+	}))
+
+	if err := manager.Remove("drop"); err != nil {
+		t.Fatalf("Remove returned an error: %v", err)
+	}
+	if list := manager.List(); len(list) != 1 || list[0].ID != "keep" {
+		t.Errorf("Expected the removed task gone from the list, got %v", list)
+	}
+	if tagged := manager.ListByTag("work"); len(tagged) != 1 {
+		t.Errorf("Expected queries to leave out the trash, got %v", tagged)
+	}
+	if n := manager.CountDone(); n != 0 {
+		t.Errorf("Expected the trashed task not counted as done, got %d", n)
+	}
+	if _, err := manager.Get("drop"); err == nil {
+		t.Error("Expected Get not to find a trashed task")
+	}
+	if all, _ := store.List(ctx); len(all) != 2 {
+		t.Errorf("Expected the trashed task kept in the store, got %v", all)
+	}
+
+	trash, err := manager.ListTrash()
+	if err != nil || len(trash) != 1 || trash[0].ID != "drop" || trash[0].DeletedAt == nil {
+		t.Fatalf("Expected the removed task in the trash, got %v, %v", trash, err)
+	}
+	if restored, err := manager.Restore("0"); err != nil || restored.ID != "drop" || restored.Trashed() {
+		t.Fatalf("Restore returned %v, %v", restored, err)
+	}
+	if list := manager.List(); len(list) != 2 {
+		t.Errorf("Expected the restored task back in the list, got %v", list)
+	}
+
+	want := []EventType{EventTrashed, EventRestored}
+	if len(events) != len(want) || events[0] != want[0] || events[1] != want[1] {
+		t.Errorf("Expected events %v, got %v", want, events)
+	}
+}
+
+func TestEmptyTrash(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	manager := NewTaskManager(store)
+	week := time.Now().AddDate(0, 0, -7)
+	manager.Add(Task{ID: "old", Title: "Old", DeletedAt: &week})
+	manager.Add(Task{ID: "new", Title: "New"})
+	manager.Add(Task{ID: "live", Title: "Live"})
+	manager.Remove("new")
+
+	if n, err := manager.EmptyTrash(24 * time.Hour); err != nil || n != 1 {
+		t.Fatalf("Expected 1 task deleted, got %d, %v", n, err)
+	}
+	if trash, _ := manager.ListTrash(); len(trash) != 1 || trash[0].ID != "new" {
+		t.Errorf("Expected the recently trashed task kept, got %v", trash)
+	}
+	if n, _ := manager.EmptyTrash(0); n != 1 {
+		t.Errorf("Expected the rest of the trash deleted, got %d", n)
+	}
+	if all, _ := store.List(ctx); len(all) != 1 || all[0].ID != "live" {
+		t.Errorf("Expected only the live task left, got %v", all)
+	}
+
+	// Delete skips the trash
+	if err := manager.Delete("live"); err != nil {
+		t.Fatalf("Delete returned an error: %v", err)
+	}
+	if all, _ := store.List(ctx); len(all) != 0 {
+		t.Errorf("Expected the deleted task gone from the store, got %v", all)
+	}
+}
+
+func TestImportRestoresTrashed(t *testing.T) {
+	manager := NewTaskManager(NewMemoryStore())
+	manager.Add(Task{ID: "a", Title: "A"})
+	manager.Remove("a")
+
+	if targets := manager.PreviewImport([]Task{{ID: "a", Title: "A again"}}); targets[0] != -1 {
+		t.Errorf("Expected the preview to add the trashed task, got %v", targets)
+	}
+	added, updated, err := manager.Import([]Task{{ID: "a", Title: "A again"}})
+	if err != nil || added != 1 || updated != 0 {
+		t.Fatalf("Expected the trashed task added back, got %d added, %d updated, %v", added, updated, err)
+	}
+	if list := manager.List(); len(list) != 1 || list[0].Title != "A again" {
+		t.Errorf("Expected the imported task out of the trash, got %v", list)
+	}
+}
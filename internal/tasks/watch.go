@@ -0,0 +1,75 @@
+package tasks
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// Watcher polls a file and signals on C whenever its modification time or
+// size changes, including when it is created or deleted.
+type Watcher struct {
+	C <-chan struct{}
+
+	filename string
+	interval time.Duration
+	events   chan struct{}
+	stop     chan struct{}
+	once     sync.Once
+}
+
+// NewWatcher starts polling filename every interval.
+func NewWatcher(filename string, interval time.Duration) *Watcher {
+	events := make(chan struct{}, 1)
+	w := &Watcher{
+		C:        events,
+		filename: filename,
+		interval: interval,
+		events:   events,
+		stop:     make(chan struct{}),
+	}
+	go w.run(fileStamp(filename))
+	return w
+}
+
+// Stop ends polling. It is safe to call more than once.
+func (w *Watcher) Stop() {
+	w.once.Do(func() { close(w.stop) })
+}
+
+func (w *Watcher) run(last stamp) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			current := fileStamp(w.filename)
+			if current == last {
+				continue
+			}
+			last = current
+			// Coalesce changes the reader has not consumed yet
+			select {
+			case w.events <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+type stamp struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+}
+
+func fileStamp(filename string) stamp {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return stamp{}
+	}
+	return stamp{exists: true, modTime: info.ModTime(), size: info.Size()}
+}
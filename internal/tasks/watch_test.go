@@ -0,0 +1,48 @@
+package tasks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "tasks.json")
+
+	w := NewWatcher(testFile, 5*time.Millisecond)
+	defer w.Stop()
+
+	// Creating the file is a change
+	if err := os.WriteFile(testFile, []byte("[]"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	select {
+	case <-w.C:
+	case <-time.After(time.Second):
+		t.Fatal("Expected change notification after file creation")
+	}
+
+	// Writes through a FileStore are changes too
+	store := NewFileStore(testFile)
+	if err := store.Add(context.Background(), Task{ID: "a", Title: "Watched"}); err != nil {
+		t.Fatalf("Add returned an error: %v", err)
+	}
+	select {
+	case <-w.C:
+	case <-time.After(time.Second):
+		t.Fatal("Expected change notification after store write")
+	}
+
+	// No further writes, no further notifications
+	select {
+	case <-w.C:
+		t.Error("Unexpected change notification without a write")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Stop is idempotent
+	w.Stop()
+	w.Stop()
+}
@@ -0,0 +1,290 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"taskmgr/internal/display"
+	"taskmgr/internal/tasks"
+)
+
+const boardHelpLine = "h/l column  j/k card  H/L move card  r refresh  q quit"
+
+// BoardOptions configures RunBoard.
+type BoardOptions struct {
+	Display display.DisplayOptions
+	Group   display.BoardGroup
+	// Tags is the tag set used as columns when grouping by tag.
+	Tags      []string
+	WatchFile string
+}
+
+// BoardModel is the interactive kanban board: the same columns as the
+// board command, with a selected card that can be moved between columns.
+type BoardModel struct {
+	manager   *tasks.TaskManager
+	formatter *display.BoardFormatter
+	group     display.BoardGroup
+	tagSet    []string
+
+	columns []display.Column
+	col     int
+	card    int
+
+	status string
+	width  int
+	height int
+	quit   bool
+}
+
+// NewBoardModel creates a board model and loads the columns.
+func NewBoardModel(manager *tasks.TaskManager, opts BoardOptions) (*BoardModel, error) {
+	m := &BoardModel{
+		manager:   manager,
+		formatter: display.NewBoardFormatter(opts.Display),
+		group:     opts.Group,
+		tagSet:    opts.Tags,
+		width:     80,
+		height:    24,
+	}
+	if err := m.Refresh(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SetSize sets the terminal dimensions used by View.
+func (m *BoardModel) SetSize(width, height int) {
+	m.width = width
+	m.height = height
+}
+
+// Quitting reports whether the user asked to leave the board.
+func (m *BoardModel) Quitting() bool {
+	return m.quit
+}
+
+// Refresh rebuilds the columns from the manager, keeping the selection on
+// the same task when possible.
+func (m *BoardModel) Refresh() error {
+	selected := ""
+	if card, ok := m.selected(); ok {
+		selected = card.Task.ID
+	}
+
+	columns, err := display.BuildColumns(m.manager.List(), m.group, m.tagSet)
+	if err != nil {
+		return err
+	}
+	m.columns = columns
+	m.selectTask(selected)
+	return nil
+}
+
+// HandleKey applies a single key press.
+func (m *BoardModel) HandleKey(k Key) {
+	m.status = ""
+	switch {
+	case k.Type == KeyCtrlC || k.Rune == 'q':
+		m.quit = true
+	case k.Type == KeyLeft || k.Rune == 'h':
+		m.selectColumn(m.col - 1)
+	case k.Type == KeyRight || k.Rune == 'l':
+		m.selectColumn(m.col + 1)
+	case k.Type == KeyUp || k.Rune == 'k':
+		m.selectCard(m.card - 1)
+	case k.Type == KeyDown || k.Rune == 'j':
+		m.selectCard(m.card + 1)
+	case k.Rune == 'H' || k.Rune == '<':
+		m.moveSelected(-1)
+	case k.Rune == 'L' || k.Rune == '>':
+		m.moveSelected(1)
+	case k.Rune == 'r':
+		if err := m.Refresh(); err != nil {
+			m.status = "Error refreshing board: " + err.Error()
+			return
+		}
+		m.status = "Refreshed."
+	}
+}
+
+// View renders the board followed by a status line, cut to the model's
+// height.
+func (m *BoardModel) View() string {
+	board := m.formatter.FormatBoardSelection(m.columns, m.width, m.col, m.card)
+	lines := strings.Split(board, "\n")
+	if max := m.height - 1; len(lines) > max && max > 0 {
+		lines = append(lines[:max-1], "…")
+	}
+
+	footer := boardHelpLine
+	if m.status != "" {
+		footer = m.status
+	}
+	lines = append(lines, display.Truncate(footer, m.width))
+	return strings.Join(lines, "\n")
+}
+
+func (m *BoardModel) selected() (display.Card, bool) {
+	if m.col < 0 || m.col >= len(m.columns) {
+		return display.Card{}, false
+	}
+	cards := m.columns[m.col].Cards
+	if m.card < 0 || m.card >= len(cards) {
+		return display.Card{}, false
+	}
+	return cards[m.card], true
+}
+
+func (m *BoardModel) selectColumn(col int) {
+	if col < 0 || col >= len(m.columns) {
+		return
+	}
+	m.col = col
+	m.selectCard(m.card)
+}
+
+func (m *BoardModel) selectCard(card int) {
+	if m.col >= len(m.columns) {
+		return
+	}
+	cards := len(m.columns[m.col].Cards)
+	if card >= cards {
+		card = cards - 1
+	}
+	if card < 0 {
+		card = 0
+	}
+	m.card = card
+}
+
+// selectTask moves the selection to the card of the task with the given
+// ID, or keeps the current position when it is gone.
+func (m *BoardModel) selectTask(id string) {
+	for c, column := range m.columns {
+		for i, card := range column.Cards {
+			if id != "" && card.Task.ID == id {
+				m.col, m.card = c, i
+				return
+			}
+		}
+	}
+	if m.col >= len(m.columns) {
+		m.col = len(m.columns) - 1
+	}
+	if m.col < 0 {
+		m.col = 0
+	}
+	m.selectCard(m.card)
+}
+
+// moveSelected moves the selected card delta columns to the left or right
+// by changing the task field the board is grouped by.
+func (m *BoardModel) moveSelected(delta int) {
+	card, ok := m.selected()
+	if !ok {
+		return
+	}
+	target := m.col + delta
+	if target < 0 || target >= len(m.columns) {
+		return
+	}
+
+	column := m.columns[target]
+	if err := moveCard(m.manager, m.group, m.tagSet, card, column); err != nil {
+		m.status = "Error moving card: " + err.Error()
+		return
+	}
+	if err := m.Refresh(); err != nil {
+		m.status = "Error refreshing board: " + err.Error()
+		return
+	}
+	m.status = fmt.Sprintf("Moved task %d to %s.", card.Index, column.Title)
+}
+
+// moveCard updates the task behind card so that it belongs in column.
+func moveCard(manager *tasks.TaskManager, group display.BoardGroup, tagSet []string, card display.Card, column display.Column) error {
+	id := card.Task.ID
+	t := card.Task
+
+	switch group {
+	case display.GroupByStatus:
+		if column.Key == "done" {
+			return manager.MarkDone(id)
+		}
+		return manager.UndoDone(id)
+	case display.GroupByPriority:
+		priority, err := tasks.ParsePriority(column.Key)
+		if err != nil {
+			return err
+		}
+		t.Priority = priority
+	case display.GroupByProject:
+		t.Project = column.Key
+	case display.GroupByTag:
+		// RemoveTag edits the slice in place, and the card's shares its
+		// array with the column shown
+		t.Tags = append([]string(nil), t.Tags...)
+		for _, tag := range tagSet {
+			t.RemoveTag(tag)
+		}
+		if column.Key != "" {
+			t.AddTag(column.Key)
+		}
+	default:
+		return fmt.Errorf("invalid board grouping: %s", group)
+	}
+	return manager.Update(id, t)
+}
+
+// RunBoard takes over the terminal with the interactive board and blocks
+// until the user quits.
+func RunBoard(manager *tasks.TaskManager, opts BoardOptions) error {
+	m, err := NewBoardModel(manager, opts)
+	if err != nil {
+		return err
+	}
+
+	t, err := openTerminal()
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	var changes <-chan struct{}
+	if opts.WatchFile != "" {
+		watcher := tasks.NewWatcher(opts.WatchFile, 500*time.Millisecond)
+		defer watcher.Stop()
+		changes = watcher.C
+	}
+
+	resize := time.NewTicker(250 * time.Millisecond)
+	defer resize.Stop()
+
+	width, height := t.Size()
+	m.SetSize(width, height)
+	t.Draw(m.View())
+
+	for !m.Quitting() {
+		select {
+		case k := <-t.keys:
+			m.HandleKey(k)
+		case <-changes:
+			if err := m.Refresh(); err != nil {
+				m.status = "Error refreshing board: " + err.Error()
+			}
+		case <-resize.C:
+			w, h := t.Size()
+			if w == width && h == height {
+				continue
+			}
+			width, height = w, h
+			m.SetSize(width, height)
+		case err := <-t.errs:
+			return readError(err)
+		}
+		t.Draw(m.View())
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package tui
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"taskmgr/internal/display"
+	"taskmgr/internal/tasks"
+)
+
+func newTestBoard(t *testing.T, group display.BoardGroup, tagSet []string, taskList ...tasks.Task) (*BoardModel, *tasks.TaskManager) {
+	t.Helper()
+	manager := tasks.NewTaskManager(tasks.NewFileStore(filepath.Join(t.TempDir(), "tasks.json")))
+	for _, task := range taskList {
+		if err := manager.Add(task); err != nil {
+			t.Fatalf("Error adding task: %v", err)
+		}
+	}
+	m, err := NewBoardModel(manager, BoardOptions{Group: group, Tags: tagSet})
+	if err != nil {
+		t.Fatalf("NewBoardModel returned an error: %v", err)
+	}
+	return m, manager
+}
+
+func TestBoardModelNavigation(t *testing.T) {
+	m, _ := newTestBoard(t, display.GroupByStatus, nil,
+		tasks.Task{Title: "One"},
+		tasks.Task{Title: "Two"},
+		tasks.Task{Title: "Three", Done: true},
+	)
+
+	m.HandleKey(Key{Type: KeyRune, Rune: 'j'})
+	if m.col != 0 || m.card != 1 {
+		t.Errorf("Expected column 0 card 1, got %d/%d", m.col, m.card)
+	}
+
+	// Moving to a shorter column clamps the card position
+	m.HandleKey(Key{Type: KeyRight})
+	if m.col != 1 || m.card != 0 {
+		t.Errorf("Expected column 1 card 0, got %d/%d", m.col, m.card)
+	}
+
+	// No column beyond the last one
+	m.HandleKey(Key{Type: KeyRune, Rune: 'l'})
+	if m.col != 1 {
+		t.Errorf("Expected to stay in column 1, got %d", m.col)
+	}
+
+	m.HandleKey(Key{Type: KeyRune, Rune: 'q'})
+	if !m.Quitting() {
+		t.Error("Expected q to quit")
+	}
+}
+
+func TestBoardModelMoveStatus(t *testing.T) {
+	m, manager := newTestBoard(t, display.GroupByStatus, nil, tasks.Task{Title: "Card"})
+
+	m.HandleKey(Key{Type: KeyRune, Rune: 'L'})
+	if !manager.List()[0].Done {
+		t.Fatal("Expected task to be done after moving to the Done column")
+	}
+	// The selection follows the moved card
+	if m.col != 1 || m.card != 0 {
+		t.Errorf("Expected selection on column 1 card 0, got %d/%d", m.col, m.card)
+	}
+	if m.status != "Moved task 0 to Done." {
+		t.Errorf("Unexpected status %q", m.status)
+	}
+
+	m.HandleKey(Key{Type: KeyRune, Rune: 'H'})
+	if manager.List()[0].Done {
+		t.Error("Expected task to be pending after moving back")
+	}
+
+	// Moving past the first column does nothing
+	m.HandleKey(Key{Type: KeyRune, Rune: '<'})
+	if manager.List()[0].Done {
+		t.Error("Expected task to stay pending")
+	}
+}
+
+func TestBoardModelMoveFields(t *testing.T) {
+	t.Run("priority", func(t *testing.T) {
+		m, manager := newTestBoard(t, display.GroupByPriority, nil, tasks.Task{Title: "Card", Priority: tasks.Critical})
+		m.HandleKey(Key{Type: KeyRune, Rune: '>'})
+		if got := manager.List()[0].Priority; got != tasks.High {
+			t.Errorf("Expected high priority, got %v", got)
+		}
+	})
+
+	t.Run("project", func(t *testing.T) {
+		m, manager := newTestBoard(t, display.GroupByProject, nil,
+			tasks.Task{Title: "Card", Project: "alpha"},
+			tasks.Task{Title: "Other", Project: "beta"},
+		)
+		m.HandleKey(Key{Type: KeyRune, Rune: 'L'})
+		if got := manager.List()[0].Project; got != "beta" {
+			t.Errorf("Expected project beta, got %q", got)
+		}
+	})
+
+	t.Run("tag set", func(t *testing.T) {
+		m, manager := newTestBoard(t, display.GroupByTag, []string{"todo", "doing"},
+			tasks.Task{Title: "Card", Tags: []string{"todo", "work"}},
+		)
+		m.HandleKey(Key{Type: KeyRune, Rune: 'L'})
+		task := manager.List()[0]
+		if task.HasTag("todo") || !task.HasTag("doing") || !task.HasTag("work") {
+			t.Errorf("Expected tags [work doing], got %v", task.Tags)
+		}
+	})
+}
+
+func TestBoardModelView(t *testing.T) {
+	m, _ := newTestBoard(t, display.GroupByStatus, nil, tasks.Task{Title: "Visible card"})
+	m.SetSize(60, 10)
+
+	view := m.View()
+	if !strings.Contains(view, "Visible card") || !strings.Contains(view, boardHelpLine) {
+		t.Errorf("Unexpected board view:\n%s", view)
+	}
+	for i, line := range strings.Split(view, "\n") {
+		if display.VisibleWidth(line) > 60 {
+			t.Errorf("Line %d exceeds width: %q", i, line)
+		}
+	}
+
+	// Tall boards are cut to the terminal height
+	m.SetSize(60, 3)
+	if lines := strings.Split(m.View(), "\n"); len(lines) != 3 {
+		t.Errorf("Expected 3 lines, got %d", len(lines))
+	}
+}
+
+func TestBoardModelEmptyProjectBoard(t *testing.T) {
+	m, _ := newTestBoard(t, display.GroupByProject, nil)
+
+	// Keys on a board without columns must not panic
+	m.HandleKey(Key{Type: KeyRune, Rune: 'j'})
+	m.HandleKey(Key{Type: KeyRune, Rune: 'L'})
+	if !strings.Contains(m.View(), "No columns to display.") {
+		t.Error("Expected empty board message")
+	}
+}
+
+func TestNewBoardModelRequiresTagSet(t *testing.T) {
+	manager := tasks.NewTaskManager(tasks.NewFileStore(filepath.Join(t.TempDir(), "tasks.json")))
+	if _, err := NewBoardModel(manager, BoardOptions{Group: display.GroupByTag}); err == nil {
+		t.Error("Expected error when grouping by tag without a tag set")
+	}
+}
+
+func TestBoardModelKeepsColumnsWhenMoving(t *testing.T) {
+	m, manager := newTestBoard(t, display.GroupByTag, []string{"todo", "doing"},
+		tasks.Task{Title: "Card", Tags: []string{"todo", "work", "home"}},
+	)
+	card, _ := m.selected()
+	manager.Events().Subscribe(tasks.SubscriberFunc(func(e tasks.Event) error {
+		return errors.New("vetoed")
+	}))
+	m.HandleKey(Key{Type: KeyRune, Rune: 'L'})
+	if got, _ := m.selected(); strings.Join(got.Task.Tags, ",") != "todo,work,home" || strings.Join(card.Task.Tags, ",") != "todo,work,home" {
+		t.Errorf("Expected a failed move to leave the card's tags alone, got %v", got.Task.Tags)
+	}
+}
+
+func TestBoardModelRefreshKeepsSelectedTask(t *testing.T) {
+	m, manager := newTestBoard(t, display.GroupByStatus, nil,
+		tasks.Task{Title: "A"}, tasks.Task{Title: "B"}, tasks.Task{Title: "C"},
+	)
+	m.HandleKey(Key{Type: KeyRune, Rune: 'j'})
+
+	// Removing the task above gives the selected one another store index
+	if err := manager.Remove("0"); err != nil {
+		t.Fatalf("Error removing task: %v", err)
+	}
+	if err := m.Refresh(); err != nil {
+		t.Fatalf("Refresh returned an error: %v", err)
+	}
+	if card, ok := m.selected(); !ok || card.Task.Title != "B" {
+		t.Errorf("Expected the selection to stay on B, got %+v", card.Task)
+	}
+}
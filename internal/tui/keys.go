@@ -0,0 +1,129 @@
+package tui
+
+import (
+	"bufio"
+	"unicode/utf8"
+)
+
+// KeyType identifies a key press read from the terminal.
+type KeyType int
+
+const (
+	KeyRune KeyType = iota
+	KeyEnter
+	KeyEsc
+	KeyBackspace
+	KeyTab
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyHome
+	KeyEnd
+	KeyPgUp
+	KeyPgDn
+	KeyCtrlC
+	KeyUnknown
+)
+
+// Key is a single key press. Rune is only set for KeyRune.
+type Key struct {
+	Type KeyType
+	Rune rune
+}
+
+// ReadKey reads one key press from a terminal in raw mode, decoding the
+// VT100/xterm escape sequences sent for arrow and navigation keys. A lone
+// ESC with nothing buffered behind it is reported as KeyEsc.
+func ReadKey(r *bufio.Reader) (Key, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return Key{}, err
+	}
+
+	switch b {
+	case '\r', '\n':
+		return Key{Type: KeyEnter}, nil
+	case '\t':
+		return Key{Type: KeyTab}, nil
+	case 127, 8:
+		return Key{Type: KeyBackspace}, nil
+	case 3:
+		return Key{Type: KeyCtrlC}, nil
+	case 27:
+		if r.Buffered() == 0 {
+			return Key{Type: KeyEsc}, nil
+		}
+		return readEscape(r)
+	}
+
+	if b < utf8.RuneSelf {
+		if b < 32 {
+			return Key{Type: KeyUnknown}, nil
+		}
+		return Key{Type: KeyRune, Rune: rune(b)}, nil
+	}
+
+	// Multi-byte UTF-8 sequence
+	if err := r.UnreadByte(); err != nil {
+		return Key{}, err
+	}
+	ru, _, err := r.ReadRune()
+	if err != nil {
+		return Key{}, err
+	}
+	return Key{Type: KeyRune, Rune: ru}, nil
+}
+
+// readEscape decodes the remainder of an escape sequence after ESC.
+func readEscape(r *bufio.Reader) (Key, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return Key{}, err
+	}
+	if b != '[' && b != 'O' {
+		return Key{Type: KeyEsc}, nil
+	}
+
+	// Collect parameter bytes up to the final byte
+	var params []byte
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return Key{}, err
+		}
+		if c >= 0x40 && c <= 0x7e {
+			return decodeEscape(string(params), c), nil
+		}
+		params = append(params, c)
+	}
+}
+
+func decodeEscape(params string, final byte) Key {
+	switch final {
+	case 'A':
+		return Key{Type: KeyUp}
+	case 'B':
+		return Key{Type: KeyDown}
+	case 'C':
+		return Key{Type: KeyRight}
+	case 'D':
+		return Key{Type: KeyLeft}
+	case 'H':
+		return Key{Type: KeyHome}
+	case 'F':
+		return Key{Type: KeyEnd}
+	case '~':
+		switch params {
+		case "1", "7":
+			return Key{Type: KeyHome}
+		case "4", "8":
+			return Key{Type: KeyEnd}
+		case "5":
+			return Key{Type: KeyPgUp}
+		case "6":
+			return Key{Type: KeyPgDn}
+		}
+	}
+	return Key{Type: KeyUnknown}
+}
@@ -0,0 +1,70 @@
+package tui
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Key
+	}{
+		{"letter", "j", Key{Type: KeyRune, Rune: 'j'}},
+		{"unicode", "é", Key{Type: KeyRune, Rune: 'é'}},
+		{"enter", "\r", Key{Type: KeyEnter}},
+		{"newline", "\n", Key{Type: KeyEnter}},
+		{"tab", "\t", Key{Type: KeyTab}},
+		{"backspace", "\x7f", Key{Type: KeyBackspace}},
+		{"ctrl-h", "\x08", Key{Type: KeyBackspace}},
+		{"ctrl-c", "\x03", Key{Type: KeyCtrlC}},
+		{"lone escape", "\x1b", Key{Type: KeyEsc}},
+		{"up", "\x1b[A", Key{Type: KeyUp}},
+		{"down", "\x1b[B", Key{Type: KeyDown}},
+		{"right", "\x1b[C", Key{Type: KeyRight}},
+		{"left", "\x1b[D", Key{Type: KeyLeft}},
+		{"application up", "\x1bOA", Key{Type: KeyUp}},
+		{"home", "\x1b[H", Key{Type: KeyHome}},
+		{"end tilde", "\x1b[4~", Key{Type: KeyEnd}},
+		{"page up", "\x1b[5~", Key{Type: KeyPgUp}},
+		{"page down", "\x1b[6~", Key{Type: KeyPgDn}},
+		{"unknown sequence", "\x1b[99~", Key{Type: KeyUnknown}},
+		{"control byte", "\x01", Key{Type: KeyUnknown}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ReadKey(bufio.NewReader(strings.NewReader(tt.input)))
+			if err != nil {
+				t.Fatalf("ReadKey returned an error: %v", err)
+			}
+			if key != tt.expected {
+				t.Errorf("ReadKey(%q) = %+v, expected %+v", tt.input, key, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReadKeySequence(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("a\x1b[Bq"))
+	expected := []Key{
+		{Type: KeyRune, Rune: 'a'},
+		{Type: KeyDown},
+		{Type: KeyRune, Rune: 'q'},
+	}
+	for i, want := range expected {
+		got, err := ReadKey(r)
+		if err != nil {
+			t.Fatalf("ReadKey %d returned an error: %v", i, err)
+		}
+		if got != want {
+			t.Errorf("ReadKey %d = %+v, expected %+v", i, got, want)
+		}
+	}
+	if _, err := ReadKey(r); err != io.EOF {
+		t.Errorf("Expected io.EOF at end of input, got %v", err)
+	}
+}
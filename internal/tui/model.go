@@ -0,0 +1,494 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"taskmgr/internal/cli"
+	"taskmgr/internal/display"
+	"taskmgr/internal/tasks"
+)
+
+type inputMode int
+
+const (
+	modeNormal inputMode = iota
+	modeFilter
+	modeAdd
+	modeEdit
+	modeTag
+)
+
+// detailHeight is the number of lines used by the detail pane, including
+// its separator.
+const detailHeight = 6
+
+const helpLine = "j/k move  / filter  a add  e edit  x done  t tag  r refresh  q quit"
+
+// row is a task together with its index in the store, which is what the
+// list shows even when it is filtered. Changes go through the task's ID, as
+// the index may point at another task once the list is refreshed.
+type row struct {
+	index int
+	task  tasks.Task
+}
+
+// Model holds the state of the interactive interface. It is driven by
+// HandleKey and Refresh and rendered by View, independently of the
+// terminal, so it can be exercised in tests.
+type Model struct {
+	manager   *tasks.TaskManager
+	formatter *display.TaskFormatter
+	options   display.DisplayOptions
+
+	rows   []row
+	cursor int
+	offset int
+
+	filterText string
+	filter     tasks.Filter
+
+	mode   inputMode
+	input  string
+	status string
+
+	width  int
+	height int
+	quit   bool
+}
+
+// NewModel creates a model showing every task of the manager.
+func NewModel(manager *tasks.TaskManager, opts display.DisplayOptions) *Model {
+	opts.TableFormat = false
+	m := &Model{
+		manager:   manager,
+		formatter: display.NewTaskFormatter(opts),
+		options:   opts,
+		width:     80,
+		height:    24,
+	}
+	m.Refresh()
+	return m
+}
+
+// SetSize sets the terminal dimensions used by View.
+func (m *Model) SetSize(width, height int) {
+	m.width = width
+	m.height = height
+	m.scroll()
+}
+
+// Quitting reports whether the user asked to leave the interface.
+func (m *Model) Quitting() bool {
+	return m.quit
+}
+
+// Refresh reloads tasks from the manager and reapplies the filter, keeping
+// the cursor on the same task when it is still visible.
+func (m *Model) Refresh() {
+	selected := ""
+	if r, ok := m.selected(); ok {
+		selected = r.task.ID
+	}
+
+	now := time.Now()
+	m.rows = m.rows[:0]
+	for i, t := range m.manager.List() {
+		if m.filter.Match(t, now) {
+			m.rows = append(m.rows, row{index: i, task: t})
+		}
+	}
+
+	for i, r := range m.rows {
+		if selected != "" && r.task.ID == selected {
+			m.cursor = i
+			break
+		}
+	}
+	m.clampCursor()
+}
+
+// HandleKey applies a single key press.
+func (m *Model) HandleKey(k Key) {
+	if k.Type == KeyCtrlC {
+		m.quit = true
+		return
+	}
+	if m.mode != modeNormal {
+		m.handleInputKey(k)
+		return
+	}
+
+	m.status = ""
+	switch {
+	case k.Type == KeyDown || k.Rune == 'j':
+		m.moveCursor(1)
+	case k.Type == KeyUp || k.Rune == 'k':
+		m.moveCursor(-1)
+	case k.Type == KeyPgDn:
+		m.moveCursor(m.listHeight())
+	case k.Type == KeyPgUp:
+		m.moveCursor(-m.listHeight())
+	case k.Type == KeyHome || k.Rune == 'g':
+		m.moveCursor(-len(m.rows))
+	case k.Type == KeyEnd || k.Rune == 'G':
+		m.moveCursor(len(m.rows))
+	case k.Type == KeyEsc:
+		if !m.filter.IsZero() {
+			m.filterText = ""
+			m.filter = tasks.Filter{}
+			m.Refresh()
+		}
+	case k.Rune == 'q':
+		m.quit = true
+	case k.Rune == 'r':
+		m.Refresh()
+		m.status = "Refreshed."
+	case k.Rune == '/':
+		m.startInput(modeFilter, m.filterText)
+	case k.Rune == 'a':
+		m.startInput(modeAdd, "")
+	case k.Rune == 'e':
+		if r, ok := m.selected(); ok {
+			m.startInput(modeEdit, taskInput(r.task))
+		}
+	case k.Rune == 't':
+		if _, ok := m.selected(); ok {
+			m.startInput(modeTag, "")
+		}
+	case k.Rune == 'x' || k.Rune == ' ':
+		m.toggleDone()
+	}
+}
+
+func (m *Model) handleInputKey(k Key) {
+	switch k.Type {
+	case KeyEsc:
+		m.mode = modeNormal
+		m.input = ""
+	case KeyEnter:
+		mode, input := m.mode, strings.TrimSpace(m.input)
+		m.mode = modeNormal
+		m.input = ""
+		m.submit(mode, input)
+	case KeyBackspace:
+		if r := []rune(m.input); len(r) > 0 {
+			m.input = string(r[:len(r)-1])
+		}
+	case KeyRune:
+		m.input += string(k.Rune)
+	}
+}
+
+func (m *Model) startInput(mode inputMode, initial string) {
+	m.mode = mode
+	m.input = initial
+}
+
+func (m *Model) submit(mode inputMode, input string) {
+	switch mode {
+	case modeFilter:
+		filter, err := cli.ParseListCommand(strings.Fields(input)).Filter()
+		if err != nil {
+			m.status = "Error parsing filter: " + err.Error()
+			return
+		}
+		m.filterText = input
+		m.filter = filter
+		m.cursor = 0
+		m.Refresh()
+	case modeAdd:
+		if input == "" {
+			return
+		}
+		t, err := parseTaskInput(input)
+		if err != nil {
+			m.status = "Error adding task: " + err.Error()
+			return
+		}
+		if err := m.manager.Add(t); err != nil {
+			m.status = "Error adding task: " + err.Error()
+			return
+		}
+		m.Refresh()
+		m.status = "Task added."
+	case modeEdit:
+		r, ok := m.selected()
+		if !ok || input == "" {
+			return
+		}
+		edited, err := parseTaskInput(input)
+		if err != nil {
+			m.status = "Error editing task: " + err.Error()
+			return
+		}
+		t := r.task
+		t.Title = edited.Title
+		t.Priority = edited.Priority
+		t.DueDate = edited.DueDate
+		t.Tags = edited.Tags
+		t.Project = edited.Project
+		if err := m.manager.Update(r.task.ID, t); err != nil {
+			m.status = "Error editing task: " + err.Error()
+			return
+		}
+		m.Refresh()
+		m.status = "Task updated."
+	case modeTag:
+		r, ok := m.selected()
+		if !ok {
+			return
+		}
+		for _, tag := range strings.Fields(input) {
+			var err error
+			if strings.HasPrefix(tag, "-") {
+				err = m.manager.RemoveTagFromTask(r.task.ID, strings.TrimPrefix(tag, "-"))
+			} else {
+				err = m.manager.AddTagToTask(r.task.ID, tag)
+			}
+			if err != nil {
+				m.status = "Error updating tags: " + err.Error()
+				m.Refresh()
+				return
+			}
+		}
+		m.Refresh()
+		m.status = "Tags updated."
+	}
+}
+
+func (m *Model) toggleDone() {
+	r, ok := m.selected()
+	if !ok {
+		return
+	}
+	if r.task.Done {
+		if err := m.manager.UndoDone(r.task.ID); err != nil {
+			m.status = "Error undoing done: " + err.Error()
+			return
+		}
+		m.status = "Task marked as not done."
+	} else {
+		if err := m.manager.MarkDone(r.task.ID); err != nil {
+			m.status = "Error marking done: " + err.Error()
+			return
+		}
+		m.status = "Task marked as done."
+	}
+	m.Refresh()
+}
+
+func (m *Model) selected() (row, bool) {
+	if m.cursor < 0 || m.cursor >= len(m.rows) {
+		return row{}, false
+	}
+	return m.rows[m.cursor], true
+}
+
+func (m *Model) moveCursor(delta int) {
+	m.cursor += delta
+	m.clampCursor()
+}
+
+func (m *Model) clampCursor() {
+	if m.cursor >= len(m.rows) {
+		m.cursor = len(m.rows) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+	m.scroll()
+}
+
+// scroll keeps the cursor inside the visible part of the list.
+func (m *Model) scroll() {
+	height := m.listHeight()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+height {
+		m.offset = m.cursor - height + 1
+	}
+	if m.offset < 0 {
+		m.offset = 0
+	}
+}
+
+func (m *Model) showDetail() bool {
+	return m.height >= detailHeight+6
+}
+
+// listHeight is the number of task rows that fit between the header and
+// the detail pane and footer.
+func (m *Model) listHeight() int {
+	height := m.height - 2
+	if m.showDetail() {
+		height -= detailHeight
+	}
+	if height < 1 {
+		height = 1
+	}
+	return height
+}
+
+// View renders the whole screen as newline-separated lines, each at most
+// the model's width.
+func (m *Model) View() string {
+	lines := []string{m.header()}
+
+	height := m.listHeight()
+	for i := m.offset; i < m.offset+height; i++ {
+		if i >= len(m.rows) {
+			if i == 0 {
+				lines = append(lines, "  No tasks found.")
+			} else {
+				lines = append(lines, "")
+			}
+			continue
+		}
+		prefix := "  "
+		if i == m.cursor {
+			prefix = "> "
+		}
+		r := m.rows[i]
+		lines = append(lines, prefix+m.formatter.FormatTask(r.index, r.task))
+	}
+
+	if m.showDetail() {
+		lines = append(lines, m.detail()...)
+	}
+	lines = append(lines, m.footer())
+
+	for i := range lines {
+		lines[i] = display.Truncate(lines[i], m.width)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m *Model) header() string {
+	header := fmt.Sprintf("taskmgr — %d tasks", len(m.rows))
+	if m.filterText != "" {
+		header += "  filter: " + m.filterText
+	}
+	if m.options.ShowColors {
+		return display.Colorize(display.Bold, header)
+	}
+	return header
+}
+
+func (m *Model) detail() []string {
+	lines := []string{strings.Repeat("─", m.width)}
+	r, ok := m.selected()
+	if !ok {
+		for len(lines) < detailHeight {
+			lines = append(lines, "")
+		}
+		return lines
+	}
+
+	t := r.task
+	status := "pending"
+	if t.Done {
+		status = "done"
+	} else if t.DueDate != nil && t.DueDate.Before(time.Now()) {
+		status = "overdue"
+	}
+	due := "none"
+	if t.DueDate != nil {
+		due = t.DueDate.Format("2006-01-02")
+	}
+	tags := "none"
+	if len(t.Tags) > 0 {
+		tags = strings.Join(t.Tags, ", ")
+	}
+	project := "none"
+	if t.Project != "" {
+		project = t.Project
+	}
+
+	lines = append(lines,
+		fmt.Sprintf("Title:    %s", t.Title),
+		fmt.Sprintf("Status:   %s    Priority: %s", status, t.Priority),
+		fmt.Sprintf("Due:      %s    Created: %s", due, t.CreatedAt.Format("2006-01-02 15:04")),
+		fmt.Sprintf("Tags:     %s    Project: %s", tags, project),
+		fmt.Sprintf("Details:  %s", t.Description),
+	)
+	return lines
+}
+
+func (m *Model) footer() string {
+	switch m.mode {
+	case modeFilter:
+		return "Filter: " + m.input + "_"
+	case modeAdd:
+		return "Add: " + m.input + "_"
+	case modeEdit:
+		return "Edit: " + m.input + "_"
+	case modeTag:
+		return "Tags (prefix - to remove): " + m.input + "_"
+	}
+	if m.status != "" {
+		return m.status
+	}
+	return helpLine
+}
+
+// taskInput renders a task in the format accepted by parseTaskInput, so the
+// edit prompt can be prefilled with the current values.
+func taskInput(t tasks.Task) string {
+	parts := []string{t.Title, "--priority=" + t.Priority.String()}
+	if t.DueDate != nil {
+		parts = append(parts, "--due="+t.DueDate.Format("2006-01-02"))
+	}
+	if len(t.Tags) > 0 {
+		parts = append(parts, "--tags="+strings.Join(t.Tags, ","))
+	}
+	if t.Project != "" {
+		parts = append(parts, "--project="+t.Project)
+	}
+	return strings.Join(parts, " ")
+}
+
+// parseTaskInput parses a prompt line such as
+// "Fix bug --priority=high --due=tomorrow --tags=work --project=web" using the same
+// options as the add command. Words that are not flags form the title.
+func parseTaskInput(input string) (tasks.Task, error) {
+	var titleWords, flags []string
+	fields := strings.Fields(input)
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if !strings.HasPrefix(field, "--") {
+			titleWords = append(titleWords, field)
+			continue
+		}
+		flags = append(flags, field)
+		if !strings.Contains(field, "=") && i+1 < len(fields) {
+			i++
+			flags = append(flags, fields[i])
+		}
+	}
+
+	title := strings.Join(titleWords, " ")
+	if title == "" {
+		return tasks.Task{}, fmt.Errorf("title is required")
+	}
+
+	opts := cli.ParseAddCommand(append([]string{title}, flags...))
+	t := tasks.Task{Title: opts.Title, Priority: tasks.Medium, Tags: opts.Tags, Project: opts.Project}
+	if opts.Priority != "" {
+		priority, err := tasks.ParsePriority(opts.Priority)
+		if err != nil {
+			return t, err
+		}
+		t.Priority = priority
+	}
+	if opts.Due != "" {
+		dueDate, err := tasks.ParseDueDate(opts.Due)
+		if err != nil {
+			return t, err
+		}
+		t.DueDate = dueDate
+	}
+	return t, nil
+}
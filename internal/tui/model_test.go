@@ -0,0 +1,318 @@
+package tui
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"taskmgr/internal/display"
+	"taskmgr/internal/tasks"
+)
+
+func newTestModel(t *testing.T, taskList ...tasks.Task) (*Model, *tasks.TaskManager) {
+	t.Helper()
+	manager := tasks.NewTaskManager(tasks.NewFileStore(filepath.Join(t.TempDir(), "tasks.json")))
+	for _, task := range taskList {
+		if err := manager.Add(task); err != nil {
+			t.Fatalf("Error adding task: %v", err)
+		}
+	}
+	m := NewModel(manager, display.DisplayOptions{ShowPriority: true, ShowTags: true})
+	m.SetSize(80, 24)
+	return m, manager
+}
+
+func typeText(m *Model, text string) {
+	for _, r := range text {
+		m.HandleKey(Key{Type: KeyRune, Rune: r})
+	}
+}
+
+func TestModelNavigation(t *testing.T) {
+	m, _ := newTestModel(t,
+		tasks.Task{Title: "First"},
+		tasks.Task{Title: "Second"},
+		tasks.Task{Title: "Third"},
+	)
+
+	if m.cursor != 0 {
+		t.Fatalf("Expected cursor at 0, got %d", m.cursor)
+	}
+
+	m.HandleKey(Key{Type: KeyRune, Rune: 'j'})
+	m.HandleKey(Key{Type: KeyDown})
+	if m.cursor != 2 {
+		t.Errorf("Expected cursor at 2 after moving down twice, got %d", m.cursor)
+	}
+
+	// Moving past the end stays on the last row
+	m.HandleKey(Key{Type: KeyDown})
+	if m.cursor != 2 {
+		t.Errorf("Expected cursor to stay at 2, got %d", m.cursor)
+	}
+
+	m.HandleKey(Key{Type: KeyRune, Rune: 'k'})
+	if m.cursor != 1 {
+		t.Errorf("Expected cursor at 1 after moving up, got %d", m.cursor)
+	}
+
+	m.HandleKey(Key{Type: KeyHome})
+	if m.cursor != 0 {
+		t.Errorf("Expected cursor at 0 after Home, got %d", m.cursor)
+	}
+
+	m.HandleKey(Key{Type: KeyRune, Rune: 'G'})
+	if m.cursor != 2 {
+		t.Errorf("Expected cursor at 2 after G, got %d", m.cursor)
+	}
+
+	m.HandleKey(Key{Type: KeyRune, Rune: 'q'})
+	if !m.Quitting() {
+		t.Error("Expected q to quit")
+	}
+}
+
+func TestModelScrolling(t *testing.T) {
+	var taskList []tasks.Task
+	for i := 0; i < 30; i++ {
+		taskList = append(taskList, tasks.Task{Title: "Task"})
+	}
+	m, _ := newTestModel(t, taskList...)
+	m.SetSize(80, 10) // too small for the detail pane: 8 list rows
+
+	m.HandleKey(Key{Type: KeyPgDn})
+	if m.cursor != 8 {
+		t.Errorf("Expected cursor at 8 after page down, got %d", m.cursor)
+	}
+	if m.offset != 1 {
+		t.Errorf("Expected offset 1, got %d", m.offset)
+	}
+
+	lines := strings.Split(m.View(), "\n")
+	if len(lines) != 10 {
+		t.Errorf("Expected 10 lines, got %d", len(lines))
+	}
+	for i, line := range lines {
+		if display.VisibleWidth(line) > 80 {
+			t.Errorf("Line %d exceeds width: %q", i, line)
+		}
+	}
+}
+
+func TestModelFilter(t *testing.T) {
+	m, _ := newTestModel(t,
+		tasks.Task{Title: "Work task", Tags: []string{"work"}, Priority: tasks.High},
+		tasks.Task{Title: "Home task", Tags: []string{"home"}, Priority: tasks.Low},
+		tasks.Task{Title: "Other work", Tags: []string{"work"}, Priority: tasks.Low},
+	)
+
+	m.HandleKey(Key{Type: KeyRune, Rune: '/'})
+	typeText(m, "--tag=work")
+	m.HandleKey(Key{Type: KeyEnter})
+
+	if len(m.rows) != 2 {
+		t.Fatalf("Expected 2 rows for tag filter, got %d", len(m.rows))
+	}
+	// Rows keep their store index so actions target the right task
+	if m.rows[1].index != 2 {
+		t.Errorf("Expected second row to have store index 2, got %d", m.rows[1].index)
+	}
+	if !strings.Contains(m.View(), "filter: --tag=work") {
+		t.Error("Expected header to show the active filter")
+	}
+
+	// Filters combine, same flags as the list command
+	m.HandleKey(Key{Type: KeyRune, Rune: '/'})
+	typeText(m, " --priority=high")
+	m.HandleKey(Key{Type: KeyEnter})
+	if len(m.rows) != 1 || m.rows[0].task.Title != "Work task" {
+		t.Errorf("Expected only 'Work task', got %v", m.rows)
+	}
+
+	// Invalid filters leave the current one in place
+	m.HandleKey(Key{Type: KeyRune, Rune: '/'})
+	typeText(m, "--priority=bogus")
+	m.HandleKey(Key{Type: KeyEnter})
+	if len(m.rows) != 1 || !strings.Contains(m.status, "Error parsing filter") {
+		t.Errorf("Expected error status and unchanged rows, got %q and %v", m.status, m.rows)
+	}
+
+	// Escape clears the filter
+	m.HandleKey(Key{Type: KeyEsc})
+	if len(m.rows) != 3 {
+		t.Errorf("Expected 3 rows after clearing filter, got %d", len(m.rows))
+	}
+}
+
+func TestModelAddEditComplete(t *testing.T) {
+	m, manager := newTestModel(t, tasks.Task{Title: "Existing"})
+
+	// Add with flags
+	m.HandleKey(Key{Type: KeyRune, Rune: 'a'})
+	typeText(m, "Write report --priority high --due=2025-07-01 --tags=work,docs --project=q3")
+	m.HandleKey(Key{Type: KeyEnter})
+
+	list := manager.List()
+	if len(list) != 2 {
+		t.Fatalf("Expected 2 tasks after add, got %d", len(list))
+	}
+	added := list[1]
+	if added.Title != "Write report" || added.Priority != tasks.High {
+		t.Errorf("Expected 'Write report' with high priority, got %v", added)
+	}
+	if added.DueDate == nil || added.DueDate.Format("2006-01-02") != "2025-07-01" {
+		t.Errorf("Expected due date 2025-07-01, got %v", added.DueDate)
+	}
+	if !added.HasTag("docs") {
+		t.Errorf("Expected tag 'docs', got %v", added.Tags)
+	}
+	if added.Project != "q3" {
+		t.Errorf("Expected project 'q3', got %q", added.Project)
+	}
+	if m.status != "Task added." {
+		t.Errorf("Expected status 'Task added.', got %q", m.status)
+	}
+
+	// Edit prompt is prefilled with the current values
+	m.HandleKey(Key{Type: KeyEnd})
+	m.HandleKey(Key{Type: KeyRune, Rune: 'e'})
+	if m.input != "Write report --priority=high --due=2025-07-01 --tags=work,docs --project=q3" {
+		t.Errorf("Unexpected edit prefill: %q", m.input)
+	}
+	m.input = "Write final report --priority=low"
+	m.HandleKey(Key{Type: KeyEnter})
+
+	edited := manager.List()[1]
+	if edited.Title != "Write final report" || edited.Priority != tasks.Low || edited.DueDate != nil {
+		t.Errorf("Expected edited task, got %v", edited)
+	}
+	if edited.CreatedAt != added.CreatedAt {
+		t.Error("Editing should keep the creation time")
+	}
+
+	// Toggle done and back
+	m.HandleKey(Key{Type: KeyRune, Rune: 'x'})
+	if !manager.List()[1].Done {
+		t.Error("Expected task to be done after x")
+	}
+	m.HandleKey(Key{Type: KeyRune, Rune: ' '})
+	if manager.List()[1].Done {
+		t.Error("Expected task to be pending after space")
+	}
+
+	// Escape cancels input without changes
+	m.HandleKey(Key{Type: KeyRune, Rune: 'a'})
+	typeText(m, "Discarded")
+	m.HandleKey(Key{Type: KeyEsc})
+	if len(manager.List()) != 2 {
+		t.Error("Cancelled add should not create a task")
+	}
+
+	// Empty title is rejected
+	m.HandleKey(Key{Type: KeyRune, Rune: 'a'})
+	typeText(m, "--priority=high")
+	m.HandleKey(Key{Type: KeyEnter})
+	if len(manager.List()) != 2 || !strings.Contains(m.status, "title is required") {
+		t.Errorf("Expected title error, got status %q", m.status)
+	}
+}
+
+func TestModelTagging(t *testing.T) {
+	m, manager := newTestModel(t, tasks.Task{Title: "Tagged", Tags: []string{"old"}})
+
+	m.HandleKey(Key{Type: KeyRune, Rune: 't'})
+	typeText(m, "new -old")
+	m.HandleKey(Key{Type: KeyEnter})
+
+	task := manager.List()[0]
+	if !task.HasTag("new") || task.HasTag("old") {
+		t.Errorf("Expected tags [new], got %v", task.Tags)
+	}
+
+	// Backspace edits the prompt
+	m.HandleKey(Key{Type: KeyRune, Rune: 't'})
+	typeText(m, "abcd")
+	m.HandleKey(Key{Type: KeyBackspace})
+	m.HandleKey(Key{Type: KeyEnter})
+	if !manager.List()[0].HasTag("abc") {
+		t.Errorf("Expected tag 'abc', got %v", manager.List()[0].Tags)
+	}
+}
+
+func TestModelDetailAndRefresh(t *testing.T) {
+	m, manager := newTestModel(t, tasks.Task{Title: "Detailed", Description: "Some details", Tags: []string{"work"}})
+
+	view := m.View()
+	for _, want := range []string{"Title:    Detailed", "Details:  Some details", "Tags:     work    Project: none", helpLine} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected view to contain %q, got:\n%s", want, view)
+		}
+	}
+
+	// Changes made outside the model show up after a refresh
+	if err := manager.Add(tasks.Task{Title: "External"}); err != nil {
+		t.Fatalf("Error adding task: %v", err)
+	}
+	m.Refresh()
+	if !strings.Contains(m.View(), "External") {
+		t.Error("Expected refreshed view to contain the external task")
+	}
+}
+
+func TestModelEmpty(t *testing.T) {
+	m, _ := newTestModel(t)
+
+	if !strings.Contains(m.View(), "No tasks found.") {
+		t.Error("Expected empty list message")
+	}
+
+	// Actions on an empty list are no-ops
+	m.HandleKey(Key{Type: KeyRune, Rune: 'x'})
+	m.HandleKey(Key{Type: KeyRune, Rune: 'e'})
+	m.HandleKey(Key{Type: KeyRune, Rune: 't'})
+	if m.mode != modeNormal {
+		t.Errorf("Expected normal mode, got %v", m.mode)
+	}
+
+	m.HandleKey(Key{Type: KeyCtrlC})
+	if !m.Quitting() {
+		t.Error("Expected Ctrl-C to quit")
+	}
+}
+
+func TestModelTargetsTaskByID(t *testing.T) {
+	m, manager := newTestModel(t, tasks.Task{Title: "First"}, tasks.Task{Title: "Second"})
+	m.HandleKey(Key{Type: KeyEnd})
+
+	// Another client removes the first task before the model refreshes, so
+	// the selected row's index now belongs to no task or another one
+	if err := manager.Remove("0"); err != nil {
+		t.Fatalf("Error removing task: %v", err)
+	}
+	m.HandleKey(Key{Type: KeyRune, Rune: 'x'})
+	list := manager.List()
+	if len(list) != 1 || list[0].Title != "Second" || !list[0].Done {
+		t.Fatalf("Expected the selected task marked done, got %v", list)
+	}
+
+	m.HandleKey(Key{Type: KeyRune, Rune: 't'})
+	typeText(m, "moved")
+	m.HandleKey(Key{Type: KeyEnter})
+	if !manager.List()[0].HasTag("moved") {
+		t.Errorf("Expected the selected task tagged, got %v", manager.List()[0].Tags)
+	}
+}
+
+func TestModelRefreshKeepsSelectedTask(t *testing.T) {
+	m, manager := newTestModel(t, tasks.Task{Title: "A"}, tasks.Task{Title: "B"}, tasks.Task{Title: "C"})
+	m.HandleKey(Key{Type: KeyDown})
+
+	// Removing the task above shifts the selected one up a row
+	if err := manager.Remove("0"); err != nil {
+		t.Fatalf("Error removing task: %v", err)
+	}
+	m.Refresh()
+	if r, ok := m.selected(); !ok || r.task.Title != "B" {
+		t.Errorf("Expected the cursor to stay on B, got %+v", r.task)
+	}
+}
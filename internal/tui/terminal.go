@@ -0,0 +1,94 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const (
+	enterAltScreen = "\033[?1049h"
+	exitAltScreen  = "\033[?1049l"
+	hideCursor     = "\033[?25l"
+	showCursor     = "\033[?25h"
+	clearScreen    = "\033[H\033[2J"
+)
+
+// terminal is the controlling terminal switched to raw mode and the
+// alternate screen for the lifetime of an interactive session.
+type terminal struct {
+	in    *os.File
+	out   *os.File
+	state *term.State
+
+	keys chan Key
+	errs chan error
+}
+
+// openTerminal puts stdin into raw mode and starts reading key presses.
+func openTerminal() (*terminal, error) {
+	in, out := os.Stdin, os.Stdout
+	if !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) {
+		return nil, fmt.Errorf("an interactive terminal is required")
+	}
+
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return nil, err
+	}
+
+	t := &terminal{
+		in:    in,
+		out:   out,
+		state: state,
+		keys:  make(chan Key),
+		errs:  make(chan error, 1),
+	}
+	fmt.Fprint(out, enterAltScreen+hideCursor)
+
+	go func() {
+		r := bufio.NewReader(in)
+		for {
+			k, err := ReadKey(r)
+			if err != nil {
+				t.errs <- err
+				return
+			}
+			t.keys <- k
+		}
+	}()
+	return t, nil
+}
+
+// Close restores the screen and the terminal mode.
+func (t *terminal) Close() error {
+	fmt.Fprint(t.out, showCursor+exitAltScreen)
+	return term.Restore(int(t.in.Fd()), t.state)
+}
+
+// Size returns the current width and height, falling back to 80x24.
+func (t *terminal) Size() (int, int) {
+	width, height, err := term.GetSize(int(t.out.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		return 80, 24
+	}
+	return width, height
+}
+
+// Draw replaces the screen contents with view. Raw mode does not translate
+// newlines, so each line ends with an explicit carriage return.
+func (t *terminal) Draw(view string) {
+	fmt.Fprint(t.out, clearScreen+strings.ReplaceAll(view, "\n", "\r\n"))
+}
+
+// readError converts the end of input into a clean exit.
+func readError(err error) error {
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,64 @@
+// Package tui implements the full-screen interactive interface started by
+// "taskmgr tui".
+package tui
+
+import (
+	"time"
+
+	"taskmgr/internal/display"
+	"taskmgr/internal/tasks"
+)
+
+// Options configures Run.
+type Options struct {
+	Display display.DisplayOptions
+	// WatchFile is polled for changes made by other processes, such as a
+	// second taskmgr invocation; the list refreshes when it changes.
+	WatchFile string
+}
+
+// Run takes over the terminal and blocks until the user quits.
+func Run(manager *tasks.TaskManager, opts Options) error {
+	t, err := openTerminal()
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	var changes <-chan struct{}
+	if opts.WatchFile != "" {
+		watcher := tasks.NewWatcher(opts.WatchFile, 500*time.Millisecond)
+		defer watcher.Stop()
+		changes = watcher.C
+	}
+
+	// Poll for resizes instead of relying on SIGWINCH, which is not
+	// available on every platform
+	resize := time.NewTicker(250 * time.Millisecond)
+	defer resize.Stop()
+
+	m := NewModel(manager, opts.Display)
+	width, height := t.Size()
+	m.SetSize(width, height)
+	t.Draw(m.View())
+
+	for !m.Quitting() {
+		select {
+		case k := <-t.keys:
+			m.HandleKey(k)
+		case <-changes:
+			m.Refresh()
+		case <-resize.C:
+			w, h := t.Size()
+			if w == width && h == height {
+				continue
+			}
+			width, height = w, h
+			m.SetSize(width, height)
+		case err := <-t.errs:
+			return readError(err)
+		}
+		t.Draw(m.View())
+	}
+	return nil
+}